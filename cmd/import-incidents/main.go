@@ -0,0 +1,88 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/joho/godotenv"
+
+	"github.com/ruslanuskembaev/geo-alerts-system/internal/config"
+	"github.com/ruslanuskembaev/geo-alerts-system/internal/domain"
+	"github.com/ruslanuskembaev/geo-alerts-system/internal/repository"
+	"github.com/ruslanuskembaev/geo-alerts-system/internal/service"
+)
+
+// Массовый импорт инцидентов из CSV или GeoJSON напрямую в базу.
+//
+//	go run ./cmd/import-incidents -file zones.geojson -tenant default -dry-run
+func main() {
+	file := flag.String("file", "-", "path to CSV or GeoJSON file (- for stdin)")
+	format := flag.String("format", "", "csv or geojson (default: by file extension)")
+	tenant := flag.String("tenant", domain.DefaultTenantID, "tenant ID")
+	actor := flag.String("actor", "cli", "actor recorded in audit log")
+	dryRun := flag.Bool("dry-run", false, "validate rows without creating incidents")
+	flag.Parse()
+
+	_ = godotenv.Load()
+	cfg := config.Load()
+
+	importFormat := domain.ImportFormat(strings.ToLower(*format))
+	if importFormat == "" {
+		importFormat = formatFromExtension(*file)
+	}
+
+	var input io.Reader = os.Stdin
+	if *file != "-" {
+		f, err := os.Open(*file)
+		if err != nil {
+			log.Fatal("Failed to open import file:", err)
+		}
+		defer f.Close()
+		input = f
+	}
+
+	dbPool, err := repository.NewPostgresPool(cfg)
+	if err != nil {
+		log.Fatal("Failed to connect to PostgreSQL:", err)
+	}
+	defer dbPool.Close()
+	redisClient := repository.NewRedisClient(cfg)
+	defer redisClient.Close()
+
+	incidentService := service.NewIncidentService(
+		repository.NewIncidentRepository(dbPool),
+		repository.NewIncidentCache(redisClient, cfg.CacheTTL),
+		repository.NewLocationCheckRepository(dbPool),
+	)
+
+	ctx := domain.WithActor(domain.WithTenant(context.Background(), *tenant), *actor)
+	result, err := incidentService.Import(ctx, importFormat, input, *dryRun)
+	if err != nil {
+		log.Fatal("Import failed:", err)
+	}
+
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(result); err != nil {
+		log.Fatal(err)
+	}
+	if len(result.Errors) > 0 {
+		os.Exit(1)
+	}
+}
+
+func formatFromExtension(path string) domain.ImportFormat {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".csv":
+		return domain.ImportFormatCSV
+	case ".geojson", ".json":
+		return domain.ImportFormatGeoJSON
+	}
+	return ""
+}
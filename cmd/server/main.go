@@ -28,6 +28,12 @@ func main() {
 	}
 
 	cfg := config.Load()
+	if err := cfg.LocationPrivacy.Validate(); err != nil {
+		log.Fatal("Invalid LOCATION_PRIVACY settings:", err)
+	}
+	if err := cfg.UserAuditKey.Validate(); err != nil {
+		log.Fatal("Invalid USER_AUDIT_KEY (required unless LOCATION_PRIVACY_USER_KEYS is set):", err)
+	}
 	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
 	defer stop()
 
@@ -46,17 +52,38 @@ func main() {
 
 	// Инициализация слоёв
 	incidentRepo := repository.NewIncidentRepository(dbPool)
-	checkRepo := repository.NewLocationCheckRepository(dbPool)
-	cache := repository.NewIncidentCache(redisClient, cfg.CacheTTL)
+	checkRepo := repository.NewLocationCheckRepository(dbPool).WithPrivacy(cfg.LocationPrivacy)
+	cache := repository.NewLocalIncidentCache(repository.NewIncidentCache(redisClient, cfg.CacheTTL), cfg.CacheTTL)
+	tileCache := repository.NewTileCache(redisClient, cfg.CacheTTL)
 	queue := repository.NewWebhookQueue(redisClient)
 	systemRepo := repository.NewSystemRepository(dbPool, redisClient)
+	rateLimiter := repository.NewRateLimiter(redisClient)
+	idempotencyStore := repository.NewIdempotencyStore(redisClient)
+	auditRepo := repository.NewAuditRepository(dbPool)
+	capRepo := repository.NewCAPRepository(dbPool)
+	partitionRepo := repository.NewLocationCheckPartitionRepository(dbPool)
+	userDataRepo := repository.NewUserDataRepository(dbPool)
+	deliveryRepo := repository.NewWebhookDeliveryRepository(dbPool)
+	outboxRepo := repository.NewWebhookOutboxRepository(dbPool)
+	pseudonymRepo := repository.NewUserPseudonymRepository(dbPool)
+	retroRepo := repository.NewRetroactiveAlertRepository(dbPool)
 
-	incidentService := service.NewIncidentService(incidentRepo, cache, checkRepo)
-	locationService := service.NewLocationService(incidentRepo, cache, checkRepo, queue)
+	incidentService := service.NewIncidentService(incidentRepo, cache, checkRepo).
+		WithRetroactiveAlerts(retroRepo, cfg.RetroactiveAlertLookback, cfg.LocationPrivacy)
+	locationService := service.NewLocationService(incidentRepo, cache, checkRepo)
 	healthService := service.NewHealthService(systemRepo, cfg.HealthTimeout)
+	auditService := service.NewAuditService(auditRepo)
+	tokenService := service.NewTokenService(cfg.UserTokenSecret, cfg.UserTokenTTL)
+	rateLimitService := service.NewRateLimitService(rateLimiter, cfg.RateLimitPerUser, cfg.RateLimitPerIP, cfg.RateLimitWindow)
+	idempotencyService := service.NewIdempotencyService(idempotencyStore, cfg.IdempotencyTTL)
+	capService := service.NewCAPService(capRepo, cache)
+	tileService := service.NewTileService(incidentRepo, cache, tileCache)
+	historyService := service.NewLocationHistoryService(incidentRepo, checkRepo, cfg.LocationPrivacy)
+	userDataService := service.NewUserDataService(userDataRepo, queue, rateLimitService, cfg.LocationPrivacy, cfg.UserAuditKey)
 
-	webhookSender := service.NewWebhookSender(cfg.WebhookURL, cfg.WebhookTimeout)
-	webhookWorker := service.NewWebhookWorker(queue, webhookSender, cfg.WebhookRetryAttempts, cfg.WebhookRetryDelay)
+	webhookSender := service.NewWebhookSender(cfg.WebhookURL, cfg.TenantWebhookURLs, cfg.WebhookTimeout)
+	webhookWorker := service.NewWebhookWorker(queue, webhookSender, cfg.WebhookRetryAttempts, cfg.WebhookRetryDelay).
+		WithDeliveryLog(deliveryRepo)
 	workerCtx, cancelWorker := context.WithCancel(context.Background())
 	var wg sync.WaitGroup
 	wg.Add(1)
@@ -65,12 +92,100 @@ func main() {
 		webhookWorker.Start(workerCtx)
 	}()
 
-	incidentHandler := handler.NewIncidentHandler(incidentService, cfg.StatsTimeWindow)
-	locationHandler := handler.NewLocationHandler(locationService)
+	// Подписка на инвалидации кеша инцидентов с других реплик
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		cache.Start(workerCtx)
+	}()
+
+	// Сброс кеша при изменениях incidents в БД в обход API (LISTEN/NOTIFY)
+	incidentWatcher := service.NewIncidentChangeWatcher(repository.NewIncidentChangeListener(dbPool), cache)
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		incidentWatcher.Start(workerCtx)
+	}()
+
+	outboxRelay := service.NewOutboxRelay(outboxRepo, queue, cfg.WebhookOutboxInterval, cfg.WebhookOutboxBatchSize, cfg.WebhookOutboxRetention)
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		outboxRelay.Start(workerCtx)
+	}()
+
+	retroactiveAlerter := service.NewRetroactiveAlerter(retroRepo, incidentRepo, checkRepo, incidentService.RetroactiveLookback(), cfg.RetroactiveAlertInterval)
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		retroactiveAlerter.Start(workerCtx)
+	}()
+
+	incidentPurger := service.NewIncidentPurger(incidentRepo, cfg.IncidentPurgeAfter, cfg.IncidentPurgeInterval)
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		incidentPurger.Start(workerCtx)
+	}()
+
+	incidentExpirer := service.NewIncidentExpirer(incidentRepo, cache, cfg.IncidentExpireInterval)
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		incidentExpirer.Start(workerCtx)
+	}()
+
+	statsRollupPruner := service.NewStatsRollupPruner(checkRepo, cfg.StatsRollupPruneInterval)
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		statsRollupPruner.Start(workerCtx)
+	}()
+
+	checkRetention := service.NewLocationCheckRetention(partitionRepo, cfg.LocationCheckRetention, cfg.LocationCheckPartitionsAhead, cfg.LocationCheckPartitionInterval)
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		checkRetention.Start(workerCtx)
+	}()
+
+	pseudonymBackfill := service.NewPseudonymBackfill(pseudonymRepo, cfg.LocationPrivacy, cfg.PseudonymBackfillInterval, cfg.PseudonymBackfillBatchSize)
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		pseudonymBackfill.Start(workerCtx)
+	}()
+
+	capFeedPoller := service.NewCAPFeedPoller(capService, cfg.CAPFeedURL, cfg.CAPFeedTenant, cfg.CAPFeedInterval, cfg.CAPFeedTimeout)
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		capFeedPoller.Start(workerCtx)
+	}()
+
+	incidentHandler := handler.NewIncidentHandler(incidentService, cfg.StatsTimeWindow).
+		WithExportTimeout(cfg.ExportWriteTimeout)
+	locationHandler := handler.NewLocationHandler(locationService, rateLimitService)
 	healthHandler := handler.NewHealthHandler(healthService)
+	authHandler := handler.NewAuthHandler(tokenService)
+	auditHandler := handler.NewAuditHandler(auditService)
+	capHandler := handler.NewCAPHandler(capService)
+	trustedProxies, err := handler.ParseTrustedProxies(cfg.TrustedProxies)
+	if err != nil {
+		log.Fatal("Invalid TRUSTED_PROXIES:", err)
+	}
+	feedHandler := handler.NewFeedHandler(incidentService, cfg.TenantAPIKeys, cfg.CAPSender).
+		WithTrustedProxies(trustedProxies)
+	tileHandler := handler.NewTileHandler(tileService)
+	userDataHandler := handler.NewUserDataHandler(userDataService)
+	historyHandler := handler.NewLocationHistoryHandler(historyService)
 
 	// HTTP сервер
 	r := gin.Default()
+	// Без доверенных прокси ClientIP — адрес соединения: иначе X-Forwarded-For обходит лимит по IP
+	if err := r.SetTrustedProxies(cfg.TrustedProxies); err != nil {
+		log.Fatal("Invalid TRUSTED_PROXIES:", err)
+	}
 
 	// API v1
 	api := r.Group("/api/v1")
@@ -78,31 +193,94 @@ func main() {
 		// Health check (публичный)
 		api.GET("/system/health", healthHandler.Health)
 
-		// Location check (публичный)
-		api.POST("/location/check", locationHandler.Check)
+		// Location check (публичный, опционально с ключом клиента или токеном пользователя)
+		api.POST("/location/check",
+			handler.IPRateLimitMiddleware(rateLimitService),
+			handler.ClientAuthMiddleware(tokenService, cfg.ClientAPIKeys, cfg.LocationAuthRequired),
+			handler.IdempotencyMiddleware(idempotencyService),
+			locationHandler.Check,
+		)
+
+		// Ленты активных инцидентов для партнёров (публичные, с условными GET)
+		feeds := api.Group("/feeds/:tenant")
+		feeds.Use(handler.IPRateLimitMiddleware(rateLimitService))
+		{
+			feeds.GET("/atom", feedHandler.Atom)
+			feeds.GET("/cap", feedHandler.CAP)
+		}
+
+		// Журнал аудита (защищённый endpoint)
+		api.GET("/audit", handler.AuthMiddleware(cfg.TenantAPIKeys), auditHandler.List)
+
+		// Векторные тайлы зон инцидентов (защищённый endpoint)
+		api.GET("/tiles/:z/:x/:y", handler.AuthMiddleware(cfg.TenantAPIKeys), tileHandler.Incidents)
+
+		// История проверок пользователя (защищённый endpoint)
+		api.GET("/users/:user_id/checks", handler.AuthMiddleware(cfg.TenantAPIKeys), historyHandler.UserChecks)
+
+		// Токены пользователей (защищённый endpoint)
+		api.POST("/auth/tokens", handler.AuthMiddleware(cfg.TenantAPIKeys), authHandler.IssueToken)
 
 		// Incidents (защищённые endpoints)
 		incidents := api.Group("/incidents")
-		incidents.Use(handler.AuthMiddleware(cfg.APIKey))
+		incidents.Use(handler.AuthMiddleware(cfg.TenantAPIKeys))
 		{
-			incidents.POST("", incidentHandler.Create)
+			incidents.POST("", handler.IdempotencyMiddleware(idempotencyService), incidentHandler.Create)
 			incidents.GET("", incidentHandler.List)
+			incidents.POST("/preview", incidentHandler.Preview)
+			incidents.POST("/import", incidentHandler.Import)
+			incidents.GET("/export", incidentHandler.Export)
+			incidents.POST("/cap", capHandler.Ingest)
 			incidents.GET("/stats", incidentHandler.Stats)
+			incidents.GET("/stats/heatmap", incidentHandler.Heatmap)
+			incidents.GET("/stats/timeseries", incidentHandler.StatsSeries)
 			incidents.GET("/:id", incidentHandler.GetByID)
+			incidents.GET("/:id/history", auditHandler.IncidentHistory)
+			incidents.GET("/:id/stats", incidentHandler.IncidentStats)
+			incidents.GET("/:id/users", historyHandler.IncidentUsers)
 			incidents.PUT("/:id", incidentHandler.Update)
 			incidents.DELETE("/:id", incidentHandler.Delete)
+			incidents.POST("/:id/reactivate", incidentHandler.Reactivate)
+		}
+
+		// Администрирование (ключи ADMIN_API_KEYS)
+		admin := api.Group("/admin")
+		admin.Use(handler.AuthMiddleware(cfg.AdminAPIKeys))
+		{
+			admin.DELETE("/incidents/:id", incidentHandler.Purge)
+			admin.GET("/users/:user_id/data", userDataHandler.Export)
+			admin.DELETE("/users/:user_id/data", userDataHandler.Erase)
 		}
 	}
 
 	fmt.Println("Available endpoints:")
 	fmt.Println("   GET  /api/v1/system/health          (public)")
 	fmt.Println("   POST /api/v1/location/check         (public)")
+	fmt.Println("   GET  /api/v1/feeds/:tenant/atom     (public)")
+	fmt.Println("   GET  /api/v1/feeds/:tenant/cap      (public)")
+	fmt.Println("   POST /api/v1/auth/tokens            (protected)")
 	fmt.Println("   POST /api/v1/incidents              (protected)")
 	fmt.Println("   GET  /api/v1/incidents              (protected)")
+	fmt.Println("   POST /api/v1/incidents/preview      (protected)")
+	fmt.Println("   POST /api/v1/incidents/import       (protected)")
+	fmt.Println("   GET  /api/v1/incidents/export       (protected)")
+	fmt.Println("   POST /api/v1/incidents/cap          (protected)")
 	fmt.Println("   GET  /api/v1/incidents/stats         (protected)")
+	fmt.Println("   GET  /api/v1/incidents/stats/heatmap (protected)")
+	fmt.Println("   GET  /api/v1/incidents/stats/timeseries (protected)")
 	fmt.Println("   GET  /api/v1/incidents/:id          (protected)")
+	fmt.Println("   GET  /api/v1/incidents/:id/history  (protected)")
+	fmt.Println("   GET  /api/v1/incidents/:id/stats    (protected)")
+	fmt.Println("   GET  /api/v1/incidents/:id/users    (protected)")
 	fmt.Println("   PUT  /api/v1/incidents/:id          (protected)")
 	fmt.Println("   DELETE /api/v1/incidents/:id        (protected)")
+	fmt.Println("   POST /api/v1/incidents/:id/reactivate (protected)")
+	fmt.Println("   DELETE /api/v1/admin/incidents/:id  (admin)")
+	fmt.Println("   GET  /api/v1/admin/users/:user_id/data (admin)")
+	fmt.Println("   DELETE /api/v1/admin/users/:user_id/data (admin)")
+	fmt.Println("   GET  /api/v1/audit                  (protected)")
+	fmt.Println("   GET  /api/v1/users/:user_id/checks  (protected)")
+	fmt.Println("   GET  /api/v1/tiles/:z/:x/:y.mvt     (protected)")
 	fmt.Println()
 	fmt.Printf("Server running at http://localhost:%s\n\n", cfg.ServerPort)
 
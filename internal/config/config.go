@@ -3,13 +3,23 @@ package config
 import (
 	"os"
 	"strconv"
+	"strings"
 	"time"
+
+	"github.com/ruslanuskembaev/geo-alerts-system/internal/domain"
 )
 
 type Config struct {
 	ServerPort string
 	APIKey     string
 
+	// Tenants: ключи операторов и URL вебхуков по арендаторам
+	TenantAPIKeys     map[string]string
+	TenantWebhookURLs map[string]string
+
+	// Admin: ключи администраторов по арендаторам (tenant -> key)
+	AdminAPIKeys map[string]string
+
 	// Database
 	DBHost     string
 	DBPort     string
@@ -36,24 +46,86 @@ type Config struct {
 	WebhookRetryDelay    time.Duration
 	WebhookTimeout       time.Duration
 
+	// Outbox вебхуков: период опроса, размер выборки и срок хранения переданных задач (0 — бессрочно)
+	WebhookOutboxInterval  time.Duration
+	WebhookOutboxBatchSize int
+	WebhookOutboxRetention time.Duration
+
 	// Stats
 	StatsTimeWindow time.Duration
 
 	// Cache
 	CacheTTL time.Duration
 
+	// Purge деактивированных инцидентов (0 — выключено)
+	IncidentPurgeAfter    time.Duration
+	IncidentPurgeInterval time.Duration
+
+	// Автоматическая деактивация инцидентов по expires_at (0 — выключено)
+	IncidentExpireInterval time.Duration
+
+	// Очистка отметок пользователей закрытых корзин статистики (0 — выключено)
+	StatsRollupPruneInterval time.Duration
+
+	// Секции и срок хранения проверок координат (retention 0 — хранить бессрочно)
+	LocationCheckRetention         time.Duration
+	LocationCheckPartitionsAhead   int
+	LocationCheckPartitionInterval time.Duration
+
+	// Ретроактивные оповещения при создании и изменении зоны (0 — выключено)
+	RetroactiveAlertLookback time.Duration
+	RetroactiveAlertInterval time.Duration
+
+	// Приватность сохраняемых проверок: режим координат, огрубление, псевдонимизация user_id
+	LocationPrivacy domain.LocationPrivacy
+	// Ключ HMAC для user_id в журнале аудита удалений ("id:secret"; по умолчанию текущий ключ
+	// псевдонимизации, без ключа сервис не запускается)
+	UserAuditKey domain.UserIDKey
+
+	// Перевод данных, сохранённых до включения псевдонимизации: повтор после ошибки и размер пачки
+	PseudonymBackfillInterval  time.Duration
+	PseudonymBackfillBatchSize int
+
+	// CAP-лента: опрос CAP-документа или Atom-ленты (пустой URL — выключено)
+	CAPFeedURL      string
+	CAPFeedTenant   string
+	CAPFeedInterval time.Duration
+	CAPFeedTimeout  time.Duration
+
+	// CAPSender значение sender в публикуемых CAP-сообщениях
+	CAPSender string
+
 	// HTTP server
+	// TrustedProxies адреса и подсети обратных прокси, которым доверяются X-Forwarded-* (пусто — никому)
+	TrustedProxies   []string
 	HTTPReadTimeout  time.Duration
 	HTTPWriteTimeout time.Duration
 	HTTPIdleTimeout  time.Duration
 	ShutdownTimeout  time.Duration
 
+	// ExportWriteTimeout срок записи потоковой выгрузки инцидентов вместо HTTPWriteTimeout
+	ExportWriteTimeout time.Duration
+
 	// Health
 	HealthTimeout time.Duration
+
+	// Location check client auth
+	LocationAuthRequired bool
+	ClientAPIKeys        map[string]string
+	UserTokenSecret      string
+	UserTokenTTL         time.Duration
+
+	// Rate limiting (0 — выключено)
+	RateLimitPerUser int
+	RateLimitPerIP   int
+	RateLimitWindow  time.Duration
+
+	// Idempotency-Key: срок хранения ответов (0 — выключено)
+	IdempotencyTTL time.Duration
 }
 
 func Load() *Config {
-	return &Config{
+	cfg := &Config{
 		ServerPort: getEnv("SERVER_PORT", "8080"),
 		APIKey:     getEnv("API_KEY", "dev_api_key_12345"),
 
@@ -79,17 +151,84 @@ func Load() *Config {
 		WebhookRetryDelay:    getEnvAsDuration("WEBHOOK_RETRY_DELAY_SECONDS", 5),
 		WebhookTimeout:       getEnvAsDuration("WEBHOOK_TIMEOUT_SECONDS", 5),
 
+		WebhookOutboxInterval:  time.Duration(getEnvAsInt("WEBHOOK_OUTBOX_INTERVAL_MS", 500)) * time.Millisecond,
+		WebhookOutboxBatchSize: getEnvAsInt("WEBHOOK_OUTBOX_BATCH_SIZE", 100),
+		WebhookOutboxRetention: time.Duration(getEnvAsInt("WEBHOOK_OUTBOX_RETENTION_HOURS", 24)) * time.Hour,
+
 		StatsTimeWindow: time.Duration(getEnvAsInt("STATS_TIME_WINDOW_MINUTES", 60)) * time.Minute,
 
 		CacheTTL: getEnvAsDuration("CACHE_TTL_SECONDS", 300),
 
+		IncidentPurgeAfter:    time.Duration(getEnvAsInt("INCIDENT_PURGE_AFTER_DAYS", 0)) * 24 * time.Hour,
+		IncidentPurgeInterval: time.Duration(getEnvAsInt("INCIDENT_PURGE_INTERVAL_MINUTES", 60)) * time.Minute,
+
+		IncidentExpireInterval: getEnvAsDuration("INCIDENT_EXPIRE_INTERVAL_SECONDS", 60),
+
+		StatsRollupPruneInterval: time.Duration(getEnvAsInt("STATS_ROLLUP_PRUNE_INTERVAL_MINUTES", 60)) * time.Minute,
+
+		LocationCheckRetention:         time.Duration(getEnvAsInt("LOCATION_CHECK_RETENTION_DAYS", 0)) * 24 * time.Hour,
+		LocationCheckPartitionsAhead:   getEnvAsInt("LOCATION_CHECK_PARTITIONS_AHEAD_MONTHS", 3),
+		LocationCheckPartitionInterval: time.Duration(getEnvAsInt("LOCATION_CHECK_PARTITION_INTERVAL_MINUTES", 60)) * time.Minute,
+
+		RetroactiveAlertLookback: time.Duration(getEnvAsInt("RETROACTIVE_ALERT_LOOKBACK_MINUTES", 15)) * time.Minute,
+		RetroactiveAlertInterval: getEnvAsDuration("RETROACTIVE_ALERT_INTERVAL_SECONDS", 1),
+
+		PseudonymBackfillInterval:  getEnvAsDuration("PSEUDONYM_BACKFILL_INTERVAL_SECONDS", 60),
+		PseudonymBackfillBatchSize: getEnvAsInt("PSEUDONYM_BACKFILL_BATCH_SIZE", 100),
+
+		CAPFeedURL:      getEnv("CAP_FEED_URL", ""),
+		CAPFeedTenant:   getEnv("CAP_FEED_TENANT", domain.DefaultTenantID),
+		CAPFeedInterval: getEnvAsDuration("CAP_FEED_INTERVAL_SECONDS", 300),
+		CAPFeedTimeout:  getEnvAsDuration("CAP_FEED_TIMEOUT_SECONDS", 10),
+
+		CAPSender: getEnv("CAP_SENDER", "geo-alerts-system"),
+
+		TrustedProxies:   getEnvAsList("TRUSTED_PROXIES"),
 		HTTPReadTimeout:  getEnvAsDuration("HTTP_READ_TIMEOUT_SECONDS", 5),
 		HTTPWriteTimeout: getEnvAsDuration("HTTP_WRITE_TIMEOUT_SECONDS", 10),
 		HTTPIdleTimeout:  getEnvAsDuration("HTTP_IDLE_TIMEOUT_SECONDS", 60),
 		ShutdownTimeout:  getEnvAsDuration("SHUTDOWN_TIMEOUT_SECONDS", 10),
 
+		ExportWriteTimeout: getEnvAsDuration("EXPORT_WRITE_TIMEOUT_SECONDS", 300),
+
 		HealthTimeout: getEnvAsDuration("HEALTH_TIMEOUT_SECONDS", 2),
+
+		LocationAuthRequired: getEnvAsBool("LOCATION_AUTH_REQUIRED", false),
+		ClientAPIKeys:        getEnvAsMap("CLIENT_API_KEYS"),
+		UserTokenSecret:      getEnv("USER_TOKEN_SECRET", ""),
+		UserTokenTTL:         getEnvAsDuration("USER_TOKEN_TTL_SECONDS", 900),
+
+		RateLimitPerUser: getEnvAsInt("RATE_LIMIT_PER_USER", 30),
+		RateLimitPerIP:   getEnvAsInt("RATE_LIMIT_PER_IP", 120),
+		RateLimitWindow:  getEnvAsDuration("RATE_LIMIT_WINDOW_SECONDS", 60),
+
+		IdempotencyTTL: time.Duration(getEnvAsInt("IDEMPOTENCY_TTL_HOURS", 24)) * time.Hour,
+	}
+
+	// API_KEY остаётся ключом арендатора по умолчанию
+	cfg.TenantAPIKeys = getEnvAsMap("TENANT_API_KEYS")
+	if _, ok := cfg.TenantAPIKeys[domain.DefaultTenantID]; !ok {
+		cfg.TenantAPIKeys[domain.DefaultTenantID] = cfg.APIKey
+	}
+	cfg.TenantWebhookURLs = getEnvAsMap("TENANT_WEBHOOK_URLS")
+	cfg.AdminAPIKeys = getEnvAsMap("ADMIN_API_KEYS")
+
+	cfg.LocationPrivacy = domain.LocationPrivacy{
+		Coordinates: domain.CoordinatePrivacy(getEnv("LOCATION_PRIVACY_COORDINATES", string(domain.CoordinatesExact))),
+		GridMeters:  float64(getEnvAsInt("LOCATION_PRIVACY_GRID_METERS", domain.DefaultPrivacyGridMeters)),
+		DangerOnly:  getEnvAsBool("LOCATION_PRIVACY_DANGER_ONLY", false),
 	}
+	for _, key := range getEnvAsPairs("LOCATION_PRIVACY_USER_KEYS") {
+		cfg.LocationPrivacy.Keys = append(cfg.LocationPrivacy.Keys, domain.UserIDKey{ID: key[0], Secret: key[1]})
+	}
+	for _, key := range getEnvAsPairs("USER_AUDIT_KEY") {
+		cfg.UserAuditKey = domain.UserIDKey{ID: key[0], Secret: key[1]}
+	}
+	if cfg.UserAuditKey.Secret == "" && len(cfg.LocationPrivacy.Keys) > 0 {
+		cfg.UserAuditKey = cfg.LocationPrivacy.Keys[0]
+	}
+
+	return cfg
 }
 
 func getEnv(key, defaultValue string) string {
@@ -110,3 +249,44 @@ func getEnvAsInt(key string, defaultValue int) int {
 func getEnvAsDuration(key string, defaultSeconds int) time.Duration {
 	return time.Duration(getEnvAsInt(key, defaultSeconds)) * time.Second
 }
+
+func getEnvAsBool(key string, defaultValue bool) bool {
+	valueStr := getEnv(key, "")
+	if value, err := strconv.ParseBool(valueStr); err == nil {
+		return value
+	}
+	return defaultValue
+}
+
+// getEnvAsList разбирает список вида "value1,value2"
+func getEnvAsList(key string) []string {
+	var result []string
+	for _, value := range strings.Split(getEnv(key, ""), ",") {
+		if value = strings.TrimSpace(value); value != "" {
+			result = append(result, value)
+		}
+	}
+	return result
+}
+
+// getEnvAsMap разбирает список вида "name1:value1,name2:value2"
+func getEnvAsMap(key string) map[string]string {
+	result := make(map[string]string)
+	for _, pair := range getEnvAsPairs(key) {
+		result[pair[0]] = pair[1]
+	}
+	return result
+}
+
+// getEnvAsPairs разбирает тот же формат, что getEnvAsMap, сохраняя порядок элементов
+func getEnvAsPairs(key string) [][2]string {
+	var result [][2]string
+	for _, pair := range strings.Split(getEnv(key, ""), ",") {
+		name, value, ok := strings.Cut(strings.TrimSpace(pair), ":")
+		if !ok || name == "" || value == "" {
+			continue
+		}
+		result = append(result, [2]string{name, value})
+	}
+	return result
+}
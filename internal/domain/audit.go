@@ -0,0 +1,103 @@
+package domain
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+)
+
+// Типы сущностей и действия журнала аудита
+const (
+	AuditEntityIncident = "incident"
+	AuditEntityUser     = "user"
+
+	AuditActionCreate     = "create"
+	AuditActionUpdate     = "update"
+	AuditActionDeactivate = "deactivate"
+	AuditActionReactivate = "reactivate"
+	AuditActionPurge      = "purge"
+	AuditActionErase      = "erase"
+)
+
+// SystemActor автор изменений, выполненных самой системой
+const SystemActor = "system"
+
+// FieldChange изменение одного поля
+type FieldChange struct {
+	Old any `json:"old"`
+	New any `json:"new"`
+}
+
+// AuditEntry неизменяемая запись журнала аудита
+type AuditEntry struct {
+	ID         int64                  `json:"id"`
+	TenantID   string                 `json:"tenant_id"`
+	EntityType string                 `json:"entity_type"`
+	EntityID   string                 `json:"entity_id"`
+	Action     string                 `json:"action"`
+	Actor      string                 `json:"actor"`
+	CreatedAt  time.Time              `json:"created_at"`
+	Before     json.RawMessage        `json:"before,omitempty"`
+	After      json.RawMessage        `json:"after,omitempty"`
+	Diff       map[string]FieldChange `json:"diff"`
+}
+
+// AuditFilter фильтры журнала аудита
+type AuditFilter struct {
+	EntityType string
+	EntityID   string
+	Action     string
+	Actor      string
+	From       *time.Time
+	To         *time.Time
+}
+
+type actorContextKey struct{}
+
+// WithActor возвращает контекст с автором изменений
+func WithActor(ctx context.Context, actor string) context.Context {
+	return context.WithValue(ctx, actorContextKey{}, actor)
+}
+
+// ActorFromContext возвращает автора изменений или SystemActor
+func ActorFromContext(ctx context.Context) string {
+	if actor, ok := ctx.Value(actorContextKey{}).(string); ok && actor != "" {
+		return actor
+	}
+	return SystemActor
+}
+
+// DiffIncidents возвращает изменённые поля инцидента (before может быть nil при создании)
+func DiffIncidents(before, after *Incident) map[string]FieldChange {
+	diff := make(map[string]FieldChange)
+	if after == nil {
+		return diff
+	}
+	if before == nil {
+		before = &Incident{}
+	}
+
+	add := func(field string, oldValue, newValue any) {
+		if oldValue != newValue {
+			diff[field] = FieldChange{Old: oldValue, New: newValue}
+		}
+	}
+	add("title", before.Title, after.Title)
+	add("description", before.Description, after.Description)
+	add("severity", before.Severity, after.Severity)
+	add("latitude", before.Latitude, after.Latitude)
+	add("longitude", before.Longitude, after.Longitude)
+	add("radius_meters", before.RadiusMeters, after.RadiusMeters)
+	add("is_active", before.IsActive, after.IsActive)
+	add("expires_at", timeValue(before.ExpiresAt), timeValue(after.ExpiresAt))
+
+	return diff
+}
+
+// timeValue приводит необязательное время к сравнимому значению для diff
+func timeValue(t *time.Time) any {
+	if t == nil {
+		return nil
+	}
+	return t.UTC().Format(time.RFC3339Nano)
+}
@@ -0,0 +1,21 @@
+package domain
+
+import "time"
+
+// UserTokenClaims данные подписанного токена пользователя
+type UserTokenClaims struct {
+	TenantID  string `json:"tid,omitempty"`
+	UserID    string `json:"uid"`
+	ExpiresAt int64  `json:"exp"`
+}
+
+// IssueTokenRequest запрос на выпуск токена пользователя
+type IssueTokenRequest struct {
+	UserID string `json:"user_id" binding:"required,min=1,max=100"`
+}
+
+// IssueTokenResponse выпущенный токен пользователя
+type IssueTokenResponse struct {
+	Token     string    `json:"token"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
@@ -0,0 +1,47 @@
+package domain
+
+import "time"
+
+// CAPMsgType тип CAP-сообщения (msgType)
+type CAPMsgType string
+
+const (
+	CAPMsgTypeAlert  CAPMsgType = "Alert"
+	CAPMsgTypeUpdate CAPMsgType = "Update"
+	CAPMsgTypeCancel CAPMsgType = "Cancel"
+)
+
+// Результат приёма CAP-сообщения
+const (
+	CAPIngestApplied   = "applied"
+	CAPIngestDuplicate = "duplicate"
+	CAPIngestIgnored   = "ignored"
+)
+
+// CAPReference ссылка на ранее полученное сообщение (references: sender,identifier,sent)
+type CAPReference struct {
+	Sender     string
+	Identifier string
+}
+
+// CAPAlert CAP-сообщение, приведённое к инцидентам: по одному на каждую геометрию <area>
+type CAPAlert struct {
+	Identifier string
+	Sender     string
+	Sent       time.Time
+	MsgType    CAPMsgType
+	References []CAPReference
+	Incidents  []CreateIncidentRequest
+}
+
+// CAPIngestResult итог приёма CAP-сообщения
+type CAPIngestResult struct {
+	Identifier  string     `json:"identifier"`
+	Sender      string     `json:"sender"`
+	MsgType     CAPMsgType `json:"msg_type"`
+	Status      string     `json:"status"`
+	Reason      string     `json:"reason,omitempty"`
+	Created     []string   `json:"created"`
+	Updated     []string   `json:"updated"`
+	Deactivated []string   `json:"deactivated"`
+}
@@ -0,0 +1,10 @@
+package domain
+
+// ExportFormat формат выгрузки инцидентов
+type ExportFormat string
+
+const (
+	ExportFormatCSV     ExportFormat = "csv"
+	ExportFormatGeoJSON ExportFormat = "geojson"
+	ExportFormatKML     ExportFormat = "kml"
+)
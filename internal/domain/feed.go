@@ -0,0 +1,13 @@
+package domain
+
+import "time"
+
+// IncidentFeed снимок активных инцидентов арендатора для публикации в лентах
+type IncidentFeed struct {
+	TenantID  string
+	Incidents []*Incident
+	// LastModified последнее изменение инцидентов арендатора (включая деактивацию)
+	LastModified time.Time
+	// ETag меняется при любом изменении набора активных инцидентов или их версий
+	ETag string
+}
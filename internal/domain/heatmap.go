@@ -0,0 +1,94 @@
+package domain
+
+import (
+	"math"
+	"time"
+)
+
+// Ограничения агрегации проверок по сетке geohash
+const (
+	MinHeatmapPrecision     = 1
+	MaxHeatmapPrecision     = 9
+	DefaultHeatmapPrecision = 6
+	DefaultHeatmapLimit     = 1000
+	MaxHeatmapLimit         = 10000
+)
+
+const geohashAlphabet = "0123456789bcdefghjkmnpqrstuvwxyz"
+
+// HeatmapQuery параметры тепловой карты проверок: ячейки geohash заданной точности
+// за интервал [Since, Until), опционально внутри BBox
+type HeatmapQuery struct {
+	Precision int
+	Since     time.Time
+	Until     time.Time
+	BBox      *BoundingBox
+	Limit     int
+}
+
+// HeatmapCell агрегаты проверок в одной ячейке сетки
+type HeatmapCell struct {
+	Geohash    string      `json:"geohash"`
+	Latitude   float64     `json:"latitude"`
+	Longitude  float64     `json:"longitude"`
+	Bounds     BoundingBox `json:"bounds"`
+	Checks     int         `json:"checks"`
+	Users      int         `json:"users"`
+	DangerHits int         `json:"danger_hits"`
+}
+
+// Heatmap результат агрегации; Truncated — ячеек больше, чем Limit (отданы самые нагруженные)
+type Heatmap struct {
+	Precision int           `json:"precision"`
+	Since     time.Time     `json:"since"`
+	Until     time.Time     `json:"until"`
+	Truncated bool          `json:"truncated"`
+	Cells     []HeatmapCell `json:"cells"`
+}
+
+// GeohashCellSize размер ячейки geohash в градусах. Ячейки geohash образуют равномерную
+// сетку: из 5*precision бит на долготу приходится ceil, на широту floor от половины.
+func GeohashCellSize(precision int) (lonStep, latStep float64) {
+	bits := 5 * precision
+	return 360 / math.Exp2(float64((bits+1)/2)), 180 / math.Exp2(float64(bits/2))
+}
+
+// GeohashCell восстанавливает ячейку по номерам столбца x (от -180°) и строки y (от -90°)
+func GeohashCell(precision int, x, y int64) HeatmapCell {
+	lonStep, latStep := GeohashCellSize(precision)
+	bounds := BoundingBox{
+		MinLongitude: -180 + float64(x)*lonStep,
+		MinLatitude:  -90 + float64(y)*latStep,
+		MaxLongitude: -180 + float64(x+1)*lonStep,
+		MaxLatitude:  -90 + float64(y+1)*latStep,
+	}
+
+	// Биты geohash чередуются, начиная с долготы; номера ячеек — это и есть биты по каждой оси
+	bits := 5 * precision
+	lonBits, latBits := (bits+1)/2, bits/2
+	hash := make([]byte, 0, precision)
+	var char, charBits int
+	for i := 0; i < bits; i++ {
+		var bit int64
+		if i%2 == 0 {
+			lonBits--
+			bit = (x >> lonBits) & 1
+		} else {
+			latBits--
+			bit = (y >> latBits) & 1
+		}
+		char = char<<1 | int(bit)
+		charBits++
+		if charBits == 5 {
+			hash = append(hash, geohashAlphabet[char])
+			char, charBits = 0, 0
+		}
+	}
+
+	return HeatmapCell{
+		Geohash:   string(hash),
+		Latitude:  (bounds.MinLatitude + bounds.MaxLatitude) / 2,
+		Longitude: (bounds.MinLongitude + bounds.MaxLongitude) / 2,
+		Bounds:    bounds,
+	}
+}
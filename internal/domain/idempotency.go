@@ -0,0 +1,21 @@
+package domain
+
+import "time"
+
+// MaxIdempotencyKeyLength ограничение длины заголовка Idempotency-Key
+const MaxIdempotencyKeyLength = 255
+
+// IdempotencyRecord сохранённый результат запроса с Idempotency-Key.
+// Status 0 — запрос ещё выполняется; Fingerprint — хеш метода, пути и тела запроса.
+type IdempotencyRecord struct {
+	Fingerprint string            `json:"fingerprint"`
+	Status      int               `json:"status"`
+	Headers     map[string]string `json:"headers,omitempty"`
+	Body        []byte            `json:"body,omitempty"`
+	CreatedAt   time.Time         `json:"created_at"`
+}
+
+// Completed сообщает, что ответ сохранён и может быть повторён
+func (r IdempotencyRecord) Completed() bool {
+	return r.Status != 0
+}
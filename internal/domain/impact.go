@@ -0,0 +1,44 @@
+package domain
+
+import "time"
+
+// ImpactZone круг зоны, для которой оценивается охват
+type ImpactZone struct {
+	Latitude     float64
+	Longitude    float64
+	RadiusMeters int
+}
+
+// ImpactZoneFromRequest зона черновика инцидента
+func ImpactZoneFromRequest(req CreateIncidentRequest) ImpactZone {
+	return ImpactZone{Latitude: req.Latitude, Longitude: req.Longitude, RadiusMeters: req.RadiusMeters}
+}
+
+// ImpactCounts проверки за интервал, попавшие бы в зону.
+// CurrentUsers — пользователи, чья последняя проверка до конца интервала внутри зоны.
+type ImpactCounts struct {
+	Checks       int `json:"checks"`
+	Users        int `json:"users"`
+	CurrentUsers int `json:"current_users"`
+}
+
+// OverlappingIncident активный инцидент, зона которого пересекается с черновиком.
+// Contains — зона черновика целиком внутри существующей.
+type OverlappingIncident struct {
+	ID             string   `json:"id"`
+	Title          string   `json:"title"`
+	Severity       Severity `json:"severity"`
+	Latitude       float64  `json:"latitude"`
+	Longitude      float64  `json:"longitude"`
+	RadiusMeters   int      `json:"radius_meters"`
+	DistanceMeters float64  `json:"distance_meters"`
+	Contains       bool     `json:"contains"`
+}
+
+// ImpactPreview оценка охвата черновика инцидента без сохранения
+type ImpactPreview struct {
+	Since       time.Time             `json:"since"`
+	Until       time.Time             `json:"until"`
+	Impact      ImpactCounts          `json:"impact"`
+	Overlapping []OverlappingIncident `json:"overlapping_incidents"`
+}
@@ -0,0 +1,25 @@
+package domain
+
+// ImportFormat формат файла массового импорта инцидентов
+type ImportFormat string
+
+const (
+	ImportFormatCSV     ImportFormat = "csv"
+	ImportFormatGeoJSON ImportFormat = "geojson"
+)
+
+// ImportRowError ошибки одной строки импорта (строки CSV или Feature GeoJSON, нумерация с 1)
+type ImportRowError struct {
+	Row    int      `json:"row"`
+	Errors []string `json:"errors"`
+}
+
+// IncidentImportResult отчёт об импорте. При ошибках хотя бы в одной строке ничего не создаётся.
+type IncidentImportResult struct {
+	DryRun    bool             `json:"dry_run"`
+	Total     int              `json:"total"`
+	Valid     int              `json:"valid"`
+	Imported  int              `json:"imported"`
+	Errors    []ImportRowError `json:"errors"`
+	Incidents []*Incident      `json:"incidents,omitempty"`
+}
@@ -0,0 +1,57 @@
+package domain
+
+import "time"
+
+// Поля сортировки списка инцидентов
+const (
+	IncidentSortCreatedAt    = "created_at"
+	IncidentSortUpdatedAt    = "updated_at"
+	IncidentSortTitle        = "title"
+	IncidentSortSeverity     = "severity"
+	IncidentSortRadiusMeters = "radius_meters"
+	IncidentSortDistance     = "distance"
+)
+
+// IncidentSortFields допустимые поля сортировки
+var IncidentSortFields = map[string]bool{
+	IncidentSortCreatedAt:    true,
+	IncidentSortUpdatedAt:    true,
+	IncidentSortTitle:        true,
+	IncidentSortSeverity:     true,
+	IncidentSortRadiusMeters: true,
+	IncidentSortDistance:     true,
+}
+
+// BoundingBox прямоугольная область по координатам
+type BoundingBox struct {
+	MinLatitude  float64 `json:"min_latitude"`
+	MinLongitude float64 `json:"min_longitude"`
+	MaxLatitude  float64 `json:"max_latitude"`
+	MaxLongitude float64 `json:"max_longitude"`
+}
+
+// GeoCircle окружность вокруг точки
+type GeoCircle struct {
+	Latitude     float64 `json:"latitude"`
+	Longitude    float64 `json:"longitude"`
+	RadiusMeters float64 `json:"radius_meters"`
+}
+
+// IncidentFilter фильтры и сортировка списка инцидентов
+type IncidentFilter struct {
+	IsActive    *bool
+	Severities  []Severity
+	CreatedFrom *time.Time
+	CreatedTo   *time.Time
+	UpdatedFrom *time.Time
+	UpdatedTo   *time.Time
+	// Query полнотекстовый поиск по title/description
+	Query string
+	// BBox центр инцидента внутри прямоугольника
+	BBox *BoundingBox
+	// Near центр инцидента не дальше RadiusMeters от точки
+	Near *GeoCircle
+
+	SortBy   string
+	SortDesc bool
+}
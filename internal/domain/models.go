@@ -13,36 +13,49 @@ const (
 
 // Incident инцидент/опасная зона
 type Incident struct {
-	ID           string    `json:"id"`
-	Title        string    `json:"title"`
-	Description  string    `json:"description"`
-	Severity     Severity  `json:"severity"`
-	Latitude     float64   `json:"latitude"`
-	Longitude    float64   `json:"longitude"`
-	RadiusMeters int       `json:"radius_meters"`
-	IsActive     bool      `json:"is_active"`
-	CreatedAt    time.Time `json:"created_at"`
-	UpdatedAt    time.Time `json:"updated_at"`
+	ID            string     `json:"id"`
+	TenantID      string     `json:"tenant_id"`
+	Title         string     `json:"title"`
+	Description   string     `json:"description"`
+	Severity      Severity   `json:"severity"`
+	Latitude      float64    `json:"latitude"`
+	Longitude     float64    `json:"longitude"`
+	RadiusMeters  int        `json:"radius_meters"`
+	IsActive      bool       `json:"is_active"`
+	Version       int        `json:"version"`
+	CreatedAt     time.Time  `json:"created_at"`
+	UpdatedAt     time.Time  `json:"updated_at"`
+	DeactivatedAt *time.Time `json:"deactivated_at,omitempty"`
+	ExpiresAt     *time.Time `json:"expires_at,omitempty"`
 }
 
 // CreateIncidentRequest запрос на создание инцидента
 type CreateIncidentRequest struct {
-	Title        string   `json:"title" binding:"required,min=3,max=200"`
-	Description  string   `json:"description" binding:"max=1000"`
-	Severity     Severity `json:"severity" binding:"required,oneof=low medium high"`
-	Latitude     float64  `json:"latitude" binding:"required,min=-90,max=90"`
-	Longitude    float64  `json:"longitude" binding:"required,min=-180,max=180"`
-	RadiusMeters int      `json:"radius_meters" binding:"required,min=10,max=100000"`
+	Title        string     `json:"title" binding:"required,min=3,max=200"`
+	Description  string     `json:"description" binding:"max=1000"`
+	Severity     Severity   `json:"severity" binding:"required,oneof=low medium high"`
+	Latitude     float64    `json:"latitude" binding:"required,min=-90,max=90"`
+	Longitude    float64    `json:"longitude" binding:"required,min=-180,max=180"`
+	RadiusMeters int        `json:"radius_meters" binding:"required,min=10,max=100000"`
+	ExpiresAt    *time.Time `json:"expires_at"`
+	// NotifyRecentUsers оповестить пользователей, чья последняя недавняя проверка попадает в зону
+	NotifyRecentUsers bool `json:"notify_recent_users"`
 }
 
 // UpdateIncidentRequest запрос на обновление инцидента
 type UpdateIncidentRequest struct {
-	Title        *string   `json:"title" binding:"omitempty,min=3,max=200"`
-	Description  *string   `json:"description" binding:"omitempty,max=1000"`
-	Severity     *Severity `json:"severity" binding:"omitempty,oneof=low medium high"`
-	Latitude     *float64  `json:"latitude" binding:"omitempty,min=-90,max=90"`
-	Longitude    *float64  `json:"longitude" binding:"omitempty,min=-180,max=180"`
-	RadiusMeters *int      `json:"radius_meters" binding:"omitempty,min=10,max=100000"`
+	Title        *string    `json:"title" binding:"omitempty,min=3,max=200"`
+	Description  *string    `json:"description" binding:"omitempty,max=1000"`
+	Severity     *Severity  `json:"severity" binding:"omitempty,oneof=low medium high"`
+	Latitude     *float64   `json:"latitude" binding:"omitempty,min=-90,max=90"`
+	Longitude    *float64   `json:"longitude" binding:"omitempty,min=-180,max=180"`
+	RadiusMeters *int       `json:"radius_meters" binding:"omitempty,min=10,max=100000"`
+	ExpiresAt    *time.Time `json:"expires_at"`
+	// ClearExpiresAt снимает срок действия; expires_at при этом не передаётся
+	ClearExpiresAt bool `json:"clear_expires_at"`
+	// NotifyRecentUsers при изменении центра или радиуса оповестить недавно проверявшихся
+	// пользователей, оказавшихся в новой зоне
+	NotifyRecentUsers bool `json:"notify_recent_users"`
 }
 
 // LocationCheckRequest запрос на проверку локации
@@ -74,6 +87,7 @@ type LocationCheckResponse struct {
 // LocationCheck запись о проверке локации
 type LocationCheck struct {
 	ID             string    `json:"id"`
+	TenantID       string    `json:"tenant_id"`
 	UserID         string    `json:"user_id"`
 	Latitude       float64   `json:"latitude"`
 	Longitude      float64   `json:"longitude"`
@@ -81,9 +95,32 @@ type LocationCheck struct {
 	CheckedAt      time.Time `json:"checked_at"`
 }
 
-// IncidentStats статистика по инциденту
+// IncidentStats статистика попаданий в зону инцидента за интервал.
+// Расстояния считаются до текущего центра зоны; при отсутствии попаданий — null.
 type IncidentStats struct {
-	IncidentID string `json:"incident_id"`
-	Title      string `json:"title"`
-	UserCount  int    `json:"user_count"`
+	IncidentID        string     `json:"incident_id"`
+	Title             string     `json:"title"`
+	Severity          Severity   `json:"severity"`
+	IsActive          bool       `json:"is_active"`
+	Checks            int        `json:"checks"`
+	UserCount         int        `json:"user_count"`
+	FirstHitAt        *time.Time `json:"first_hit_at"`
+	LastHitAt         *time.Time `json:"last_hit_at"`
+	AvgDistanceMeters *float64   `json:"avg_distance_meters"`
+	P50DistanceMeters *float64   `json:"p50_distance_meters"`
+	P95DistanceMeters *float64   `json:"p95_distance_meters"`
+	MaxDistanceMeters *float64   `json:"max_distance_meters"`
+}
+
+// MaxStatsWindow наибольший интервал статистики, оценки охвата и тепловой карты: запросы читают
+// все проверки интервала (перцентили, уникальные пользователи, ячейки geohash)
+const MaxStatsWindow = 31 * 24 * time.Hour
+
+// IncidentStatsQuery параметры статистики: попадания за [Since, Until).
+// Без IncidentIDs выбираются только активные инциденты арендатора.
+type IncidentStatsQuery struct {
+	Since       time.Time
+	Until       time.Time
+	Severities  []Severity
+	IncidentIDs []string
 }
@@ -0,0 +1,51 @@
+package domain
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+)
+
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// PageRequest параметры страницы: курсор (keyset) или смещение (offset)
+type PageRequest struct {
+	Limit        int
+	Offset       int
+	Cursor       *Cursor
+	IncludeTotal bool
+}
+
+// PageInfo курсоры соседних страниц и (опционально) общее число записей
+type PageInfo struct {
+	NextCursor string `json:"next_cursor,omitempty"`
+	PrevCursor string `json:"prev_cursor,omitempty"`
+	Total      *int   `json:"total,omitempty"`
+}
+
+// Cursor позиция в упорядоченной выборке: значение ключа сортировки и id последней записи.
+// Клиенту передаётся как непрозрачная строка.
+type Cursor struct {
+	Sort     string          `json:"s"`
+	Desc     bool            `json:"d,omitempty"`
+	Value    json.RawMessage `json:"v"`
+	ID       string          `json:"id"`
+	Backward bool            `json:"b,omitempty"`
+}
+
+func (c Cursor) Encode() string {
+	raw, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(raw)
+}
+
+func DecodeCursor(value string) (*Cursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	var cursor Cursor
+	if err := json.Unmarshal(raw, &cursor); err != nil || cursor.ID == "" || len(cursor.Value) == 0 {
+		return nil, ErrInvalidCursor
+	}
+	return &cursor, nil
+}
@@ -0,0 +1,155 @@
+package domain
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math"
+)
+
+// CoordinatePrivacy режим хранения координат проверки
+type CoordinatePrivacy string
+
+const (
+	// CoordinatesExact координаты сохраняются как есть
+	CoordinatesExact CoordinatePrivacy = "exact"
+	// CoordinatesGrid координаты заменяются центром ячейки сетки GridMeters
+	CoordinatesGrid CoordinatePrivacy = "grid"
+	// CoordinatesNone координаты не сохраняются, остаются только совпавшие инциденты
+	CoordinatesNone CoordinatePrivacy = "none"
+)
+
+// DefaultPrivacyGridMeters размер ячейки сетки по умолчанию
+const DefaultPrivacyGridMeters = 500
+
+// metersPerDegree длина градуса широты (и долготы на экваторе)
+const metersPerDegree = 111320.0
+
+// pseudonymHexLength длина HMAC в псевдониме: 128 бит достаточно для уникальности
+const pseudonymHexLength = 32
+
+// UserIDKey ключ HMAC для псевдонимизации user_id; ID попадает в HMAC, поэтому
+// значения под разными ключами не совпадают
+type UserIDKey struct {
+	ID     string
+	Secret string
+}
+
+// StoredUser пользователь арендатора, данные которого сохранены под исходным user_id
+type StoredUser struct {
+	TenantID string
+	UserID   string
+}
+
+// Validate проверяет, что ключ задан
+func (k UserIDKey) Validate() error {
+	if k.ID == "" || k.Secret == "" {
+		return errors.New("key must be set as id:secret")
+	}
+	return nil
+}
+
+// LocationPrivacy политика хранения проверок координат.
+// Keys упорядочены от текущего ключа к старым: новые пользователи получают псевдоним
+// под первым, остальные нужны только для поиска псевдонимов, выданных до смены ключа.
+// Псевдоним пользователя не меняется со сменой ключа: история, число уникальных
+// пользователей, выгрузка и удаление данных требуют связывать все его проверки.
+type LocationPrivacy struct {
+	Coordinates CoordinatePrivacy
+	GridMeters  float64
+	DangerOnly  bool
+	Keys        []UserIDKey
+}
+
+// Apply возвращает проверку в том виде, в каком её можно сохранить; false — проверку
+// сохранять не нужно. user_id заменяется HMAC под текущим ключом; хранилище подменяет его
+// стабильным псевдонимом пользователя, выданным до смены ключа (см. UserIDLookups).
+func (p LocationPrivacy) Apply(check LocationCheck) (LocationCheck, bool) {
+	if p.DangerOnly && !check.IsInDangerZone {
+		return check, false
+	}
+
+	switch p.Coordinates {
+	case CoordinatesGrid:
+		check.Latitude, check.Longitude = SnapToGrid(check.Latitude, check.Longitude, p.GridMeters)
+	case CoordinatesNone:
+		check.Latitude, check.Longitude = 0, 0
+	}
+
+	if len(p.Keys) > 0 {
+		check.UserID = PseudonymizeUserID(p.Keys[0], check.TenantID, check.UserID)
+	}
+
+	return check, true
+}
+
+// StoresCoordinates сохраняются ли координаты проверок
+func (p LocationPrivacy) StoresCoordinates() bool {
+	return p.Coordinates != CoordinatesNone
+}
+
+// Validate проверяет режим координат и размер ячейки сетки
+func (p LocationPrivacy) Validate() error {
+	switch p.Coordinates {
+	case CoordinatesExact, CoordinatesNone:
+	case CoordinatesGrid:
+		if p.GridMeters <= 0 {
+			return fmt.Errorf("grid size must be positive, got %v", p.GridMeters)
+		}
+	default:
+		return fmt.Errorf("unknown coordinates mode %q (expected %s, %s or %s)",
+			p.Coordinates, CoordinatesExact, CoordinatesGrid, CoordinatesNone)
+	}
+	return nil
+}
+
+// UserIDLookups HMAC пользователя под всеми ключами, текущий первым. По ним хранилище находит
+// стабильный псевдоним: он выдаётся один раз (HMAC под ключом, текущим в тот момент) и остаётся
+// user_id проверок после смены ключа.
+func (p LocationPrivacy) UserIDLookups(tenantID, userID string) []string {
+	lookups := make([]string, 0, len(p.Keys))
+	for _, key := range p.Keys {
+		lookups = append(lookups, PseudonymizeUserID(key, tenantID, userID))
+	}
+	return lookups
+}
+
+// StoredUserIDs значения user_id, под которыми могли быть сохранены проверки пользователя:
+// исходный идентификатор и HMAC под всеми ключами; хранилище дополняет их псевдонимами,
+// найденными по этим HMAC
+func (p LocationPrivacy) StoredUserIDs(tenantID, userID string) []string {
+	return append([]string{userID}, p.UserIDLookups(tenantID, userID)...)
+}
+
+// PseudonymizeUserID возвращает псевдоним вида "<key id>:<hmac>"
+func PseudonymizeUserID(key UserIDKey, tenantID, userID string) string {
+	mac := hmac.New(sha256.New, []byte(key.Secret))
+	mac.Write([]byte(tenantID))
+	mac.Write([]byte{0})
+	mac.Write([]byte(userID))
+	return key.ID + ":" + hex.EncodeToString(mac.Sum(nil))[:pseudonymHexLength]
+}
+
+// SnapToGrid заменяет координаты центром ячейки размером примерно gridMeters.
+// Шаг по долготе подбирается по широте ряда, чтобы ячейки оставались близки к квадрату.
+func SnapToGrid(latitude, longitude, gridMeters float64) (float64, float64) {
+	if gridMeters <= 0 {
+		return latitude, longitude
+	}
+
+	latStep := gridMeters / metersPerDegree
+	row := math.Floor((latitude + 90) / latStep)
+	lat := math.Min(-90+(row+0.5)*latStep, 90)
+
+	// У полюсов ячейка по долготе вырождается в полный круг
+	lonStep := 360.0
+	if cos := math.Cos(lat * math.Pi / 180); cos > gridMeters/(metersPerDegree*360) {
+		lonStep = math.Min(gridMeters/(metersPerDegree*cos), 360)
+	}
+	column := math.Floor((longitude + 180) / lonStep)
+	lon := math.Min(-180+(column+0.5)*lonStep, 180)
+
+	return lat, lon
+}
@@ -0,0 +1,62 @@
+package domain
+
+import "time"
+
+// StatsBucket размер корзины временного ряда статистики (границы корзин — по UTC)
+type StatsBucket string
+
+const (
+	StatsBucketMinute StatsBucket = "minute"
+	StatsBucketHour   StatsBucket = "hour"
+	StatsBucketDay    StatsBucket = "day"
+)
+
+// StatsBuckets корзины, которые поддерживаются агрегатами
+var StatsBuckets = []StatsBucket{StatsBucketMinute, StatsBucketHour, StatsBucketDay}
+
+// MaxStatsSeriesPoints максимум корзин в одном ряду; для длинных интервалов берётся корзина крупнее
+const MaxStatsSeriesPoints = 5000
+
+// Duration длительность корзины; 0 для неизвестного значения
+func (b StatsBucket) Duration() time.Duration {
+	switch b {
+	case StatsBucketMinute:
+		return time.Minute
+	case StatsBucketHour:
+		return time.Hour
+	case StatsBucketDay:
+		return 24 * time.Hour
+	}
+	return 0
+}
+
+// StatsSeriesQuery параметры временного ряда: корзины, начало которых в [From, To)
+type StatsSeriesQuery struct {
+	From        time.Time
+	To          time.Time
+	Bucket      StatsBucket
+	IncidentIDs []string
+}
+
+// StatsPoint агрегаты одной корзины. NewUsers — пользователи, впервые попавшие в зону.
+type StatsPoint struct {
+	BucketStart time.Time `json:"bucket_start"`
+	Checks      int64     `json:"checks"`
+	Users       int64     `json:"users"`
+	NewUsers    int64     `json:"new_users"`
+}
+
+// IncidentStatsSeries временной ряд по инциденту
+type IncidentStatsSeries struct {
+	IncidentID string       `json:"incident_id"`
+	Title      string       `json:"title"`
+	Points     []StatsPoint `json:"points"`
+}
+
+// StatsSeries временные ряды инцидентов арендатора за интервал
+type StatsSeries struct {
+	From   time.Time             `json:"from"`
+	To     time.Time             `json:"to"`
+	Bucket StatsBucket           `json:"bucket"`
+	Series []IncidentStatsSeries `json:"series"`
+}
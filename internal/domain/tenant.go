@@ -0,0 +1,21 @@
+package domain
+
+import "context"
+
+// DefaultTenantID арендатор по умолчанию (однотенантная установка и legacy API_KEY)
+const DefaultTenantID = "default"
+
+type tenantContextKey struct{}
+
+// WithTenant возвращает контекст с идентификатором арендатора
+func WithTenant(ctx context.Context, tenantID string) context.Context {
+	return context.WithValue(ctx, tenantContextKey{}, tenantID)
+}
+
+// TenantFromContext возвращает арендатора из контекста или DefaultTenantID
+func TenantFromContext(ctx context.Context) string {
+	if tenantID, ok := ctx.Value(tenantContextKey{}).(string); ok && tenantID != "" {
+		return tenantID
+	}
+	return DefaultTenantID
+}
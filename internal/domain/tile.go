@@ -0,0 +1,20 @@
+package domain
+
+// MaxTileZoom максимальный уровень масштаба векторных тайлов
+const MaxTileZoom = 22
+
+// TileCoord адрес тайла в схеме XYZ (Web Mercator, ось Y направлена на юг)
+type TileCoord struct {
+	Z int
+	X int
+	Y int
+}
+
+// Valid проверяет, что тайл существует на своём уровне масштаба
+func (t TileCoord) Valid() bool {
+	if t.Z < 0 || t.Z > MaxTileZoom {
+		return false
+	}
+	n := 1 << t.Z
+	return t.X >= 0 && t.X < n && t.Y >= 0 && t.Y < n
+}
@@ -0,0 +1,66 @@
+package domain
+
+import "time"
+
+// Статусы попыток доставки вебхука
+const (
+	WebhookDelivered = "delivered"
+	WebhookRetrying  = "retrying"
+	WebhookFailed    = "failed"
+)
+
+// WebhookDelivery запись об одной попытке доставки вебхука по проверке
+type WebhookDelivery struct {
+	ID        int64     `json:"id"`
+	TenantID  string    `json:"tenant_id"`
+	CheckID   string    `json:"check_id"`
+	Attempt   int       `json:"attempt"`
+	Status    string    `json:"status"`
+	Error     string    `json:"error,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// UserCheckIncident инцидент, в зону которого попала проверка
+type UserCheckIncident struct {
+	IncidentID string   `json:"incident_id"`
+	Title      string   `json:"title"`
+	Severity   Severity `json:"severity"`
+}
+
+// UserCheck сохранённая проверка пользователя. UserID — значение в хранилище (может быть
+// псевдонимом), координаты отсутствуют, если политика приватности их не сохраняет.
+type UserCheck struct {
+	ID             string              `json:"id"`
+	UserID         string              `json:"user_id"`
+	Latitude       *float64            `json:"latitude"`
+	Longitude      *float64            `json:"longitude"`
+	IsInDangerZone bool                `json:"is_in_danger_zone"`
+	CheckedAt      time.Time           `json:"checked_at"`
+	Incidents      []UserCheckIncident `json:"incidents"`
+}
+
+// UserDataExport все данные пользователя арендатора: проверки с совпавшими зонами,
+// попытки доставки вебхуков и ещё не отправленные задачи (outbox, очередь и отложенные повторы)
+type UserDataExport struct {
+	TenantID          string            `json:"tenant_id"`
+	UserID            string            `json:"user_id"`
+	ExportedAt        time.Time         `json:"exported_at"`
+	Checks            []UserCheck       `json:"checks"`
+	WebhookDeliveries []WebhookDelivery `json:"webhook_deliveries"`
+	QueuedWebhooks    []WebhookJob      `json:"queued_webhooks"`
+}
+
+// UserErasure итог удаления данных пользователя; сохраняется в журнале аудита без UserID:
+// запись находится по UserIDHash — ключевому хешу идентификатора
+type UserErasure struct {
+	TenantID          string    `json:"tenant_id"`
+	UserID            string    `json:"user_id,omitempty"`
+	UserIDHash        string    `json:"user_id_hash"`
+	ErasedAt          time.Time `json:"erased_at"`
+	Checks            int       `json:"checks"`
+	IncidentMatches   int       `json:"incident_matches"`
+	StatsMarks        int       `json:"stats_marks"`
+	WebhookDeliveries int       `json:"webhook_deliveries"`
+	QueuedWebhooks    int       `json:"queued_webhooks"`
+	RateLimitKeys     int       `json:"rate_limit_keys"`
+}
@@ -0,0 +1,42 @@
+package domain
+
+import "time"
+
+// Ограничения списка пользователей в зоне инцидента
+const (
+	DefaultIncidentUsersWindow = 24 * time.Hour
+	MaxIncidentUsersWindow     = 31 * 24 * time.Hour
+)
+
+// UserChecksFilter фильтры истории проверок пользователя: интервал [From, To) и только попадания
+type UserChecksFilter struct {
+	From       *time.Time
+	To         *time.Time
+	DangerOnly bool
+}
+
+// IncidentUsersQuery интервал [Since, Until), за который ищутся попадания в зону
+type IncidentUsersQuery struct {
+	Since time.Time
+	Until time.Time
+}
+
+// UserPosition последняя известная позиция пользователя на момент Until запроса.
+// Координаты отсутствуют, если политика приватности их не сохраняет.
+type UserPosition struct {
+	CheckID        string    `json:"check_id"`
+	Latitude       *float64  `json:"latitude"`
+	Longitude      *float64  `json:"longitude"`
+	IsInDangerZone bool      `json:"is_in_danger_zone"`
+	CheckedAt      time.Time `json:"checked_at"`
+}
+
+// IncidentUser пользователь, попадавший в зону инцидента за интервал.
+// UserID — значение в хранилище (псевдоним, если включена псевдонимизация).
+type IncidentUser struct {
+	UserID       string       `json:"user_id"`
+	Checks       int          `json:"checks"`
+	FirstHitAt   time.Time    `json:"first_hit_at"`
+	LastHitAt    time.Time    `json:"last_hit_at"`
+	LastPosition UserPosition `json:"last_position"`
+}
@@ -11,11 +11,23 @@ type WebhookPayload struct {
 	IsInDangerZone bool             `json:"is_in_danger_zone"`
 	CheckedAt      time.Time        `json:"checked_at"`
 	Incidents      []NearbyIncident `json:"incidents"`
+	// Retroactive оповещение по прошлой проверке о зоне, созданной или расширенной позже
+	Retroactive bool `json:"retroactive,omitempty"`
 }
 
 // WebhookJob задача для очереди
 type WebhookJob struct {
+	TenantID  string         `json:"tenant_id,omitempty"`
 	Payload   WebhookPayload `json:"payload"`
 	Attempt   int            `json:"attempt"`
 	CreatedAt time.Time      `json:"created_at"`
 }
+
+// RetroactiveAlertRequest запрос ретроактивных оповещений о зоне инцидента: пользователей,
+// чья последняя проверка с момента Since попадает в зону, оповещает фоновая задача
+type RetroactiveAlertRequest struct {
+	ID         int64
+	TenantID   string
+	IncidentID string
+	Since      time.Time
+}
@@ -0,0 +1,44 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/ruslanuskembaev/geo-alerts-system/internal/domain"
+	"github.com/ruslanuskembaev/geo-alerts-system/internal/service"
+)
+
+// AuthHandler выпускает токены пользователей для публичных эндпоинтов
+type AuthHandler struct {
+	tokens *service.TokenService
+}
+
+func NewAuthHandler(tokens *service.TokenService) *AuthHandler {
+	return &AuthHandler{tokens: tokens}
+}
+
+// IssueToken выпускает короткоживущий токен, привязанный к user_id
+func (h *AuthHandler) IssueToken(c *gin.Context) {
+	var req domain.IssueTokenRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "invalid request",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	token, err := h.tokens.Issue(c.Request.Context(), req.UserID)
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, service.ErrTokensDisabled) {
+			status = http.StatusNotImplemented
+		}
+		c.JSON(status, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, token)
+}
@@ -0,0 +1,56 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/ruslanuskembaev/geo-alerts-system/internal/domain"
+	"github.com/ruslanuskembaev/geo-alerts-system/internal/service"
+)
+
+// maxCAPBodyBytes ограничение размера CAP-документа
+const maxCAPBodyBytes = 1 << 20
+
+// CAPHandler обработчик приёма сообщений Common Alerting Protocol
+type CAPHandler struct {
+	service *service.CAPService
+}
+
+func NewCAPHandler(service *service.CAPService) *CAPHandler {
+	return &CAPHandler{service: service}
+}
+
+// Ingest принимает CAP 1.2 документ (<alert>) и создаёт, обновляет или деактивирует инциденты.
+// Повторно присланное сообщение не применяется (status "duplicate").
+func (h *CAPHandler) Ingest(c *gin.Context) {
+	body := http.MaxBytesReader(c.Writer, c.Request.Body, maxCAPBodyBytes)
+	result, err := h.service.Ingest(c.Request.Context(), body)
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		switch {
+		case errors.As(err, &tooLarge):
+			c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": "CAP document too large"})
+		case errors.Is(err, service.ErrCAPAreaTooLarge):
+			c.JSON(http.StatusUnprocessableEntity, gin.H{
+				"error":   "CAP area too large",
+				"details": err.Error(),
+			})
+		case errors.Is(err, service.ErrInvalidCAPAlert):
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":   "invalid request",
+				"details": err.Error(),
+			})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
+
+	status := http.StatusOK
+	if result.Status == domain.CAPIngestApplied && len(result.Created) > 0 {
+		status = http.StatusCreated
+	}
+	c.JSON(status, result)
+}
@@ -0,0 +1,38 @@
+package handler
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// incidentETag формирует ETag по версии инцидента
+func incidentETag(version int) string {
+	return `"` + strconv.Itoa(version) + `"`
+}
+
+// parseIfMatch разбирает заголовок If-Match в список версий.
+// Возвращает nil, если заголовок не передан или равен "*".
+// If-Match сравнивает ETag строго (RFC 9110, 13.1.1): слабые теги (W/"3") и теги,
+// не являющиеся версией, не совпадают ни с одной версией и пропускаются.
+// ok=false означает, что в списке не осталось ни одной версии.
+func parseIfMatch(c *gin.Context) (versions []int, ok bool) {
+	header := strings.TrimSpace(c.GetHeader("If-Match"))
+	if header == "" || header == "*" {
+		return nil, true
+	}
+
+	for _, tag := range strings.Split(header, ",") {
+		tag = strings.TrimSpace(tag)
+		if len(tag) < 2 || !strings.HasPrefix(tag, `"`) || !strings.HasSuffix(tag, `"`) {
+			continue
+		}
+		parsed, err := strconv.Atoi(tag[1 : len(tag)-1])
+		if err != nil || parsed < 1 {
+			continue
+		}
+		versions = append(versions, parsed)
+	}
+	return versions, len(versions) > 0
+}
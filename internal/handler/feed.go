@@ -0,0 +1,115 @@
+package handler
+
+import (
+	"bytes"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/ruslanuskembaev/geo-alerts-system/internal/domain"
+	"github.com/ruslanuskembaev/geo-alerts-system/internal/service"
+)
+
+// FeedHandler публичные ленты активных инцидентов арендатора (CAP и Atom/GeoRSS)
+type FeedHandler struct {
+	service   *service.IncidentService
+	tenants   map[string]string
+	capSender string
+	proxies   TrustedProxies
+}
+
+// NewFeedHandler создаёт обработчик лент; tenants — известные арендаторы (tenant -> key)
+func NewFeedHandler(service *service.IncidentService, tenants map[string]string, capSender string) *FeedHandler {
+	return &FeedHandler{
+		service:   service,
+		tenants:   tenants,
+		capSender: capSender,
+	}
+}
+
+// WithTrustedProxies задаёт прокси, от которых принимается X-Forwarded-Proto для ссылок лент
+func (h *FeedHandler) WithTrustedProxies(proxies TrustedProxies) *FeedHandler {
+	h.proxies = proxies
+	return h
+}
+
+// Atom отдаёт Atom-ленту активных инцидентов с геометрией GeoRSS
+func (h *FeedHandler) Atom(c *gin.Context) {
+	h.serve(c, "application/atom+xml; charset=utf-8", func(buf *bytes.Buffer, feed *domain.IncidentFeed) error {
+		return service.EncodeAtomFeed(buf, feed, h.requestURL(c))
+	})
+}
+
+// CAP отдаёт Atom-ленту CAP 1.2 сообщений об активных инцидентах
+func (h *FeedHandler) CAP(c *gin.Context) {
+	h.serve(c, "application/atom+xml; charset=utf-8", func(buf *bytes.Buffer, feed *domain.IncidentFeed) error {
+		return service.EncodeCAPFeed(buf, feed, h.requestURL(c), h.capSender)
+	})
+}
+
+// serve отвечает 304 по If-None-Match/If-Modified-Since, иначе отдаёт ленту с ETag и Last-Modified
+func (h *FeedHandler) serve(c *gin.Context, contentType string, encode func(*bytes.Buffer, *domain.IncidentFeed) error) {
+	tenantID := c.Param("tenant")
+	if _, ok := h.tenants[tenantID]; !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": "feed not found"})
+		return
+	}
+	setTenant(c, tenantID)
+
+	feed, err := h.service.ActiveFeed(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.Header("ETag", feed.ETag)
+	if !feed.LastModified.IsZero() {
+		c.Header("Last-Modified", feed.LastModified.UTC().Format(http.TimeFormat))
+	}
+	c.Header("Cache-Control", "public, max-age=60")
+	if feedNotModified(c, feed) {
+		c.Status(http.StatusNotModified)
+		return
+	}
+
+	var buf bytes.Buffer
+	if err := encode(&buf, feed); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.Data(http.StatusOK, contentType, buf.Bytes())
+}
+
+// feedNotModified проверяет условный GET. If-None-Match приоритетнее If-Modified-Since (RFC 9110).
+func feedNotModified(c *gin.Context, feed *domain.IncidentFeed) bool {
+	if header := c.GetHeader("If-None-Match"); header != "" {
+		for _, tag := range strings.Split(header, ",") {
+			tag = strings.TrimSpace(tag)
+			if tag == "*" || strings.TrimPrefix(tag, "W/") == feed.ETag {
+				return true
+			}
+		}
+		return false
+	}
+
+	if header := c.GetHeader("If-Modified-Since"); header != "" && !feed.LastModified.IsZero() {
+		since, err := http.ParseTime(header)
+		return err == nil && !feed.LastModified.Truncate(time.Second).After(since)
+	}
+	return false
+}
+
+// requestURL восстанавливает абсолютный URL запроса для ссылки rel="self".
+// X-Forwarded-Proto учитывается только от доверенного прокси.
+func (h *FeedHandler) requestURL(c *gin.Context) string {
+	scheme := "http"
+	if c.Request.TLS != nil {
+		scheme = "https"
+	}
+	if proto := c.GetHeader("X-Forwarded-Proto"); (proto == "http" || proto == "https") && h.proxies.Contains(c.RemoteIP()) {
+		scheme = proto
+	}
+	return scheme + "://" + c.Request.Host + c.Request.URL.RequestURI()
+}
@@ -0,0 +1,119 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/ruslanuskembaev/geo-alerts-system/internal/domain"
+	"github.com/ruslanuskembaev/geo-alerts-system/internal/service"
+)
+
+const idempotencyKeyHeader = "Idempotency-Key"
+
+// idempotencyReplayHeaders заголовки ответа, которые сохраняются и повторяются вместе с телом
+var idempotencyReplayHeaders = []string{"Content-Type", "ETag", "Location"}
+
+// IdempotencyMiddleware повторяет сохранённый ответ для запросов с тем же Idempotency-Key.
+// Ключ действует в пределах арендатора, маршрута и клиента (приложения или пользователя токена),
+// поэтому middleware подключается после аутентификации. Тот же ключ с другим телом — 422,
+// пока первый запрос выполняется — 409.
+func IdempotencyMiddleware(idempotency *service.IdempotencyService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		key := strings.TrimSpace(c.GetHeader(idempotencyKeyHeader))
+		if key == "" || !idempotency.Enabled() {
+			c.Next()
+			return
+		}
+
+		body, err := io.ReadAll(c.Request.Body)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":   "invalid request",
+				"details": err.Error(),
+			})
+			c.Abort()
+			return
+		}
+		c.Request.Body = io.NopCloser(bytes.NewReader(body))
+
+		// Ответ сохраняется и после обрыва соединения клиентом, чтобы повтор получил результат
+		ctx := context.WithoutCancel(c.Request.Context())
+		scope := c.FullPath() + "\x00" + c.GetString(contextKeyClientApp) + "\x00" + c.GetString(contextKeyTokenUserID)
+		fingerprint := service.IdempotencyFingerprint(c.Request.Method, c.Request.URL.RequestURI(), body)
+
+		replay, reserved, err := idempotency.Begin(ctx, scope, key, fingerprint)
+		switch {
+		case errors.Is(err, service.ErrIdempotencyKeyTooLong):
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":   "invalid request",
+				"details": err.Error(),
+			})
+			c.Abort()
+			return
+		case errors.Is(err, service.ErrIdempotencyKeyReused):
+			c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+			c.Abort()
+			return
+		case errors.Is(err, service.ErrIdempotencyInProgress):
+			c.Header("Retry-After", "1")
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+			c.Abort()
+			return
+		}
+
+		if replay != nil {
+			for name, value := range replay.Headers {
+				c.Header(name, value)
+			}
+			c.Header("Idempotent-Replayed", "true")
+			c.Data(replay.Status, replay.Headers["Content-Type"], replay.Body)
+			c.Abort()
+			return
+		}
+		if !reserved {
+			c.Next()
+			return
+		}
+
+		recorder := &responseRecorder{ResponseWriter: c.Writer}
+		c.Writer = recorder
+		c.Next()
+
+		record := domain.IdempotencyRecord{
+			Fingerprint: fingerprint,
+			Status:      recorder.Status(),
+			Headers:     make(map[string]string),
+			Body:        recorder.body.Bytes(),
+			CreatedAt:   time.Now().UTC(),
+		}
+		for _, name := range idempotencyReplayHeaders {
+			if value := recorder.Header().Get(name); value != "" {
+				record.Headers[name] = value
+			}
+		}
+		idempotency.Complete(ctx, scope, key, record)
+	}
+}
+
+// responseRecorder копирует тело ответа для сохранения
+type responseRecorder struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (w *responseRecorder) Write(data []byte) (int, error) {
+	w.body.Write(data)
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *responseRecorder) WriteString(s string) (int, error) {
+	w.body.WriteString(s)
+	return w.ResponseWriter.WriteString(s)
+}
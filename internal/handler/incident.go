@@ -2,7 +2,9 @@ package handler
 
 import (
 	"errors"
+	"fmt"
 	"net/http"
+	"slices"
 	"strconv"
 	"time"
 
@@ -15,8 +17,9 @@ import (
 
 // IncidentHandler обработчик HTTP запросов для инцидентов
 type IncidentHandler struct {
-	service     *service.IncidentService
-	statsWindow time.Duration
+	service       *service.IncidentService
+	statsWindow   time.Duration
+	exportTimeout time.Duration
 }
 
 func NewIncidentHandler(service *service.IncidentService, statsWindow time.Duration) *IncidentHandler {
@@ -26,6 +29,12 @@ func NewIncidentHandler(service *service.IncidentService, statsWindow time.Durat
 	}
 }
 
+// WithExportTimeout задаёт срок записи потоковой выгрузки вместо WriteTimeout сервера (0 — не менять)
+func (h *IncidentHandler) WithExportTimeout(timeout time.Duration) *IncidentHandler {
+	h.exportTimeout = timeout
+	return h
+}
+
 // Create создаёт новый инцидент
 func (h *IncidentHandler) Create(c *gin.Context) {
 	var req domain.CreateIncidentRequest
@@ -48,6 +57,37 @@ func (h *IncidentHandler) Create(c *gin.Context) {
 	c.JSON(http.StatusCreated, incident)
 }
 
+// Preview оценивает охват черновика инцидента без сохранения: тело как у Create,
+// интервал проверок — window или since/until, как у Stats
+func (h *IncidentHandler) Preview(c *gin.Context) {
+	var req domain.CreateIncidentRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "invalid request",
+			"details": err.Error(),
+		})
+		return
+	}
+	query, err := h.parseStatsQuery(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "invalid request",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	preview, err := h.service.Preview(c.Request.Context(), req, query.Since, query.Until)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, preview)
+}
+
 // GetByID получает инцидент по ID
 func (h *IncidentHandler) GetByID(c *gin.Context) {
 	id := c.Param("id")
@@ -64,33 +104,59 @@ func (h *IncidentHandler) GetByID(c *gin.Context) {
 		return
 	}
 
+	c.Header("ETag", incidentETag(incident.Version))
 	c.JSON(http.StatusOK, incident)
 }
 
-// List возвращает список всех активных инцидентов
+// List возвращает список инцидентов с фильтрами, поиском и сортировкой
 func (h *IncidentHandler) List(c *gin.Context) {
-	page, pageSize, limit, offset := parsePagination(c)
+	filter, err := parseIncidentFilter(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "invalid request",
+			"details": err.Error(),
+		})
+		return
+	}
 
-	incidents, total, err := h.service.List(c.Request.Context(), limit, offset)
+	page, err := parsePageRequest(c)
 	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "invalid request",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	incidents, info, err := h.service.List(c.Request.Context(), filter, page)
+	if err != nil {
+		if errors.Is(err, domain.ErrInvalidCursor) {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":   "invalid request",
+				"details": err.Error(),
+			})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"error": err.Error(),
 		})
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"incidents": incidents,
-		"total":     total,
-		"page":      page,
-		"page_size": pageSize,
-	})
+	c.JSON(http.StatusOK, pageResponse(c, gin.H{"incidents": incidents}, page, info))
 }
 
-// Update обновляет инцидент
+// Update обновляет инцидент (If-Match — условное обновление по ETag)
 func (h *IncidentHandler) Update(c *gin.Context) {
 	id := c.Param("id")
 
+	versions, ok := parseIfMatch(c)
+	if !ok {
+		c.JSON(http.StatusPreconditionFailed, gin.H{"error": "If-Match does not match current incident version"})
+		return
+	}
+	expectedVersion := h.expectedVersion(c, id, versions)
+
 	var req domain.UpdateIncidentRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{
@@ -99,8 +165,15 @@ func (h *IncidentHandler) Update(c *gin.Context) {
 		})
 		return
 	}
+	if req.ClearExpiresAt && req.ExpiresAt != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "invalid request",
+			"details": "expires_at and clear_expires_at are mutually exclusive",
+		})
+		return
+	}
 
-	incident, err := h.service.Update(c.Request.Context(), id, req)
+	incident, err := h.service.Update(c.Request.Context(), id, req, expectedVersion)
 	if err != nil {
 		status := http.StatusInternalServerError
 		if errors.Is(err, repository.ErrNotFound) {
@@ -108,24 +181,58 @@ func (h *IncidentHandler) Update(c *gin.Context) {
 			c.JSON(status, gin.H{"error": "incident not found"})
 			return
 		}
+		if errors.Is(err, repository.ErrVersionConflict) {
+			status = http.StatusPreconditionFailed
+			c.JSON(status, gin.H{"error": "incident was modified by another request"})
+			return
+		}
 		c.JSON(status, gin.H{"error": err.Error()})
 		return
 	}
 
+	c.Header("ETag", incidentETag(incident.Version))
 	c.JSON(http.StatusOK, incident)
 }
 
-// Delete деактивирует инцидент
+// expectedVersion выбирает из списка If-Match версию, с которой сравнивается изменение.
+// Из нескольких тегов берётся текущая версия инцидента, если она в списке: изменение остаётся
+// условным по ней. Иначе берётся первая версия, и хранилище ответит конфликтом или отсутствием.
+func (h *IncidentHandler) expectedVersion(c *gin.Context, id string, versions []int) *int {
+	if len(versions) == 0 {
+		return nil
+	}
+	if len(versions) > 1 {
+		incident, err := h.service.GetByID(c.Request.Context(), id)
+		if err == nil && slices.Contains(versions, incident.Version) {
+			return &incident.Version
+		}
+	}
+	return &versions[0]
+}
+
+// Delete деактивирует инцидент (If-Match — условная деактивация по ETag)
 func (h *IncidentHandler) Delete(c *gin.Context) {
 	id := c.Param("id")
 
-	if err := h.service.Deactivate(c.Request.Context(), id); err != nil {
+	versions, ok := parseIfMatch(c)
+	if !ok {
+		c.JSON(http.StatusPreconditionFailed, gin.H{"error": "If-Match does not match current incident version"})
+		return
+	}
+	expectedVersion := h.expectedVersion(c, id, versions)
+
+	if err := h.service.Deactivate(c.Request.Context(), id, expectedVersion); err != nil {
 		status := http.StatusInternalServerError
 		if errors.Is(err, repository.ErrNotFound) {
 			status = http.StatusNotFound
 			c.JSON(status, gin.H{"error": "incident not found"})
 			return
 		}
+		if errors.Is(err, repository.ErrVersionConflict) {
+			status = http.StatusPreconditionFailed
+			c.JSON(status, gin.H{"error": "incident was modified by another request"})
+			return
+		}
 		c.JSON(status, gin.H{"error": err.Error()})
 		return
 	}
@@ -135,11 +242,77 @@ func (h *IncidentHandler) Delete(c *gin.Context) {
 	})
 }
 
-// Stats возвращает статистику по инцидентам
+// Reactivate возвращает деактивированный инцидент в работу
+func (h *IncidentHandler) Reactivate(c *gin.Context) {
+	id := c.Param("id")
+
+	versions, ok := parseIfMatch(c)
+	if !ok {
+		c.JSON(http.StatusPreconditionFailed, gin.H{"error": "If-Match does not match current incident version"})
+		return
+	}
+	expectedVersion := h.expectedVersion(c, id, versions)
+
+	incident, err := h.service.Reactivate(c.Request.Context(), id, expectedVersion)
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, repository.ErrNotFound) {
+			status = http.StatusNotFound
+			c.JSON(status, gin.H{"error": "inactive incident not found"})
+			return
+		}
+		if errors.Is(err, repository.ErrVersionConflict) {
+			status = http.StatusPreconditionFailed
+			c.JSON(status, gin.H{"error": "incident was modified by another request"})
+			return
+		}
+		c.JSON(status, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.Header("ETag", incidentETag(incident.Version))
+	c.JSON(http.StatusOK, incident)
+}
+
+// Purge безвозвратно удаляет инцидент (только администратор)
+func (h *IncidentHandler) Purge(c *gin.Context) {
+	id := c.Param("id")
+
+	if err := h.service.Purge(c.Request.Context(), id); err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, repository.ErrNotFound) {
+			status = http.StatusNotFound
+			c.JSON(status, gin.H{"error": "incident not found"})
+			return
+		}
+		c.JSON(status, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "incident purged successfully",
+	})
+}
+
+// Stats возвращает статистику по инцидентам: window (длительность, например 90m или 24h) либо
+// since, until (RFC3339, по умолчанию — сейчас), severity и incident_id (через запятую)
 func (h *IncidentHandler) Stats(c *gin.Context) {
-	since := time.Now().Add(-h.statsWindow)
+	query, err := h.parseStatsQuery(c)
+	if err == nil {
+		query.Severities, err = parseSeverityQuery(c)
+	}
+	if err == nil {
+		query.IncidentIDs, err = parseIncidentIDsQuery(c)
+	}
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "invalid request",
+			"details": err.Error(),
+		})
+		return
+	}
 
-	stats, err := h.service.StatsByIncident(c.Request.Context(), since)
+	stats, err := h.service.StatsByIncident(c.Request.Context(), query)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"error": err.Error(),
@@ -148,11 +321,217 @@ func (h *IncidentHandler) Stats(c *gin.Context) {
 	}
 
 	c.JSON(http.StatusOK, gin.H{
-		"window_minutes": int(h.statsWindow.Minutes()),
+		"window_minutes": int(query.Until.Sub(query.Since).Minutes()),
+		"since":          query.Since,
+		"until":          query.Until,
 		"stats":          stats,
 	})
 }
 
+// IncidentStats возвращает статистику одного инцидента; параметры интервала как у Stats
+func (h *IncidentHandler) IncidentStats(c *gin.Context) {
+	query, err := h.parseStatsQuery(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "invalid request",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	stats, err := h.service.IncidentStats(c.Request.Context(), c.Param("id"), query)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "incident not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"window_minutes": int(query.Until.Sub(query.Since).Minutes()),
+		"since":          query.Since,
+		"until":          query.Until,
+		"stats":          stats,
+	})
+}
+
+// parseStatsQuery разбирает интервал статистики; без параметров — последние STATS_TIME_WINDOW_MINUTES
+func (h *IncidentHandler) parseStatsQuery(c *gin.Context) (domain.IncidentStatsQuery, error) {
+	query := domain.IncidentStatsQuery{Until: time.Now().UTC()}
+
+	until, err := parseTimeQuery(c, "until")
+	if err != nil {
+		return query, err
+	}
+	if until != nil {
+		query.Until = until.UTC()
+	}
+
+	window := h.statsWindow
+	if value := c.Query("window"); value != "" {
+		window, err = time.ParseDuration(value)
+		if err != nil || window <= 0 {
+			return query, fmt.Errorf("window must be a positive duration such as 90m or 24h")
+		}
+	}
+	query.Since = query.Until.Add(-window)
+
+	since, err := parseTimeQuery(c, "since")
+	if err != nil {
+		return query, err
+	}
+	if since != nil {
+		if c.Query("window") != "" {
+			return query, fmt.Errorf("use either window or since")
+		}
+		query.Since = since.UTC()
+	}
+	if !query.Since.Before(query.Until) {
+		return query, fmt.Errorf("since must be before until")
+	}
+	if query.Until.Sub(query.Since) > domain.MaxStatsWindow {
+		return query, fmt.Errorf("interval must not exceed %s", domain.MaxStatsWindow)
+	}
+	return query, nil
+}
+
+// Heatmap агрегирует проверки координат по ячейкам geohash: precision (1..9, по умолчанию 6),
+// since/until (RFC3339, по умолчанию — окно статистики), bbox, limit (по умолчанию 1000, максимум 10000)
+func (h *IncidentHandler) Heatmap(c *gin.Context) {
+	query, err := h.parseHeatmapQuery(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "invalid request",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	heatmap, err := h.service.Heatmap(c.Request.Context(), query)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, heatmap)
+}
+
+func (h *IncidentHandler) parseHeatmapQuery(c *gin.Context) (domain.HeatmapQuery, error) {
+	query := domain.HeatmapQuery{
+		Precision: domain.DefaultHeatmapPrecision,
+		Until:     time.Now().UTC(),
+		Limit:     domain.DefaultHeatmapLimit,
+	}
+
+	if value := c.Query("precision"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed < domain.MinHeatmapPrecision || parsed > domain.MaxHeatmapPrecision {
+			return query, fmt.Errorf("precision must be between %d and %d", domain.MinHeatmapPrecision, domain.MaxHeatmapPrecision)
+		}
+		query.Precision = parsed
+	}
+	if value := c.Query("limit"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed <= 0 || parsed > domain.MaxHeatmapLimit {
+			return query, fmt.Errorf("limit must be between 1 and %d", domain.MaxHeatmapLimit)
+		}
+		query.Limit = parsed
+	}
+
+	until, err := parseTimeQuery(c, "until")
+	if err != nil {
+		return query, err
+	}
+	if until != nil {
+		query.Until = until.UTC()
+	}
+	query.Since = query.Until.Add(-h.statsWindow)
+	since, err := parseTimeQuery(c, "since")
+	if err != nil {
+		return query, err
+	}
+	if since != nil {
+		query.Since = since.UTC()
+	}
+	if !query.Since.Before(query.Until) {
+		return query, fmt.Errorf("since must be before until")
+	}
+	if query.Until.Sub(query.Since) > domain.MaxStatsWindow {
+		return query, fmt.Errorf("interval must not exceed %s", domain.MaxStatsWindow)
+	}
+
+	if query.BBox, err = parseBBoxQuery(c); err != nil {
+		return query, err
+	}
+	return query, nil
+}
+
+// StatsSeries возвращает временные ряды проверок по инцидентам: from/to (RFC3339, по умолчанию —
+// окно статистики), bucket (minute, hour, day; по умолчанию hour), incident_id (через запятую)
+func (h *IncidentHandler) StatsSeries(c *gin.Context) {
+	query, err := h.parseStatsSeriesQuery(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "invalid request",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	series, err := h.service.StatsSeries(c.Request.Context(), query)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, series)
+}
+
+func (h *IncidentHandler) parseStatsSeriesQuery(c *gin.Context) (domain.StatsSeriesQuery, error) {
+	query := domain.StatsSeriesQuery{
+		To:     time.Now().UTC(),
+		Bucket: domain.StatsBucketHour,
+	}
+
+	if value := c.Query("bucket"); value != "" {
+		query.Bucket = domain.StatsBucket(value)
+		if query.Bucket.Duration() == 0 {
+			return query, fmt.Errorf("bucket must be one of minute, hour, day")
+		}
+	}
+
+	to, err := parseTimeQuery(c, "to")
+	if err != nil {
+		return query, err
+	}
+	if to != nil {
+		query.To = to.UTC()
+	}
+	query.From = query.To.Add(-h.statsWindow)
+	from, err := parseTimeQuery(c, "from")
+	if err != nil {
+		return query, err
+	}
+	if from != nil {
+		query.From = from.UTC()
+	}
+	if !query.From.Before(query.To) {
+		return query, fmt.Errorf("from must be before to")
+	}
+	if query.To.Sub(query.From)/query.Bucket.Duration() >= domain.MaxStatsSeriesPoints {
+		return query, fmt.Errorf("interval exceeds %d %s buckets, use a larger bucket", domain.MaxStatsSeriesPoints, query.Bucket)
+	}
+
+	query.IncidentIDs, err = parseIncidentIDsQuery(c)
+	return query, err
+}
+
 func parsePagination(c *gin.Context) (int, int, int, int) {
 	page := 1
 	pageSize := 20
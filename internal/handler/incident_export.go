@@ -0,0 +1,60 @@
+package handler
+
+import (
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/ruslanuskembaev/geo-alerts-system/internal/domain"
+)
+
+var exportContentTypes = map[domain.ExportFormat]string{
+	domain.ExportFormatCSV:     "text/csv; charset=utf-8",
+	domain.ExportFormatGeoJSON: "application/geo+json",
+	domain.ExportFormatKML:     "application/vnd.google-earth.kml+xml",
+}
+
+// Export выгружает инциденты в CSV, GeoJSON или KML (format) с фильтрами списка.
+// Ответ пишется потоком по мере чтения из базы.
+func (h *IncidentHandler) Export(c *gin.Context) {
+	format := domain.ExportFormat(c.DefaultQuery("format", string(domain.ExportFormatGeoJSON)))
+	contentType, ok := exportContentTypes[format]
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "invalid request",
+			"details": "format must be csv, geojson or kml",
+		})
+		return
+	}
+
+	filter, err := parseIncidentFilter(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "invalid request",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	// Большая выгрузка пишется дольше WriteTimeout сервера: иначе клиент получит обрезанный файл
+	if h.exportTimeout > 0 {
+		if err := http.NewResponseController(c.Writer).SetWriteDeadline(time.Now().Add(h.exportTimeout)); err != nil {
+			log.Printf("Incident export write deadline not extended: %v\n", err)
+		}
+	}
+
+	c.Header("Content-Type", contentType)
+	c.Header("Content-Disposition", `attachment; filename="incidents.`+string(format)+`"`)
+
+	if err := h.service.Export(c.Request.Context(), format, filter, c.Writer); err != nil {
+		if !c.Writer.Written() {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		// Заголовки уже отправлены — остаётся оборвать поток
+		log.Printf("Incident export aborted: %v\n", err)
+		_ = c.Error(err)
+	}
+}
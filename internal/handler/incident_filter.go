@@ -0,0 +1,166 @@
+package handler
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+
+	"github.com/ruslanuskembaev/geo-alerts-system/internal/domain"
+)
+
+// parseIncidentFilter разбирает query-параметры списка инцидентов:
+// is_active, severity (через запятую), created_from/created_to, updated_from/updated_to (RFC3339),
+// q (полнотекстовый поиск), bbox=minLon,minLat,maxLon,maxLat, near=lat,lon + radius_meters,
+// sort (поле, "-" в начале — по убыванию).
+func parseIncidentFilter(c *gin.Context) (domain.IncidentFilter, error) {
+	filter := domain.IncidentFilter{
+		Query:    strings.TrimSpace(c.Query("q")),
+		SortBy:   domain.IncidentSortCreatedAt,
+		SortDesc: true,
+	}
+
+	if value := c.Query("is_active"); value != "" {
+		parsed, err := strconv.ParseBool(value)
+		if err != nil {
+			return filter, fmt.Errorf("is_active must be true or false")
+		}
+		filter.IsActive = &parsed
+	}
+
+	var err error
+	if filter.Severities, err = parseSeverityQuery(c); err != nil {
+		return filter, err
+	}
+	if filter.CreatedFrom, err = parseTimeQuery(c, "created_from"); err != nil {
+		return filter, err
+	}
+	if filter.CreatedTo, err = parseTimeQuery(c, "created_to"); err != nil {
+		return filter, err
+	}
+	if filter.UpdatedFrom, err = parseTimeQuery(c, "updated_from"); err != nil {
+		return filter, err
+	}
+	if filter.UpdatedTo, err = parseTimeQuery(c, "updated_to"); err != nil {
+		return filter, err
+	}
+
+	if filter.BBox, err = parseBBoxQuery(c); err != nil {
+		return filter, err
+	}
+
+	if value := c.Query("near"); value != "" {
+		coords, err := parseFloatList(value, 2)
+		if err != nil || !validLatitude(coords[0]) || !validLongitude(coords[1]) {
+			return filter, fmt.Errorf("near must be lat,lon")
+		}
+		radius, err := strconv.ParseFloat(c.Query("radius_meters"), 64)
+		if err != nil || radius <= 0 {
+			return filter, fmt.Errorf("radius_meters must be a positive number when near is set")
+		}
+		filter.Near = &domain.GeoCircle{
+			Latitude:     coords[0],
+			Longitude:    coords[1],
+			RadiusMeters: radius,
+		}
+	}
+
+	if value := c.Query("sort"); value != "" {
+		field, desc := strings.CutPrefix(value, "-")
+		if !domain.IncidentSortFields[field] {
+			return filter, fmt.Errorf("unsupported sort field %q", field)
+		}
+		if field == domain.IncidentSortDistance && filter.Near == nil {
+			return filter, fmt.Errorf("sort by distance requires near")
+		}
+		filter.SortBy = field
+		filter.SortDesc = desc
+	}
+
+	return filter, nil
+}
+
+// parseSeverityQuery разбирает severity=low,medium,high
+func parseSeverityQuery(c *gin.Context) ([]domain.Severity, error) {
+	value := c.Query("severity")
+	if value == "" {
+		return nil, nil
+	}
+	var severities []domain.Severity
+	for _, item := range strings.Split(value, ",") {
+		severity := domain.Severity(strings.TrimSpace(item))
+		switch severity {
+		case domain.SeverityLow, domain.SeverityMedium, domain.SeverityHigh:
+			severities = append(severities, severity)
+		default:
+			return nil, fmt.Errorf("severity must be one of low, medium, high")
+		}
+	}
+	return severities, nil
+}
+
+// parseIncidentIDsQuery разбирает incident_id — UUID инцидентов через запятую
+func parseIncidentIDsQuery(c *gin.Context) ([]string, error) {
+	value := c.Query("incident_id")
+	if value == "" {
+		return nil, nil
+	}
+	var ids []string
+	for _, id := range strings.Split(value, ",") {
+		id = strings.TrimSpace(id)
+		if _, err := uuid.Parse(id); err != nil {
+			return nil, fmt.Errorf("incident_id must be a comma-separated list of UUIDs")
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
+
+// parseBBoxQuery разбирает bbox=minLon,minLat,maxLon,maxLat
+func parseBBoxQuery(c *gin.Context) (*domain.BoundingBox, error) {
+	value := c.Query("bbox")
+	if value == "" {
+		return nil, nil
+	}
+	coords, err := parseFloatList(value, 4)
+	if err != nil {
+		return nil, fmt.Errorf("bbox must be minLon,minLat,maxLon,maxLat")
+	}
+	box := domain.BoundingBox{
+		MinLongitude: coords[0],
+		MinLatitude:  coords[1],
+		MaxLongitude: coords[2],
+		MaxLatitude:  coords[3],
+	}
+	if !validLatitude(box.MinLatitude) || !validLatitude(box.MaxLatitude) || box.MinLatitude > box.MaxLatitude ||
+		!validLongitude(box.MinLongitude) || !validLongitude(box.MaxLongitude) {
+		return nil, fmt.Errorf("bbox is out of range")
+	}
+	return &box, nil
+}
+
+func parseFloatList(value string, count int) ([]float64, error) {
+	parts := strings.Split(value, ",")
+	if len(parts) != count {
+		return nil, fmt.Errorf("expected %d values", count)
+	}
+	result := make([]float64, 0, count)
+	for _, part := range parts {
+		parsed, err := strconv.ParseFloat(strings.TrimSpace(part), 64)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, parsed)
+	}
+	return result, nil
+}
+
+func validLatitude(value float64) bool {
+	return value >= -90 && value <= 90
+}
+
+func validLongitude(value float64) bool {
+	return value >= -180 && value <= 180
+}
@@ -0,0 +1,90 @@
+package handler
+
+import (
+	"errors"
+	"mime"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/ruslanuskembaev/geo-alerts-system/internal/domain"
+	"github.com/ruslanuskembaev/geo-alerts-system/internal/service"
+)
+
+// maxImportBodyBytes ограничение размера файла импорта
+const maxImportBodyBytes = 10 << 20
+
+// Import массово создаёт инциденты из CSV или GeoJSON FeatureCollection.
+// Формат берётся из параметра format, иначе из Content-Type; dry_run=true только проверяет строки.
+func (h *IncidentHandler) Import(c *gin.Context) {
+	format, ok := importFormat(c)
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "invalid request",
+			"details": "format must be csv or geojson",
+		})
+		return
+	}
+
+	dryRun := false
+	if value := c.Query("dry_run"); value != "" {
+		parsed, err := strconv.ParseBool(value)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":   "invalid request",
+				"details": "dry_run must be boolean",
+			})
+			return
+		}
+		dryRun = parsed
+	}
+
+	body := http.MaxBytesReader(c.Writer, c.Request.Body, maxImportBodyBytes)
+	result, err := h.service.Import(c.Request.Context(), format, body, dryRun)
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		switch {
+		case errors.As(err, &tooLarge):
+			c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": "import file too large"})
+		case errors.Is(err, service.ErrInvalidImportFile), errors.Is(err, service.ErrUnsupportedImportFormat):
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":   "invalid request",
+				"details": err.Error(),
+			})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
+
+	status := http.StatusCreated
+	switch {
+	case len(result.Errors) > 0:
+		status = http.StatusUnprocessableEntity
+	case dryRun:
+		status = http.StatusOK
+	}
+	c.JSON(status, result)
+}
+
+func importFormat(c *gin.Context) (domain.ImportFormat, bool) {
+	switch c.Query("format") {
+	case string(domain.ImportFormatCSV):
+		return domain.ImportFormatCSV, true
+	case string(domain.ImportFormatGeoJSON):
+		return domain.ImportFormatGeoJSON, true
+	case "":
+	default:
+		return "", false
+	}
+
+	mediaType, _, _ := mime.ParseMediaType(c.GetHeader("Content-Type"))
+	switch mediaType {
+	case "text/csv":
+		return domain.ImportFormatCSV, true
+	case "application/geo+json", "application/json":
+		return domain.ImportFormatGeoJSON, true
+	}
+	return "", false
+}
@@ -12,10 +12,14 @@ import (
 // LocationHandler обработчик проверки локаций
 type LocationHandler struct {
 	service *service.LocationService
+	limits  *service.RateLimitService
 }
 
-func NewLocationHandler(service *service.LocationService) *LocationHandler {
-	return &LocationHandler{service: service}
+func NewLocationHandler(service *service.LocationService, limits *service.RateLimitService) *LocationHandler {
+	return &LocationHandler{
+		service: service,
+		limits:  limits,
+	}
 }
 
 func (h *LocationHandler) Check(c *gin.Context) {
@@ -28,6 +32,18 @@ func (h *LocationHandler) Check(c *gin.Context) {
 		return
 	}
 
+	if tokenUserID := c.GetString(contextKeyTokenUserID); tokenUserID != "" && tokenUserID != req.UserID {
+		c.JSON(http.StatusForbidden, gin.H{
+			"error": "user_id does not match user token",
+		})
+		return
+	}
+
+	if allowed, retryAfter := h.limits.AllowUser(c.Request.Context(), req.UserID); !allowed {
+		abortRateLimited(c, retryAfter)
+		return
+	}
+
 	response, err := h.service.CheckLocation(c.Request.Context(), req)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
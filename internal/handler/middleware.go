@@ -2,22 +2,162 @@ package handler
 
 import (
 	"crypto/subtle"
+	"errors"
+	"math"
 	"net/http"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
+
+	"github.com/ruslanuskembaev/geo-alerts-system/internal/domain"
+	"github.com/ruslanuskembaev/geo-alerts-system/internal/service"
+)
+
+const (
+	contextKeyClientApp   = "client_app"
+	contextKeyTokenUserID = "token_user_id"
 )
 
-// AuthMiddleware проверяет API-key в заголовке
-func AuthMiddleware(apiKey string) gin.HandlerFunc {
+// AuthMiddleware проверяет API-key в заголовке и определяет по нему арендатора.
+// tenantKeys — ключи операторов по арендаторам (tenant -> key).
+// Автор изменений для журнала аудита — "api_key:<tenant>": ключ не привязан к человеку, поэтому
+// X-Actor не подменяет автора, а только дописывается к нему как непроверенное уточнение
+// ("api_key:<tenant>/<X-Actor>").
+func AuthMiddleware(tenantKeys map[string]string) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		key := c.GetHeader("X-API-Key")
-		if key == "" || subtle.ConstantTimeCompare([]byte(key), []byte(apiKey)) != 1 {
+		tenantID, ok := matchKey(tenantKeys, key)
+		if key == "" || !ok {
 			c.JSON(http.StatusUnauthorized, gin.H{
 				"error": "unauthorized - valid API key required",
 			})
 			c.Abort()
 			return
 		}
+		setTenant(c, tenantID)
+
+		actor := "api_key:" + tenantID
+		if claimed := strings.TrimSpace(c.GetHeader("X-Actor")); claimed != "" {
+			actor += "/" + claimed
+		}
+		c.Request = c.Request.WithContext(domain.WithActor(c.Request.Context(), actor))
 		c.Next()
 	}
 }
+
+// ClientAuthMiddleware аутентифицирует клиентов публичных эндпоинтов.
+// Принимает ключ приложения (X-Client-Key) или токен пользователя (Authorization: Bearer).
+// Токен привязывает запрос к user_id. При required=false анонимные запросы пропускаются,
+// но переданные неверные учётные данные всё равно отклоняются.
+//
+// Арендатор берётся из учётных данных (имя приложения вида "tenant/app", claim токена),
+// для анонимных запросов — из заголовка X-Tenant-ID.
+func ClientAuthMiddleware(tokens *service.TokenService, clientKeys map[string]string, required bool) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		authenticated := false
+		tenantID := ""
+
+		if key := c.GetHeader("X-Client-Key"); key != "" {
+			app, ok := matchKey(clientKeys, key)
+			if !ok {
+				abortUnauthorized(c, "unauthorized - invalid client key")
+				return
+			}
+			c.Set(contextKeyClientApp, app)
+			tenantID = domain.DefaultTenantID
+			if appTenant, _, found := strings.Cut(app, "/"); found {
+				tenantID = appTenant
+			}
+			authenticated = true
+		}
+
+		if header := c.GetHeader("Authorization"); header != "" {
+			token, ok := strings.CutPrefix(header, "Bearer ")
+			if !ok {
+				abortUnauthorized(c, "unauthorized - bearer token expected")
+				return
+			}
+			claims, err := tokens.Verify(strings.TrimSpace(token))
+			if err != nil {
+				message := "unauthorized - invalid user token"
+				if errors.Is(err, service.ErrTokenExpired) {
+					message = "unauthorized - user token expired"
+				}
+				abortUnauthorized(c, message)
+				return
+			}
+			if tenantID != "" && tenantID != claims.TenantID {
+				abortUnauthorized(c, "unauthorized - client key and user token belong to different tenants")
+				return
+			}
+			c.Set(contextKeyTokenUserID, claims.UserID)
+			tenantID = claims.TenantID
+			authenticated = true
+		}
+
+		if required && !authenticated {
+			abortUnauthorized(c, "unauthorized - client key or user token required")
+			return
+		}
+
+		if header := c.GetHeader("X-Tenant-ID"); header != "" {
+			if tenantID != "" && header != tenantID {
+				c.JSON(http.StatusForbidden, gin.H{"error": "tenant does not match credentials"})
+				c.Abort()
+				return
+			}
+			tenantID = header
+		}
+		if tenantID == "" {
+			tenantID = domain.DefaultTenantID
+		}
+
+		setTenant(c, tenantID)
+		c.Next()
+	}
+}
+
+// IPRateLimitMiddleware ограничивает число запросов с одного IP
+func IPRateLimitMiddleware(limits *service.RateLimitService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if allowed, retryAfter := limits.AllowIP(c.Request.Context(), c.ClientIP()); !allowed {
+			abortRateLimited(c, retryAfter)
+			return
+		}
+		c.Next()
+	}
+}
+
+// matchKey ищет имя, которому соответствует ключ (name -> key)
+func matchKey(keys map[string]string, key string) (string, bool) {
+	for name, expected := range keys {
+		if subtle.ConstantTimeCompare([]byte(key), []byte(expected)) == 1 {
+			return name, true
+		}
+	}
+	return "", false
+}
+
+func setTenant(c *gin.Context, tenantID string) {
+	c.Request = c.Request.WithContext(domain.WithTenant(c.Request.Context(), tenantID))
+}
+
+func abortUnauthorized(c *gin.Context, message string) {
+	c.JSON(http.StatusUnauthorized, gin.H{"error": message})
+	c.Abort()
+}
+
+func abortRateLimited(c *gin.Context, retryAfter time.Duration) {
+	seconds := int(math.Ceil(retryAfter.Seconds()))
+	if seconds < 1 {
+		seconds = 1
+	}
+	c.Header("Retry-After", strconv.Itoa(seconds))
+	c.JSON(http.StatusTooManyRequests, gin.H{
+		"error":       "rate limit exceeded",
+		"retry_after": seconds,
+	})
+	c.Abort()
+}
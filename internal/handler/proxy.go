@@ -0,0 +1,45 @@
+package handler
+
+import (
+	"net/netip"
+	"strings"
+)
+
+// TrustedProxies адреса и подсети обратных прокси, чьим заголовкам X-Forwarded-* можно верить
+type TrustedProxies []netip.Prefix
+
+// ParseTrustedProxies разбирает адреса ("10.0.0.1") и подсети ("10.0.0.0/8")
+func ParseTrustedProxies(values []string) (TrustedProxies, error) {
+	proxies := make(TrustedProxies, 0, len(values))
+	for _, value := range values {
+		if !strings.Contains(value, "/") {
+			addr, err := netip.ParseAddr(value)
+			if err != nil {
+				return nil, err
+			}
+			proxies = append(proxies, netip.PrefixFrom(addr, addr.BitLen()))
+			continue
+		}
+		prefix, err := netip.ParsePrefix(value)
+		if err != nil {
+			return nil, err
+		}
+		proxies = append(proxies, prefix.Masked())
+	}
+	return proxies, nil
+}
+
+// Contains сообщает, пришло ли соединение от доверенного прокси
+func (p TrustedProxies) Contains(remoteIP string) bool {
+	addr, err := netip.ParseAddr(remoteIP)
+	if err != nil {
+		return false
+	}
+	addr = addr.Unmap()
+	for _, prefix := range p {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,69 @@
+package handler
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/ruslanuskembaev/geo-alerts-system/internal/domain"
+)
+
+// parseTimeQuery разбирает необязательный query-параметр в формате RFC3339
+func parseTimeQuery(c *gin.Context, name string) (*time.Time, error) {
+	value := c.Query(name)
+	if value == "" {
+		return nil, nil
+	}
+	parsed, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return nil, fmt.Errorf("%s must be RFC3339 timestamp", name)
+	}
+	return &parsed, nil
+}
+
+// parsePageRequest разбирает параметры страницы: cursor (keyset) или page/page_size (offset).
+// total по умолчанию считается только в режиме смещения; include_total=true|false переопределяет.
+func parsePageRequest(c *gin.Context) (domain.PageRequest, error) {
+	_, _, limit, offset := parsePagination(c)
+	req := domain.PageRequest{Limit: limit, Offset: offset, IncludeTotal: true}
+
+	if value := c.Query("cursor"); value != "" {
+		cursor, err := domain.DecodeCursor(value)
+		if err != nil {
+			return req, err
+		}
+		req.Cursor = cursor
+		req.Offset = 0
+		req.IncludeTotal = false
+	}
+	if value := c.Query("include_total"); value != "" {
+		include, err := strconv.ParseBool(value)
+		if err != nil {
+			return req, fmt.Errorf("include_total must be boolean")
+		}
+		req.IncludeTotal = include
+	}
+
+	return req, nil
+}
+
+// pageResponse добавляет к ответу списка параметры страницы и курсоры соседних страниц
+func pageResponse(c *gin.Context, body gin.H, req domain.PageRequest, info domain.PageInfo) gin.H {
+	page, pageSize, _, _ := parsePagination(c)
+	if req.Cursor == nil {
+		body["page"] = page
+	}
+	body["page_size"] = pageSize
+	if info.Total != nil {
+		body["total"] = *info.Total
+	}
+	if info.NextCursor != "" {
+		body["next_cursor"] = info.NextCursor
+	}
+	if info.PrevCursor != "" {
+		body["prev_cursor"] = info.PrevCursor
+	}
+	return body
+}
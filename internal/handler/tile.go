@@ -0,0 +1,78 @@
+package handler
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/ruslanuskembaev/geo-alerts-system/internal/domain"
+	"github.com/ruslanuskembaev/geo-alerts-system/internal/service"
+)
+
+const mvtContentType = "application/vnd.mapbox-vector-tile"
+
+// TileHandler векторные тайлы зон инцидентов для карты
+type TileHandler struct {
+	service *service.TileService
+}
+
+func NewTileHandler(service *service.TileService) *TileHandler {
+	return &TileHandler{service: service}
+}
+
+// Incidents отдаёт тайл /tiles/:z/:x/:y.mvt; ETag по содержимому позволяет отвечать 304
+func (h *TileHandler) Incidents(c *gin.Context) {
+	coord, err := parseTileCoord(c)
+	if err == nil && !coord.Valid() {
+		err = service.ErrInvalidTile
+	}
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "invalid request",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	tile, err := h.service.IncidentTile(c.Request.Context(), coord)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	sum := sha256.Sum256(tile)
+	etag := `"` + hex.EncodeToString(sum[:16]) + `"`
+	c.Header("ETag", etag)
+	c.Header("Cache-Control", "private, max-age=60")
+	if c.GetHeader("If-None-Match") == etag {
+		c.Status(http.StatusNotModified)
+		return
+	}
+	c.Data(http.StatusOK, mvtContentType, tile)
+}
+
+// parseTileCoord разбирает z/x/y из пути; y передаётся с расширением .mvt
+func parseTileCoord(c *gin.Context) (domain.TileCoord, error) {
+	y, ok := strings.CutSuffix(c.Param("y"), ".mvt")
+	if !ok {
+		return domain.TileCoord{}, errors.New("tile path must end with .mvt")
+	}
+
+	var coord domain.TileCoord
+	var err error
+	if coord.Z, err = strconv.Atoi(c.Param("z")); err != nil {
+		return domain.TileCoord{}, errors.New("z must be an integer")
+	}
+	if coord.X, err = strconv.Atoi(c.Param("x")); err != nil {
+		return domain.TileCoord{}, errors.New("x must be an integer")
+	}
+	if coord.Y, err = strconv.Atoi(y); err != nil {
+		return domain.TileCoord{}, errors.New("y must be an integer")
+	}
+	return coord, nil
+}
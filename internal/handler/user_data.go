@@ -0,0 +1,41 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/ruslanuskembaev/geo-alerts-system/internal/service"
+)
+
+// UserDataHandler обработчик запросов субъектов данных (выгрузка и удаление)
+type UserDataHandler struct {
+	service *service.UserDataService
+}
+
+func NewUserDataHandler(service *service.UserDataService) *UserDataHandler {
+	return &UserDataHandler{service: service}
+}
+
+// Export возвращает все данные пользователя арендатора в JSON
+func (h *UserDataHandler) Export(c *gin.Context) {
+	export, err := h.service.Export(c.Request.Context(), c.Param("user_id"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.Header("Content-Disposition", `attachment; filename="user-data.json"`)
+	c.JSON(http.StatusOK, export)
+}
+
+// Erase удаляет данные пользователя и возвращает итог удаления
+func (h *UserDataHandler) Erase(c *gin.Context) {
+	erasure, err := h.service.Erase(c.Request.Context(), c.Param("user_id"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, erasure)
+}
@@ -0,0 +1,132 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/ruslanuskembaev/geo-alerts-system/internal/domain"
+	"github.com/ruslanuskembaev/geo-alerts-system/internal/repository"
+	"github.com/ruslanuskembaev/geo-alerts-system/internal/service"
+)
+
+// LocationHistoryHandler обработчик истории проверок пользователей
+type LocationHistoryHandler struct {
+	service *service.LocationHistoryService
+}
+
+func NewLocationHistoryHandler(service *service.LocationHistoryService) *LocationHistoryHandler {
+	return &LocationHistoryHandler{service: service}
+}
+
+// UserChecks возвращает проверки пользователя: from, to (RFC3339), danger_only, cursor или page/page_size
+func (h *LocationHistoryHandler) UserChecks(c *gin.Context) {
+	filter, err := parseUserChecksFilter(c)
+	var page domain.PageRequest
+	if err == nil {
+		page, err = parsePageRequest(c)
+	}
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request", "details": err.Error()})
+		return
+	}
+
+	checks, info, err := h.service.UserChecks(c.Request.Context(), c.Param("user_id"), filter, page)
+	if err != nil {
+		if errors.Is(err, domain.ErrInvalidCursor) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request", "details": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, pageResponse(c, gin.H{"checks": checks}, page, info))
+}
+
+// IncidentUsers возвращает пользователей, попадавших в зону: since, until (RFC3339,
+// по умолчанию — последние 24 часа, не больше 31 дня), cursor или page/page_size
+func (h *LocationHistoryHandler) IncidentUsers(c *gin.Context) {
+	query, err := parseIncidentUsersQuery(c)
+	var page domain.PageRequest
+	if err == nil {
+		page, err = parsePageRequest(c)
+	}
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request", "details": err.Error()})
+		return
+	}
+
+	users, info, err := h.service.IncidentUsers(c.Request.Context(), c.Param("id"), query, page)
+	if err != nil {
+		switch {
+		case errors.Is(err, repository.ErrNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": "incident not found"})
+		case errors.Is(err, domain.ErrInvalidCursor):
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request", "details": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, pageResponse(c, gin.H{
+		"since": query.Since,
+		"until": query.Until,
+		"users": users,
+	}, page, info))
+}
+
+func parseUserChecksFilter(c *gin.Context) (domain.UserChecksFilter, error) {
+	var filter domain.UserChecksFilter
+	var err error
+
+	if filter.From, err = parseTimeQuery(c, "from"); err != nil {
+		return filter, err
+	}
+	if filter.To, err = parseTimeQuery(c, "to"); err != nil {
+		return filter, err
+	}
+	if filter.From != nil && filter.To != nil && !filter.From.Before(*filter.To) {
+		return filter, fmt.Errorf("from must be before to")
+	}
+	if value := c.Query("danger_only"); value != "" {
+		if filter.DangerOnly, err = strconv.ParseBool(value); err != nil {
+			return filter, fmt.Errorf("danger_only must be boolean")
+		}
+	}
+	return filter, nil
+}
+
+func parseIncidentUsersQuery(c *gin.Context) (domain.IncidentUsersQuery, error) {
+	query := domain.IncidentUsersQuery{Until: time.Now().UTC()}
+
+	until, err := parseTimeQuery(c, "until")
+	if err != nil {
+		return query, err
+	}
+	if until != nil {
+		query.Until = until.UTC()
+	}
+	query.Since = query.Until.Add(-domain.DefaultIncidentUsersWindow)
+
+	since, err := parseTimeQuery(c, "since")
+	if err != nil {
+		return query, err
+	}
+	if since != nil {
+		query.Since = since.UTC()
+	}
+
+	if !query.Since.Before(query.Until) {
+		return query, fmt.Errorf("since must be before until")
+	}
+	if query.Until.Sub(query.Since) > domain.MaxIncidentUsersWindow {
+		return query, fmt.Errorf("interval must not exceed %s", domain.MaxIncidentUsersWindow)
+	}
+	return query, nil
+}
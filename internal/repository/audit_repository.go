@@ -0,0 +1,162 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"strconv"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/ruslanuskembaev/geo-alerts-system/internal/domain"
+)
+
+// AuditRepository defines read access to the audit log.
+// Записи создаются в той же транзакции, что и изменение сущности.
+type AuditRepository interface {
+	List(ctx context.Context, filter domain.AuditFilter, page domain.PageRequest) ([]domain.AuditEntry, domain.PageInfo, error)
+}
+
+// PostgresAuditRepository implements AuditRepository using PostgreSQL.
+type PostgresAuditRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewAuditRepository(db *pgxpool.Pool) *PostgresAuditRepository {
+	return &PostgresAuditRepository{db: db}
+}
+
+// auditOrder порядок журнала: новые записи первыми
+var auditOrder = keysetOrder{
+	key:         "created_at",
+	expr:        "created_at",
+	idExpr:      "id",
+	desc:        true,
+	decodeValue: decodeTimeValue,
+	decodeID: func(id string) (any, error) {
+		return strconv.ParseInt(id, 10, 64)
+	},
+}
+
+func (r *PostgresAuditRepository) List(ctx context.Context, filter domain.AuditFilter, page domain.PageRequest) ([]domain.AuditEntry, domain.PageInfo, error) {
+	var where whereBuilder
+	where.add("tenant_id = ?", domain.TenantFromContext(ctx))
+	if filter.EntityType != "" {
+		where.add("entity_type = ?", filter.EntityType)
+	}
+	if filter.EntityID != "" {
+		where.add("entity_id = ?", filter.EntityID)
+	}
+	if filter.Action != "" {
+		where.add("action = ?", filter.Action)
+	}
+	if filter.Actor != "" {
+		where.add("actor = ?", filter.Actor)
+	}
+	if filter.From != nil {
+		where.add("created_at >= ?", *filter.From)
+	}
+	if filter.To != nil {
+		where.add("created_at < ?", *filter.To)
+	}
+
+	var total *int
+	if page.IncludeTotal {
+		var count int
+		if err := r.db.QueryRow(ctx, `SELECT COUNT(*) FROM audit_log `+where.sql(), where.args...).Scan(&count); err != nil {
+			return nil, domain.PageInfo{}, err
+		}
+		total = &count
+	}
+
+	orderBy, err := auditOrder.apply(&where, page.Cursor)
+	if err != nil {
+		return nil, domain.PageInfo{}, err
+	}
+	limit := auditOrder.limitOffset(&where, page)
+
+	rows, err := r.db.Query(ctx, `
+		SELECT id, tenant_id, entity_type, entity_id, action, actor, created_at, before, after, diff
+		FROM audit_log
+		`+where.sql()+`
+		`+orderBy+`
+		`+limit, where.args...)
+	if err != nil {
+		return nil, domain.PageInfo{}, err
+	}
+	defer rows.Close()
+
+	keyed := make([]keysetRow[domain.AuditEntry], 0, page.Limit+1)
+	for rows.Next() {
+		var entry domain.AuditEntry
+		if err := rows.Scan(
+			&entry.ID,
+			&entry.TenantID,
+			&entry.EntityType,
+			&entry.EntityID,
+			&entry.Action,
+			&entry.Actor,
+			&entry.CreatedAt,
+			&entry.Before,
+			&entry.After,
+			&entry.Diff,
+		); err != nil {
+			return nil, domain.PageInfo{}, err
+		}
+		keyed = append(keyed, keysetRow[domain.AuditEntry]{
+			item:      entry,
+			sortValue: entry.CreatedAt,
+			id:        strconv.FormatInt(entry.ID, 10),
+		})
+	}
+	if err := rows.Err(); err != nil {
+		return nil, domain.PageInfo{}, err
+	}
+
+	entries, info, err := paginate(auditOrder, keyed, page)
+	if err != nil {
+		return nil, domain.PageInfo{}, err
+	}
+	info.Total = total
+
+	return entries, info, nil
+}
+
+// recordIncidentRevision пишет ревизию инцидента в журнал аудита внутри транзакции
+func recordIncidentRevision(ctx context.Context, tx pgx.Tx, action string, before, after *domain.Incident) error {
+	entityID := ""
+	tenantID := domain.TenantFromContext(ctx)
+	switch {
+	case after != nil:
+		entityID, tenantID = after.ID, after.TenantID
+	case before != nil:
+		entityID, tenantID = before.ID, before.TenantID
+	}
+
+	beforeRaw, err := marshalNullable(before)
+	if err != nil {
+		return err
+	}
+	afterRaw, err := marshalNullable(after)
+	if err != nil {
+		return err
+	}
+	diff, err := json.Marshal(domain.DiffIncidents(before, after))
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(ctx, `
+		INSERT INTO audit_log (tenant_id, entity_type, entity_id, action, actor, created_at, before, after, diff)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+	`, tenantID, domain.AuditEntityIncident, entityID, action, domain.ActorFromContext(ctx), time.Now().UTC(), beforeRaw, afterRaw, diff)
+	return err
+}
+
+func marshalNullable(incident *domain.Incident) ([]byte, error) {
+	if incident == nil {
+		return nil, nil
+	}
+	return json.Marshal(incident)
+}
@@ -0,0 +1,166 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/ruslanuskembaev/geo-alerts-system/internal/domain"
+)
+
+// CAPRepository хранит принятые CAP-сообщения и применяет их к инцидентам арендатора из контекста.
+type CAPRepository interface {
+	// Apply в одной транзакции регистрирует сообщение и создаёт, обновляет или деактивирует
+	// его инциденты. Повторно полученное сообщение (тот же sender и identifier) не применяется.
+	Apply(ctx context.Context, alert domain.CAPAlert) (*domain.CAPIngestResult, error)
+}
+
+// PostgresCAPRepository implements CAPRepository using PostgreSQL.
+type PostgresCAPRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewCAPRepository(db *pgxpool.Pool) *PostgresCAPRepository {
+	return &PostgresCAPRepository{db: db}
+}
+
+// Apply сопоставляет геометрии сообщения с активными инцидентами сообщений из references по порядку:
+// Update обновляет совпавшие, создаёт недостающие и деактивирует лишние, Cancel деактивирует все.
+func (r *PostgresCAPRepository) Apply(ctx context.Context, alert domain.CAPAlert) (*domain.CAPIngestResult, error) {
+	result := &domain.CAPIngestResult{
+		Identifier:  alert.Identifier,
+		Sender:      alert.Sender,
+		MsgType:     alert.MsgType,
+		Status:      domain.CAPIngestApplied,
+		Created:     []string{},
+		Updated:     []string{},
+		Deactivated: []string{},
+	}
+	tenantID := domain.TenantFromContext(ctx)
+
+	err := inTx(ctx, r.db, func(tx pgx.Tx) error {
+		now := time.Now().UTC()
+		tag, err := tx.Exec(ctx, `
+			INSERT INTO cap_alerts (tenant_id, sender, identifier, sent, msg_type, received_at)
+			VALUES ($1, $2, $3, $4, $5, $6)
+			ON CONFLICT DO NOTHING
+		`, tenantID, alert.Sender, alert.Identifier, alert.Sent, alert.MsgType, now)
+		if err != nil {
+			return err
+		}
+		if tag.RowsAffected() == 0 {
+			result.Status = domain.CAPIngestDuplicate
+			return nil
+		}
+
+		referenced, err := referencedCAPIncidents(ctx, tx, alert.References)
+		if err != nil {
+			return err
+		}
+
+		current := []uuid.UUID{}
+		if alert.MsgType != domain.CAPMsgTypeCancel {
+			for i, req := range alert.Incidents {
+				var incident *domain.Incident
+				if i < len(referenced) {
+					incident, err = updateIncident(ctx, tx, referenced[i].ID, updateFromCreate(req), nil)
+					result.Updated = append(result.Updated, referenced[i].ID)
+				} else {
+					incident, err = insertIncident(ctx, tx, req, now)
+					if err == nil {
+						result.Created = append(result.Created, incident.ID)
+					}
+				}
+				if err != nil {
+					return err
+				}
+
+				id, err := uuid.Parse(incident.ID)
+				if err != nil {
+					return err
+				}
+				current = append(current, id)
+			}
+			referenced = referenced[min(len(referenced), len(alert.Incidents)):]
+		}
+
+		for _, incident := range referenced {
+			if err := deactivateIncident(ctx, tx, incident.ID, nil); err != nil {
+				return err
+			}
+			result.Deactivated = append(result.Deactivated, incident.ID)
+		}
+
+		_, err = tx.Exec(ctx, `
+			UPDATE cap_alerts SET incident_ids = $4
+			WHERE tenant_id = $1 AND sender = $2 AND identifier = $3
+		`, tenantID, alert.Sender, alert.Identifier, current)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// referencedCAPIncidents возвращает активные инциденты сообщений из references
+// в порядке отправки сообщений и их геометрий
+func referencedCAPIncidents(ctx context.Context, tx pgx.Tx, references []domain.CAPReference) ([]*domain.Incident, error) {
+	if len(references) == 0 {
+		return nil, nil
+	}
+	senders := make([]string, len(references))
+	identifiers := make([]string, len(references))
+	for i, ref := range references {
+		senders[i], identifiers[i] = ref.Sender, ref.Identifier
+	}
+
+	rows, err := tx.Query(ctx, `
+		SELECT `+incidentColumnList("i")+`
+		FROM cap_alerts a
+		CROSS JOIN LATERAL unnest(a.incident_ids) WITH ORDINALITY AS ref(incident_id, position)
+		JOIN incidents i ON i.id = ref.incident_id AND i.tenant_id = a.tenant_id
+		WHERE a.tenant_id = $1
+			AND (a.sender, a.identifier) IN (SELECT * FROM unnest($2::text[], $3::text[]))
+			AND i.is_active = true
+		ORDER BY a.sent, ref.position
+	`, domain.TenantFromContext(ctx), senders, identifiers)
+	if err != nil {
+		return nil, err
+	}
+
+	incidents, err := collectIncidents(rows)
+	if err != nil {
+		return nil, err
+	}
+
+	// Инцидент цепочки Update встречается во всех сообщениях, которые на него ссылаются
+	seen := make(map[string]bool, len(incidents))
+	unique := incidents[:0]
+	for _, incident := range incidents {
+		if !seen[incident.ID] {
+			seen[incident.ID] = true
+			unique = append(unique, incident)
+		}
+	}
+	return unique, nil
+}
+
+// updateFromCreate превращает новую геометрию сообщения в полное обновление инцидента;
+// Update без expires снимает срок действия прежнего сообщения
+func updateFromCreate(req domain.CreateIncidentRequest) domain.UpdateIncidentRequest {
+	return domain.UpdateIncidentRequest{
+		Title:          &req.Title,
+		Description:    &req.Description,
+		Severity:       &req.Severity,
+		Latitude:       &req.Latitude,
+		Longitude:      &req.Longitude,
+		RadiusMeters:   &req.RadiusMeters,
+		ExpiresAt:      req.ExpiresAt,
+		ClearExpiresAt: req.ExpiresAt == nil,
+	}
+}
@@ -0,0 +1,110 @@
+package repository
+
+import (
+	"context"
+	"math"
+
+	"github.com/ruslanuskembaev/geo-alerts-system/internal/domain"
+)
+
+const metersPerDegreeLatitude = 111320.0
+
+// incidentSearchVector должен совпадать с выражением индекса idx_incidents_search
+const incidentSearchVector = `to_tsvector('simple', title || ' ' || description)`
+
+const severityRank = `CASE severity WHEN 'low' THEN 1 WHEN 'medium' THEN 2 WHEN 'high' THEN 3 ELSE 0 END`
+
+// incidentFilterWhere строит условия выборки инцидентов арендатора из контекста
+func incidentFilterWhere(ctx context.Context, filter domain.IncidentFilter) *whereBuilder {
+	where := &whereBuilder{}
+	where.add("tenant_id = ?", domain.TenantFromContext(ctx))
+
+	if filter.IsActive != nil {
+		where.add("is_active = ?", *filter.IsActive)
+	}
+	if len(filter.Severities) > 0 {
+		severities := make([]string, 0, len(filter.Severities))
+		for _, severity := range filter.Severities {
+			severities = append(severities, string(severity))
+		}
+		where.add("severity = ANY(?)", severities)
+	}
+	if filter.CreatedFrom != nil {
+		where.add("created_at >= ?", *filter.CreatedFrom)
+	}
+	if filter.CreatedTo != nil {
+		where.add("created_at < ?", *filter.CreatedTo)
+	}
+	if filter.UpdatedFrom != nil {
+		where.add("updated_at >= ?", *filter.UpdatedFrom)
+	}
+	if filter.UpdatedTo != nil {
+		where.add("updated_at < ?", *filter.UpdatedTo)
+	}
+	if filter.Query != "" {
+		where.add(incidentSearchVector+" @@ plainto_tsquery('simple', ?)", filter.Query)
+	}
+	if box := filter.BBox; box != nil {
+		where.add("latitude BETWEEN ? AND ?", box.MinLatitude, box.MaxLatitude)
+		if box.MinLongitude <= box.MaxLongitude {
+			where.add("longitude BETWEEN ? AND ?", box.MinLongitude, box.MaxLongitude)
+		} else {
+			// Прямоугольник пересекает антимеридиан
+			where.add("(longitude >= ? OR longitude <= ?)", box.MinLongitude, box.MaxLongitude)
+		}
+	}
+	if near := filter.Near; near != nil {
+		// Грубый прямоугольник для индекса, затем точное расстояние
+		latDelta := near.RadiusMeters / metersPerDegreeLatitude
+		where.add("latitude BETWEEN ? AND ?", near.Latitude-latDelta, near.Latitude+latDelta)
+		if cosLat := math.Cos(near.Latitude * math.Pi / 180); cosLat > 0.01 {
+			lonDelta := near.RadiusMeters / (metersPerDegreeLatitude * cosLat)
+			minLon, maxLon := near.Longitude-lonDelta, near.Longitude+lonDelta
+			switch {
+			case lonDelta >= 180:
+			case minLon < -180:
+				// Круг пересекает антимеридиан, как и прямоугольник bbox
+				where.add("(longitude >= ? OR longitude <= ?)", minLon+360, maxLon)
+			case maxLon > 180:
+				where.add("(longitude >= ? OR longitude <= ?)", minLon, maxLon-360)
+			default:
+				where.add("longitude BETWEEN ? AND ?", minLon, maxLon)
+			}
+		}
+		where.add("geo_distance_meters(?, ?, latitude, longitude) <= ?", near.Latitude, near.Longitude, near.RadiusMeters)
+	}
+
+	return where
+}
+
+// incidentKeysetOrder возвращает сортировку списка; id добавляется для стабильного порядка
+func incidentKeysetOrder(filter domain.IncidentFilter, where *whereBuilder) keysetOrder {
+	order := keysetOrder{
+		key:         domain.IncidentSortCreatedAt,
+		expr:        "created_at",
+		idExpr:      "id",
+		desc:        filter.SortDesc,
+		decodeValue: decodeTimeValue,
+	}
+
+	switch filter.SortBy {
+	case domain.IncidentSortUpdatedAt:
+		order.key, order.expr = filter.SortBy, "updated_at"
+	case domain.IncidentSortTitle:
+		order.key, order.expr, order.decodeValue = filter.SortBy, "title", decodeStringValue
+	case domain.IncidentSortSeverity:
+		order.key, order.expr, order.decodeValue = filter.SortBy, severityRank, decodeIntValue
+	case domain.IncidentSortRadiusMeters:
+		order.key, order.expr, order.decodeValue = filter.SortBy, "radius_meters", decodeIntValue
+	case domain.IncidentSortDistance:
+		if filter.Near != nil {
+			order.key, order.decodeValue = filter.SortBy, decodeFloatValue
+			order.expr = "geo_distance_meters(" + where.arg(filter.Near.Latitude) + ", " + where.arg(filter.Near.Longitude) + ", latitude, longitude)"
+		}
+	case domain.IncidentSortCreatedAt:
+	default:
+		order.desc = true
+	}
+
+	return order
+}
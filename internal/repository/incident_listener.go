@@ -0,0 +1,115 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+const (
+	// incidentChangesChannel канал NOTIFY триггера incidents_notify_change
+	incidentChangesChannel = "incident_changes"
+	// IncidentListenerApplicationName application_name соединения подписки в pg_stat_activity
+	IncidentListenerApplicationName = "geoalerts-incident-listener"
+	// incidentListenerLockKey ключ advisory-блокировки ведущего слушателя ("geoalert")
+	incidentListenerLockKey int64 = 0x67656f616c657274
+	// incidentListenerLockRetry пауза между попытками резервной реплики стать ведущей
+	incidentListenerLockRetry = 5 * time.Second
+)
+
+// IncidentChangeListener defines a subscription to incident changes made in the database.
+type IncidentChangeListener interface {
+	// Listen подписывается на изменения incidents и вызывает onChange с арендатором изменённой
+	// строки. Подписан только один слушатель среди реплик (ведущий): остальные ждут, пока он
+	// не отключится, так что каждое изменение обрабатывается один раз. Уведомления до подписки
+	// теряются, поэтому сразу после неё onChange вызывается для всех арендаторов с инцидентами.
+	// Блокируется до ошибки соединения или отмены ctx.
+	Listen(ctx context.Context, onChange func(tenantID string)) error
+}
+
+// PostgresIncidentChangeListener implements IncidentChangeListener using LISTEN/NOTIFY.
+// Для подписки открывается отдельное соединение вне пула; ведущего выбирает сессионная
+// advisory-блокировка, которая снимается вместе с соединением.
+type PostgresIncidentChangeListener struct {
+	db        *pgxpool.Pool
+	lockRetry time.Duration
+}
+
+func NewIncidentChangeListener(db *pgxpool.Pool) *PostgresIncidentChangeListener {
+	return &PostgresIncidentChangeListener{db: db, lockRetry: incidentListenerLockRetry}
+}
+
+// WithLockRetry задаёт паузу между попытками стать ведущим слушателем
+func (l *PostgresIncidentChangeListener) WithLockRetry(interval time.Duration) *PostgresIncidentChangeListener {
+	l.lockRetry = interval
+	return l
+}
+
+func (l *PostgresIncidentChangeListener) Listen(ctx context.Context, onChange func(tenantID string)) error {
+	config := l.db.Config().ConnConfig.Copy()
+	config.RuntimeParams["application_name"] = IncidentListenerApplicationName
+	conn, err := pgx.ConnectConfig(ctx, config)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = conn.Close(context.WithoutCancel(ctx))
+	}()
+
+	if err := l.waitForLock(ctx, conn); err != nil {
+		return err
+	}
+	if _, err := conn.Exec(ctx, "LISTEN "+incidentChangesChannel); err != nil {
+		return err
+	}
+
+	rows, err := conn.Query(ctx, `SELECT DISTINCT tenant_id FROM incidents`)
+	if err != nil {
+		return err
+	}
+	tenants := make([]string, 0)
+	for rows.Next() {
+		var tenantID string
+		if err := rows.Scan(&tenantID); err != nil {
+			rows.Close()
+			return err
+		}
+		tenants = append(tenants, tenantID)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	for _, tenantID := range tenants {
+		onChange(tenantID)
+	}
+
+	for {
+		notification, err := conn.WaitForNotification(ctx)
+		if err != nil {
+			return err
+		}
+		onChange(notification.Payload)
+	}
+}
+
+// waitForLock ждёт, пока соединение не получит блокировку ведущего слушателя
+func (l *PostgresIncidentChangeListener) waitForLock(ctx context.Context, conn *pgx.Conn) error {
+	for {
+		var locked bool
+		if err := conn.QueryRow(ctx, `SELECT pg_try_advisory_lock($1)`, incidentListenerLockKey).Scan(&locked); err != nil {
+			return err
+		}
+		if locked {
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(l.lockRetry):
+		}
+	}
+}
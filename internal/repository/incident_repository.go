@@ -3,6 +3,7 @@ package repository
 import (
 	"context"
 	"errors"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
@@ -12,16 +13,56 @@ import (
 	"github.com/ruslanuskembaev/geo-alerts-system/internal/domain"
 )
 
-var ErrNotFound = errors.New("not found")
+var (
+	ErrNotFound        = errors.New("not found")
+	ErrVersionConflict = errors.New("version conflict")
+)
+
+var incidentFields = []string{
+	"id", "tenant_id", "title", "description", "severity", "latitude", "longitude", "radius_meters",
+	"is_active", "version", "created_at", "updated_at", "deactivated_at", "expires_at",
+}
+
+var incidentColumns = incidentColumnList("")
+
+// incidentColumnList возвращает список колонок инцидента с алиасом таблицы
+func incidentColumnList(alias string) string {
+	prefix := ""
+	if alias != "" {
+		prefix = alias + "."
+	}
+	columns := make([]string, len(incidentFields))
+	for i, field := range incidentFields {
+		columns[i] = prefix + field
+	}
+	return strings.Join(columns, ", ")
+}
 
 // IncidentRepository defines incident storage operations.
+// Все операции ограничены арендатором из контекста (domain.TenantFromContext).
+// expectedVersion (если задан) делает изменение условным: при несовпадении версии
+// возвращается ErrVersionConflict.
 type IncidentRepository interface {
 	Create(ctx context.Context, req domain.CreateIncidentRequest) (*domain.Incident, error)
+	CreateBatch(ctx context.Context, reqs []domain.CreateIncidentRequest) ([]*domain.Incident, error)
 	GetByID(ctx context.Context, id string) (*domain.Incident, error)
-	List(ctx context.Context, limit, offset int) ([]*domain.Incident, int, error)
-	Update(ctx context.Context, id string, req domain.UpdateIncidentRequest) (*domain.Incident, error)
-	Deactivate(ctx context.Context, id string) error
+	List(ctx context.Context, filter domain.IncidentFilter, page domain.PageRequest) ([]*domain.Incident, domain.PageInfo, error)
+	// Stream передаёт в fn все инциденты по фильтру, не загружая выборку в память
+	Stream(ctx context.Context, filter domain.IncidentFilter, fn func(*domain.Incident) error) error
+	Update(ctx context.Context, id string, req domain.UpdateIncidentRequest, expectedVersion *int) (*domain.Incident, error)
+	Deactivate(ctx context.Context, id string, expectedVersion *int) error
+	Reactivate(ctx context.Context, id string, expectedVersion *int) (*domain.Incident, error)
+	// Purge безвозвратно удаляет инцидент вместе со связями location_check_incidents
+	Purge(ctx context.Context, id string) error
+	// PurgeDeactivatedBefore удаляет инциденты всех арендаторов, деактивированные раньше before
+	PurgeDeactivatedBefore(ctx context.Context, before time.Time) (int, error)
+	// DeactivateExpired деактивирует активные инциденты всех арендаторов с expires_at <= now
+	DeactivateExpired(ctx context.Context, now time.Time) ([]*domain.Incident, error)
 	ListActive(ctx context.Context) ([]*domain.Incident, error)
+	// LastUpdatedAt время последнего изменения инцидентов арендатора (нулевое, если их нет)
+	LastUpdatedAt(ctx context.Context) (time.Time, error)
+	// ListOverlapping возвращает активные инциденты арендатора, чьи зоны пересекаются с zone, ближайшие первыми
+	ListOverlapping(ctx context.Context, zone domain.ImpactZone) ([]domain.OverlappingIncident, error)
 }
 
 // PostgresIncidentRepository implements IncidentRepository using PostgreSQL.
@@ -34,11 +75,45 @@ func NewIncidentRepository(db *pgxpool.Pool) *PostgresIncidentRepository {
 }
 
 func (r *PostgresIncidentRepository) Create(ctx context.Context, req domain.CreateIncidentRequest) (*domain.Incident, error) {
-	id := uuid.New().String()
-	now := time.Now().UTC()
+	var incident *domain.Incident
+	err := inTx(ctx, r.db, func(tx pgx.Tx) error {
+		var err error
+		incident, err = insertIncident(ctx, tx, req, time.Now().UTC())
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return incident, nil
+}
+
+// CreateBatch создаёт инциденты в одной транзакции: при ошибке не создаётся ни один
+func (r *PostgresIncidentRepository) CreateBatch(ctx context.Context, reqs []domain.CreateIncidentRequest) ([]*domain.Incident, error) {
+	incidents := make([]*domain.Incident, 0, len(reqs))
+	err := inTx(ctx, r.db, func(tx pgx.Tx) error {
+		now := time.Now().UTC()
+		for _, req := range reqs {
+			incident, err := insertIncident(ctx, tx, req, now)
+			if err != nil {
+				return err
+			}
+			incidents = append(incidents, incident)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return incidents, nil
+}
 
+// insertIncident вставляет инцидент и ревизию создания в рамках транзакции
+func insertIncident(ctx context.Context, tx pgx.Tx, req domain.CreateIncidentRequest, now time.Time) (*domain.Incident, error) {
 	incident := &domain.Incident{
-		ID:           id,
+		ID:           uuid.New().String(),
+		TenantID:     domain.TenantFromContext(ctx),
 		Title:        req.Title,
 		Description:  req.Description,
 		Severity:     req.Severity,
@@ -46,17 +121,21 @@ func (r *PostgresIncidentRepository) Create(ctx context.Context, req domain.Crea
 		Longitude:    req.Longitude,
 		RadiusMeters: req.RadiusMeters,
 		IsActive:     true,
+		Version:      1,
 		CreatedAt:    now,
 		UpdatedAt:    now,
+		ExpiresAt:    req.ExpiresAt,
 	}
 
-	_, err := r.db.Exec(ctx, `
+	if _, err := tx.Exec(ctx, `
 		INSERT INTO incidents (
-			id, title, description, severity, latitude, longitude, radius_meters,
-			is_active, created_at, updated_at
-		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
-	`, incident.ID, incident.Title, incident.Description, incident.Severity, incident.Latitude, incident.Longitude, incident.RadiusMeters, incident.IsActive, incident.CreatedAt, incident.UpdatedAt)
-	if err != nil {
+			id, tenant_id, title, description, severity, latitude, longitude, radius_meters,
+			is_active, version, created_at, updated_at, expires_at
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13)
+	`, incident.ID, incident.TenantID, incident.Title, incident.Description, incident.Severity, incident.Latitude, incident.Longitude, incident.RadiusMeters, incident.IsActive, incident.Version, incident.CreatedAt, incident.UpdatedAt, incident.ExpiresAt); err != nil {
+		return nil, err
+	}
+	if err := recordIncidentRevision(ctx, tx, domain.AuditActionCreate, nil, incident); err != nil {
 		return nil, err
 	}
 
@@ -64,25 +143,11 @@ func (r *PostgresIncidentRepository) Create(ctx context.Context, req domain.Crea
 }
 
 func (r *PostgresIncidentRepository) GetByID(ctx context.Context, id string) (*domain.Incident, error) {
-	var incident domain.Incident
-
-	err := r.db.QueryRow(ctx, `
-		SELECT id, title, description, severity, latitude, longitude, radius_meters,
-		       is_active, created_at, updated_at
+	incident, err := scanIncident(r.db.QueryRow(ctx, `
+		SELECT `+incidentColumns+`
 		FROM incidents
-		WHERE id = $1
-	`, id).Scan(
-		&incident.ID,
-		&incident.Title,
-		&incident.Description,
-		&incident.Severity,
-		&incident.Latitude,
-		&incident.Longitude,
-		&incident.RadiusMeters,
-		&incident.IsActive,
-		&incident.CreatedAt,
-		&incident.UpdatedAt,
-	)
+		WHERE id = $1 AND tenant_id = $2
+	`, id, domain.TenantFromContext(ctx)))
 	if errors.Is(err, pgx.ErrNoRows) {
 		return nil, ErrNotFound
 	}
@@ -90,145 +155,471 @@ func (r *PostgresIncidentRepository) GetByID(ctx context.Context, id string) (*d
 		return nil, err
 	}
 
-	return &incident, nil
+	return incident, nil
 }
 
-func (r *PostgresIncidentRepository) List(ctx context.Context, limit, offset int) ([]*domain.Incident, int, error) {
-	var total int
-	if err := r.db.QueryRow(ctx, `SELECT COUNT(*) FROM incidents`).Scan(&total); err != nil {
-		return nil, 0, err
+// List возвращает страницу инцидентов: по курсору (keyset) либо по смещению.
+// Общее число записей считается только при page.IncludeTotal.
+func (r *PostgresIncidentRepository) List(ctx context.Context, filter domain.IncidentFilter, page domain.PageRequest) ([]*domain.Incident, domain.PageInfo, error) {
+	where := incidentFilterWhere(ctx, filter)
+
+	var total *int
+	if page.IncludeTotal {
+		var count int
+		if err := r.db.QueryRow(ctx, `SELECT COUNT(*) FROM incidents `+where.sql(), where.args...).Scan(&count); err != nil {
+			return nil, domain.PageInfo{}, err
+		}
+		total = &count
+	}
+
+	order := incidentKeysetOrder(filter, where)
+	orderBy, err := order.apply(where, page.Cursor)
+	if err != nil {
+		return nil, domain.PageInfo{}, err
 	}
+	limit := order.limitOffset(where, page)
 
 	rows, err := r.db.Query(ctx, `
-		SELECT id, title, description, severity, latitude, longitude, radius_meters,
-		       is_active, created_at, updated_at
+		SELECT `+incidentColumns+`, `+order.expr+`
 		FROM incidents
-		ORDER BY created_at DESC
-		LIMIT $1 OFFSET $2
-	`, limit, offset)
+		`+where.sql()+`
+		`+orderBy+`
+		`+limit, where.args...)
 	if err != nil {
-		return nil, 0, err
+		return nil, domain.PageInfo{}, err
 	}
 	defer rows.Close()
 
-	incidents := make([]*domain.Incident, 0)
+	keyed := make([]keysetRow[*domain.Incident], 0, page.Limit+1)
 	for rows.Next() {
 		var incident domain.Incident
-		if err := rows.Scan(
-			&incident.ID,
-			&incident.Title,
-			&incident.Description,
-			&incident.Severity,
-			&incident.Latitude,
-			&incident.Longitude,
-			&incident.RadiusMeters,
-			&incident.IsActive,
-			&incident.CreatedAt,
-			&incident.UpdatedAt,
-		); err != nil {
-			return nil, 0, err
-		}
-		incidents = append(incidents, &incident)
+		var sortValue any
+		if err := rows.Scan(append(incidentScanTargets(&incident), &sortValue)...); err != nil {
+			return nil, domain.PageInfo{}, err
+		}
+		keyed = append(keyed, keysetRow[*domain.Incident]{item: &incident, sortValue: sortValue, id: incident.ID})
 	}
 	if err := rows.Err(); err != nil {
-		return nil, 0, err
+		return nil, domain.PageInfo{}, err
+	}
+
+	incidents, info, err := paginate(order, keyed, page)
+	if err != nil {
+		return nil, domain.PageInfo{}, err
 	}
+	info.Total = total
 
-	return incidents, total, nil
+	return incidents, info, nil
 }
 
-func (r *PostgresIncidentRepository) Update(ctx context.Context, id string, req domain.UpdateIncidentRequest) (*domain.Incident, error) {
-	existing, err := r.GetByID(ctx, id)
+func (r *PostgresIncidentRepository) Stream(ctx context.Context, filter domain.IncidentFilter, fn func(*domain.Incident) error) error {
+	where := incidentFilterWhere(ctx, filter)
+	orderBy, err := incidentKeysetOrder(filter, where).apply(where, nil)
 	if err != nil {
-		return nil, err
+		return err
 	}
 
-	if req.Title != nil {
-		existing.Title = *req.Title
+	rows, err := r.db.Query(ctx, `
+		SELECT `+incidentColumns+`
+		FROM incidents
+		`+where.sql()+`
+		`+orderBy, where.args...)
+	if err != nil {
+		return err
 	}
-	if req.Description != nil {
-		existing.Description = *req.Description
+	defer rows.Close()
+
+	for rows.Next() {
+		var incident domain.Incident
+		if err := rows.Scan(incidentScanTargets(&incident)...); err != nil {
+			return err
+		}
+		if err := fn(&incident); err != nil {
+			return err
+		}
 	}
-	if req.Severity != nil {
-		existing.Severity = *req.Severity
+	return rows.Err()
+}
+
+func (r *PostgresIncidentRepository) Update(ctx context.Context, id string, req domain.UpdateIncidentRequest, expectedVersion *int) (*domain.Incident, error) {
+	var updated *domain.Incident
+	err := inTx(ctx, r.db, func(tx pgx.Tx) error {
+		var err error
+		updated, err = updateIncident(ctx, tx, id, req, expectedVersion)
+		return err
+	})
+	if err != nil {
+		return nil, err
 	}
-	if req.Latitude != nil {
-		existing.Latitude = *req.Latitude
+
+	return updated, nil
+}
+
+// updateIncident применяет переданные поля и записывает ревизию в рамках транзакции
+func updateIncident(ctx context.Context, tx pgx.Tx, id string, req domain.UpdateIncidentRequest, expectedVersion *int) (*domain.Incident, error) {
+	// Условное обновление одним выражением: CTE блокирует строку и отдаёт состояние "до",
+	// UPDATE применяет только переданные поля и увеличивает версию.
+	before, after, err := scanIncidentChange(tx.QueryRow(ctx, `
+		WITH before AS (
+			SELECT `+incidentColumns+`
+			FROM incidents
+			WHERE id = $1 AND tenant_id = $2
+			FOR UPDATE
+		)
+		UPDATE incidents i
+		SET title = COALESCE($3, i.title),
+			description = COALESCE($4, i.description),
+			severity = COALESCE($5, i.severity),
+			latitude = COALESCE($6, i.latitude),
+			longitude = COALESCE($7, i.longitude),
+			radius_meters = COALESCE($8, i.radius_meters),
+			expires_at = CASE WHEN $12 THEN NULL ELSE COALESCE($11, i.expires_at) END,
+			version = i.version + 1,
+			updated_at = $9
+		FROM before b
+		WHERE i.id = b.id AND ($10::integer IS NULL OR b.version = $10)
+		RETURNING `+incidentColumnList("b")+`, `+incidentColumnList("i")+`
+	`, id, domain.TenantFromContext(ctx), req.Title, req.Description, req.Severity, req.Latitude, req.Longitude, req.RadiusMeters, time.Now().UTC(), expectedVersion, req.ExpiresAt, req.ClearExpiresAt))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, missOrConflict(ctx, tx, id)
 	}
-	if req.Longitude != nil {
-		existing.Longitude = *req.Longitude
+	if err != nil {
+		return nil, err
 	}
-	if req.RadiusMeters != nil {
-		existing.RadiusMeters = *req.RadiusMeters
+
+	if err := recordIncidentRevision(ctx, tx, domain.AuditActionUpdate, before, after); err != nil {
+		return nil, err
 	}
+	return after, nil
+}
 
-	existing.UpdatedAt = time.Now().UTC()
+func (r *PostgresIncidentRepository) Deactivate(ctx context.Context, id string, expectedVersion *int) error {
+	return inTx(ctx, r.db, func(tx pgx.Tx) error {
+		return deactivateIncident(ctx, tx, id, expectedVersion)
+	})
+}
 
-	_, err = r.db.Exec(ctx, `
-		UPDATE incidents
-		SET title = $2,
-			description = $3,
-			severity = $4,
-			latitude = $5,
-			longitude = $6,
-			radius_meters = $7,
-			is_active = $8,
-			updated_at = $9
-		WHERE id = $1
-	`, existing.ID, existing.Title, existing.Description, existing.Severity, existing.Latitude, existing.Longitude, existing.RadiusMeters, existing.IsActive, existing.UpdatedAt)
+// deactivateIncident снимает инцидент с активных и записывает ревизию в рамках транзакции
+func deactivateIncident(ctx context.Context, tx pgx.Tx, id string, expectedVersion *int) error {
+	before, after, err := scanIncidentChange(tx.QueryRow(ctx, `
+		WITH before AS (
+			SELECT `+incidentColumns+`
+			FROM incidents
+			WHERE id = $1 AND tenant_id = $2 AND is_active = true
+			FOR UPDATE
+		)
+		UPDATE incidents i
+		SET is_active = false,
+			version = i.version + 1,
+			updated_at = $3,
+			deactivated_at = $3
+		FROM before b
+		WHERE i.id = b.id AND ($4::integer IS NULL OR b.version = $4)
+		RETURNING `+incidentColumnList("b")+`, `+incidentColumnList("i")+`
+	`, id, domain.TenantFromContext(ctx), time.Now().UTC(), expectedVersion))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return missOrConflict(ctx, tx, id, "is_active = true")
+	}
+	if err != nil {
+		return err
+	}
+
+	return recordIncidentRevision(ctx, tx, domain.AuditActionDeactivate, before, after)
+}
+
+func (r *PostgresIncidentRepository) Reactivate(ctx context.Context, id string, expectedVersion *int) (*domain.Incident, error) {
+	var reactivated *domain.Incident
+	err := inTx(ctx, r.db, func(tx pgx.Tx) error {
+		before, after, err := scanIncidentChange(tx.QueryRow(ctx, `
+			WITH before AS (
+				SELECT `+incidentColumns+`
+				FROM incidents
+				WHERE id = $1 AND tenant_id = $2 AND is_active = false
+				FOR UPDATE
+			)
+			UPDATE incidents i
+			SET is_active = true,
+				version = i.version + 1,
+				updated_at = $3,
+				deactivated_at = NULL
+			FROM before b
+			WHERE i.id = b.id AND ($4::integer IS NULL OR b.version = $4)
+			RETURNING `+incidentColumnList("b")+`, `+incidentColumnList("i")+`
+		`, id, domain.TenantFromContext(ctx), time.Now().UTC(), expectedVersion))
+		if errors.Is(err, pgx.ErrNoRows) {
+			return missOrConflict(ctx, tx, id, "is_active = false")
+		}
+		if err != nil {
+			return err
+		}
+
+		reactivated = after
+		return recordIncidentRevision(ctx, tx, domain.AuditActionReactivate, before, after)
+	})
 	if err != nil {
 		return nil, err
 	}
 
-	return existing, nil
+	return reactivated, nil
+}
+
+func (r *PostgresIncidentRepository) Purge(ctx context.Context, id string) error {
+	return inTx(ctx, r.db, func(tx pgx.Tx) error {
+		purged, err := r.deleteIncidents(ctx, tx, `id = $1 AND tenant_id = $2`, id, domain.TenantFromContext(ctx))
+		if err != nil {
+			return err
+		}
+		if len(purged) == 0 {
+			return ErrNotFound
+		}
+		return nil
+	})
 }
 
-func (r *PostgresIncidentRepository) Deactivate(ctx context.Context, id string) error {
-	tag, err := r.db.Exec(ctx, `
-		UPDATE incidents
-		SET is_active = false, updated_at = $2
-		WHERE id = $1 AND is_active = true
-	`, id, time.Now().UTC())
+func (r *PostgresIncidentRepository) PurgeDeactivatedBefore(ctx context.Context, before time.Time) (int, error) {
+	var count int
+	err := inTx(ctx, r.db, func(tx pgx.Tx) error {
+		purged, err := r.deleteIncidents(ctx, tx, `is_active = false AND deactivated_at < $1`, before)
+		count = len(purged)
+		return err
+	})
 	if err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
+// DeactivateExpired деактивирует истёкшие инциденты одним UPDATE, записывая ревизию deactivate для каждого
+func (r *PostgresIncidentRepository) DeactivateExpired(ctx context.Context, now time.Time) ([]*domain.Incident, error) {
+	var expired []*domain.Incident
+	err := inTx(ctx, r.db, func(tx pgx.Tx) error {
+		rows, err := tx.Query(ctx, `
+			WITH before AS (
+				SELECT `+incidentColumns+`
+				FROM incidents
+				WHERE is_active = true AND expires_at <= $1
+				FOR UPDATE
+			)
+			UPDATE incidents i
+			SET is_active = false,
+				version = i.version + 1,
+				updated_at = $1,
+				deactivated_at = $1
+			FROM before b
+			WHERE i.id = b.id
+			RETURNING `+incidentColumnList("b")+`, `+incidentColumnList("i"), now)
+		if err != nil {
+			return err
+		}
+
+		var befores []*domain.Incident
+		for rows.Next() {
+			before, after, err := scanIncidentChange(rows)
+			if err != nil {
+				rows.Close()
+				return err
+			}
+			befores = append(befores, before)
+			expired = append(expired, after)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return err
+		}
+
+		for i, after := range expired {
+			if err := recordIncidentRevision(ctx, tx, domain.AuditActionDeactivate, befores[i], after); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return expired, nil
+}
+
+// deleteIncidents удаляет подходящие инциденты, их связи с проверками и ссылки
+// из принятых CAP-сообщений, записывая ревизию purge для каждого
+func (r *PostgresIncidentRepository) deleteIncidents(ctx context.Context, tx pgx.Tx, where string, args ...any) ([]*domain.Incident, error) {
+	rows, err := tx.Query(ctx, `
+		DELETE FROM incidents
+		WHERE `+where+`
+		RETURNING `+incidentColumns, args...)
+	if err != nil {
+		return nil, err
+	}
+	purged, err := collectIncidents(rows)
+	if err != nil {
+		return nil, err
+	}
+	if len(purged) == 0 {
+		return purged, nil
+	}
+
+	ids := make([]uuid.UUID, 0, len(purged))
+	for _, incident := range purged {
+		parsed, err := uuid.Parse(incident.ID)
+		if err != nil {
+			return nil, err
+		}
+		ids = append(ids, parsed)
+	}
+	// Связи удаляются и каскадом FK; явное удаление не зависит от схемы ключей
+	if _, err := tx.Exec(ctx, `
+		DELETE FROM location_check_incidents WHERE incident_id = ANY($1::uuid[])
+	`, ids); err != nil {
+		return nil, err
+	}
+	// Иначе Update/Cancel по сообщению сопоставлял бы свои зоны с удалёнными инцидентами
+	if _, err := tx.Exec(ctx, `
+		UPDATE cap_alerts
+		SET incident_ids = ARRAY(
+			SELECT ref FROM unnest(incident_ids) WITH ORDINALITY AS t(ref, position)
+			WHERE NOT ref = ANY($1::uuid[])
+			ORDER BY position
+		)
+		WHERE incident_ids && $1::uuid[]
+	`, ids); err != nil {
+		return nil, err
+	}
+
+	for _, incident := range purged {
+		if err := recordIncidentRevision(ctx, tx, domain.AuditActionPurge, incident, nil); err != nil {
+			return nil, err
+		}
+	}
+	return purged, nil
+}
+
+// missOrConflict объясняет, почему условное изменение не затронуло строк:
+// инцидента нет (или он не подходит под extra-условие) либо не совпала версия
+func missOrConflict(ctx context.Context, tx pgx.Tx, id string, extra ...string) error {
+	query := `SELECT EXISTS (SELECT 1 FROM incidents WHERE id = $1 AND tenant_id = $2`
+	for _, cond := range extra {
+		query += " AND " + cond
+	}
+	query += ")"
+
+	var exists bool
+	if err := tx.QueryRow(ctx, query, id, domain.TenantFromContext(ctx)).Scan(&exists); err != nil {
 		return err
 	}
-	if tag.RowsAffected() == 0 {
+	if !exists {
 		return ErrNotFound
 	}
-	return nil
+	return ErrVersionConflict
 }
 
 func (r *PostgresIncidentRepository) ListActive(ctx context.Context) ([]*domain.Incident, error) {
 	rows, err := r.db.Query(ctx, `
-		SELECT id, title, description, severity, latitude, longitude, radius_meters,
-		       is_active, created_at, updated_at
+		SELECT `+incidentColumns+`
 		FROM incidents
-		WHERE is_active = true
+		WHERE tenant_id = $1 AND is_active = true AND (expires_at IS NULL OR expires_at > $2)
 		ORDER BY created_at DESC
-	`)
+	`, domain.TenantFromContext(ctx), time.Now().UTC())
 	if err != nil {
 		return nil, err
 	}
+
+	return collectIncidents(rows)
+}
+
+func (r *PostgresIncidentRepository) LastUpdatedAt(ctx context.Context) (time.Time, error) {
+	var last *time.Time
+	if err := r.db.QueryRow(ctx, `
+		SELECT MAX(updated_at) FROM incidents WHERE tenant_id = $1
+	`, domain.TenantFromContext(ctx)).Scan(&last); err != nil {
+		return time.Time{}, err
+	}
+	if last == nil {
+		return time.Time{}, nil
+	}
+	return last.UTC(), nil
+}
+
+func (r *PostgresIncidentRepository) ListOverlapping(ctx context.Context, zone domain.ImpactZone) ([]domain.OverlappingIncident, error) {
+	rows, err := r.db.Query(ctx, `
+		SELECT id, title, severity, latitude, longitude, radius_meters, distance
+		FROM (
+			SELECT id, title, severity, latitude, longitude, radius_meters,
+			       geo_distance_meters($2, $3, latitude, longitude) AS distance
+			FROM incidents
+			WHERE tenant_id = $1 AND is_active = true AND (expires_at IS NULL OR expires_at > $5)
+		) candidates
+		WHERE distance < radius_meters + $4
+		ORDER BY distance, id
+	`, domain.TenantFromContext(ctx), zone.Latitude, zone.Longitude, zone.RadiusMeters, time.Now().UTC())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	overlapping := make([]domain.OverlappingIncident, 0)
+	for rows.Next() {
+		var incident domain.OverlappingIncident
+		if err := rows.Scan(&incident.ID, &incident.Title, &incident.Severity, &incident.Latitude,
+			&incident.Longitude, &incident.RadiusMeters, &incident.DistanceMeters); err != nil {
+			return nil, err
+		}
+		incident.Contains = incident.DistanceMeters+float64(zone.RadiusMeters) <= float64(incident.RadiusMeters)
+		overlapping = append(overlapping, incident)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return overlapping, nil
+}
+
+func scanIncident(row pgx.Row) (*domain.Incident, error) {
+	var incident domain.Incident
+	if err := row.Scan(incidentScanTargets(&incident)...); err != nil {
+		return nil, err
+	}
+	return &incident, nil
+}
+
+// incidentScanTargets возвращает адреса полей в порядке incidentFields
+func incidentScanTargets(incident *domain.Incident) []any {
+	return []any{
+		&incident.ID,
+		&incident.TenantID,
+		&incident.Title,
+		&incident.Description,
+		&incident.Severity,
+		&incident.Latitude,
+		&incident.Longitude,
+		&incident.RadiusMeters,
+		&incident.IsActive,
+		&incident.Version,
+		&incident.CreatedAt,
+		&incident.UpdatedAt,
+		&incident.DeactivatedAt,
+		&incident.ExpiresAt,
+	}
+}
+
+// scanIncidentChange читает пару состояний "до"/"после" из RETURNING
+func scanIncidentChange(row pgx.Row) (*domain.Incident, *domain.Incident, error) {
+	var before, after domain.Incident
+	if err := row.Scan(append(incidentScanTargets(&before), incidentScanTargets(&after)...)...); err != nil {
+		return nil, nil, err
+	}
+	return &before, &after, nil
+}
+
+func collectIncidents(rows pgx.Rows) ([]*domain.Incident, error) {
 	defer rows.Close()
 
 	incidents := make([]*domain.Incident, 0)
 	for rows.Next() {
-		var incident domain.Incident
-		if err := rows.Scan(
-			&incident.ID,
-			&incident.Title,
-			&incident.Description,
-			&incident.Severity,
-			&incident.Latitude,
-			&incident.Longitude,
-			&incident.RadiusMeters,
-			&incident.IsActive,
-			&incident.CreatedAt,
-			&incident.UpdatedAt,
-		); err != nil {
+		incident, err := scanIncident(rows)
+		if err != nil {
 			return nil, err
 		}
-		incidents = append(incidents, &incident)
+		incidents = append(incidents, incident)
 	}
 	if err := rows.Err(); err != nil {
 		return nil, err
@@ -0,0 +1,156 @@
+package repository
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/ruslanuskembaev/geo-alerts-system/internal/domain"
+)
+
+// keysetOrder сортировка, по которой строятся курсоры: выражение ключа и id как tie-breaker
+type keysetOrder struct {
+	key    string
+	expr   string
+	idExpr string
+	desc   bool
+	// decodeValue приводит значение ключа из курсора к типу параметра запроса
+	decodeValue func(json.RawMessage) (any, error)
+	// decodeID приводит id из курсора к типу параметра запроса
+	decodeID func(string) (any, error)
+}
+
+// keysetRow запись выборки вместе с её позицией в сортировке
+type keysetRow[T any] struct {
+	item      T
+	sortValue any
+	id        string
+}
+
+// apply добавляет условие курсора и возвращает ORDER BY.
+// Для движения назад порядок инвертируется, результат разворачивается в paginate.
+func (o keysetOrder) apply(where *whereBuilder, cursor *domain.Cursor) (string, error) {
+	backward := false
+	if cursor != nil {
+		if cursor.Sort != o.key || cursor.Desc != o.desc {
+			return "", domain.ErrInvalidCursor
+		}
+		value, err := o.decodeValue(cursor.Value)
+		if err != nil {
+			return "", domain.ErrInvalidCursor
+		}
+		id := any(cursor.ID)
+		if o.decodeID != nil {
+			if id, err = o.decodeID(cursor.ID); err != nil {
+				return "", domain.ErrInvalidCursor
+			}
+		}
+
+		backward = cursor.Backward
+		op := ">"
+		if o.desc != backward {
+			op = "<"
+		}
+		where.add("("+o.expr+", "+o.idExpr+") "+op+" (?, ?)", value, id)
+	}
+
+	direction := "ASC"
+	if o.desc != backward {
+		direction = "DESC"
+	}
+	return "ORDER BY " + o.expr + " " + direction + ", " + o.idExpr + " " + direction, nil
+}
+
+// limitOffset возвращает LIMIT/OFFSET; запрашивается на одну запись больше, чтобы узнать о следующей странице
+func (o keysetOrder) limitOffset(where *whereBuilder, page domain.PageRequest) string {
+	clause := "LIMIT " + where.arg(page.Limit+1)
+	if page.Cursor == nil && page.Offset > 0 {
+		clause += " OFFSET " + where.arg(page.Offset)
+	}
+	return clause
+}
+
+// paginate обрезает лишнюю запись, восстанавливает порядок и строит курсоры соседних страниц
+func paginate[T any](o keysetOrder, rows []keysetRow[T], page domain.PageRequest) ([]T, domain.PageInfo, error) {
+	var info domain.PageInfo
+
+	hasMore := len(rows) > page.Limit
+	if hasMore {
+		rows = rows[:page.Limit]
+	}
+	backward := page.Cursor != nil && page.Cursor.Backward
+	if backward {
+		for i, j := 0, len(rows)-1; i < j; i, j = i+1, j-1 {
+			rows[i], rows[j] = rows[j], rows[i]
+		}
+	}
+
+	items := make([]T, 0, len(rows))
+	for _, row := range rows {
+		items = append(items, row.item)
+	}
+	if len(rows) == 0 {
+		return items, info, nil
+	}
+
+	first, last := rows[0], rows[len(rows)-1]
+	hasNext := hasMore
+	hasPrev := page.Cursor != nil || page.Offset > 0
+	if backward {
+		hasNext, hasPrev = true, hasMore
+	}
+
+	if hasNext {
+		cursor, err := o.cursor(last.sortValue, last.id, false)
+		if err != nil {
+			return nil, info, err
+		}
+		info.NextCursor = cursor
+	}
+	if hasPrev {
+		cursor, err := o.cursor(first.sortValue, first.id, true)
+		if err != nil {
+			return nil, info, err
+		}
+		info.PrevCursor = cursor
+	}
+
+	return items, info, nil
+}
+
+func (o keysetOrder) cursor(value any, id string, backward bool) (string, error) {
+	raw, err := json.Marshal(value)
+	if err != nil {
+		return "", err
+	}
+	return domain.Cursor{
+		Sort:     o.key,
+		Desc:     o.desc,
+		Value:    raw,
+		ID:       id,
+		Backward: backward,
+	}.Encode(), nil
+}
+
+func decodeTimeValue(raw json.RawMessage) (any, error) {
+	var value time.Time
+	err := json.Unmarshal(raw, &value)
+	return value, err
+}
+
+func decodeStringValue(raw json.RawMessage) (any, error) {
+	var value string
+	err := json.Unmarshal(raw, &value)
+	return value, err
+}
+
+func decodeIntValue(raw json.RawMessage) (any, error) {
+	var value int64
+	err := json.Unmarshal(raw, &value)
+	return value, err
+}
+
+func decodeFloatValue(raw json.RawMessage) (any, error) {
+	var value float64
+	err := json.Unmarshal(raw, &value)
+	return value, err
+}
@@ -0,0 +1,180 @@
+package repository
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"golang.org/x/sync/singleflight"
+
+	"github.com/ruslanuskembaev/geo-alerts-system/internal/domain"
+)
+
+// IncidentLoader defines a cache that loads active incidents itself on a miss.
+// Одновременные промахи одного арендатора объединяются в одну загрузку; возвращаемый срез
+// общий для всех запросов и не должен изменяться.
+type IncidentLoader interface {
+	LoadActive(ctx context.Context, load func(context.Context) ([]*domain.Incident, error)) ([]*domain.Incident, error)
+}
+
+// LocalIncidentCache хранит копию активных инцидентов в памяти процесса поверх VersionedIncidentCache.
+// Пока подписка на инвалидации активна, копия используется без обращения к Redis (не дольше ttl);
+// без подписки перед каждым чтением сверяется версия в Redis. Копия перечитывается из Redis
+// только при смене версии, а из БД — если в Redis нет записи этой версии.
+type LocalIncidentCache struct {
+	remote VersionedIncidentCache
+	ttl    time.Duration
+	group  singleflight.Group
+
+	mu         sync.Mutex
+	entries    map[string]localIncidents
+	epochs     map[string]uint64
+	generation uint64
+	subscribed bool
+}
+
+type localIncidents struct {
+	version   int64
+	incidents []*domain.Incident
+	checkedAt time.Time
+}
+
+func NewLocalIncidentCache(remote VersionedIncidentCache, ttl time.Duration) *LocalIncidentCache {
+	return &LocalIncidentCache{
+		remote:  remote,
+		ttl:     ttl,
+		entries: make(map[string]localIncidents),
+		epochs:  make(map[string]uint64),
+	}
+}
+
+func (c *LocalIncidentCache) LoadActive(ctx context.Context, load func(context.Context) ([]*domain.Incident, error)) ([]*domain.Incident, error) {
+	if incidents, ok := c.trusted(ctx); ok {
+		return incidents, nil
+	}
+
+	tenantID := domain.TenantFromContext(ctx)
+	// Загрузку ведёт первый запрос; его отмена не должна прерывать ожидающих
+	result, err, _ := c.group.Do(tenantID, func() (interface{}, error) {
+		return c.refresh(context.WithoutCancel(ctx), load)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result.([]*domain.Incident), nil
+}
+
+func (c *LocalIncidentCache) GetActive(ctx context.Context) ([]*domain.Incident, bool, error) {
+	if incidents, ok := c.trusted(ctx); ok {
+		return incidents, true, nil
+	}
+
+	incidents, err := c.refresh(ctx, nil)
+	if err != nil || incidents == nil {
+		return nil, false, err
+	}
+	return incidents, true, nil
+}
+
+func (c *LocalIncidentCache) SetActive(ctx context.Context, incidents []*domain.Incident) error {
+	return c.remote.SetActive(ctx, incidents)
+}
+
+func (c *LocalIncidentCache) Invalidate(ctx context.Context) error {
+	c.drop(domain.TenantFromContext(ctx))
+	return c.remote.Invalidate(ctx)
+}
+
+// Start подписывается на инвалидации других реплик; блокируется до отмены ctx
+func (c *LocalIncidentCache) Start(ctx context.Context) {
+	c.remote.WatchInvalidations(ctx, c.drop, c.setSubscribed)
+}
+
+// trusted возвращает локальную копию, если ей можно верить без обращения к Redis
+func (c *LocalIncidentCache) trusted(ctx context.Context) ([]*domain.Incident, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry, ok := c.entries[domain.TenantFromContext(ctx)]
+	if !ok || !c.subscribed || time.Since(entry.checkedAt) >= c.ttl {
+		return nil, false
+	}
+	return entry.incidents, true
+}
+
+// refresh сверяет версию в Redis и при её смене перечитывает запись из Redis или, если load
+// задан, из БД. Копия не сохраняется, если за время загрузки пришла инвалидация.
+// Без load при отсутствии записи возвращает nil.
+func (c *LocalIncidentCache) refresh(ctx context.Context, load func(context.Context) ([]*domain.Incident, error)) ([]*domain.Incident, error) {
+	tenantID := domain.TenantFromContext(ctx)
+
+	c.mu.Lock()
+	epoch := localEpoch{tenant: c.epochs[tenantID], generation: c.generation}
+	entry, cached := c.entries[tenantID]
+	c.mu.Unlock()
+
+	version, err := c.remote.Version(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if cached && entry.version == version {
+		c.store(tenantID, epoch, version, entry.incidents)
+		return entry.incidents, nil
+	}
+
+	incidents, ok, err := c.remote.GetVersion(ctx, version)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		if load == nil {
+			return nil, nil
+		}
+		if incidents, err = load(ctx); err != nil {
+			return nil, err
+		}
+		_ = c.remote.SetVersion(ctx, version, incidents)
+	}
+
+	c.store(tenantID, epoch, version, incidents)
+	return incidents, nil
+}
+
+// localEpoch счётчики инвалидаций арендатора и состояний подписки на момент начала загрузки
+type localEpoch struct {
+	tenant     uint64
+	generation uint64
+}
+
+func (c *LocalIncidentCache) store(tenantID string, epoch localEpoch, version int64, incidents []*domain.Incident) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.epochs[tenantID] != epoch.tenant || c.generation != epoch.generation {
+		return
+	}
+	c.entries[tenantID] = localIncidents{
+		version:   version,
+		incidents: incidents,
+		checkedAt: time.Now(),
+	}
+}
+
+func (c *LocalIncidentCache) drop(tenantID string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.epochs[tenantID]++
+	delete(c.entries, tenantID)
+}
+
+// setSubscribed при смене состояния подписки сбрасывает все копии: инвалидации,
+// отправленные во время обрыва, могли быть пропущены
+func (c *LocalIncidentCache) setSubscribed(subscribed bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.subscribed = subscribed
+	c.generation++
+	c.entries = make(map[string]localIncidents)
+}
@@ -2,81 +2,325 @@ package repository
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 
 	"github.com/ruslanuskembaev/geo-alerts-system/internal/domain"
 )
 
 // LocationCheckRepository defines storage operations for location checks.
+// Статистика ограничена арендатором из контекста.
 type LocationCheckRepository interface {
-	Create(ctx context.Context, check domain.LocationCheck, incidentIDs []string) error
-	StatsByIncident(ctx context.Context, since time.Time) ([]domain.IncidentStats, error)
+	// Create сохраняет проверку и её связи с зонами; job (если задан) записывается в outbox
+	// вебхуков в той же транзакции и передаётся в очередь ретранслятором
+	Create(ctx context.Context, check domain.LocationCheck, incidentIDs []string, job *domain.WebhookJob) error
+	StatsByIncident(ctx context.Context, query domain.IncidentStatsQuery) ([]domain.IncidentStats, error)
+	// Heatmap агрегирует проверки по ячейкам geohash; возвращает не больше query.Limit+1 ячеек,
+	// начиная с самых нагруженных, чтобы вызывающий мог определить усечение
+	Heatmap(ctx context.Context, query domain.HeatmapQuery) ([]domain.HeatmapCell, error)
+	// StatsSeries читает агрегаты incident_stats_rollup; пустые корзины не возвращаются
+	StatsSeries(ctx context.Context, query domain.StatsSeriesQuery) ([]domain.IncidentStatsSeries, error)
+	// PruneStatsBucketUsers удаляет отметки пользователей закрытых корзин (всех арендаторов)
+	PruneStatsBucketUsers(ctx context.Context, before time.Time) (int, error)
+	// ListByUser возвращает проверки пользователя (под любым из userIDs), новые первыми
+	ListByUser(ctx context.Context, userIDs []string, filter domain.UserChecksFilter, page domain.PageRequest) ([]domain.UserCheck, domain.PageInfo, error)
+	// IncidentUsers возвращает пользователей, попадавших в зону за интервал, с последней
+	// известной позицией; сначала пользователи с самым поздним попаданием
+	IncidentUsers(ctx context.Context, incidentID string, query domain.IncidentUsersQuery, page domain.PageRequest) ([]domain.IncidentUser, domain.PageInfo, error)
+	// LatestChecksInZone возвращает последние с момента since проверки пользователей, попавшие
+	// в зону инцидента и ещё не связанные с ним: ни совпадением при проверке, ни отправленным
+	// ранее ретроактивным оповещением
+	LatestChecksInZone(ctx context.Context, incident domain.Incident, since time.Time) ([]domain.LocationCheck, error)
+	// ZoneImpact считает проверки арендатора за [since, until), попадающие в zone
+	ZoneImpact(ctx context.Context, zone domain.ImpactZone, since, until time.Time) (domain.ImpactCounts, error)
 }
 
 // PostgresLocationCheckRepository implements LocationCheckRepository using PostgreSQL.
 type PostgresLocationCheckRepository struct {
-	db *pgxpool.Pool
+	db      *pgxpool.Pool
+	privacy domain.LocationPrivacy
 }
 
 func NewLocationCheckRepository(db *pgxpool.Pool) *PostgresLocationCheckRepository {
 	return &PostgresLocationCheckRepository{db: db}
 }
 
-func (r *PostgresLocationCheckRepository) Create(ctx context.Context, check domain.LocationCheck, incidentIDs []string) error {
-	tx, err := r.db.Begin(ctx)
+// WithPrivacy задаёт политику хранения проверок; по умолчанию проверки сохраняются как есть
+func (r *PostgresLocationCheckRepository) WithPrivacy(privacy domain.LocationPrivacy) *PostgresLocationCheckRepository {
+	r.privacy = privacy
+	return r
+}
+
+// Create сохраняет проверку с учётом политики приватности: координаты могут быть огрублены
+// или опущены, user_id заменён стабильным псевдонимом, а проверки вне зон не сохранены вовсе.
+// Агрегаты статистики считаются по тому же псевдониму, что и сохранённая проверка.
+func (r *PostgresLocationCheckRepository) Create(ctx context.Context, check domain.LocationCheck, incidentIDs []string, job *domain.WebhookJob) error {
+	if check.TenantID == "" {
+		check.TenantID = domain.TenantFromContext(ctx)
+	}
+
+	userID := check.UserID
+	check, store := r.privacy.Apply(check)
+	if !store && job == nil {
+		return nil
+	}
+	var latitude, longitude *float64
+	if r.privacy.StoresCoordinates() {
+		latitude, longitude = &check.Latitude, &check.Longitude
+	}
+
+	return inTx(ctx, r.db, func(tx pgx.Tx) error {
+		if store {
+			if len(r.privacy.Keys) > 0 {
+				pseudonym, err := resolveUserPseudonym(ctx, tx, check.TenantID, r.privacy.UserIDLookups(check.TenantID, userID))
+				if err != nil {
+					return err
+				}
+				check.UserID = pseudonym
+			}
+			if err := insertLocationCheck(ctx, tx, check, latitude, longitude, incidentIDs); err != nil {
+				return err
+			}
+		}
+		if job != nil {
+			outboxJob := *job
+			if outboxJob.TenantID == "" {
+				outboxJob.TenantID = check.TenantID
+			}
+			return insertOutboxJobs(ctx, tx, []domain.WebhookJob{outboxJob})
+		}
+		return nil
+	})
+}
+
+func insertLocationCheck(ctx context.Context, tx pgx.Tx, check domain.LocationCheck, latitude, longitude *float64, incidentIDs []string) error {
+	_, err := tx.Exec(ctx, `
+		INSERT INTO location_checks (
+			id, tenant_id, user_id, latitude, longitude, is_in_danger_zone, checked_at
+		) VALUES ($1, $2, $3, $4, $5, $6, $7)
+	`, check.ID, check.TenantID, check.UserID, latitude, longitude, check.IsInDangerZone, check.CheckedAt)
 	if err != nil {
 		return err
 	}
-	defer func() {
-		_ = tx.Rollback(ctx)
-	}()
+
+	if len(incidentIDs) == 0 {
+		return nil
+	}
+
+	uuids := make([]uuid.UUID, 0, len(incidentIDs))
+	for _, id := range incidentIDs {
+		parsed, parseErr := uuid.Parse(id)
+		if parseErr != nil {
+			return parseErr
+		}
+		uuids = append(uuids, parsed)
+	}
 
 	_, err = tx.Exec(ctx, `
-		INSERT INTO location_checks (
-			id, user_id, latitude, longitude, is_in_danger_zone, checked_at
-		) VALUES ($1, $2, $3, $4, $5, $6)
-	`, check.ID, check.UserID, check.Latitude, check.Longitude, check.IsInDangerZone, check.CheckedAt)
+		INSERT INTO location_check_incidents (check_id, incident_id, checked_at)
+		SELECT $1, UNNEST($2::uuid[]), $3
+	`, check.ID, uuids, check.CheckedAt)
 	if err != nil {
 		return err
 	}
 
-	if len(incidentIDs) > 0 {
-		uuids := make([]uuid.UUID, 0, len(incidentIDs))
-		for _, id := range incidentIDs {
-			parsed, parseErr := uuid.Parse(id)
-			if parseErr != nil {
-				return parseErr
-			}
-			uuids = append(uuids, parsed)
-		}
+	return updateIncidentStatsRollup(ctx, tx, check, uuids)
+}
 
-		_, err = tx.Exec(ctx, `
-			INSERT INTO location_check_incidents (check_id, incident_id)
-			SELECT $1, UNNEST($2::uuid[])
-		`, check.ID, uuids)
+// insertOutboxJobs записывает задачи вебхуков в outbox одним запросом (check_id — из полезной нагрузки).
+// Полезная нагрузка содержит исходные user_id и координаты независимо от политики приватности:
+// её получает адресат вебхука. Поэтому удаление данных пользователя находит строки outbox по
+// user_id нагрузки, а при передаче в очередь нагрузка стирается.
+func insertOutboxJobs(ctx context.Context, tx pgx.Tx, jobs []domain.WebhookJob) error {
+	tenantIDs := make([]string, 0, len(jobs))
+	checkIDs := make([]uuid.UUID, 0, len(jobs))
+	raws := make([]string, 0, len(jobs))
+	createdAt := make([]time.Time, 0, len(jobs))
+	for _, job := range jobs {
+		checkID, err := uuid.Parse(job.Payload.CheckID)
+		if err != nil {
+			return err
+		}
+		raw, err := json.Marshal(job)
 		if err != nil {
 			return err
 		}
+		tenantIDs = append(tenantIDs, job.TenantID)
+		checkIDs = append(checkIDs, checkID)
+		raws = append(raws, string(raw))
+		createdAt = append(createdAt, job.CreatedAt)
+	}
+
+	_, err := tx.Exec(ctx, `
+		INSERT INTO webhook_outbox (tenant_id, check_id, job, created_at)
+		SELECT * FROM UNNEST($1::text[], $2::uuid[], $3::jsonb[], $4::timestamptz[])
+	`, tenantIDs, checkIDs, raws, createdAt)
+	return err
+}
+
+// resolveUserPseudonym возвращает стабильный псевдоним пользователя по его HMAC под всеми ключами
+// (lookups, текущий первым). Найденный под старым ключом псевдоним запоминается и под текущим, чтобы
+// пережить удаление старого ключа; новый пользователь получает HMAC под текущим ключом. Данные,
+// сохранённые до включения псевдонимизации под исходным user_id, переводит PseudonymBackfill.
+func resolveUserPseudonym(ctx context.Context, tx pgx.Tx, tenantID string, lookups []string) (string, error) {
+	var pseudonym, lookup string
+	err := tx.QueryRow(ctx, `
+		SELECT pseudonym, lookup
+		FROM user_pseudonyms
+		WHERE tenant_id = $1 AND lookup = ANY($2)
+		ORDER BY array_position($2, lookup)
+		LIMIT 1
+	`, tenantID, lookups).Scan(&pseudonym, &lookup)
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		return "", err
+	}
+	if err == nil && lookup == lookups[0] {
+		return pseudonym, nil
 	}
+	if errors.Is(err, pgx.ErrNoRows) {
+		pseudonym = lookups[0]
+	}
+
+	// При гонке первых проверок пользователя все получают псевдоним первой вставки
+	err = tx.QueryRow(ctx, `
+		INSERT INTO user_pseudonyms (tenant_id, lookup, pseudonym, created_at)
+		VALUES ($1, $2, $3, NOW())
+		ON CONFLICT (tenant_id, lookup) DO UPDATE SET lookup = EXCLUDED.lookup
+		RETURNING pseudonym
+	`, tenantID, lookups[0], pseudonym).Scan(&pseudonym)
+	return pseudonym, err
+}
 
-	return tx.Commit(ctx)
+// rowsQuerier общий для пула и транзакции метод запроса
+type rowsQuerier interface {
+	Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error)
+}
+
+// withPseudonyms дополняет значения user_id пользователя стабильными псевдонимами, найденными
+// по ним в user_pseudonyms: псевдоним, выданный под уже удалённым ключом, находится по HMAC
+// под текущим
+func withPseudonyms(ctx context.Context, q rowsQuerier, tenantID string, userIDs []string) ([]string, error) {
+	rows, err := q.Query(ctx, `
+		SELECT DISTINCT pseudonym FROM user_pseudonyms WHERE tenant_id = $1 AND lookup = ANY($2)
+	`, tenantID, userIDs)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	ids := append([]string{}, userIDs...)
+	for rows.Next() {
+		var pseudonym string
+		if err := rows.Scan(&pseudonym); err != nil {
+			return nil, err
+		}
+		ids = append(ids, pseudonym)
+	}
+	return ids, rows.Err()
 }
 
-func (r *PostgresLocationCheckRepository) StatsByIncident(ctx context.Context, since time.Time) ([]domain.IncidentStats, error) {
+// updateIncidentStatsRollup добавляет проверку в агрегаты всех корзин. Пользователь учитывается
+// в users корзины и в new_users только при первой вставке отметки, поэтому конкурентные
+// проверки одного пользователя не завышают счётчики.
+func updateIncidentStatsRollup(ctx context.Context, tx pgx.Tx, check domain.LocationCheck, incidentIDs []uuid.UUID) error {
+	buckets := make([]string, 0, len(domain.StatsBuckets))
+	for _, bucket := range domain.StatsBuckets {
+		buckets = append(buckets, string(bucket))
+	}
+
+	_, err := tx.Exec(ctx, `
+		WITH first_seen AS (
+			INSERT INTO incident_stats_users (incident_id, user_id, first_seen_at)
+			SELECT UNNEST($1::uuid[]), $2, $3::timestamptz
+			ON CONFLICT DO NOTHING
+			RETURNING incident_id
+		), counted AS (
+			INSERT INTO incident_stats_bucket_users (incident_id, bucket, bucket_start, user_id)
+			SELECT ids.id, b.bucket, date_trunc(b.bucket, $3::timestamptz, 'UTC'), $2
+			FROM UNNEST($1::uuid[]) AS ids (id)
+			CROSS JOIN UNNEST($5::text[]) AS b (bucket)
+			ON CONFLICT DO NOTHING
+			RETURNING incident_id, bucket
+		)
+		INSERT INTO incident_stats_rollup (incident_id, tenant_id, bucket, bucket_start, checks, users, new_users)
+		SELECT ids.id,
+		       $4,
+		       b.bucket,
+		       date_trunc(b.bucket, $3::timestamptz, 'UTC'),
+		       1,
+		       (SELECT COUNT(*) FROM counted c WHERE c.incident_id = ids.id AND c.bucket = b.bucket),
+		       (SELECT COUNT(*) FROM first_seen f WHERE f.incident_id = ids.id)
+		FROM UNNEST($1::uuid[]) AS ids (id)
+		CROSS JOIN UNNEST($5::text[]) AS b (bucket)
+		ON CONFLICT (incident_id, bucket, bucket_start) DO UPDATE SET
+			checks = incident_stats_rollup.checks + 1,
+			users = incident_stats_rollup.users + EXCLUDED.users,
+			new_users = incident_stats_rollup.new_users + EXCLUDED.new_users
+	`, incidentIDs, check.UserID, check.CheckedAt, check.TenantID, buckets)
+	return err
+}
+
+func (r *PostgresLocationCheckRepository) StatsByIncident(ctx context.Context, query domain.IncidentStatsQuery) ([]domain.IncidentStats, error) {
+	tenantID := domain.TenantFromContext(ctx)
+	where := &whereBuilder{}
+	where.add("i.tenant_id = ?", tenantID)
+	if len(query.IncidentIDs) > 0 {
+		uuids := make([]uuid.UUID, 0, len(query.IncidentIDs))
+		for _, id := range query.IncidentIDs {
+			parsed, err := uuid.Parse(id)
+			if err != nil {
+				return nil, err
+			}
+			uuids = append(uuids, parsed)
+		}
+		where.add("i.id = ANY(?)", uuids)
+	} else {
+		where.add("i.is_active = true")
+	}
+	if len(query.Severities) > 0 {
+		severities := make([]string, 0, len(query.Severities))
+		for _, severity := range query.Severities {
+			severities = append(severities, string(severity))
+		}
+		where.add("i.severity = ANY(?)", severities)
+	}
+
+	// Попадания за интервал отбираются по индексу (tenant_id, checked_at) до соединения с инцидентами
+	hits := `
+		SELECT lci.incident_id, lc.user_id, lc.latitude, lc.longitude, lc.checked_at
+		FROM location_checks lc
+		JOIN location_check_incidents lci ON lci.check_id = lc.id AND lci.checked_at = lc.checked_at
+		WHERE lc.tenant_id = ` + where.arg(tenantID) + ` AND lc.checked_at >= ` + where.arg(query.Since) +
+		` AND lc.checked_at < ` + where.arg(query.Until)
+
 	rows, err := r.db.Query(ctx, `
 		SELECT i.id,
 		       i.title,
-		       COALESCE(COUNT(DISTINCT lc.user_id), 0) AS user_count
+		       i.severity,
+		       i.is_active,
+		       COUNT(h.incident_id) AS checks,
+		       COUNT(DISTINCT h.user_id) AS user_count,
+		       MIN(h.checked_at),
+		       MAX(h.checked_at),
+		       AVG(d.distance),
+		       percentile_cont(0.5) WITHIN GROUP (ORDER BY d.distance),
+		       percentile_cont(0.95) WITHIN GROUP (ORDER BY d.distance),
+		       MAX(d.distance)
 		FROM incidents i
-		LEFT JOIN location_check_incidents lci ON i.id = lci.incident_id
-		LEFT JOIN location_checks lc ON lc.id = lci.check_id AND lc.checked_at >= $1
-		WHERE i.is_active = true
-		GROUP BY i.id, i.title
+		LEFT JOIN (`+hits+`) h ON h.incident_id = i.id
+		LEFT JOIN LATERAL (
+			SELECT geo_distance_meters(i.latitude, i.longitude, h.latitude, h.longitude) AS distance
+		) d ON true
+		`+where.sql()+`
+		GROUP BY i.id, i.title, i.severity, i.is_active, i.created_at
 		ORDER BY i.created_at DESC
-	`, since)
+	`, where.args...)
 	if err != nil {
 		return nil, err
 	}
@@ -85,7 +329,11 @@ func (r *PostgresLocationCheckRepository) StatsByIncident(ctx context.Context, s
 	stats := make([]domain.IncidentStats, 0)
 	for rows.Next() {
 		var item domain.IncidentStats
-		if err := rows.Scan(&item.IncidentID, &item.Title, &item.UserCount); err != nil {
+		if err := rows.Scan(
+			&item.IncidentID, &item.Title, &item.Severity, &item.IsActive, &item.Checks, &item.UserCount,
+			&item.FirstHitAt, &item.LastHitAt,
+			&item.AvgDistanceMeters, &item.P50DistanceMeters, &item.P95DistanceMeters, &item.MaxDistanceMeters,
+		); err != nil {
 			return nil, err
 		}
 		stats = append(stats, item)
@@ -96,3 +344,374 @@ func (r *PostgresLocationCheckRepository) StatsByIncident(ctx context.Context, s
 
 	return stats, nil
 }
+
+func (r *PostgresLocationCheckRepository) Heatmap(ctx context.Context, query domain.HeatmapQuery) ([]domain.HeatmapCell, error) {
+	lonStep, latStep := domain.GeohashCellSize(query.Precision)
+	columns := int64(360/lonStep) - 1
+	rows := int64(180/latStep) - 1
+
+	args := []interface{}{domain.TenantFromContext(ctx), query.Since, query.Until, lonStep, latStep, columns, rows}
+	bboxClause := ""
+	if box := query.BBox; box != nil {
+		args = append(args, box.MinLatitude, box.MaxLatitude, box.MinLongitude, box.MaxLongitude)
+		bboxClause = fmt.Sprintf(" AND latitude BETWEEN $%d AND $%d AND longitude BETWEEN $%d AND $%d",
+			len(args)-3, len(args)-2, len(args)-1, len(args))
+	}
+	args = append(args, query.Limit+1)
+
+	// Номера ячеек ограничены сеткой: точки на 180° и 90° попадают в крайние ячейки.
+	// Проверки без сохранённых координат в тепловую карту не попадают.
+	result, err := r.db.Query(ctx, `
+		SELECT LEAST(FLOOR((longitude + 180) / $4)::bigint, $6) AS cell_x,
+		       LEAST(FLOOR((latitude + 90) / $5)::bigint, $7) AS cell_y,
+		       COUNT(*) AS checks,
+		       COUNT(DISTINCT user_id) AS users,
+		       COUNT(*) FILTER (WHERE is_in_danger_zone) AS danger_hits
+		FROM location_checks
+		WHERE tenant_id = $1 AND checked_at >= $2 AND checked_at < $3 AND latitude IS NOT NULL`+bboxClause+`
+		GROUP BY cell_x, cell_y
+		ORDER BY checks DESC, cell_x, cell_y
+		LIMIT $`+fmt.Sprint(len(args)), args...)
+	if err != nil {
+		return nil, err
+	}
+	defer result.Close()
+
+	cells := make([]domain.HeatmapCell, 0)
+	for result.Next() {
+		var x, y int64
+		var checks, users, dangerHits int
+		if err := result.Scan(&x, &y, &checks, &users, &dangerHits); err != nil {
+			return nil, err
+		}
+		cell := domain.GeohashCell(query.Precision, x, y)
+		cell.Checks, cell.Users, cell.DangerHits = checks, users, dangerHits
+		cells = append(cells, cell)
+	}
+	if err := result.Err(); err != nil {
+		return nil, err
+	}
+
+	return cells, nil
+}
+
+func (r *PostgresLocationCheckRepository) StatsSeries(ctx context.Context, query domain.StatsSeriesQuery) ([]domain.IncidentStatsSeries, error) {
+	args := []interface{}{domain.TenantFromContext(ctx), string(query.Bucket), query.From, query.To}
+	incidentClause := ""
+	if len(query.IncidentIDs) > 0 {
+		uuids := make([]uuid.UUID, 0, len(query.IncidentIDs))
+		for _, id := range query.IncidentIDs {
+			parsed, err := uuid.Parse(id)
+			if err != nil {
+				return nil, err
+			}
+			uuids = append(uuids, parsed)
+		}
+		args = append(args, uuids)
+		incidentClause = " AND r.incident_id = ANY($5::uuid[])"
+	}
+
+	rows, err := r.db.Query(ctx, `
+		SELECT r.incident_id, i.title, r.bucket_start, r.checks, r.users, r.new_users
+		FROM incident_stats_rollup r
+		JOIN incidents i ON i.id = r.incident_id
+		WHERE r.tenant_id = $1 AND r.bucket = $2 AND r.bucket_start >= $3 AND r.bucket_start < $4`+incidentClause+`
+		ORDER BY i.created_at DESC, r.incident_id, r.bucket_start
+	`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	series := make([]domain.IncidentStatsSeries, 0)
+	for rows.Next() {
+		var incidentID, title string
+		var point domain.StatsPoint
+		if err := rows.Scan(&incidentID, &title, &point.BucketStart, &point.Checks, &point.Users, &point.NewUsers); err != nil {
+			return nil, err
+		}
+		point.BucketStart = point.BucketStart.UTC()
+		if len(series) == 0 || series[len(series)-1].IncidentID != incidentID {
+			series = append(series, domain.IncidentStatsSeries{IncidentID: incidentID, Title: title})
+		}
+		last := &series[len(series)-1]
+		last.Points = append(last.Points, point)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return series, nil
+}
+
+func (r *PostgresLocationCheckRepository) PruneStatsBucketUsers(ctx context.Context, before time.Time) (int, error) {
+	tag, err := r.db.Exec(ctx, `DELETE FROM incident_stats_bucket_users WHERE bucket_start < $1`, before)
+	if err != nil {
+		return 0, err
+	}
+	return int(tag.RowsAffected()), nil
+}
+
+// userCheckColumns столбцы проверки вместе с совпавшими зонами (алиас location_checks — lc)
+const userCheckColumns = `
+	lc.id, lc.user_id, lc.latitude, lc.longitude, lc.is_in_danger_zone, lc.checked_at,
+	COALESCE((
+		SELECT jsonb_agg(jsonb_build_object('incident_id', i.id, 'title', i.title, 'severity', i.severity)
+		                 ORDER BY i.title)
+		FROM location_check_incidents lci
+		JOIN incidents i ON i.id = lci.incident_id
+		WHERE lci.check_id = lc.id AND lci.checked_at = lc.checked_at
+	), '[]'::jsonb)`
+
+func scanUserCheck(row pgx.Row) (domain.UserCheck, error) {
+	var check domain.UserCheck
+	var incidents []byte
+	if err := row.Scan(&check.ID, &check.UserID, &check.Latitude, &check.Longitude,
+		&check.IsInDangerZone, &check.CheckedAt, &incidents); err != nil {
+		return check, err
+	}
+	err := json.Unmarshal(incidents, &check.Incidents)
+	return check, err
+}
+
+// userChecksOrder история проверок: новые первыми
+var userChecksOrder = keysetOrder{
+	key:         "checked_at",
+	expr:        "lc.checked_at",
+	idExpr:      "lc.id",
+	desc:        true,
+	decodeValue: decodeTimeValue,
+}
+
+func (r *PostgresLocationCheckRepository) ListByUser(ctx context.Context, userIDs []string, filter domain.UserChecksFilter, page domain.PageRequest) ([]domain.UserCheck, domain.PageInfo, error) {
+	tenantID := domain.TenantFromContext(ctx)
+	userIDs, err := withPseudonyms(ctx, r.db, tenantID, userIDs)
+	if err != nil {
+		return nil, domain.PageInfo{}, err
+	}
+
+	var where whereBuilder
+	where.add("lc.tenant_id = ?", tenantID)
+	where.add("lc.user_id = ANY(?)", userIDs)
+	if filter.From != nil {
+		where.add("lc.checked_at >= ?", *filter.From)
+	}
+	if filter.To != nil {
+		where.add("lc.checked_at < ?", *filter.To)
+	}
+	if filter.DangerOnly {
+		where.add("lc.is_in_danger_zone")
+	}
+
+	var total *int
+	if page.IncludeTotal {
+		var count int
+		if err := r.db.QueryRow(ctx, `SELECT COUNT(*) FROM location_checks lc `+where.sql(), where.args...).Scan(&count); err != nil {
+			return nil, domain.PageInfo{}, err
+		}
+		total = &count
+	}
+
+	orderBy, err := userChecksOrder.apply(&where, page.Cursor)
+	if err != nil {
+		return nil, domain.PageInfo{}, err
+	}
+	limit := userChecksOrder.limitOffset(&where, page)
+
+	rows, err := r.db.Query(ctx, `
+		SELECT `+userCheckColumns+`
+		FROM location_checks lc
+		`+where.sql()+`
+		`+orderBy+`
+		`+limit, where.args...)
+	if err != nil {
+		return nil, domain.PageInfo{}, err
+	}
+	defer rows.Close()
+
+	keyed := make([]keysetRow[domain.UserCheck], 0, page.Limit+1)
+	for rows.Next() {
+		check, err := scanUserCheck(rows)
+		if err != nil {
+			return nil, domain.PageInfo{}, err
+		}
+		keyed = append(keyed, keysetRow[domain.UserCheck]{item: check, sortValue: check.CheckedAt, id: check.ID})
+	}
+	if err := rows.Err(); err != nil {
+		return nil, domain.PageInfo{}, err
+	}
+
+	checks, info, err := paginate(userChecksOrder, keyed, page)
+	if err != nil {
+		return nil, domain.PageInfo{}, err
+	}
+	info.Total = total
+
+	return checks, info, nil
+}
+
+// incidentUsersOrder пользователи зоны: сначала с самым поздним попаданием
+var incidentUsersOrder = keysetOrder{
+	key:         "last_hit_at",
+	expr:        "h.last_hit_at",
+	idExpr:      "h.user_id",
+	desc:        true,
+	decodeValue: decodeTimeValue,
+}
+
+func (r *PostgresLocationCheckRepository) IncidentUsers(ctx context.Context, incidentID string, query domain.IncidentUsersQuery, page domain.PageRequest) ([]domain.IncidentUser, domain.PageInfo, error) {
+	id, err := uuid.Parse(incidentID)
+	if err != nil {
+		return nil, domain.PageInfo{}, ErrNotFound
+	}
+
+	// Попадания берутся из location_check_incidents по (incident_id, checked_at),
+	// последняя позиция — по индексу (tenant_id, user_id, checked_at)
+	var where whereBuilder
+	incidentArg := where.arg(id)
+	tenantArg := where.arg(domain.TenantFromContext(ctx))
+	sinceArg := where.arg(query.Since)
+	untilArg := where.arg(query.Until)
+	hits := `
+		WITH hits AS (
+			SELECT lc.user_id,
+			       COUNT(*) AS checks,
+			       MIN(lc.checked_at) AS first_hit_at,
+			       MAX(lc.checked_at) AS last_hit_at
+			FROM location_check_incidents lci
+			JOIN location_checks lc ON lc.id = lci.check_id AND lc.checked_at = lci.checked_at
+			WHERE lci.incident_id = ` + incidentArg + ` AND lc.tenant_id = ` + tenantArg + `
+			  AND lci.checked_at >= ` + sinceArg + ` AND lci.checked_at < ` + untilArg + `
+			GROUP BY lc.user_id
+		)`
+
+	var total *int
+	if page.IncludeTotal {
+		var count int
+		if err := r.db.QueryRow(ctx, hits+` SELECT COUNT(*) FROM hits h`, where.args...).Scan(&count); err != nil {
+			return nil, domain.PageInfo{}, err
+		}
+		total = &count
+	}
+
+	orderBy, err := incidentUsersOrder.apply(&where, page.Cursor)
+	if err != nil {
+		return nil, domain.PageInfo{}, err
+	}
+	limit := incidentUsersOrder.limitOffset(&where, page)
+
+	rows, err := r.db.Query(ctx, hits+`
+		SELECT h.user_id, h.checks, h.first_hit_at, h.last_hit_at,
+		       pos.id, pos.latitude, pos.longitude, pos.is_in_danger_zone, pos.checked_at
+		FROM hits h
+		CROSS JOIN LATERAL (
+			SELECT lc.id, lc.latitude, lc.longitude, lc.is_in_danger_zone, lc.checked_at
+			FROM location_checks lc
+			WHERE lc.tenant_id = `+tenantArg+` AND lc.user_id = h.user_id AND lc.checked_at < `+untilArg+`
+			ORDER BY lc.checked_at DESC
+			LIMIT 1
+		) pos
+		`+where.sql()+`
+		`+orderBy+`
+		`+limit, where.args...)
+	if err != nil {
+		return nil, domain.PageInfo{}, err
+	}
+	defer rows.Close()
+
+	keyed := make([]keysetRow[domain.IncidentUser], 0, page.Limit+1)
+	for rows.Next() {
+		var user domain.IncidentUser
+		position := &user.LastPosition
+		if err := rows.Scan(&user.UserID, &user.Checks, &user.FirstHitAt, &user.LastHitAt,
+			&position.CheckID, &position.Latitude, &position.Longitude, &position.IsInDangerZone, &position.CheckedAt); err != nil {
+			return nil, domain.PageInfo{}, err
+		}
+		keyed = append(keyed, keysetRow[domain.IncidentUser]{item: user, sortValue: user.LastHitAt, id: user.UserID})
+	}
+	if err := rows.Err(); err != nil {
+		return nil, domain.PageInfo{}, err
+	}
+
+	users, info, err := paginate(incidentUsersOrder, keyed, page)
+	if err != nil {
+		return nil, domain.PageInfo{}, err
+	}
+	info.Total = total
+
+	return users, info, nil
+}
+
+func (r *PostgresLocationCheckRepository) LatestChecksInZone(ctx context.Context, incident domain.Incident, since time.Time) ([]domain.LocationCheck, error) {
+	id, err := uuid.Parse(incident.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := r.db.Query(ctx, `
+		WITH latest AS (
+			SELECT DISTINCT ON (user_id) id, tenant_id, user_id, latitude, longitude, is_in_danger_zone, checked_at
+			FROM location_checks
+			WHERE tenant_id = $1 AND checked_at >= $2
+			ORDER BY user_id, checked_at DESC
+		)
+		SELECT l.id, l.tenant_id, l.user_id, l.latitude, l.longitude, l.is_in_danger_zone, l.checked_at
+		FROM latest l
+		WHERE l.latitude IS NOT NULL
+		  AND geo_distance_meters($3, $4, l.latitude, l.longitude) <= $5
+		  AND NOT EXISTS (
+			SELECT 1 FROM location_check_incidents lci
+			WHERE lci.check_id = l.id AND lci.checked_at = l.checked_at AND lci.incident_id = $6
+		  )
+		  AND NOT EXISTS (
+			SELECT 1 FROM retroactive_alerts ra
+			WHERE ra.incident_id = $6 AND ra.check_id = l.id
+		  )
+		ORDER BY l.checked_at DESC, l.user_id
+	`, incident.TenantID, since, incident.Latitude, incident.Longitude, incident.RadiusMeters, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	checks := make([]domain.LocationCheck, 0)
+	for rows.Next() {
+		var check domain.LocationCheck
+		if err := rows.Scan(&check.ID, &check.TenantID, &check.UserID, &check.Latitude, &check.Longitude,
+			&check.IsInDangerZone, &check.CheckedAt); err != nil {
+			return nil, err
+		}
+		checks = append(checks, check)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return checks, nil
+}
+
+func (r *PostgresLocationCheckRepository) ZoneImpact(ctx context.Context, zone domain.ImpactZone, since, until time.Time) (domain.ImpactCounts, error) {
+	// Грубый фильтр по широте отсекает проверки до вычисления расстояния
+	latDelta := float64(zone.RadiusMeters) / 111320
+
+	var counts domain.ImpactCounts
+	err := r.db.QueryRow(ctx, `
+		WITH inside AS (
+			SELECT user_id
+			FROM location_checks
+			WHERE tenant_id = $1 AND checked_at >= $5 AND checked_at < $6
+			  AND latitude BETWEEN $2 - $7 AND $2 + $7
+			  AND geo_distance_meters($2, $3, latitude, longitude) <= $4
+		), latest AS (
+			SELECT DISTINCT ON (user_id) user_id, latitude, longitude
+			FROM location_checks
+			WHERE tenant_id = $1 AND checked_at >= $5 AND checked_at < $6
+			ORDER BY user_id, checked_at DESC
+		)
+		SELECT (SELECT COUNT(*) FROM inside),
+		       (SELECT COUNT(DISTINCT user_id) FROM inside),
+		       (SELECT COUNT(*) FROM latest WHERE geo_distance_meters($2, $3, latitude, longitude) <= $4)
+	`, domain.TenantFromContext(ctx), zone.Latitude, zone.Longitude, zone.RadiusMeters, since, until, latDelta).
+		Scan(&counts.Checks, &counts.Users, &counts.CurrentUsers)
+	return counts, err
+}
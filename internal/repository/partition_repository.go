@@ -0,0 +1,171 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Секции проверок: location_checks_pYYYYMM и location_check_incidents_pYYYYMM с границами по UTC
+const (
+	checkPartitionPrefix         = "location_checks_p"
+	checkIncidentPartitionPrefix = "location_check_incidents_p"
+	checkPartitionMonthLayout    = "200601"
+)
+
+// LocationCheckPartitionRepository defines maintenance of monthly location check partitions.
+// Секции общие для всех арендаторов.
+type LocationCheckPartitionRepository interface {
+	// EnsurePartitions создаёт недостающие секции обеих таблиц на месяцы от from до to включительно
+	EnsurePartitions(ctx context.Context, from, to time.Time) ([]string, error)
+	// DropPartitionsBefore удаляет месячные секции, целиком лежащие раньше before,
+	// и строки старше before из DEFAULT-секций; возвращает удалённые секции location_checks
+	DropPartitionsBefore(ctx context.Context, before time.Time) ([]string, error)
+}
+
+// PostgresLocationCheckPartitionRepository implements LocationCheckPartitionRepository using PostgreSQL.
+type PostgresLocationCheckPartitionRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewLocationCheckPartitionRepository(db *pgxpool.Pool) *PostgresLocationCheckPartitionRepository {
+	return &PostgresLocationCheckPartitionRepository{db: db}
+}
+
+func (r *PostgresLocationCheckPartitionRepository) EnsurePartitions(ctx context.Context, from, to time.Time) ([]string, error) {
+	existing, err := r.partitions(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	created := make([]string, 0)
+	for month := monthStart(from); !month.After(monthStart(to)); month = month.AddDate(0, 1, 0) {
+		suffix := month.Format(checkPartitionMonthLayout)
+		if existing[checkPartitionPrefix+suffix] {
+			continue
+		}
+
+		// Обе секции месяца создаются вместе: связи проверок лежат в секции того же месяца
+		err := inTx(ctx, r.db, func(tx pgx.Tx) error {
+			if err := createMonthPartition(ctx, tx, "location_checks", checkPartitionPrefix+suffix, month); err != nil {
+				return err
+			}
+			return createMonthPartition(ctx, tx, "location_check_incidents", checkIncidentPartitionPrefix+suffix, month)
+		})
+		if err != nil {
+			return created, fmt.Errorf("create partition %s: %w", suffix, err)
+		}
+		created = append(created, checkPartitionPrefix+suffix)
+	}
+
+	return created, nil
+}
+
+// createMonthPartition создаёт секцию parent на месяц month. Строки месяца, попавшие в DEFAULT-секцию,
+// пока секции не было (например, задача была выключена), переносятся в новую секцию: иначе PostgreSQL
+// отказывает в её создании из-за строк DEFAULT-секции, подходящих под границы.
+func createMonthPartition(ctx context.Context, tx pgx.Tx, parent, name string, month time.Time) error {
+	defaultPartition := parent + "_default"
+	from, to := month, month.AddDate(0, 1, 0)
+	bounds := fmt.Sprintf("FOR VALUES FROM ('%s') TO ('%s')", from.Format(time.RFC3339), to.Format(time.RFC3339))
+
+	// Новые строки месяца ждут окончания переноса, а не попадают в DEFAULT после него
+	if _, err := tx.Exec(ctx, `LOCK TABLE `+defaultPartition+` IN EXCLUSIVE MODE`); err != nil {
+		return err
+	}
+	var stranded bool
+	if err := tx.QueryRow(ctx, `SELECT EXISTS (SELECT 1 FROM `+defaultPartition+` WHERE checked_at >= $1 AND checked_at < $2)`,
+		from, to).Scan(&stranded); err != nil {
+		return err
+	}
+	if !stranded {
+		_, err := tx.Exec(ctx, `CREATE TABLE IF NOT EXISTS `+name+` PARTITION OF `+parent+` `+bounds)
+		return err
+	}
+
+	if _, err := tx.Exec(ctx, `CREATE TABLE `+name+` (LIKE `+parent+` INCLUDING DEFAULTS INCLUDING CONSTRAINTS)`); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(ctx, `
+		WITH moved AS (
+			DELETE FROM `+defaultPartition+` WHERE checked_at >= $1 AND checked_at < $2
+			RETURNING *
+		)
+		INSERT INTO `+name+` SELECT * FROM moved
+	`, from, to); err != nil {
+		return err
+	}
+	_, err := tx.Exec(ctx, `ALTER TABLE `+parent+` ATTACH PARTITION `+name+` `+bounds)
+	return err
+}
+
+func (r *PostgresLocationCheckPartitionRepository) DropPartitionsBefore(ctx context.Context, before time.Time) ([]string, error) {
+	existing, err := r.partitions(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	dropped := make([]string, 0)
+	for name := range existing {
+		month, err := time.Parse(checkPartitionMonthLayout, strings.TrimPrefix(name, checkPartitionPrefix))
+		if err != nil || month.AddDate(0, 1, 0).After(before) {
+			continue
+		}
+
+		suffix := month.Format(checkPartitionMonthLayout)
+		err = inTx(ctx, r.db, func(tx pgx.Tx) error {
+			if _, err := tx.Exec(ctx, `DROP TABLE IF EXISTS `+checkIncidentPartitionPrefix+suffix); err != nil {
+				return err
+			}
+			_, err := tx.Exec(ctx, `DROP TABLE IF EXISTS `+checkPartitionPrefix+suffix)
+			return err
+		})
+		if err != nil {
+			return dropped, fmt.Errorf("drop partition %s: %w", suffix, err)
+		}
+		dropped = append(dropped, name)
+	}
+
+	err = inTx(ctx, r.db, func(tx pgx.Tx) error {
+		if _, err := tx.Exec(ctx, `DELETE FROM location_check_incidents_default WHERE checked_at < $1`, before); err != nil {
+			return err
+		}
+		_, err := tx.Exec(ctx, `DELETE FROM location_checks_default WHERE checked_at < $1`, before)
+		return err
+	})
+	return dropped, err
+}
+
+// partitions возвращает месячные секции location_checks (без DEFAULT)
+func (r *PostgresLocationCheckPartitionRepository) partitions(ctx context.Context) (map[string]bool, error) {
+	rows, err := r.db.Query(ctx, `
+		SELECT c.relname
+		FROM pg_inherits i
+		JOIN pg_class c ON c.oid = i.inhrelid
+		JOIN pg_class p ON p.oid = i.inhparent
+		WHERE p.relname = 'location_checks' AND c.relname LIKE $1
+	`, checkPartitionPrefix+"%")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	partitions := make(map[string]bool)
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, err
+		}
+		partitions[name] = true
+	}
+	return partitions, rows.Err()
+}
+
+func monthStart(t time.Time) time.Time {
+	t = t.UTC()
+	return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC)
+}
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"time"
 
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 
 	"github.com/ruslanuskembaev/geo-alerts-system/internal/config"
@@ -53,3 +54,19 @@ func NewPostgresPool(cfg *config.Config) (*pgxpool.Pool, error) {
 
 	return pool, nil
 }
+
+// inTx выполняет fn в транзакции; при ошибке транзакция откатывается
+func inTx(ctx context.Context, db *pgxpool.Pool, fn func(tx pgx.Tx) error) error {
+	tx, err := db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = tx.Rollback(ctx)
+	}()
+
+	if err := fn(tx); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
@@ -0,0 +1,41 @@
+package repository
+
+import (
+	"strconv"
+	"strings"
+)
+
+// whereBuilder собирает условия WHERE с позиционными параметрами.
+// В условиях используется "?", который заменяется на $N по порядку.
+type whereBuilder struct {
+	conds []string
+	args  []any
+}
+
+func (w *whereBuilder) add(cond string, args ...any) {
+	var b strings.Builder
+	next := 0
+	for _, r := range cond {
+		if r == '?' && next < len(args) {
+			w.args = append(w.args, args[next])
+			next++
+			b.WriteString("$" + strconv.Itoa(len(w.args)))
+			continue
+		}
+		b.WriteRune(r)
+	}
+	w.conds = append(w.conds, b.String())
+}
+
+// arg добавляет параметр без условия и возвращает его плейсхолдер
+func (w *whereBuilder) arg(value any) string {
+	w.args = append(w.args, value)
+	return "$" + strconv.Itoa(len(w.args))
+}
+
+func (w *whereBuilder) sql() string {
+	if len(w.conds) == 0 {
+		return ""
+	}
+	return "WHERE " + strings.Join(w.conds, " AND ")
+}
@@ -3,6 +3,8 @@ package repository
 import (
 	"context"
 	"encoding/json"
+	"fmt"
+	"log"
 	"time"
 
 	"github.com/redis/go-redis/v9"
@@ -10,16 +12,38 @@ import (
 	"github.com/ruslanuskembaev/geo-alerts-system/internal/domain"
 )
 
-const activeIncidentsCacheKey = "geoalerts:active_incidents"
+const (
+	activeIncidentsCacheKey   = "geoalerts:active_incidents"
+	activeIncidentsVersionKey = "geoalerts:active_incidents_version"
+	// incidentInvalidationChannel канал pub/sub, в который Invalidate публикует арендатора
+	incidentInvalidationChannel = "geoalerts:incident_invalidations"
+	// incidentWatchRetryDelay пауза перед повторным чтением подписки после ошибки
+	incidentWatchRetryDelay = time.Second
+)
 
 // IncidentCache defines active incidents cache behavior.
+// Кэш разделён по арендатору из контекста.
 type IncidentCache interface {
 	GetActive(ctx context.Context) ([]*domain.Incident, bool, error)
 	SetActive(ctx context.Context, incidents []*domain.Incident) error
 	Invalidate(ctx context.Context) error
 }
 
-// RedisIncidentCache implements IncidentCache using Redis.
+// VersionedIncidentCache defines an incident cache keyed by a per-tenant version.
+// Invalidate увеличивает версию, поэтому запись, загруженная из БД до инвалидации,
+// сохраняется под старой версией и уже не читается.
+type VersionedIncidentCache interface {
+	IncidentCache
+	Version(ctx context.Context) (int64, error)
+	GetVersion(ctx context.Context, version int64) ([]*domain.Incident, bool, error)
+	SetVersion(ctx context.Context, version int64, incidents []*domain.Incident) error
+	// WatchInvalidations блокируется до отмены ctx и вызывает onInvalidate для каждого
+	// арендатора, кэш которого инвалидирован на любой реплике. onSubscribed(true) вызывается
+	// после (пере)подписки, onSubscribed(false) — при обрыве: сообщения могли быть пропущены.
+	WatchInvalidations(ctx context.Context, onInvalidate func(tenantID string), onSubscribed func(bool))
+}
+
+// RedisIncidentCache implements VersionedIncidentCache using Redis.
 type RedisIncidentCache struct {
 	client *redis.Client
 	ttl    time.Duration
@@ -33,7 +57,31 @@ func NewIncidentCache(client *redis.Client, ttl time.Duration) *RedisIncidentCac
 }
 
 func (c *RedisIncidentCache) GetActive(ctx context.Context) ([]*domain.Incident, bool, error) {
-	raw, err := c.client.Get(ctx, activeIncidentsCacheKey).Result()
+	version, err := c.Version(ctx)
+	if err != nil {
+		return nil, false, err
+	}
+	return c.GetVersion(ctx, version)
+}
+
+func (c *RedisIncidentCache) SetActive(ctx context.Context, incidents []*domain.Incident) error {
+	version, err := c.Version(ctx)
+	if err != nil {
+		return err
+	}
+	return c.SetVersion(ctx, version, incidents)
+}
+
+func (c *RedisIncidentCache) Version(ctx context.Context) (int64, error) {
+	version, err := c.client.Get(ctx, activeIncidentsVersionKey+":"+domain.TenantFromContext(ctx)).Int64()
+	if err == redis.Nil {
+		return 0, nil
+	}
+	return version, err
+}
+
+func (c *RedisIncidentCache) GetVersion(ctx context.Context, version int64) ([]*domain.Incident, bool, error) {
+	raw, err := c.client.Get(ctx, activeIncidentsKey(ctx, version)).Bytes()
 	if err == redis.Nil {
 		return nil, false, nil
 	}
@@ -42,22 +90,73 @@ func (c *RedisIncidentCache) GetActive(ctx context.Context) ([]*domain.Incident,
 	}
 
 	var incidents []*domain.Incident
-	if err := json.Unmarshal([]byte(raw), &incidents); err != nil {
+	if err := json.Unmarshal(raw, &incidents); err != nil {
 		return nil, false, err
 	}
 
 	return incidents, true, nil
 }
 
-func (c *RedisIncidentCache) SetActive(ctx context.Context, incidents []*domain.Incident) error {
+func (c *RedisIncidentCache) SetVersion(ctx context.Context, version int64, incidents []*domain.Incident) error {
 	raw, err := json.Marshal(incidents)
 	if err != nil {
 		return err
 	}
 
-	return c.client.Set(ctx, activeIncidentsCacheKey, raw, c.ttl).Err()
+	return c.client.Set(ctx, activeIncidentsKey(ctx, version), raw, c.ttl).Err()
 }
 
+// Invalidate увеличивает версию активных инцидентов и поколение векторных тайлов арендатора
+// и оповещает остальные реплики; записи прежней версии истекают по TTL
 func (c *RedisIncidentCache) Invalidate(ctx context.Context) error {
-	return c.client.Del(ctx, activeIncidentsCacheKey).Err()
+	tenantID := domain.TenantFromContext(ctx)
+	pipe := c.client.TxPipeline()
+	pipe.Incr(ctx, activeIncidentsVersionKey+":"+tenantID)
+	pipe.Incr(ctx, tileGenerationKey(ctx))
+	pipe.Publish(ctx, incidentInvalidationChannel, tenantID)
+	_, err := pipe.Exec(ctx)
+	return err
+}
+
+func (c *RedisIncidentCache) WatchInvalidations(ctx context.Context, onInvalidate func(tenantID string), onSubscribed func(bool)) {
+	pubsub := c.client.Subscribe(ctx, incidentInvalidationChannel)
+	defer func() {
+		_ = pubsub.Close()
+	}()
+
+	subscribed := false
+	for {
+		msg, err := pubsub.Receive(ctx)
+		if err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			if subscribed {
+				subscribed = false
+				onSubscribed(false)
+			}
+			log.Printf("Incident invalidation subscription error: %v\n", err)
+			// Следующий Receive переподключается и повторяет подписку
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(incidentWatchRetryDelay):
+			}
+			continue
+		}
+
+		switch msg := msg.(type) {
+		case *redis.Subscription:
+			if msg.Kind == "subscribe" {
+				subscribed = true
+				onSubscribed(true)
+			}
+		case *redis.Message:
+			onInvalidate(msg.Payload)
+		}
+	}
+}
+
+func activeIncidentsKey(ctx context.Context, version int64) string {
+	return fmt.Sprintf("%s:%s:%d", activeIncidentsCacheKey, domain.TenantFromContext(ctx), version)
 }
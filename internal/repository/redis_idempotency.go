@@ -0,0 +1,76 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+
+	"github.com/ruslanuskembaev/geo-alerts-system/internal/domain"
+)
+
+const idempotencyKeyPrefix = "geoalerts:idempotency:"
+
+// IdempotencyStore defines storage of responses for Idempotency-Key requests.
+type IdempotencyStore interface {
+	// Reserve атомарно занимает ключ записью record на ttl. Если ключ уже занят,
+	// возвращает существующую запись и false.
+	Reserve(ctx context.Context, key string, record domain.IdempotencyRecord, ttl time.Duration) (*domain.IdempotencyRecord, bool, error)
+	// Save сохраняет итоговый ответ на ttl
+	Save(ctx context.Context, key string, record domain.IdempotencyRecord, ttl time.Duration) error
+	// Release освобождает ключ, чтобы запрос можно было повторить
+	Release(ctx context.Context, key string) error
+}
+
+// RedisIdempotencyStore implements IdempotencyStore using Redis strings.
+type RedisIdempotencyStore struct {
+	client *redis.Client
+}
+
+func NewIdempotencyStore(client *redis.Client) *RedisIdempotencyStore {
+	return &RedisIdempotencyStore{client: client}
+}
+
+func (s *RedisIdempotencyStore) Reserve(ctx context.Context, key string, record domain.IdempotencyRecord, ttl time.Duration) (*domain.IdempotencyRecord, bool, error) {
+	raw, err := json.Marshal(record)
+	if err != nil {
+		return nil, false, err
+	}
+
+	fullKey := idempotencyKeyPrefix + key
+	reserved, err := s.client.SetNX(ctx, fullKey, raw, ttl).Result()
+	if err != nil {
+		return nil, false, err
+	}
+	if reserved {
+		return nil, true, nil
+	}
+
+	existing, err := s.client.Get(ctx, fullKey).Bytes()
+	if err == redis.Nil {
+		// Ключ истёк между SETNX и GET — занимаем повторно
+		return s.Reserve(ctx, key, record, ttl)
+	}
+	if err != nil {
+		return nil, false, err
+	}
+
+	var stored domain.IdempotencyRecord
+	if err := json.Unmarshal(existing, &stored); err != nil {
+		return nil, false, err
+	}
+	return &stored, false, nil
+}
+
+func (s *RedisIdempotencyStore) Save(ctx context.Context, key string, record domain.IdempotencyRecord, ttl time.Duration) error {
+	raw, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	return s.client.Set(ctx, idempotencyKeyPrefix+key, raw, ttl).Err()
+}
+
+func (s *RedisIdempotencyStore) Release(ctx context.Context, key string) error {
+	return s.client.Del(ctx, idempotencyKeyPrefix+key).Err()
+}
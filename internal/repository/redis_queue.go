@@ -10,15 +10,39 @@ import (
 	"github.com/ruslanuskembaev/geo-alerts-system/internal/domain"
 )
 
-const webhookQueueKey = "geoalerts:webhook_queue"
+const (
+	webhookQueueKey = "geoalerts:webhook_queue"
+	// webhookRetryKey отложенные повторы: sorted set с временем повтора (мс) в score
+	webhookRetryKey = "geoalerts:webhook_retry"
+	// webhookRetryBatch сколько наступивших повторов переносится в очередь за один Dequeue
+	webhookRetryBatch = 100
+)
+
+// promoteDueScript переносит в очередь повторы, время которых наступило. Скрипт выполняется
+// атомарно, поэтому каждую задачу переносит только один воркер.
+var promoteDueScript = redis.NewScript(`
+local due = redis.call('ZRANGEBYSCORE', KEYS[1], '-inf', ARGV[1], 'LIMIT', 0, ARGV[2])
+for _, job in ipairs(due) do
+	redis.call('ZREM', KEYS[1], job)
+	redis.call('LPUSH', KEYS[2], job)
+end
+return #due
+`)
 
 // WebhookQueue defines enqueue/dequeue operations for webhook jobs.
 type WebhookQueue interface {
 	Enqueue(ctx context.Context, job domain.WebhookJob) error
 	Dequeue(ctx context.Context, timeout time.Duration) (*domain.WebhookJob, bool, error)
+	// Retry откладывает задачу до момента at: повтор хранится в Redis, а не в памяти воркера,
+	// поэтому переживает перезапуск и виден UserJobs/RemoveUserJobs
+	Retry(ctx context.Context, job domain.WebhookJob, at time.Time) error
+	// UserJobs возвращает ожидающие отправки (и отложенные) задачи пользователя арендатора
+	UserJobs(ctx context.Context, tenantID, userID string) ([]domain.WebhookJob, error)
+	// RemoveUserJobs удаляет ожидающие и отложенные задачи пользователя и возвращает их число
+	RemoveUserJobs(ctx context.Context, tenantID, userID string) (int, error)
 }
 
-// RedisWebhookQueue implements WebhookQueue using Redis lists.
+// RedisWebhookQueue implements WebhookQueue using a Redis list and a sorted set of delayed retries.
 type RedisWebhookQueue struct {
 	client *redis.Client
 }
@@ -35,7 +59,22 @@ func (q *RedisWebhookQueue) Enqueue(ctx context.Context, job domain.WebhookJob)
 	return q.client.LPush(ctx, webhookQueueKey, raw).Err()
 }
 
+func (q *RedisWebhookQueue) Retry(ctx context.Context, job domain.WebhookJob, at time.Time) error {
+	raw, err := json.Marshal(job)
+	if err != nil {
+		return err
+	}
+	return q.client.ZAdd(ctx, webhookRetryKey, redis.Z{Score: float64(at.UnixMilli()), Member: raw}).Err()
+}
+
+// Dequeue сначала переносит в очередь наступившие повторы, затем ждёт задачу не дольше timeout
 func (q *RedisWebhookQueue) Dequeue(ctx context.Context, timeout time.Duration) (*domain.WebhookJob, bool, error) {
+	err := promoteDueScript.Run(ctx, q.client, []string{webhookRetryKey, webhookQueueKey},
+		time.Now().UnixMilli(), webhookRetryBatch).Err()
+	if err != nil && err != redis.Nil {
+		return nil, false, err
+	}
+
 	result, err := q.client.BRPop(ctx, timeout, webhookQueueKey).Result()
 	if err == redis.Nil {
 		return nil, false, nil
@@ -54,3 +93,72 @@ func (q *RedisWebhookQueue) Dequeue(ctx context.Context, timeout time.Duration)
 
 	return &job, true, nil
 }
+
+func (q *RedisWebhookQueue) UserJobs(ctx context.Context, tenantID, userID string) ([]domain.WebhookJob, error) {
+	_, _, jobs, err := q.userJobs(ctx, tenantID, userID)
+	return jobs, err
+}
+
+func (q *RedisWebhookQueue) RemoveUserJobs(ctx context.Context, tenantID, userID string) (int, error) {
+	queued, delayed, _, err := q.userJobs(ctx, tenantID, userID)
+	if err != nil || len(queued)+len(delayed) == 0 {
+		return 0, err
+	}
+
+	// Задачу мог уже забрать воркер, поэтому считаются только фактически удалённые
+	pipe := q.client.TxPipeline()
+	removed := make([]*redis.IntCmd, 0, len(queued)+1)
+	for _, raw := range queued {
+		removed = append(removed, pipe.LRem(ctx, webhookQueueKey, 1, raw))
+	}
+	if len(delayed) > 0 {
+		members := make([]any, 0, len(delayed))
+		for _, raw := range delayed {
+			members = append(members, raw)
+		}
+		removed = append(removed, pipe.ZRem(ctx, webhookRetryKey, members...))
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
+		return 0, err
+	}
+
+	count := 0
+	for _, cmd := range removed {
+		count += int(cmd.Val())
+	}
+	return count, nil
+}
+
+// userJobs читает очередь и отложенные повторы целиком и отбирает задачи пользователя вместе
+// с исходными элементами списка (queued) и набора повторов (delayed)
+func (q *RedisWebhookQueue) userJobs(ctx context.Context, tenantID, userID string) ([]string, []string, []domain.WebhookJob, error) {
+	items, err := q.client.LRange(ctx, webhookQueueKey, 0, -1).Result()
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	retries, err := q.client.ZRange(ctx, webhookRetryKey, 0, -1).Result()
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	jobs := make([]domain.WebhookJob, 0)
+	match := func(items []string) []string {
+		raws := make([]string, 0)
+		for _, item := range items {
+			var job domain.WebhookJob
+			if err := json.Unmarshal([]byte(item), &job); err != nil {
+				continue
+			}
+			jobTenant := job.TenantID
+			if jobTenant == "" {
+				jobTenant = domain.DefaultTenantID
+			}
+			if jobTenant == tenantID && job.Payload.UserID == userID {
+				raws = append(raws, item)
+				jobs = append(jobs, job)
+			}
+		}
+		return raws
+	}
+	return match(items), match(retries), jobs, nil
+}
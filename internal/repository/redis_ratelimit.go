@@ -0,0 +1,58 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const rateLimitKeyPrefix = "geoalerts:ratelimit:"
+
+// RateLimiter defines fixed-window request counting.
+type RateLimiter interface {
+	// Allow увеличивает счётчик ключа и сообщает, укладывается ли запрос в лимит.
+	// Если нет — возвращает время до сброса окна.
+	Allow(ctx context.Context, key string, limit int, window time.Duration) (bool, time.Duration, error)
+	// Reset удаляет счётчик ключа; false — счётчика не было
+	Reset(ctx context.Context, key string) (bool, error)
+}
+
+// RedisRateLimiter implements RateLimiter using Redis counters.
+type RedisRateLimiter struct {
+	client *redis.Client
+}
+
+func NewRateLimiter(client *redis.Client) *RedisRateLimiter {
+	return &RedisRateLimiter{client: client}
+}
+
+func (l *RedisRateLimiter) Allow(ctx context.Context, key string, limit int, window time.Duration) (bool, time.Duration, error) {
+	fullKey := rateLimitKeyPrefix + key
+
+	pipe := l.client.TxPipeline()
+	incr := pipe.Incr(ctx, fullKey)
+	pipe.ExpireNX(ctx, fullKey, window)
+	ttl := pipe.PTTL(ctx, fullKey)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return false, 0, err
+	}
+
+	if incr.Val() <= int64(limit) {
+		return true, 0, nil
+	}
+
+	retryAfter := ttl.Val()
+	if retryAfter <= 0 {
+		retryAfter = window
+	}
+	return false, retryAfter, nil
+}
+
+func (l *RedisRateLimiter) Reset(ctx context.Context, key string) (bool, error) {
+	deleted, err := l.client.Del(ctx, rateLimitKeyPrefix+key).Result()
+	if err != nil {
+		return false, err
+	}
+	return deleted > 0, nil
+}
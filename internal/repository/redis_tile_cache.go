@@ -0,0 +1,69 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+
+	"github.com/ruslanuskembaev/geo-alerts-system/internal/domain"
+)
+
+const (
+	tileCacheKeyPrefix      = "geoalerts:tiles"
+	tileGenerationKeyPrefix = "geoalerts:tiles_generation"
+)
+
+// TileCache defines rendered vector tiles cache behavior.
+// Тайлы хранятся под поколением арендатора: RedisIncidentCache.Invalidate увеличивает поколение,
+// и все ранее отрисованные тайлы перестают читаться, истекая затем по TTL.
+type TileCache interface {
+	Generation(ctx context.Context) (int64, error)
+	Get(ctx context.Context, generation int64, tile domain.TileCoord) ([]byte, bool, error)
+	Set(ctx context.Context, generation int64, tile domain.TileCoord, data []byte) error
+}
+
+// RedisTileCache implements TileCache using Redis.
+type RedisTileCache struct {
+	client *redis.Client
+	ttl    time.Duration
+}
+
+func NewTileCache(client *redis.Client, ttl time.Duration) *RedisTileCache {
+	return &RedisTileCache{
+		client: client,
+		ttl:    ttl,
+	}
+}
+
+func (c *RedisTileCache) Generation(ctx context.Context) (int64, error) {
+	generation, err := c.client.Get(ctx, tileGenerationKey(ctx)).Int64()
+	if err == redis.Nil {
+		return 0, nil
+	}
+	return generation, err
+}
+
+func (c *RedisTileCache) Get(ctx context.Context, generation int64, tile domain.TileCoord) ([]byte, bool, error) {
+	data, err := c.client.Get(ctx, tileKey(ctx, generation, tile)).Bytes()
+	if err == redis.Nil {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	return data, true, nil
+}
+
+func (c *RedisTileCache) Set(ctx context.Context, generation int64, tile domain.TileCoord, data []byte) error {
+	return c.client.Set(ctx, tileKey(ctx, generation, tile), data, c.ttl).Err()
+}
+
+func tileGenerationKey(ctx context.Context) string {
+	return tileGenerationKeyPrefix + ":" + domain.TenantFromContext(ctx)
+}
+
+func tileKey(ctx context.Context, generation int64, tile domain.TileCoord) string {
+	return fmt.Sprintf("%s:%s:%d:%d/%d/%d", tileCacheKeyPrefix, domain.TenantFromContext(ctx), generation, tile.Z, tile.X, tile.Y)
+}
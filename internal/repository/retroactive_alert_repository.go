@@ -0,0 +1,161 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/ruslanuskembaev/geo-alerts-system/internal/domain"
+)
+
+// RetroactiveAlertRepository хранит запросы ретроактивных оповещений и отправленные оповещения.
+// Отметки (incident_id, check_id) исключают повторное оповещение по той же проверке при
+// следующих изменениях зоны (см. LocationCheckRepository.LatestChecksInZone).
+type RetroactiveAlertRepository interface {
+	// Request ставит в очередь оповещение о зоне инцидента арендатора из ctx
+	// по проверкам начиная с since
+	Request(ctx context.Context, incidentID string, since time.Time) error
+	// Claim арендует на lease до limit запросов (всех арендаторов), пропуская арендованные
+	// другими репликами; запрос с истёкшей арендой выдаётся повторно
+	Claim(ctx context.Context, limit int, lease time.Duration) ([]domain.RetroactiveAlertRequest, error)
+	// Complete одной транзакцией отмечает проверки из jobs оповещёнными, записывает в outbox
+	// задачи по ещё не отмеченным проверкам и удаляет запрос. Возвращает число записанных задач.
+	Complete(ctx context.Context, req domain.RetroactiveAlertRequest, jobs []domain.WebhookJob) (int, error)
+	// PruneAlerts удаляет отметки, созданные раньше before и раньше начала окна ожидающих
+	// запросов: проверки старше окна поиска повторно не выбираются
+	PruneAlerts(ctx context.Context, before time.Time) (int, error)
+}
+
+// PostgresRetroactiveAlertRepository implements RetroactiveAlertRepository using PostgreSQL.
+type PostgresRetroactiveAlertRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewRetroactiveAlertRepository(db *pgxpool.Pool) *PostgresRetroactiveAlertRepository {
+	return &PostgresRetroactiveAlertRepository{db: db}
+}
+
+func (r *PostgresRetroactiveAlertRepository) Request(ctx context.Context, incidentID string, since time.Time) error {
+	id, err := uuid.Parse(incidentID)
+	if err != nil {
+		return err
+	}
+
+	_, err = r.db.Exec(ctx, `
+		INSERT INTO retroactive_alert_requests (tenant_id, incident_id, since, requested_at)
+		VALUES ($1, $2, $3, $4)
+	`, domain.TenantFromContext(ctx), id, since, time.Now().UTC())
+	return err
+}
+
+func (r *PostgresRetroactiveAlertRepository) Claim(ctx context.Context, limit int, lease time.Duration) ([]domain.RetroactiveAlertRequest, error) {
+	now := time.Now().UTC()
+	rows, err := r.db.Query(ctx, `
+		UPDATE retroactive_alert_requests SET locked_until = $3
+		WHERE id IN (
+			SELECT id FROM retroactive_alert_requests
+			WHERE locked_until IS NULL OR locked_until < $2
+			ORDER BY id
+			LIMIT $1
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING id, tenant_id, incident_id, since
+	`, limit, now, now.Add(lease))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	requests := make([]domain.RetroactiveAlertRequest, 0)
+	for rows.Next() {
+		var req domain.RetroactiveAlertRequest
+		if err := rows.Scan(&req.ID, &req.TenantID, &req.IncidentID, &req.Since); err != nil {
+			return nil, err
+		}
+		requests = append(requests, req)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return requests, nil
+}
+
+func (r *PostgresRetroactiveAlertRepository) Complete(ctx context.Context, req domain.RetroactiveAlertRequest, jobs []domain.WebhookJob) (int, error) {
+	written := 0
+	err := inTx(ctx, r.db, func(tx pgx.Tx) error {
+		if len(jobs) > 0 {
+			incidentID, err := uuid.Parse(req.IncidentID)
+			if err != nil {
+				return err
+			}
+			checkIDs := make([]uuid.UUID, 0, len(jobs))
+			for _, job := range jobs {
+				checkID, err := uuid.Parse(job.Payload.CheckID)
+				if err != nil {
+					return err
+				}
+				checkIDs = append(checkIDs, checkID)
+			}
+
+			// Отметку по проверке получает только одна транзакция: параллельный запрос по той же
+			// зоне ждёт её фиксации и не пишет повторную задачу
+			rows, err := tx.Query(ctx, `
+				INSERT INTO retroactive_alerts (incident_id, check_id, created_at)
+				SELECT $1, check_id, $3 FROM UNNEST($2::uuid[]) AS check_id
+				ON CONFLICT DO NOTHING
+				RETURNING check_id
+			`, incidentID, checkIDs, time.Now().UTC())
+			if err != nil {
+				return err
+			}
+			marked := make(map[uuid.UUID]bool, len(checkIDs))
+			for rows.Next() {
+				var checkID uuid.UUID
+				if err := rows.Scan(&checkID); err != nil {
+					rows.Close()
+					return err
+				}
+				marked[checkID] = true
+			}
+			rows.Close()
+			if err := rows.Err(); err != nil {
+				return err
+			}
+
+			fresh := make([]domain.WebhookJob, 0, len(marked))
+			for i, job := range jobs {
+				if marked[checkIDs[i]] {
+					fresh = append(fresh, job)
+				}
+			}
+			if len(fresh) > 0 {
+				if err := insertOutboxJobs(ctx, tx, fresh); err != nil {
+					return err
+				}
+			}
+			written = len(fresh)
+		}
+
+		_, err := tx.Exec(ctx, `DELETE FROM retroactive_alert_requests WHERE id = $1`, req.ID)
+		return err
+	})
+	if err != nil {
+		return 0, err
+	}
+	return written, nil
+}
+
+func (r *PostgresRetroactiveAlertRepository) PruneAlerts(ctx context.Context, before time.Time) (int, error) {
+	tag, err := r.db.Exec(ctx, `
+		DELETE FROM retroactive_alerts
+		WHERE created_at < LEAST($1, (SELECT MIN(since) FROM retroactive_alert_requests))
+	`, before)
+	if err != nil {
+		return 0, err
+	}
+	return int(tag.RowsAffected()), nil
+}
@@ -0,0 +1,247 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/ruslanuskembaev/geo-alerts-system/internal/domain"
+)
+
+// UserDataRepository defines export and erasure of a user's stored data.
+// userIDs — все значения user_id, под которыми могли сохраниться проверки пользователя
+// (исходный идентификатор и псевдонимы); данные ограничены арендатором из контекста.
+type UserDataRepository interface {
+	// Export возвращает проверки, журнал доставок и ещё не переданные в очередь задачи outbox
+	// (по user_id полезной нагрузки) в полях Checks, WebhookDeliveries и QueuedWebhooks
+	Export(ctx context.Context, userIDs []string) (*domain.UserDataExport, error)
+	// Erase удаляет проверки, связи с зонами, отметки статистики, журнал доставок и outbox вебхуков,
+	// дополняет erasure счётчиками и записывает его в журнал аудита в той же транзакции
+	// (entity_id — erasure.UserIDHash, исходный user_id не сохраняется)
+	Erase(ctx context.Context, userIDs []string, erasure *domain.UserErasure) error
+}
+
+// PostgresUserDataRepository implements UserDataRepository using PostgreSQL.
+type PostgresUserDataRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewUserDataRepository(db *pgxpool.Pool) *PostgresUserDataRepository {
+	return &PostgresUserDataRepository{db: db}
+}
+
+func (r *PostgresUserDataRepository) Export(ctx context.Context, userIDs []string) (*domain.UserDataExport, error) {
+	tenantID := domain.TenantFromContext(ctx)
+	userIDs, err := withPseudonyms(ctx, r.db, tenantID, userIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := r.db.Query(ctx, `
+		SELECT `+userCheckColumns+`
+		FROM location_checks lc
+		WHERE lc.tenant_id = $1 AND lc.user_id = ANY($2)
+		ORDER BY lc.checked_at, lc.id
+	`, tenantID, userIDs)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	checks := make([]domain.UserCheck, 0)
+	for rows.Next() {
+		check, err := scanUserCheck(rows)
+		if err != nil {
+			return nil, err
+		}
+		checks = append(checks, check)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	rows, err = r.db.Query(ctx, `
+		SELECT d.id, d.tenant_id, d.check_id, d.attempt, d.status, COALESCE(d.error, ''), d.created_at
+		FROM webhook_deliveries d
+		WHERE d.tenant_id = $1 AND d.check_id IN (
+			SELECT id FROM location_checks WHERE tenant_id = $1 AND user_id = ANY($2)
+		)
+		ORDER BY d.created_at, d.id
+	`, tenantID, userIDs)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	deliveries := make([]domain.WebhookDelivery, 0)
+	for rows.Next() {
+		var delivery domain.WebhookDelivery
+		if err := rows.Scan(&delivery.ID, &delivery.TenantID, &delivery.CheckID, &delivery.Attempt,
+			&delivery.Status, &delivery.Error, &delivery.CreatedAt); err != nil {
+			return nil, err
+		}
+		deliveries = append(deliveries, delivery)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	// Полезная нагрузка содержит исходный user_id, а проверки может не быть (режим только опасных зон)
+	rows, err = r.db.Query(ctx, `
+		SELECT job
+		FROM webhook_outbox
+		WHERE tenant_id = $1 AND dispatched_at IS NULL AND job->'payload'->>'user_id' = ANY($2)
+		ORDER BY id
+	`, tenantID, userIDs)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	pending := make([]domain.WebhookJob, 0)
+	for rows.Next() {
+		var raw []byte
+		if err := rows.Scan(&raw); err != nil {
+			return nil, err
+		}
+		var job domain.WebhookJob
+		if err := json.Unmarshal(raw, &job); err != nil {
+			return nil, err
+		}
+		pending = append(pending, job)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return &domain.UserDataExport{Checks: checks, WebhookDeliveries: deliveries, QueuedWebhooks: pending}, nil
+}
+
+func (r *PostgresUserDataRepository) Erase(ctx context.Context, userIDs []string, erasure *domain.UserErasure) error {
+	tenantID := domain.TenantFromContext(ctx)
+
+	return inTx(ctx, r.db, func(tx pgx.Tx) error {
+		userIDs, err := withPseudonyms(ctx, tx, tenantID, userIDs)
+		if err != nil {
+			return err
+		}
+
+		// Проверки удаляются последними: по ним находятся связи и доставки
+		tag, err := tx.Exec(ctx, `
+			DELETE FROM webhook_deliveries
+			WHERE tenant_id = $1 AND check_id IN (
+				SELECT id FROM location_checks WHERE tenant_id = $1 AND user_id = ANY($2)
+			)
+		`, tenantID, userIDs)
+		if err != nil {
+			return err
+		}
+		erasure.WebhookDeliveries = int(tag.RowsAffected())
+
+		// Ещё не переданные в очередь задачи outbox учитываются как ожидающие вебхуки. Нагрузка
+		// хранит исходный user_id, поэтому находятся и задачи по несохранённым или псевдонимизированным
+		// проверкам.
+		var pending int
+		if err := tx.QueryRow(ctx, `
+			WITH deleted AS (
+				DELETE FROM webhook_outbox
+				WHERE tenant_id = $1 AND (job->'payload'->>'user_id' = ANY($2) OR check_id IN (
+					SELECT id FROM location_checks WHERE tenant_id = $1 AND user_id = ANY($2)
+				))
+				RETURNING dispatched_at
+			)
+			SELECT COUNT(*) FILTER (WHERE dispatched_at IS NULL) FROM deleted
+		`, tenantID, userIDs).Scan(&pending); err != nil {
+			return err
+		}
+		erasure.QueuedWebhooks += pending
+
+		tag, err = tx.Exec(ctx, `
+			DELETE FROM location_check_incidents lci
+			USING location_checks lc
+			WHERE lci.check_id = lc.id AND lci.checked_at = lc.checked_at
+			  AND lc.tenant_id = $1 AND lc.user_id = ANY($2)
+		`, tenantID, userIDs)
+		if err != nil {
+			return err
+		}
+		erasure.IncidentMatches = int(tag.RowsAffected())
+
+		tag, err = tx.Exec(ctx, `
+			DELETE FROM location_checks WHERE tenant_id = $1 AND user_id = ANY($2)
+		`, tenantID, userIDs)
+		if err != nil {
+			return err
+		}
+		erasure.Checks = int(tag.RowsAffected())
+
+		// Агрегаты incident_stats_rollup обезличены и остаются; удаляются только отметки пользователя
+		var marks int
+		if err := tx.QueryRow(ctx, `
+			WITH tenant_incidents AS (
+				SELECT id FROM incidents WHERE tenant_id = $1
+			), users AS (
+				DELETE FROM incident_stats_users
+				WHERE user_id = ANY($2) AND incident_id IN (SELECT id FROM tenant_incidents)
+				RETURNING 1
+			), bucket_users AS (
+				DELETE FROM incident_stats_bucket_users
+				WHERE user_id = ANY($2) AND incident_id IN (SELECT id FROM tenant_incidents)
+				RETURNING 1
+			)
+			SELECT (SELECT COUNT(*) FROM users) + (SELECT COUNT(*) FROM bucket_users)
+		`, tenantID, userIDs).Scan(&marks); err != nil {
+			return err
+		}
+		erasure.StatsMarks = marks
+
+		// Без псевдонима новые проверки пользователя не связываются с удалёнными
+		if _, err := tx.Exec(ctx, `
+			DELETE FROM user_pseudonyms WHERE tenant_id = $1 AND (lookup = ANY($2) OR pseudonym = ANY($2))
+		`, tenantID, userIDs); err != nil {
+			return err
+		}
+
+		// Исходный user_id в журнал не попадает: иначе удалённый пользователь остался бы в нём навсегда
+		audited := *erasure
+		audited.UserID = ""
+		after, err := json.Marshal(audited)
+		if err != nil {
+			return err
+		}
+		_, err = tx.Exec(ctx, `
+			INSERT INTO audit_log (tenant_id, entity_type, entity_id, action, actor, created_at, after)
+			VALUES ($1, $2, $3, $4, $5, $6, $7)
+		`, tenantID, domain.AuditEntityUser, erasure.UserIDHash, domain.AuditActionErase,
+			domain.ActorFromContext(ctx), erasure.ErasedAt, after)
+		return err
+	})
+}
+
+// WebhookDeliveryRepository defines the log of webhook delivery attempts.
+type WebhookDeliveryRepository interface {
+	Record(ctx context.Context, delivery domain.WebhookDelivery) error
+}
+
+// PostgresWebhookDeliveryRepository implements WebhookDeliveryRepository using PostgreSQL.
+type PostgresWebhookDeliveryRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewWebhookDeliveryRepository(db *pgxpool.Pool) *PostgresWebhookDeliveryRepository {
+	return &PostgresWebhookDeliveryRepository{db: db}
+}
+
+func (r *PostgresWebhookDeliveryRepository) Record(ctx context.Context, delivery domain.WebhookDelivery) error {
+	if delivery.CreatedAt.IsZero() {
+		delivery.CreatedAt = time.Now().UTC()
+	}
+	_, err := r.db.Exec(ctx, `
+		INSERT INTO webhook_deliveries (tenant_id, check_id, attempt, status, error, created_at)
+		VALUES ($1, $2, $3, $4, NULLIF($5, ''), $6)
+	`, delivery.TenantID, delivery.CheckID, delivery.Attempt, delivery.Status, delivery.Error, delivery.CreatedAt)
+	return err
+}
@@ -0,0 +1,138 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/ruslanuskembaev/geo-alerts-system/internal/domain"
+)
+
+// pseudonymPattern вид псевдонима "<key id>:<hmac>"; исходные user_id такого вида считаются
+// уже псевдонимизированными
+const pseudonymPattern = `^[^:]+:[0-9a-f]{32}$`
+
+// UserPseudonymRepository defines operations that move data stored under raw user ids to pseudonyms.
+// Новые проверки получают псевдоним в LocationCheckRepository.Create.
+type UserPseudonymRepository interface {
+	// RawUsers возвращает до limit пользователей (всех арендаторов), чьи проверки или отметки
+	// статистики сохранены под исходным user_id
+	RawUsers(ctx context.Context, limit int) ([]domain.StoredUser, error)
+	// Pseudonymize переводит проверки и отметки статистики пользователя на его стабильный
+	// псевдоним (lookups — HMAC под всеми ключами, текущий первым)
+	Pseudonymize(ctx context.Context, user domain.StoredUser, lookups []string) error
+}
+
+// PostgresUserPseudonymRepository implements UserPseudonymRepository using PostgreSQL.
+type PostgresUserPseudonymRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewUserPseudonymRepository(db *pgxpool.Pool) *PostgresUserPseudonymRepository {
+	return &PostgresUserPseudonymRepository{db: db}
+}
+
+func (r *PostgresUserPseudonymRepository) RawUsers(ctx context.Context, limit int) ([]domain.StoredUser, error) {
+	rows, err := r.db.Query(ctx, `
+		SELECT tenant_id, user_id FROM location_checks WHERE user_id !~ $1
+		UNION
+		SELECT i.tenant_id, s.user_id
+		FROM incident_stats_users s
+		JOIN incidents i ON i.id = s.incident_id
+		WHERE s.user_id !~ $1
+		UNION
+		SELECT i.tenant_id, s.user_id
+		FROM incident_stats_bucket_users s
+		JOIN incidents i ON i.id = s.incident_id
+		WHERE s.user_id !~ $1
+		LIMIT $2
+	`, pseudonymPattern, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	users := make([]domain.StoredUser, 0)
+	for rows.Next() {
+		var user domain.StoredUser
+		if err := rows.Scan(&user.TenantID, &user.UserID); err != nil {
+			return nil, err
+		}
+		users = append(users, user)
+	}
+	return users, rows.Err()
+}
+
+// Pseudonymize переносит данные пользователя в одной транзакции. Если после включения
+// псевдонимизации пользователь уже попал в ту же корзину или зону под псевдонимом, отметки
+// под исходным user_id удаляются, а users и new_users агрегатов уменьшаются, чтобы
+// пользователь не учитывался дважды.
+func (r *PostgresUserPseudonymRepository) Pseudonymize(ctx context.Context, user domain.StoredUser, lookups []string) error {
+	buckets := make([]string, 0, len(domain.StatsBuckets))
+	for _, bucket := range domain.StatsBuckets {
+		buckets = append(buckets, string(bucket))
+	}
+
+	return inTx(ctx, r.db, func(tx pgx.Tx) error {
+		pseudonym, err := resolveUserPseudonym(ctx, tx, user.TenantID, lookups)
+		if err != nil {
+			return err
+		}
+
+		if _, err := tx.Exec(ctx, `
+			UPDATE location_checks SET user_id = $3 WHERE tenant_id = $1 AND user_id = $2
+		`, user.TenantID, user.UserID, pseudonym); err != nil {
+			return err
+		}
+
+		if _, err := tx.Exec(ctx, `
+			WITH duplicates AS (
+				DELETE FROM incident_stats_bucket_users raw
+				USING incidents i, incident_stats_bucket_users p
+				WHERE raw.user_id = $2 AND i.id = raw.incident_id AND i.tenant_id = $1
+				  AND p.incident_id = raw.incident_id AND p.bucket = raw.bucket
+				  AND p.bucket_start = raw.bucket_start AND p.user_id = $3
+				RETURNING raw.incident_id, raw.bucket, raw.bucket_start
+			)
+			UPDATE incident_stats_rollup r SET users = r.users - 1
+			FROM duplicates d
+			WHERE r.incident_id = d.incident_id AND r.bucket = d.bucket AND r.bucket_start = d.bucket_start
+		`, user.TenantID, user.UserID, pseudonym); err != nil {
+			return err
+		}
+
+		// Первым попаданием остаётся более раннее; new_users уменьшается в корзинах более позднего
+		if _, err := tx.Exec(ctx, `
+			WITH duplicates AS (
+				DELETE FROM incident_stats_users raw
+				USING incidents i, incident_stats_users p
+				WHERE raw.user_id = $2 AND i.id = raw.incident_id AND i.tenant_id = $1
+				  AND p.incident_id = raw.incident_id AND p.user_id = $3
+				RETURNING raw.incident_id, raw.first_seen_at AS raw_seen_at, p.first_seen_at AS pseudonym_seen_at
+			), earliest AS (
+				UPDATE incident_stats_users s SET first_seen_at = d.raw_seen_at
+				FROM duplicates d
+				WHERE s.incident_id = d.incident_id AND s.user_id = $3 AND d.raw_seen_at < d.pseudonym_seen_at
+			)
+			UPDATE incident_stats_rollup r SET new_users = r.new_users - 1
+			FROM duplicates d, UNNEST($4::text[]) AS b (bucket)
+			WHERE r.incident_id = d.incident_id AND r.bucket = b.bucket
+			  AND r.bucket_start = date_trunc(b.bucket, GREATEST(d.raw_seen_at, d.pseudonym_seen_at), 'UTC')
+		`, user.TenantID, user.UserID, pseudonym, buckets); err != nil {
+			return err
+		}
+
+		_, err = tx.Exec(ctx, `
+			WITH tenant_incidents AS (
+				SELECT id FROM incidents WHERE tenant_id = $1
+			), users AS (
+				UPDATE incident_stats_users SET user_id = $3
+				WHERE user_id = $2 AND incident_id IN (SELECT id FROM tenant_incidents)
+			)
+			UPDATE incident_stats_bucket_users SET user_id = $3
+			WHERE user_id = $2 AND incident_id IN (SELECT id FROM tenant_incidents)
+		`, user.TenantID, user.UserID, pseudonym)
+		return err
+	})
+}
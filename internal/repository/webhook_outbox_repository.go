@@ -0,0 +1,111 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/ruslanuskembaev/geo-alerts-system/internal/domain"
+)
+
+// WebhookOutboxRepository defines relay operations over the webhook outbox.
+// Строки outbox создаются LocationCheckRepository.Create вместе с проверкой
+// и RetroactiveAlertRepository.Complete вместе с отметками оповещений.
+type WebhookOutboxRepository interface {
+	// Dispatch блокирует до limit ожидающих задач (пропуская заблокированные другими
+	// репликами), передаёт их send по порядку и отмечает переданные. При ошибке send
+	// переданные до неё задачи всё равно отмечаются, остальные остаются в outbox.
+	// У переданных задач стирается полезная нагрузка с user_id и координатами.
+	Dispatch(ctx context.Context, limit int, send func(domain.WebhookJob) error) (int, error)
+	// PruneDispatched удаляет задачи, переданные в очередь раньше before (всех арендаторов)
+	PruneDispatched(ctx context.Context, before time.Time) (int, error)
+}
+
+// PostgresWebhookOutboxRepository implements WebhookOutboxRepository using PostgreSQL.
+type PostgresWebhookOutboxRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewWebhookOutboxRepository(db *pgxpool.Pool) *PostgresWebhookOutboxRepository {
+	return &PostgresWebhookOutboxRepository{db: db}
+}
+
+func (r *PostgresWebhookOutboxRepository) Dispatch(ctx context.Context, limit int, send func(domain.WebhookJob) error) (int, error) {
+	dispatched := 0
+	var sendErr error
+
+	err := inTx(ctx, r.db, func(tx pgx.Tx) error {
+		rows, err := tx.Query(ctx, `
+			SELECT id, job
+			FROM webhook_outbox
+			WHERE dispatched_at IS NULL
+			ORDER BY id
+			LIMIT $1
+			FOR UPDATE SKIP LOCKED
+		`, limit)
+		if err != nil {
+			return err
+		}
+
+		type outboxRow struct {
+			id  int64
+			raw []byte
+		}
+		pending := make([]outboxRow, 0)
+		for rows.Next() {
+			var row outboxRow
+			if err := rows.Scan(&row.id, &row.raw); err != nil {
+				rows.Close()
+				return err
+			}
+			pending = append(pending, row)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return err
+		}
+
+		ids := make([]int64, 0, len(pending))
+		for _, row := range pending {
+			var job domain.WebhookJob
+			if err := json.Unmarshal(row.raw, &job); err != nil {
+				return err
+			}
+			if sendErr = send(job); sendErr != nil {
+				break
+			}
+			ids = append(ids, row.id)
+		}
+		if len(ids) == 0 {
+			return nil
+		}
+
+		// Если коммит не пройдёт, задачи будут переданы повторно: доставка не реже одного раза.
+		// Нагрузка уже в очереди, в outbox остаются только сведения о передаче.
+		tag, err := tx.Exec(ctx, `
+			UPDATE webhook_outbox SET dispatched_at = $2, job = job - 'payload' WHERE id = ANY($1)
+		`, ids, time.Now().UTC())
+		if err != nil {
+			return err
+		}
+		dispatched = int(tag.RowsAffected())
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return dispatched, sendErr
+}
+
+func (r *PostgresWebhookOutboxRepository) PruneDispatched(ctx context.Context, before time.Time) (int, error) {
+	tag, err := r.db.Exec(ctx, `
+		DELETE FROM webhook_outbox WHERE dispatched_at IS NOT NULL AND dispatched_at < $1
+	`, before)
+	if err != nil {
+		return 0, err
+	}
+	return int(tag.RowsAffected()), nil
+}
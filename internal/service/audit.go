@@ -0,0 +1,29 @@
+package service
+
+import (
+	"context"
+
+	"github.com/ruslanuskembaev/geo-alerts-system/internal/domain"
+	"github.com/ruslanuskembaev/geo-alerts-system/internal/repository"
+)
+
+// AuditService чтение журнала аудита
+type AuditService struct {
+	repo repository.AuditRepository
+}
+
+func NewAuditService(repo repository.AuditRepository) *AuditService {
+	return &AuditService{repo: repo}
+}
+
+func (s *AuditService) List(ctx context.Context, filter domain.AuditFilter, page domain.PageRequest) ([]domain.AuditEntry, domain.PageInfo, error) {
+	return s.repo.List(ctx, filter, page)
+}
+
+// IncidentHistory возвращает ревизии инцидента, новые первыми
+func (s *AuditService) IncidentHistory(ctx context.Context, incidentID string, page domain.PageRequest) ([]domain.AuditEntry, domain.PageInfo, error) {
+	return s.repo.List(ctx, domain.AuditFilter{
+		EntityType: domain.AuditEntityIncident,
+		EntityID:   incidentID,
+	}, page)
+}
@@ -0,0 +1,351 @@
+package service
+
+import (
+	"context"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ruslanuskembaev/geo-alerts-system/internal/domain"
+	"github.com/ruslanuskembaev/geo-alerts-system/internal/repository"
+)
+
+var ErrInvalidCAPAlert = errors.New("invalid CAP alert")
+
+// ErrCAPAreaTooLarge область сообщения шире допустимой зоны. Сужение зоны дало бы людям внутри
+// настоящей области ответ «безопасно», поэтому такое сообщение не применяется целиком.
+// Ошибка оборачивается вместе с ErrInvalidCAPAlert.
+var ErrCAPAreaTooLarge = errors.New("CAP area exceeds the zone radius limit")
+
+// Границы радиуса зоны из CAP: меньший круг расширяется до минимума, больший отклоняется
+const (
+	capMinRadiusMeters = 10
+	capMaxRadiusMeters = 100000
+)
+
+// capSeverities соответствие severity CAP уровням инцидента
+var capSeverities = map[string]domain.Severity{
+	"Extreme":  domain.SeverityHigh,
+	"Severe":   domain.SeverityHigh,
+	"Moderate": domain.SeverityMedium,
+	"Minor":    domain.SeverityLow,
+	"Unknown":  domain.SeverityLow,
+}
+
+// CAPService принимает сообщения Common Alerting Protocol и ведёт по ним инциденты
+type CAPService struct {
+	repo  repository.CAPRepository
+	cache repository.IncidentCache
+}
+
+func NewCAPService(repo repository.CAPRepository, cache repository.IncidentCache) *CAPService {
+	return &CAPService{
+		repo:  repo,
+		cache: cache,
+	}
+}
+
+type capAlertXML struct {
+	XMLName    xml.Name     `xml:"alert"`
+	Identifier string       `xml:"identifier"`
+	Sender     string       `xml:"sender"`
+	Sent       string       `xml:"sent"`
+	Status     string       `xml:"status"`
+	MsgType    string       `xml:"msgType"`
+	References string       `xml:"references"`
+	Infos      []capInfoXML `xml:"info"`
+}
+
+type capInfoXML struct {
+	Event       string       `xml:"event"`
+	Severity    string       `xml:"severity"`
+	Expires     string       `xml:"expires"`
+	Headline    string       `xml:"headline"`
+	Description string       `xml:"description"`
+	Areas       []capAreaXML `xml:"area"`
+}
+
+type capAreaXML struct {
+	AreaDesc string   `xml:"areaDesc"`
+	Polygons []string `xml:"polygon"`
+	Circles  []string `xml:"circle"`
+}
+
+// Ingest разбирает CAP 1.2 (<alert>) и применяет его к инцидентам арендатора из контекста.
+// Сообщения со status, отличным от Actual, msgType Ack/Error и уже истёкшие Alert пропускаются.
+func (s *CAPService) Ingest(ctx context.Context, r io.Reader) (*domain.CAPIngestResult, error) {
+	var doc capAlertXML
+	if err := xml.NewDecoder(r).Decode(&doc); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidCAPAlert, err)
+	}
+	return s.apply(ctx, &doc)
+}
+
+func (s *CAPService) apply(ctx context.Context, doc *capAlertXML) (*domain.CAPIngestResult, error) {
+	alert, reason, err := mapCAPAlert(doc, time.Now().UTC())
+	if err != nil {
+		return nil, err
+	}
+	if reason != "" {
+		return &domain.CAPIngestResult{
+			Identifier:  alert.Identifier,
+			Sender:      alert.Sender,
+			MsgType:     alert.MsgType,
+			Status:      domain.CAPIngestIgnored,
+			Reason:      reason,
+			Created:     []string{},
+			Updated:     []string{},
+			Deactivated: []string{},
+		}, nil
+	}
+
+	result, err := s.repo.Apply(ctx, alert)
+	if err != nil {
+		return nil, err
+	}
+	if result.Status == domain.CAPIngestApplied {
+		_ = s.cache.Invalidate(ctx)
+	}
+	return result, nil
+}
+
+// mapCAPAlert приводит CAP-документ к инцидентам. Непустой reason означает, что сообщение
+// корректно, но применять его не нужно.
+func mapCAPAlert(doc *capAlertXML, now time.Time) (domain.CAPAlert, string, error) {
+	alert := domain.CAPAlert{
+		Identifier: strings.TrimSpace(doc.Identifier),
+		Sender:     strings.TrimSpace(doc.Sender),
+		MsgType:    domain.CAPMsgType(strings.TrimSpace(doc.MsgType)),
+	}
+	if alert.Identifier == "" || alert.Sender == "" {
+		return alert, "", fmt.Errorf("%w: identifier and sender are required", ErrInvalidCAPAlert)
+	}
+	sent, err := time.Parse(time.RFC3339, strings.TrimSpace(doc.Sent))
+	if err != nil {
+		return alert, "", fmt.Errorf("%w: sent must be a CAP date-time", ErrInvalidCAPAlert)
+	}
+	alert.Sent = sent
+
+	if status := strings.TrimSpace(doc.Status); status != "Actual" {
+		return alert, "status " + status, nil
+	}
+	switch alert.MsgType {
+	case domain.CAPMsgTypeAlert:
+	case domain.CAPMsgTypeUpdate, domain.CAPMsgTypeCancel:
+		alert.References = parseCAPReferences(doc.References)
+		if len(alert.References) == 0 {
+			return alert, "", fmt.Errorf("%w: %s requires references", ErrInvalidCAPAlert, alert.MsgType)
+		}
+		if alert.MsgType == domain.CAPMsgTypeCancel {
+			return alert, "", nil
+		}
+	default:
+		return alert, "msgType " + string(alert.MsgType), nil
+	}
+
+	var info *capInfoXML
+	for i := range doc.Infos {
+		if len(doc.Infos[i].Areas) > 0 {
+			info = &doc.Infos[i]
+			break
+		}
+	}
+	if info == nil {
+		return alert, "", fmt.Errorf("%w: no info with area", ErrInvalidCAPAlert)
+	}
+
+	var expiresAt *time.Time
+	if value := strings.TrimSpace(info.Expires); value != "" {
+		expires, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			return alert, "", fmt.Errorf("%w: expires must be a CAP date-time", ErrInvalidCAPAlert)
+		}
+		if alert.MsgType == domain.CAPMsgTypeAlert && !expires.After(now) {
+			return alert, "expired", nil
+		}
+		expires = expires.UTC()
+		expiresAt = &expires
+	}
+
+	severity, ok := capSeverities[strings.TrimSpace(info.Severity)]
+	if !ok {
+		severity = domain.SeverityLow
+	}
+	title := firstNonEmpty(info.Headline, info.Event)
+
+	var problems []string
+	oversized := false
+	geometries := 0
+	for _, area := range info.Areas {
+		geometries += len(area.Circles) + len(area.Polygons)
+	}
+	for i, area := range info.Areas {
+		// Несколько зон одного сообщения различаются по описанию области
+		areaDesc := strings.TrimSpace(area.AreaDesc)
+		areaTitle := firstNonEmpty(title, areaDesc)
+		if geometries > 1 && title != "" && areaDesc != "" {
+			areaTitle += " — " + areaDesc
+		}
+
+		var circles [][3]float64
+		for _, raw := range area.Circles {
+			circle, err := parseCAPCircle(raw)
+			if err != nil {
+				problems = append(problems, fmt.Sprintf("area %d: circle: %v", i+1, err))
+				continue
+			}
+			circles = append(circles, circle)
+		}
+		for _, raw := range area.Polygons {
+			circle, err := parseCAPPolygon(raw)
+			if err != nil {
+				problems = append(problems, fmt.Sprintf("area %d: polygon: %v", i+1, err))
+				continue
+			}
+			circles = append(circles, circle)
+		}
+		if len(area.Circles)+len(area.Polygons) == 0 {
+			problems = append(problems, fmt.Sprintf("area %d: circle or polygon required", i+1))
+		}
+
+		for _, circle := range circles {
+			req := domain.CreateIncidentRequest{
+				Title:        truncateRunes(areaTitle, 200),
+				Description:  truncateRunes(strings.TrimSpace(info.Description), 1000),
+				Severity:     severity,
+				Latitude:     circle[0],
+				Longitude:    circle[1],
+				RadiusMeters: capRadius(circle[2]),
+				ExpiresAt:    expiresAt,
+			}
+			if circle[2] > capMaxRadiusMeters {
+				oversized = true
+				problems = append(problems, fmt.Sprintf(
+					"area %d: radius %.0f m exceeds the %d m zone limit", i+1, circle[2], capMaxRadiusMeters))
+				continue
+			}
+			for _, message := range validateCreateIncident(req) {
+				problems = append(problems, fmt.Sprintf("area %d: %s", i+1, message))
+			}
+			alert.Incidents = append(alert.Incidents, req)
+		}
+	}
+	if len(problems) > 0 {
+		if oversized {
+			return alert, "", fmt.Errorf("%w: %w: %s", ErrInvalidCAPAlert, ErrCAPAreaTooLarge, strings.Join(problems, "; "))
+		}
+		return alert, "", fmt.Errorf("%w: %s", ErrInvalidCAPAlert, strings.Join(problems, "; "))
+	}
+
+	return alert, "", nil
+}
+
+// parseCAPReferences разбирает references: "sender,identifier,sent" через пробел
+func parseCAPReferences(value string) []domain.CAPReference {
+	var references []domain.CAPReference
+	for _, triple := range strings.Fields(value) {
+		parts := strings.Split(triple, ",")
+		if len(parts) < 2 || parts[0] == "" || parts[1] == "" {
+			continue
+		}
+		references = append(references, domain.CAPReference{Sender: parts[0], Identifier: parts[1]})
+	}
+	return references
+}
+
+// parseCAPCircle разбирает "lat,lon radius" (радиус в километрах) в [lat, lon, метры]
+func parseCAPCircle(value string) ([3]float64, error) {
+	fields := strings.Fields(value)
+	if len(fields) != 2 {
+		return [3]float64{}, errors.New(`expected "lat,lon radius"`)
+	}
+	lat, lon, err := parseCAPPoint(fields[0])
+	if err != nil {
+		return [3]float64{}, err
+	}
+	radiusKm, err := strconv.ParseFloat(fields[1], 64)
+	if err != nil || radiusKm < 0 {
+		return [3]float64{}, errors.New("radius must be a non-negative number of kilometres")
+	}
+	return [3]float64{lat, lon, radiusKm * 1000}, nil
+}
+
+// parseCAPPolygon разбирает "lat,lon lat,lon ..." и описывает многоугольник кругом:
+// центр — среднее вершин, радиус — расстояние до самой дальней вершины. Долготы усредняются
+// относительно первой вершины, чтобы многоугольник через ±180° не получил центр на другой стороне Земли.
+func parseCAPPolygon(value string) ([3]float64, error) {
+	fields := strings.Fields(value)
+	if len(fields) < 3 {
+		return [3]float64{}, errors.New("at least 3 points required")
+	}
+	points := make([][2]float64, 0, len(fields))
+	for _, field := range fields {
+		lat, lon, err := parseCAPPoint(field)
+		if err != nil {
+			return [3]float64{}, err
+		}
+		points = append(points, [2]float64{lat, lon})
+	}
+	if len(points) > 3 && points[0] == points[len(points)-1] {
+		points = points[:len(points)-1]
+	}
+
+	var sumLat, sumLon float64
+	for _, point := range points {
+		sumLat += point[0]
+		sumLon += points[0][1] + wrapLongitude(point[1]-points[0][1])
+	}
+	centerLat, centerLon := sumLat/float64(len(points)), wrapLongitude(sumLon/float64(len(points)))
+
+	var radius float64
+	for _, point := range points {
+		radius = math.Max(radius, distanceMeters(centerLat, centerLon, point[0], point[1]))
+	}
+	return [3]float64{centerLat, centerLon, radius}, nil
+}
+
+func parseCAPPoint(value string) (float64, float64, error) {
+	latRaw, lonRaw, ok := strings.Cut(value, ",")
+	if !ok {
+		return 0, 0, fmt.Errorf("invalid point %q", value)
+	}
+	lat, errLat := strconv.ParseFloat(latRaw, 64)
+	lon, errLon := strconv.ParseFloat(lonRaw, 64)
+	if errLat != nil || errLon != nil {
+		return 0, 0, fmt.Errorf("invalid point %q", value)
+	}
+	return lat, lon, nil
+}
+
+// wrapLongitude приводит долготу к [-180, 180)
+func wrapLongitude(lon float64) float64 {
+	return math.Mod(math.Mod(lon+180, 360)+360, 360) - 180
+}
+
+// capRadius округляет радиус области вверх и расширяет до минимального; радиус больше
+// максимального отклоняется до этого вызова
+func capRadius(meters float64) int {
+	return int(math.Max(math.Ceil(meters), capMinRadiusMeters))
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, value := range values {
+		if value = strings.TrimSpace(value); value != "" {
+			return value
+		}
+	}
+	return ""
+}
+
+func truncateRunes(value string, limit int) string {
+	runes := []rune(value)
+	if len(runes) <= limit {
+		return value
+	}
+	return string(runes[:limit])
+}
@@ -0,0 +1,209 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/ruslanuskembaev/geo-alerts-system/internal/domain"
+)
+
+// capFeedActor автор изменений, внесённых по CAP-ленте
+const capFeedActor = "cap_feed"
+
+// maxCAPDocumentBytes ограничение размера ленты или отдельного CAP-документа
+const maxCAPDocumentBytes = 10 << 20
+
+type atomFeedXML struct {
+	Entries []atomEntryXML `xml:"entry"`
+}
+
+type atomEntryXML struct {
+	ID      string `xml:"id"`
+	Updated string `xml:"updated"`
+	Links   []struct {
+		Href string `xml:"href,attr"`
+		Rel  string `xml:"rel,attr"`
+		Type string `xml:"type,attr"`
+	} `xml:"link"`
+	Content struct {
+		Alert *capAlertXML `xml:"alert"`
+	} `xml:"content"`
+}
+
+// CAPFeedPoller периодически читает CAP-документ или Atom-ленту CAP-сообщений
+// и принимает новые записи от имени арендатора tenantID
+type CAPFeedPoller struct {
+	service  *CAPService
+	client   *http.Client
+	url      string
+	tenantID string
+	interval time.Duration
+	// seen записи ленты (id и updated), уже принятые в прошлых проходах
+	seen map[string]string
+}
+
+func NewCAPFeedPoller(service *CAPService, feedURL, tenantID string, interval, timeout time.Duration) *CAPFeedPoller {
+	return &CAPFeedPoller{
+		service:  service,
+		client:   &http.Client{Timeout: timeout},
+		url:      feedURL,
+		tenantID: tenantID,
+		interval: interval,
+		seen:     make(map[string]string),
+	}
+}
+
+// RunOnce читает ленту и принимает записи, которых не было в прошлом проходе или которые
+// с тех пор обновились. Записи со связанным CAP-документом загружаются по ссылке.
+// Запись с ошибкой загрузки повторяется в следующем проходе.
+func (p *CAPFeedPoller) RunOnce(ctx context.Context) ([]*domain.CAPIngestResult, error) {
+	ctx = domain.WithActor(domain.WithTenant(ctx, p.tenantID), capFeedActor)
+
+	raw, err := p.fetch(ctx, p.url)
+	if err != nil {
+		return nil, err
+	}
+
+	var root struct {
+		XMLName xml.Name
+	}
+	if err := xml.Unmarshal(raw, &root); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidCAPAlert, err)
+	}
+	switch root.XMLName.Local {
+	case "alert":
+		result, err := p.service.Ingest(ctx, bytes.NewReader(raw))
+		if err != nil {
+			return nil, err
+		}
+		return []*domain.CAPIngestResult{result}, nil
+	case "feed":
+	default:
+		return nil, fmt.Errorf("%w: unexpected root element %s", ErrInvalidCAPAlert, root.XMLName.Local)
+	}
+
+	var feed atomFeedXML
+	if err := xml.Unmarshal(raw, &feed); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidCAPAlert, err)
+	}
+
+	var results []*domain.CAPIngestResult
+	seen := make(map[string]string, len(feed.Entries))
+	for _, entry := range feed.Entries {
+		if updated, ok := p.seen[entry.ID]; ok && updated == entry.Updated {
+			seen[entry.ID] = entry.Updated
+			continue
+		}
+
+		result, err := p.ingestEntry(ctx, entry)
+		if err != nil {
+			log.Printf("CAP feed entry %s: %v\n", entry.ID, err)
+			// Некорректное сообщение не исправится повтором
+			if !errors.Is(err, ErrInvalidCAPAlert) {
+				continue
+			}
+		} else {
+			results = append(results, result)
+		}
+		seen[entry.ID] = entry.Updated
+	}
+	p.seen = seen
+
+	return results, nil
+}
+
+func (p *CAPFeedPoller) ingestEntry(ctx context.Context, entry atomEntryXML) (*domain.CAPIngestResult, error) {
+	if entry.Content.Alert != nil {
+		return p.service.apply(ctx, entry.Content.Alert)
+	}
+
+	link := capEntryLink(entry)
+	if link == "" {
+		return nil, fmt.Errorf("%w: entry has neither embedded alert nor link", ErrInvalidCAPAlert)
+	}
+	base, err := url.Parse(p.url)
+	if err != nil {
+		return nil, err
+	}
+	ref, err := url.Parse(link)
+	if err != nil {
+		return nil, fmt.Errorf("%w: invalid link %q", ErrInvalidCAPAlert, link)
+	}
+
+	raw, err := p.fetch(ctx, base.ResolveReference(ref).String())
+	if err != nil {
+		return nil, err
+	}
+	return p.service.Ingest(ctx, bytes.NewReader(raw))
+}
+
+// capEntryLink выбирает ссылку на CAP-документ: по типу application/cap+xml, иначе alternate
+func capEntryLink(entry atomEntryXML) string {
+	fallback := ""
+	for _, link := range entry.Links {
+		if strings.Contains(link.Type, "cap") {
+			return link.Href
+		}
+		if fallback == "" && (link.Rel == "" || link.Rel == "alternate") {
+			fallback = link.Href
+		}
+	}
+	return fallback
+}
+
+func (p *CAPFeedPoller) fetch(ctx context.Context, target string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, target, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("GET %s: unexpected status %d", target, resp.StatusCode)
+	}
+	return io.ReadAll(io.LimitReader(resp.Body, maxCAPDocumentBytes))
+}
+
+func (p *CAPFeedPoller) Start(ctx context.Context) {
+	if p.url == "" || p.interval <= 0 {
+		log.Println("CAP feed poller disabled")
+		return
+	}
+
+	log.Println("CAP feed poller started")
+	ticker := time.NewTicker(p.interval)
+	defer ticker.Stop()
+
+	for {
+		if results, err := p.RunOnce(ctx); err != nil {
+			log.Printf("CAP feed poll error: %v\n", err)
+		} else {
+			for _, result := range results {
+				if result.Status == domain.CAPIngestApplied {
+					log.Printf("CAP %s %s applied: %d created, %d updated, %d deactivated\n",
+						result.MsgType, result.Identifier, len(result.Created), len(result.Updated), len(result.Deactivated))
+				}
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			log.Println("CAP feed poller stopped")
+			return
+		case <-ticker.C:
+		}
+	}
+}
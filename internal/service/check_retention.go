@@ -0,0 +1,73 @@
+package service
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/ruslanuskembaev/geo-alerts-system/internal/repository"
+)
+
+// LocationCheckRetention поддерживает помесячные секции проверок: заранее создаёт секции
+// на aheadMonths вперёд и удаляет секции старше retention (0 — хранить бессрочно).
+// Агрегаты incident_stats_rollup при удалении секций сохраняются.
+type LocationCheckRetention struct {
+	repo        repository.LocationCheckPartitionRepository
+	retention   time.Duration
+	aheadMonths int
+	interval    time.Duration
+}
+
+func NewLocationCheckRetention(repo repository.LocationCheckPartitionRepository, retention time.Duration, aheadMonths int, interval time.Duration) *LocationCheckRetention {
+	return &LocationCheckRetention{
+		repo:        repo,
+		retention:   retention,
+		aheadMonths: aheadMonths,
+		interval:    interval,
+	}
+}
+
+// RunOnce создаёт недостающие секции и удаляет секции, целиком лежащие раньше now-retention
+func (r *LocationCheckRetention) RunOnce(ctx context.Context) (created, dropped []string, err error) {
+	now := time.Now().UTC()
+	created, err = r.repo.EnsurePartitions(ctx, now, now.AddDate(0, r.aheadMonths, 0))
+	if err != nil {
+		return created, nil, err
+	}
+	if r.retention <= 0 {
+		return created, nil, nil
+	}
+	dropped, err = r.repo.DropPartitionsBefore(ctx, now.Add(-r.retention))
+	return created, dropped, err
+}
+
+func (r *LocationCheckRetention) Start(ctx context.Context) {
+	if r.interval <= 0 {
+		log.Println("Location check retention disabled")
+		return
+	}
+
+	log.Println("Location check retention started")
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+
+	for {
+		created, dropped, err := r.RunOnce(ctx)
+		if err != nil {
+			log.Printf("Location check retention error: %v\n", err)
+		}
+		if len(created) > 0 {
+			log.Printf("Created location check partitions: %v\n", created)
+		}
+		if len(dropped) > 0 {
+			log.Printf("Dropped expired location check partitions: %v\n", dropped)
+		}
+
+		select {
+		case <-ctx.Done():
+			log.Println("Location check retention stopped")
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,69 @@
+package service
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/ruslanuskembaev/geo-alerts-system/internal/domain"
+	"github.com/ruslanuskembaev/geo-alerts-system/internal/repository"
+)
+
+// IncidentExpirer периодически деактивирует инциденты с истёкшим expires_at
+type IncidentExpirer struct {
+	repo     repository.IncidentRepository
+	cache    repository.IncidentCache
+	interval time.Duration
+}
+
+func NewIncidentExpirer(repo repository.IncidentRepository, cache repository.IncidentCache, interval time.Duration) *IncidentExpirer {
+	return &IncidentExpirer{
+		repo:     repo,
+		cache:    cache,
+		interval: interval,
+	}
+}
+
+// RunOnce деактивирует истёкшие инциденты и сбрасывает кеш затронутых арендаторов
+func (e *IncidentExpirer) RunOnce(ctx context.Context) (int, error) {
+	ctx = domain.WithActor(ctx, domain.SystemActor)
+	expired, err := e.repo.DeactivateExpired(ctx, time.Now().UTC())
+	if err != nil {
+		return 0, err
+	}
+
+	tenants := make(map[string]struct{})
+	for _, incident := range expired {
+		tenants[incident.TenantID] = struct{}{}
+	}
+	for tenantID := range tenants {
+		_ = e.cache.Invalidate(domain.WithTenant(ctx, tenantID))
+	}
+	return len(expired), nil
+}
+
+func (e *IncidentExpirer) Start(ctx context.Context) {
+	if e.interval <= 0 {
+		log.Println("Incident expirer disabled")
+		return
+	}
+
+	log.Println("Incident expirer started")
+	ticker := time.NewTicker(e.interval)
+	defer ticker.Stop()
+
+	for {
+		if expired, err := e.RunOnce(ctx); err != nil {
+			log.Printf("Incident expiry error: %v\n", err)
+		} else if expired > 0 {
+			log.Printf("Deactivated %d expired incidents\n", expired)
+		}
+
+		select {
+		case <-ctx.Done():
+			log.Println("Incident expirer stopped")
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,26 @@
+package service
+
+import "math"
+
+// circleRing аппроксимирует круг многоугольником: замкнутое кольцо точек [lon, lat]
+func circleRing(lat, lon float64, radiusMeters float64, segments int) [][2]float64 {
+	const earthRadius = 6371000
+
+	latRad := lat * math.Pi / 180
+	lonRad := lon * math.Pi / 180
+	angular := radiusMeters / earthRadius
+
+	ring := make([][2]float64, 0, segments+1)
+	for i := 0; i < segments; i++ {
+		bearing := 2 * math.Pi * float64(i) / float64(segments)
+		pointLat := math.Asin(math.Sin(latRad)*math.Cos(angular) + math.Cos(latRad)*math.Sin(angular)*math.Cos(bearing))
+		pointLon := lonRad + math.Atan2(
+			math.Sin(bearing)*math.Sin(angular)*math.Cos(latRad),
+			math.Cos(angular)-math.Sin(latRad)*math.Sin(pointLat),
+		)
+		// Нормализация долготы в [-180, 180)
+		lonDeg := math.Mod(pointLon*180/math.Pi+540, 360) - 180
+		ring = append(ring, [2]float64{lonDeg, pointLat * 180 / math.Pi})
+	}
+	return append(ring, ring[0])
+}
@@ -0,0 +1,107 @@
+package service
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/ruslanuskembaev/geo-alerts-system/internal/domain"
+	"github.com/ruslanuskembaev/geo-alerts-system/internal/repository"
+)
+
+// idempotencyLockTTL время, на которое ключ занимается выполняющимся запросом:
+// если процесс упадёт до сохранения ответа, ключ освободится сам
+const idempotencyLockTTL = time.Minute
+
+var (
+	ErrIdempotencyKeyReused  = errors.New("idempotency key reused with a different request")
+	ErrIdempotencyInProgress = errors.New("request with this idempotency key is in progress")
+	ErrIdempotencyKeyTooLong = errors.New("idempotency key is too long")
+)
+
+// IdempotencyService сохраняет ответы запросов с Idempotency-Key и повторяет их
+// для повторных запросов с тем же ключом. ttl 0 — поддержка выключена.
+// Недоступность Redis не блокирует запросы: они выполняются без защиты от повторов.
+type IdempotencyService struct {
+	store repository.IdempotencyStore
+	ttl   time.Duration
+}
+
+func NewIdempotencyService(store repository.IdempotencyStore, ttl time.Duration) *IdempotencyService {
+	return &IdempotencyService{
+		store: store,
+		ttl:   ttl,
+	}
+}
+
+func (s *IdempotencyService) Enabled() bool {
+	return s.ttl > 0
+}
+
+// IdempotencyFingerprint хеш метода, пути и тела запроса
+func IdempotencyFingerprint(method, path string, body []byte) string {
+	hash := sha256.New()
+	hash.Write([]byte(method + " " + path + "\x00"))
+	hash.Write(body)
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+// Begin занимает ключ арендатора из контекста в пределах scope (маршрут и клиент).
+// Возвращает сохранённый ответ для повтора либо reserved=true, если запрос нужно выполнить
+// и затем передать результат в Complete. При ошибке Redis оба значения пустые.
+func (s *IdempotencyService) Begin(ctx context.Context, scope, key, fingerprint string) (*domain.IdempotencyRecord, bool, error) {
+	if len(key) > domain.MaxIdempotencyKeyLength {
+		return nil, false, ErrIdempotencyKeyTooLong
+	}
+
+	lockTTL := idempotencyLockTTL
+	if s.ttl < lockTTL {
+		lockTTL = s.ttl
+	}
+	existing, reserved, err := s.store.Reserve(ctx, idempotencyStoreKey(ctx, scope, key), domain.IdempotencyRecord{
+		Fingerprint: fingerprint,
+		CreatedAt:   time.Now().UTC(),
+	}, lockTTL)
+	if err != nil {
+		log.Printf("Idempotency store error: %v\n", err)
+		return nil, false, nil
+	}
+	if reserved {
+		return nil, true, nil
+	}
+
+	if existing.Fingerprint != fingerprint {
+		return nil, false, ErrIdempotencyKeyReused
+	}
+	if !existing.Completed() {
+		return nil, false, ErrIdempotencyInProgress
+	}
+	return existing, false, nil
+}
+
+// Complete сохраняет ответ на ttl. Ошибки сервера и превышение лимита не сохраняются:
+// ключ освобождается, и клиент может повторить запрос.
+func (s *IdempotencyService) Complete(ctx context.Context, scope, key string, record domain.IdempotencyRecord) {
+	storeKey := idempotencyStoreKey(ctx, scope, key)
+
+	var err error
+	if record.Status >= http.StatusInternalServerError || record.Status == http.StatusTooManyRequests {
+		err = s.store.Release(ctx, storeKey)
+	} else {
+		err = s.store.Save(ctx, storeKey, record, s.ttl)
+	}
+	if err != nil {
+		log.Printf("Idempotency store error: %v\n", err)
+	}
+}
+
+// idempotencyStoreKey ключ хранилища; scope и ключ клиента хешируются, чтобы
+// произвольные символы заголовка не попадали в имя ключа Redis
+func idempotencyStoreKey(ctx context.Context, scope, key string) string {
+	hash := sha256.Sum256([]byte(scope + "\x00" + key))
+	return domain.TenantFromContext(ctx) + ":" + hex.EncodeToString(hash[:])
+}
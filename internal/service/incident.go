@@ -13,6 +13,10 @@ type IncidentService struct {
 	repo      repository.IncidentRepository
 	cache     repository.IncidentCache
 	checkRepo repository.LocationCheckRepository
+
+	// Ретроактивные оповещения (см. WithRetroactiveAlerts)
+	retro         repository.RetroactiveAlertRepository
+	retroLookback time.Duration
 }
 
 func NewIncidentService(
@@ -33,6 +37,9 @@ func (s *IncidentService) Create(ctx context.Context, req domain.CreateIncidentR
 		return nil, err
 	}
 	_ = s.cache.Invalidate(ctx)
+	if req.NotifyRecentUsers {
+		s.requestRecentUserAlerts(ctx, incident)
+	}
 	return incident, nil
 }
 
@@ -40,12 +47,34 @@ func (s *IncidentService) GetByID(ctx context.Context, id string) (*domain.Incid
 	return s.repo.GetByID(ctx, id)
 }
 
-func (s *IncidentService) List(ctx context.Context, limit, offset int) ([]*domain.Incident, int, error) {
-	return s.repo.List(ctx, limit, offset)
+func (s *IncidentService) List(ctx context.Context, filter domain.IncidentFilter, page domain.PageRequest) ([]*domain.Incident, domain.PageInfo, error) {
+	return s.repo.List(ctx, filter, page)
+}
+
+// Update изменяет инцидент; expectedVersion (из If-Match) делает изменение условным
+func (s *IncidentService) Update(ctx context.Context, id string, req domain.UpdateIncidentRequest, expectedVersion *int) (*domain.Incident, error) {
+	incident, err := s.repo.Update(ctx, id, req, expectedVersion)
+	if err != nil {
+		return nil, err
+	}
+	_ = s.cache.Invalidate(ctx)
+	if req.NotifyRecentUsers && (req.Latitude != nil || req.Longitude != nil || req.RadiusMeters != nil) {
+		s.requestRecentUserAlerts(ctx, incident)
+	}
+	return incident, nil
+}
+
+func (s *IncidentService) Deactivate(ctx context.Context, id string, expectedVersion *int) error {
+	if err := s.repo.Deactivate(ctx, id, expectedVersion); err != nil {
+		return err
+	}
+	_ = s.cache.Invalidate(ctx)
+	return nil
 }
 
-func (s *IncidentService) Update(ctx context.Context, id string, req domain.UpdateIncidentRequest) (*domain.Incident, error) {
-	incident, err := s.repo.Update(ctx, id, req)
+// Reactivate возвращает деактивированный инцидент в работу
+func (s *IncidentService) Reactivate(ctx context.Context, id string, expectedVersion *int) (*domain.Incident, error) {
+	incident, err := s.repo.Reactivate(ctx, id, expectedVersion)
 	if err != nil {
 		return nil, err
 	}
@@ -53,14 +82,131 @@ func (s *IncidentService) Update(ctx context.Context, id string, req domain.Upda
 	return incident, nil
 }
 
-func (s *IncidentService) Deactivate(ctx context.Context, id string) error {
-	if err := s.repo.Deactivate(ctx, id); err != nil {
+// Purge безвозвратно удаляет инцидент и его связи с проверками
+func (s *IncidentService) Purge(ctx context.Context, id string) error {
+	if err := s.repo.Purge(ctx, id); err != nil {
 		return err
 	}
 	_ = s.cache.Invalidate(ctx)
 	return nil
 }
 
-func (s *IncidentService) StatsByIncident(ctx context.Context, since time.Time) ([]domain.IncidentStats, error) {
-	return s.checkRepo.StatsByIncident(ctx, since)
+// Preview оценивает охват черновика инцидента по проверкам за [since, until) и находит
+// пересекающиеся активные зоны; ничего не сохраняет
+func (s *IncidentService) Preview(ctx context.Context, req domain.CreateIncidentRequest, since, until time.Time) (*domain.ImpactPreview, error) {
+	zone := domain.ImpactZoneFromRequest(req)
+
+	impact, err := s.checkRepo.ZoneImpact(ctx, zone, since, until)
+	if err != nil {
+		return nil, err
+	}
+	overlapping, err := s.repo.ListOverlapping(ctx, zone)
+	if err != nil {
+		return nil, err
+	}
+
+	return &domain.ImpactPreview{
+		Since:       since,
+		Until:       until,
+		Impact:      impact,
+		Overlapping: overlapping,
+	}, nil
+}
+
+func (s *IncidentService) StatsByIncident(ctx context.Context, query domain.IncidentStatsQuery) ([]domain.IncidentStats, error) {
+	return s.checkRepo.StatsByIncident(ctx, query)
+}
+
+// IncidentStats статистика одного инцидента арендатора, в том числе деактивированного
+func (s *IncidentService) IncidentStats(ctx context.Context, id string, query domain.IncidentStatsQuery) (*domain.IncidentStats, error) {
+	if _, err := s.repo.GetByID(ctx, id); err != nil {
+		return nil, err
+	}
+
+	query.IncidentIDs = []string{id}
+	query.Severities = nil
+	stats, err := s.checkRepo.StatsByIncident(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	if len(stats) == 0 {
+		return nil, repository.ErrNotFound
+	}
+	return &stats[0], nil
+}
+
+// Heatmap агрегирует проверки арендатора по сетке geohash
+func (s *IncidentService) Heatmap(ctx context.Context, query domain.HeatmapQuery) (*domain.Heatmap, error) {
+	cells, err := s.checkRepo.Heatmap(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+
+	heatmap := &domain.Heatmap{
+		Precision: query.Precision,
+		Since:     query.Since,
+		Until:     query.Until,
+		Cells:     cells,
+	}
+	if len(cells) > query.Limit {
+		heatmap.Cells = cells[:query.Limit]
+		heatmap.Truncated = true
+	}
+	return heatmap, nil
+}
+
+// StatsSeries возвращает временные ряды по агрегатам. From выравнивается на начало корзины,
+// пропущенные корзины заполняются нулями.
+func (s *IncidentService) StatsSeries(ctx context.Context, query domain.StatsSeriesQuery) (*domain.StatsSeries, error) {
+	step := query.Bucket.Duration()
+	query.From = query.From.UTC().Truncate(step)
+	query.To = query.To.UTC()
+
+	series, err := s.checkRepo.StatsSeries(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range series {
+		filled := make([]domain.StatsPoint, 0, int(query.To.Sub(query.From)/step)+1)
+		points := series[i].Points
+		for start := query.From; start.Before(query.To); start = start.Add(step) {
+			if len(points) > 0 && points[0].BucketStart.Equal(start) {
+				filled = append(filled, points[0])
+				points = points[1:]
+				continue
+			}
+			filled = append(filled, domain.StatsPoint{BucketStart: start})
+		}
+		series[i].Points = filled
+	}
+
+	return &domain.StatsSeries{
+		From:   query.From,
+		To:     query.To,
+		Bucket: query.Bucket,
+		Series: series,
+	}, nil
+}
+
+// activeIncidents читает активные инциденты арендатора из кеша, при промахе — из БД с прогревом кеша
+func activeIncidents(ctx context.Context, repo repository.IncidentRepository, cache repository.IncidentCache) ([]*domain.Incident, error) {
+	if loader, ok := cache.(repository.IncidentLoader); ok {
+		return loader.LoadActive(ctx, repo.ListActive)
+	}
+
+	incidents, ok, err := cache.GetActive(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if ok {
+		return incidents, nil
+	}
+
+	incidents, err = repo.ListActive(ctx)
+	if err != nil {
+		return nil, err
+	}
+	_ = cache.SetActive(ctx, incidents)
+	return incidents, nil
 }
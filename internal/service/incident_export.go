@@ -0,0 +1,243 @@
+package service
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ruslanuskembaev/geo-alerts-system/internal/domain"
+)
+
+var ErrUnsupportedExportFormat = errors.New("unsupported export format")
+
+// kmlCircleSegments число вершин многоугольника, которым зона рисуется в KML
+const kmlCircleSegments = 64
+
+// incidentEncoder пишет выгрузку инцидентов в поток по одной записи
+type incidentEncoder interface {
+	Begin() error
+	Encode(incident *domain.Incident) error
+	End() error
+}
+
+// Export выгружает инциденты по фильтру в w, не буферизуя выборку.
+// Пока из базы не получена первая запись, в w ничего не пишется — ошибку запроса
+// ещё можно вернуть клиенту обычным ответом.
+func (s *IncidentService) Export(ctx context.Context, format domain.ExportFormat, filter domain.IncidentFilter, w io.Writer) error {
+	var encoder incidentEncoder
+	switch format {
+	case domain.ExportFormatCSV:
+		encoder = &csvIncidentEncoder{w: csv.NewWriter(w)}
+	case domain.ExportFormatGeoJSON:
+		encoder = &geoJSONIncidentEncoder{w: w}
+	case domain.ExportFormatKML:
+		encoder = &kmlIncidentEncoder{w: w, enc: xml.NewEncoder(w)}
+	default:
+		return ErrUnsupportedExportFormat
+	}
+
+	started := false
+	err := s.repo.Stream(ctx, filter, func(incident *domain.Incident) error {
+		if !started {
+			started = true
+			if err := encoder.Begin(); err != nil {
+				return err
+			}
+		}
+		return encoder.Encode(incident)
+	})
+	if err != nil {
+		return err
+	}
+	if !started {
+		if err := encoder.Begin(); err != nil {
+			return err
+		}
+	}
+	return encoder.End()
+}
+
+// csvIncidentHeader совместим с заголовком импорта (лишние колонки импорт игнорирует)
+var csvIncidentHeader = []string{
+	"id", "title", "description", "severity", "latitude", "longitude", "radius_meters",
+	"is_active", "version", "created_at", "updated_at", "deactivated_at",
+	"expires_at",
+}
+
+type csvIncidentEncoder struct {
+	w *csv.Writer
+}
+
+func (e *csvIncidentEncoder) Begin() error {
+	return e.w.Write(csvIncidentHeader)
+}
+
+func (e *csvIncidentEncoder) Encode(incident *domain.Incident) error {
+	return e.w.Write([]string{
+		incident.ID,
+		escapeCSVFormula(incident.Title),
+		escapeCSVFormula(incident.Description),
+		string(incident.Severity),
+		strconv.FormatFloat(incident.Latitude, 'f', -1, 64),
+		strconv.FormatFloat(incident.Longitude, 'f', -1, 64),
+		strconv.Itoa(incident.RadiusMeters),
+		strconv.FormatBool(incident.IsActive),
+		strconv.Itoa(incident.Version),
+		incident.CreatedAt.UTC().Format(time.RFC3339),
+		incident.UpdatedAt.UTC().Format(time.RFC3339),
+		optionalTimeCell(incident.DeactivatedAt),
+		optionalTimeCell(incident.ExpiresAt),
+	})
+}
+
+// csvFormulaPrefixes символы, с которых электронные таблицы начинают формулу
+const csvFormulaPrefixes = "=+-@\t\r"
+
+// escapeCSVFormula добавляет апостроф перед текстом, который таблица исполнила бы как формулу;
+// импорт CSV снимает его обратно
+func escapeCSVFormula(value string) string {
+	if value != "" && strings.IndexByte(csvFormulaPrefixes, value[0]) >= 0 {
+		return "'" + value
+	}
+	return value
+}
+
+func optionalTimeCell(t *time.Time) string {
+	if t == nil {
+		return ""
+	}
+	return t.UTC().Format(time.RFC3339)
+}
+
+func (e *csvIncidentEncoder) End() error {
+	e.w.Flush()
+	return e.w.Error()
+}
+
+type geoJSONIncidentEncoder struct {
+	w     io.Writer
+	count int
+}
+
+type geoJSONPointFeature struct {
+	Type     string `json:"type"`
+	Geometry struct {
+		Type        string     `json:"type"`
+		Coordinates [2]float64 `json:"coordinates"`
+	} `json:"geometry"`
+	Properties *domain.Incident `json:"properties"`
+}
+
+func (e *geoJSONIncidentEncoder) Begin() error {
+	_, err := io.WriteString(e.w, `{"type":"FeatureCollection","features":[`)
+	return err
+}
+
+func (e *geoJSONIncidentEncoder) Encode(incident *domain.Incident) error {
+	feature := geoJSONPointFeature{Type: "Feature", Properties: incident}
+	feature.Geometry.Type = "Point"
+	feature.Geometry.Coordinates = [2]float64{incident.Longitude, incident.Latitude}
+
+	raw, err := json.Marshal(feature)
+	if err != nil {
+		return err
+	}
+	if e.count > 0 {
+		raw = append([]byte{','}, raw...)
+	}
+	e.count++
+	_, err = e.w.Write(raw)
+	return err
+}
+
+func (e *geoJSONIncidentEncoder) End() error {
+	_, err := io.WriteString(e.w, "]}\n")
+	return err
+}
+
+// kmlSeverityColors цвета зон в формате KML (aabbggrr): заливка и контур
+var kmlSeverityColors = map[domain.Severity][2]string{
+	domain.SeverityLow:    {"5000ffff", "ff00ffff"},
+	domain.SeverityMedium: {"5000a5ff", "ff00a5ff"},
+	domain.SeverityHigh:   {"500000ff", "ff0000ff"},
+}
+
+type kmlIncidentEncoder struct {
+	w   io.Writer
+	enc *xml.Encoder
+}
+
+type kmlData struct {
+	Name  string `xml:"name,attr"`
+	Value string `xml:"value"`
+}
+
+type kmlPlacemark struct {
+	XMLName      xml.Name  `xml:"Placemark"`
+	ID           string    `xml:"id,attr"`
+	Name         string    `xml:"name"`
+	Description  string    `xml:"description"`
+	StyleURL     string    `xml:"styleUrl"`
+	ExtendedData []kmlData `xml:"ExtendedData>Data"`
+	Point        string    `xml:"MultiGeometry>Point>coordinates"`
+	Polygon      string    `xml:"MultiGeometry>Polygon>outerBoundaryIs>LinearRing>coordinates"`
+}
+
+func (e *kmlIncidentEncoder) Begin() error {
+	var styles strings.Builder
+	for _, severity := range []domain.Severity{domain.SeverityLow, domain.SeverityMedium, domain.SeverityHigh} {
+		colors := kmlSeverityColors[severity]
+		styles.WriteString(`<Style id="severity-` + string(severity) + `">` +
+			`<LineStyle><color>` + colors[1] + `</color><width>2</width></LineStyle>` +
+			`<PolyStyle><color>` + colors[0] + `</color></PolyStyle>` +
+			`</Style>`)
+	}
+	_, err := io.WriteString(e.w, xml.Header+
+		`<kml xmlns="http://www.opengis.net/kml/2.2"><Document><name>Geo Alerts incidents</name>`+
+		styles.String())
+	return err
+}
+
+func (e *kmlIncidentEncoder) Encode(incident *domain.Incident) error {
+	var ring strings.Builder
+	for i, point := range circleRing(incident.Latitude, incident.Longitude, float64(incident.RadiusMeters), kmlCircleSegments) {
+		if i > 0 {
+			ring.WriteByte(' ')
+		}
+		ring.WriteString(kmlCoordinate(point[0], point[1]))
+	}
+
+	placemark := kmlPlacemark{
+		ID:          incident.ID,
+		Name:        incident.Title,
+		Description: incident.Description,
+		StyleURL:    "#severity-" + string(incident.Severity),
+		ExtendedData: []kmlData{
+			{Name: "severity", Value: string(incident.Severity)},
+			{Name: "radius_meters", Value: strconv.Itoa(incident.RadiusMeters)},
+			{Name: "is_active", Value: strconv.FormatBool(incident.IsActive)},
+			{Name: "updated_at", Value: incident.UpdatedAt.UTC().Format(time.RFC3339)},
+		},
+		Point:   kmlCoordinate(incident.Longitude, incident.Latitude),
+		Polygon: ring.String(),
+	}
+	if err := e.enc.Encode(placemark); err != nil {
+		return err
+	}
+	return e.enc.Flush()
+}
+
+func (e *kmlIncidentEncoder) End() error {
+	_, err := io.WriteString(e.w, "</Document></kml>\n")
+	return err
+}
+
+func kmlCoordinate(lon, lat float64) string {
+	return strconv.FormatFloat(lon, 'f', 6, 64) + "," + strconv.FormatFloat(lat, 'f', 6, 64)
+}
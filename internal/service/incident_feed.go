@@ -0,0 +1,245 @@
+package service
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/ruslanuskembaev/geo-alerts-system/internal/domain"
+)
+
+// capOutSeverities соответствие уровней инцидента severity CAP
+var capOutSeverities = map[domain.Severity]string{
+	domain.SeverityLow:    "Minor",
+	domain.SeverityMedium: "Moderate",
+	domain.SeverityHigh:   "Severe",
+}
+
+// ActiveFeed возвращает снимок активных неистёкших инцидентов арендатора с валидаторами
+// для условных запросов. Снимок читается из того же кеша, что и проверки координат.
+func (s *IncidentService) ActiveFeed(ctx context.Context) (*domain.IncidentFeed, error) {
+	incidents, err := activeIncidents(ctx, s.repo, s.cache)
+	if err != nil {
+		return nil, err
+	}
+
+	lastModified, err := s.repo.LastUpdatedAt(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now().UTC()
+	hash := sha256.New()
+	active := make([]*domain.Incident, 0, len(incidents))
+	for _, incident := range incidents {
+		// Истёкший, но ещё не деактивированный инцидент исчезает из ленты в момент expires_at
+		if incident.ExpiresAt != nil && !incident.ExpiresAt.After(now) {
+			if incident.ExpiresAt.After(lastModified) {
+				lastModified = incident.ExpiresAt.UTC()
+			}
+			continue
+		}
+		if incident.UpdatedAt.After(lastModified) {
+			lastModified = incident.UpdatedAt.UTC()
+		}
+		active = append(active, incident)
+		fmt.Fprintf(hash, "%s:%d;", incident.ID, incident.Version)
+	}
+	fmt.Fprintf(hash, "%d", lastModified.UnixNano())
+
+	return &domain.IncidentFeed{
+		TenantID:     domain.TenantFromContext(ctx),
+		Incidents:    active,
+		LastModified: lastModified,
+		ETag:         `"` + hex.EncodeToString(hash.Sum(nil)[:16]) + `"`,
+	}, nil
+}
+
+type atomFeedOut struct {
+	XMLName     xml.Name       `xml:"feed"`
+	Xmlns       string         `xml:"xmlns,attr"`
+	XmlnsGeoRSS string         `xml:"xmlns:georss,attr,omitempty"`
+	ID          string         `xml:"id"`
+	Title       string         `xml:"title"`
+	Updated     string         `xml:"updated"`
+	Author      atomAuthorOut  `xml:"author"`
+	Links       []atomLinkOut  `xml:"link"`
+	Entries     []atomEntryOut `xml:"entry"`
+}
+
+type atomAuthorOut struct {
+	Name string `xml:"name"`
+}
+
+type atomLinkOut struct {
+	Rel  string `xml:"rel,attr"`
+	Type string `xml:"type,attr,omitempty"`
+	Href string `xml:"href,attr"`
+}
+
+type atomEntryOut struct {
+	ID        string `xml:"id"`
+	Title     string `xml:"title"`
+	Updated   string `xml:"updated"`
+	Published string `xml:"published"`
+	Summary   string `xml:"summary,omitempty"`
+	Category  struct {
+		Term string `xml:"term,attr"`
+	} `xml:"category"`
+	Point   string          `xml:"georss:point,omitempty"`
+	Radius  int             `xml:"georss:radius,omitempty"`
+	Content *atomContentOut `xml:"content"`
+}
+
+type atomContentOut struct {
+	Type  string       `xml:"type,attr"`
+	Alert *capAlertOut `xml:"alert"`
+}
+
+type capAlertOut struct {
+	XMLName    xml.Name   `xml:"urn:oasis:names:tc:emergency:cap:1.2 alert"`
+	Identifier string     `xml:"identifier"`
+	Sender     string     `xml:"sender"`
+	Sent       string     `xml:"sent"`
+	Status     string     `xml:"status"`
+	MsgType    string     `xml:"msgType"`
+	Scope      string     `xml:"scope"`
+	References string     `xml:"references,omitempty"`
+	Info       capInfoOut `xml:"info"`
+}
+
+type capInfoOut struct {
+	Category    string     `xml:"category"`
+	Event       string     `xml:"event"`
+	Urgency     string     `xml:"urgency"`
+	Severity    string     `xml:"severity"`
+	Certainty   string     `xml:"certainty"`
+	Effective   string     `xml:"effective"`
+	Expires     string     `xml:"expires,omitempty"`
+	Headline    string     `xml:"headline"`
+	Description string     `xml:"description,omitempty"`
+	Area        capAreaOut `xml:"area"`
+}
+
+type capAreaOut struct {
+	AreaDesc string `xml:"areaDesc"`
+	Circle   string `xml:"circle"`
+}
+
+// EncodeAtomFeed пишет Atom-ленту активных инцидентов с геометрией GeoRSS (центр и радиус зоны)
+func EncodeAtomFeed(w io.Writer, feed *domain.IncidentFeed, selfURL string) error {
+	out := newAtomFeed(feed, selfURL, "Geo Alerts: active incidents")
+	out.XmlnsGeoRSS = "http://www.georss.org/georss"
+	for _, incident := range feed.Incidents {
+		entry := newAtomEntry(incident, incidentURN(feed.TenantID, incident.ID))
+		entry.Point = strconv.FormatFloat(incident.Latitude, 'f', -1, 64) + " " + strconv.FormatFloat(incident.Longitude, 'f', -1, 64)
+		entry.Radius = incident.RadiusMeters
+		out.Entries = append(out.Entries, entry)
+	}
+	return encodeFeedXML(w, out)
+}
+
+// EncodeCAPFeed пишет Atom-ленту, каждая запись которой содержит CAP 1.2 сообщение об инциденте.
+// Идентификатор сообщения включает версию инцидента: первая версия публикуется как Alert,
+// последующие — как Update со ссылкой на первую.
+func EncodeCAPFeed(w io.Writer, feed *domain.IncidentFeed, selfURL, sender string) error {
+	out := newAtomFeed(feed, selfURL, "Geo Alerts: CAP alerts")
+	for _, incident := range feed.Incidents {
+		alert := incidentCAPAlert(feed.TenantID, incident, sender)
+		entry := newAtomEntry(incident, alert.Identifier)
+		entry.Content = &atomContentOut{Type: "application/cap+xml", Alert: alert}
+		out.Entries = append(out.Entries, entry)
+	}
+	return encodeFeedXML(w, out)
+}
+
+func newAtomFeed(feed *domain.IncidentFeed, selfURL, title string) *atomFeedOut {
+	updated := feed.LastModified
+	if updated.IsZero() {
+		updated = time.Unix(0, 0)
+	}
+	return &atomFeedOut{
+		Xmlns:   "http://www.w3.org/2005/Atom",
+		ID:      "urn:geoalerts:" + feed.TenantID + ":incidents",
+		Title:   title + " (" + feed.TenantID + ")",
+		Updated: updated.UTC().Format(time.RFC3339),
+		Author:  atomAuthorOut{Name: "Geo Alerts System"},
+		Links:   []atomLinkOut{{Rel: "self", Type: "application/atom+xml", Href: selfURL}},
+		Entries: []atomEntryOut{},
+	}
+}
+
+func newAtomEntry(incident *domain.Incident, id string) atomEntryOut {
+	entry := atomEntryOut{
+		ID:        id,
+		Title:     incident.Title,
+		Updated:   incident.UpdatedAt.UTC().Format(time.RFC3339),
+		Published: incident.CreatedAt.UTC().Format(time.RFC3339),
+		Summary:   incident.Description,
+	}
+	entry.Category.Term = string(incident.Severity)
+	return entry
+}
+
+func incidentCAPAlert(tenantID string, incident *domain.Incident, sender string) *capAlertOut {
+	alert := &capAlertOut{
+		Identifier: incidentURN(tenantID, incident.ID) + ":" + strconv.Itoa(incident.Version),
+		Sender:     sender,
+		Sent:       capTime(incident.UpdatedAt),
+		Status:     "Actual",
+		MsgType:    string(domain.CAPMsgTypeAlert),
+		Scope:      "Public",
+		Info: capInfoOut{
+			Category:    "Safety",
+			Event:       incident.Title,
+			Urgency:     "Immediate",
+			Severity:    capOutSeverities[incident.Severity],
+			Certainty:   "Observed",
+			Effective:   capTime(incident.CreatedAt),
+			Headline:    incident.Title,
+			Description: incident.Description,
+			Area: capAreaOut{
+				AreaDesc: incident.Title,
+				Circle: strconv.FormatFloat(incident.Latitude, 'f', -1, 64) + "," + strconv.FormatFloat(incident.Longitude, 'f', -1, 64) +
+					" " + strconv.FormatFloat(float64(incident.RadiusMeters)/1000, 'f', -1, 64),
+			},
+		},
+	}
+	if incident.Version > 1 {
+		alert.MsgType = string(domain.CAPMsgTypeUpdate)
+		alert.References = sender + "," + incidentURN(tenantID, incident.ID) + ":1," + capTime(incident.CreatedAt)
+	}
+	if incident.ExpiresAt != nil {
+		alert.Info.Expires = capTime(*incident.ExpiresAt)
+	}
+	if alert.Info.Severity == "" {
+		alert.Info.Severity = "Unknown"
+	}
+	return alert
+}
+
+func incidentURN(tenantID, id string) string {
+	return "urn:geoalerts:" + tenantID + ":incident:" + id
+}
+
+// capTime форматирует время по CAP 1.2: без дробных секунд, UTC записывается как -00:00
+func capTime(t time.Time) string {
+	return t.UTC().Format("2006-01-02T15:04:05") + "-00:00"
+}
+
+func encodeFeedXML(w io.Writer, feed *atomFeedOut) error {
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	if err := enc.Encode(feed); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
@@ -0,0 +1,246 @@
+package service
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+
+	"github.com/ruslanuskembaev/geo-alerts-system/internal/domain"
+)
+
+// MaxImportRows ограничение числа строк в одном импорте
+const MaxImportRows = 5000
+
+var (
+	ErrUnsupportedImportFormat = errors.New("unsupported import format")
+	ErrInvalidImportFile       = errors.New("invalid import file")
+)
+
+// importRow строка импорта: разобранный запрос либо ошибки разбора
+type importRow struct {
+	req    domain.CreateIncidentRequest
+	errors []string
+}
+
+// Import разбирает файл, проверяет каждую строку правилами CreateIncidentRequest и,
+// если ошибок нет и это не dry-run, создаёт все инциденты в одной транзакции.
+func (s *IncidentService) Import(ctx context.Context, format domain.ImportFormat, r io.Reader, dryRun bool) (*domain.IncidentImportResult, error) {
+	var rows []importRow
+	var err error
+	switch format {
+	case domain.ImportFormatCSV:
+		rows, err = parseIncidentCSV(r)
+	case domain.ImportFormatGeoJSON:
+		rows, err = parseIncidentGeoJSON(r)
+	default:
+		return nil, ErrUnsupportedImportFormat
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	result := &domain.IncidentImportResult{
+		DryRun: dryRun,
+		Total:  len(rows),
+		Errors: []domain.ImportRowError{},
+	}
+	reqs := make([]domain.CreateIncidentRequest, 0, len(rows))
+	for i, row := range rows {
+		rowErrors := row.errors
+		if len(rowErrors) == 0 {
+			rowErrors = validateCreateIncident(row.req)
+		}
+		if len(rowErrors) > 0 {
+			result.Errors = append(result.Errors, domain.ImportRowError{Row: i + 1, Errors: rowErrors})
+			continue
+		}
+		reqs = append(reqs, row.req)
+	}
+	result.Valid = len(reqs)
+
+	if dryRun || len(result.Errors) > 0 || len(reqs) == 0 {
+		return result, nil
+	}
+
+	incidents, err := s.repo.CreateBatch(ctx, reqs)
+	if err != nil {
+		return nil, err
+	}
+	_ = s.cache.Invalidate(ctx)
+
+	result.Imported = len(incidents)
+	result.Incidents = incidents
+	return result, nil
+}
+
+// parseIncidentCSV читает CSV с заголовком: title, description, severity, latitude, longitude, radius_meters
+func parseIncidentCSV(r io.Reader) ([]importRow, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("%w: missing CSV header", ErrInvalidImportFile)
+	}
+	columns := make(map[string]int, len(header))
+	for i, name := range header {
+		columns[strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, "\ufeff")))] = i
+	}
+	for _, required := range []string{"title", "severity", "latitude", "longitude", "radius_meters"} {
+		if _, ok := columns[required]; !ok {
+			return nil, fmt.Errorf("%w: CSV header must contain %s", ErrInvalidImportFile, required)
+		}
+	}
+
+	var rows []importRow
+	for {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidImportFile, err)
+		}
+		if len(rows) >= MaxImportRows {
+			return nil, fmt.Errorf("%w: more than %d rows", ErrInvalidImportFile, MaxImportRows)
+		}
+
+		cell := func(name string) string {
+			if i, ok := columns[name]; ok && i < len(record) {
+				return strings.TrimSpace(record[i])
+			}
+			return ""
+		}
+
+		var row importRow
+		row.req = domain.CreateIncidentRequest{
+			Title:       unescapeCSVFormula(cell("title")),
+			Description: unescapeCSVFormula(cell("description")),
+			Severity:    domain.Severity(strings.ToLower(cell("severity"))),
+		}
+		row.req.Latitude = parseFloatCell(cell("latitude"), "latitude", &row.errors)
+		row.req.Longitude = parseFloatCell(cell("longitude"), "longitude", &row.errors)
+		row.req.RadiusMeters = parseIntCell(cell("radius_meters"), "radius_meters", &row.errors)
+		rows = append(rows, row)
+	}
+
+	return rows, nil
+}
+
+// unescapeCSVFormula снимает апостроф, которым выгрузка экранирует формулы
+func unescapeCSVFormula(value string) string {
+	if len(value) > 1 && value[0] == '\'' && strings.IndexByte(csvFormulaPrefixes, value[1]) >= 0 {
+		return value[1:]
+	}
+	return value
+}
+
+func parseFloatCell(value, name string, rowErrors *[]string) float64 {
+	parsed, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		*rowErrors = append(*rowErrors, name+": must be a number")
+	}
+	return parsed
+}
+
+func parseIntCell(value, name string, rowErrors *[]string) int {
+	parsed, err := strconv.Atoi(value)
+	if err != nil {
+		*rowErrors = append(*rowErrors, name+": must be an integer")
+	}
+	return parsed
+}
+
+type geoJSONFeatureCollection struct {
+	Type     string           `json:"type"`
+	Features []geoJSONFeature `json:"features"`
+}
+
+type geoJSONFeature struct {
+	Type     string `json:"type"`
+	Geometry *struct {
+		Type        string          `json:"type"`
+		Coordinates json.RawMessage `json:"coordinates"`
+	} `json:"geometry"`
+	Properties json.RawMessage `json:"properties"`
+}
+
+// parseIncidentGeoJSON читает FeatureCollection из Point-объектов; свойства — поля CreateIncidentRequest
+func parseIncidentGeoJSON(r io.Reader) ([]importRow, error) {
+	var collection geoJSONFeatureCollection
+	if err := json.NewDecoder(r).Decode(&collection); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidImportFile, err)
+	}
+	if collection.Type != "FeatureCollection" {
+		return nil, fmt.Errorf("%w: expected FeatureCollection", ErrInvalidImportFile)
+	}
+	if len(collection.Features) > MaxImportRows {
+		return nil, fmt.Errorf("%w: more than %d features", ErrInvalidImportFile, MaxImportRows)
+	}
+
+	rows := make([]importRow, 0, len(collection.Features))
+	for _, feature := range collection.Features {
+		var row importRow
+		if len(feature.Properties) > 0 {
+			if err := json.Unmarshal(feature.Properties, &row.req); err != nil {
+				row.errors = append(row.errors, "properties: "+err.Error())
+			}
+		}
+
+		var coordinates []float64
+		switch {
+		case feature.Geometry == nil || feature.Geometry.Type != "Point":
+			row.errors = append(row.errors, "geometry: must be a Point")
+		case json.Unmarshal(feature.Geometry.Coordinates, &coordinates) != nil || len(coordinates) < 2:
+			row.errors = append(row.errors, "geometry: coordinates must be [longitude, latitude]")
+		default:
+			row.req.Longitude, row.req.Latitude = coordinates[0], coordinates[1]
+		}
+		rows = append(rows, row)
+	}
+
+	return rows, nil
+}
+
+// validateCreateIncident проверяет запрос теми же правилами (теги binding), что и POST /incidents
+func validateCreateIncident(req domain.CreateIncidentRequest) []string {
+	err := binding.Validator.ValidateStruct(&req)
+	if err == nil {
+		return nil
+	}
+
+	var validationErrors validator.ValidationErrors
+	if !errors.As(err, &validationErrors) {
+		return []string{err.Error()}
+	}
+	messages := make([]string, 0, len(validationErrors))
+	for _, fieldErr := range validationErrors {
+		message := jsonFieldName(fieldErr.StructField()) + ": failed on " + fieldErr.Tag()
+		if fieldErr.Param() != "" {
+			message += "=" + fieldErr.Param()
+		}
+		messages = append(messages, message)
+	}
+	return messages
+}
+
+var createIncidentType = reflect.TypeOf(domain.CreateIncidentRequest{})
+
+func jsonFieldName(structField string) string {
+	field, ok := createIncidentType.FieldByName(structField)
+	if !ok {
+		return structField
+	}
+	name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+	return name
+}
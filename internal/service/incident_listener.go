@@ -0,0 +1,79 @@
+package service
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/ruslanuskembaev/geo-alerts-system/internal/domain"
+	"github.com/ruslanuskembaev/geo-alerts-system/internal/repository"
+)
+
+// Паузы между переподключениями к Postgres: удваиваются до максимума
+const (
+	incidentListenMinRetryDelay = time.Second
+	incidentListenMaxRetryDelay = 30 * time.Second
+)
+
+// IncidentChangeWatcher сбрасывает кеш активных инцидентов (Redis и копии в памяти реплик)
+// при изменении incidents в БД, в том числе в обход API: импорт, ручные правки, другие сервисы.
+// Уведомления получает только ведущая реплика (см. IncidentChangeListener): один сброс версии
+// в Redis на изменение, копии остальных реплик сбрасываются через pub/sub кеша.
+// Изменения через API сбрасывают кеш и сами; повторный сброс безопасен.
+type IncidentChangeWatcher struct {
+	listener repository.IncidentChangeListener
+	cache    repository.IncidentCache
+	minDelay time.Duration
+	maxDelay time.Duration
+}
+
+func NewIncidentChangeWatcher(listener repository.IncidentChangeListener, cache repository.IncidentCache) *IncidentChangeWatcher {
+	return &IncidentChangeWatcher{
+		listener: listener,
+		cache:    cache,
+		minDelay: incidentListenMinRetryDelay,
+		maxDelay: incidentListenMaxRetryDelay,
+	}
+}
+
+// WithRetryDelay задаёт паузы между переподключениями
+func (w *IncidentChangeWatcher) WithRetryDelay(minDelay, maxDelay time.Duration) *IncidentChangeWatcher {
+	w.minDelay = minDelay
+	w.maxDelay = maxDelay
+	return w
+}
+
+func (w *IncidentChangeWatcher) Start(ctx context.Context) {
+	log.Println("Incident change listener started")
+	delay := w.minDelay
+
+	for {
+		startedAt := time.Now()
+		err := w.listener.Listen(ctx, func(tenantID string) {
+			if err := w.cache.Invalidate(domain.WithTenant(ctx, tenantID)); err != nil {
+				log.Printf("Incident cache invalidation error: %v\n", err)
+			}
+		})
+		if ctx.Err() != nil {
+			log.Println("Incident change listener stopped")
+			return
+		}
+
+		// Соединение, проработавшее дольше максимальной паузы, считается восстановленным
+		if time.Since(startedAt) > w.maxDelay {
+			delay = w.minDelay
+		}
+		log.Printf("Incident change listener error: %v; reconnecting in %s\n", err, delay)
+
+		select {
+		case <-ctx.Done():
+			log.Println("Incident change listener stopped")
+			return
+		case <-time.After(delay):
+		}
+		delay *= 2
+		if delay > w.maxDelay {
+			delay = w.maxDelay
+		}
+	}
+}
@@ -17,39 +17,34 @@ type LocationService struct {
 	incidentRepo repository.IncidentRepository
 	cache        repository.IncidentCache
 	checkRepo    repository.LocationCheckRepository
-	queue        repository.WebhookQueue
 }
 
 func NewLocationService(
 	incidentRepo repository.IncidentRepository,
 	cache repository.IncidentCache,
 	checkRepo repository.LocationCheckRepository,
-	queue repository.WebhookQueue,
 ) *LocationService {
 	return &LocationService{
 		incidentRepo: incidentRepo,
 		cache:        cache,
 		checkRepo:    checkRepo,
-		queue:        queue,
 	}
 }
 
 func (s *LocationService) CheckLocation(ctx context.Context, req domain.LocationCheckRequest) (*domain.LocationCheckResponse, error) {
-	incidents, ok, err := s.cache.GetActive(ctx)
+	incidents, err := activeIncidents(ctx, s.incidentRepo, s.cache)
 	if err != nil {
 		return nil, err
 	}
-	if !ok {
-		incidents, err = s.incidentRepo.ListActive(ctx)
-		if err != nil {
-			return nil, err
-		}
-		_ = s.cache.SetActive(ctx, incidents)
-	}
 
+	now := time.Now().UTC()
 	matched := make([]domain.NearbyIncident, 0)
 	incidentIDs := make([]string, 0)
 	for _, incident := range incidents {
+		// Кеш может пережить expires_at до ближайшего прохода IncidentExpirer
+		if incident.ExpiresAt != nil && !incident.ExpiresAt.After(now) {
+			continue
+		}
 		distance := distanceMeters(req.Latitude, req.Longitude, incident.Latitude, incident.Longitude)
 		if distance <= float64(incident.RadiusMeters) {
 			matched = append(matched, domain.NearbyIncident{
@@ -69,9 +64,10 @@ func (s *LocationService) CheckLocation(ctx context.Context, req domain.Location
 		return matched[i].DistanceMeters < matched[j].DistanceMeters
 	})
 
-	now := time.Now().UTC()
+	tenantID := domain.TenantFromContext(ctx)
 	check := domain.LocationCheck{
 		ID:             uuid.New().String(),
+		TenantID:       tenantID,
 		UserID:         req.UserID,
 		Latitude:       req.Latitude,
 		Longitude:      req.Longitude,
@@ -79,12 +75,12 @@ func (s *LocationService) CheckLocation(ctx context.Context, req domain.Location
 		CheckedAt:      now,
 	}
 
-	if err := s.checkRepo.Create(ctx, check, incidentIDs); err != nil {
-		return nil, err
-	}
-
+	// Задача вебхука сохраняется в outbox вместе с проверкой и попадает в очередь
+	// через ретранслятор, поэтому оповещение отправляется только по записанной проверке
+	var job *domain.WebhookJob
 	if len(matched) > 0 {
-		job := domain.WebhookJob{
+		job = &domain.WebhookJob{
+			TenantID: tenantID,
 			Payload: domain.WebhookPayload{
 				CheckID:        check.ID,
 				UserID:         check.UserID,
@@ -97,10 +93,10 @@ func (s *LocationService) CheckLocation(ctx context.Context, req domain.Location
 			Attempt:   0,
 			CreatedAt: now,
 		}
+	}
 
-		if err := s.queue.Enqueue(ctx, job); err != nil {
-			return nil, err
-		}
+	if err := s.checkRepo.Create(ctx, check, incidentIDs, job); err != nil {
+		return nil, err
 	}
 
 	return &domain.LocationCheckResponse{
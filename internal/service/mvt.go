@@ -0,0 +1,238 @@
+package service
+
+import (
+	"encoding/binary"
+	"math"
+)
+
+// Минимальный кодировщик Mapbox Vector Tile 2.1 (protobuf без сгенерированного кода).
+// Номера полей соответствуют vector_tile.proto.
+
+const (
+	mvtExtent = 4096
+	// mvtBuffer запас за границей тайла, чтобы контуры соседних тайлов не обрывались на стыке
+	mvtBuffer = 64
+
+	mvtGeomPoint   = 1
+	mvtGeomPolygon = 3
+
+	mvtCmdMoveTo    = 1
+	mvtCmdLineTo    = 2
+	mvtCmdClosePath = 7
+
+	pbVarint = 0
+	pbBytes  = 2
+)
+
+// mvtValue значение атрибута: строка либо неотрицательное целое
+type mvtValue struct {
+	str   string
+	num   uint64
+	isNum bool
+}
+
+// mvtLayer слой тайла со словарями ключей и значений атрибутов
+type mvtLayer struct {
+	name       string
+	keys       []string
+	keyIndex   map[string]uint32
+	values     []mvtValue
+	valueIndex map[mvtValue]uint32
+	features   [][]byte
+}
+
+type mvtAttribute struct {
+	key   string
+	value mvtValue
+}
+
+func newMVTLayer(name string) *mvtLayer {
+	return &mvtLayer{
+		name:       name,
+		keyIndex:   make(map[string]uint32),
+		valueIndex: make(map[mvtValue]uint32),
+	}
+}
+
+// addFeature добавляет объект; geometry — уже закодированные команды геометрии
+func (l *mvtLayer) addFeature(geomType uint64, geometry []uint32, attributes []mvtAttribute) {
+	tags := make([]uint32, 0, len(attributes)*2)
+	for _, attr := range attributes {
+		tags = append(tags, l.key(attr.key), l.value(attr.value))
+	}
+
+	var feature []byte
+	feature = pbAppendPacked(feature, 2, tags)
+	feature = pbAppendVarint(feature, 3, geomType)
+	feature = pbAppendPacked(feature, 4, geometry)
+	l.features = append(l.features, feature)
+}
+
+func (l *mvtLayer) key(key string) uint32 {
+	if idx, ok := l.keyIndex[key]; ok {
+		return idx
+	}
+	idx := uint32(len(l.keys))
+	l.keys = append(l.keys, key)
+	l.keyIndex[key] = idx
+	return idx
+}
+
+func (l *mvtLayer) value(value mvtValue) uint32 {
+	if idx, ok := l.valueIndex[value]; ok {
+		return idx
+	}
+	idx := uint32(len(l.values))
+	l.values = append(l.values, value)
+	l.valueIndex[value] = idx
+	return idx
+}
+
+func (l *mvtLayer) encode() []byte {
+	var layer []byte
+	layer = pbAppendVarint(layer, 15, 2)
+	layer = pbAppendBytes(layer, 1, []byte(l.name))
+	for _, feature := range l.features {
+		layer = pbAppendBytes(layer, 2, feature)
+	}
+	for _, key := range l.keys {
+		layer = pbAppendBytes(layer, 3, []byte(key))
+	}
+	for _, value := range l.values {
+		var encoded []byte
+		if value.isNum {
+			encoded = pbAppendVarint(encoded, 5, value.num)
+		} else {
+			encoded = pbAppendBytes(encoded, 1, []byte(value.str))
+		}
+		layer = pbAppendBytes(layer, 4, encoded)
+	}
+	return pbAppendVarint(layer, 5, mvtExtent)
+}
+
+// encodeMVT собирает тайл; пустые слои не пишутся, тайл без объектов — пустое сообщение
+func encodeMVT(layers ...*mvtLayer) []byte {
+	tile := []byte{}
+	for _, layer := range layers {
+		if len(layer.features) == 0 {
+			continue
+		}
+		tile = pbAppendBytes(tile, 3, layer.encode())
+	}
+	return tile
+}
+
+// mvtPointGeometry кодирует одиночную точку
+func mvtPointGeometry(x, y int) []uint32 {
+	return []uint32{mvtCommand(mvtCmdMoveTo, 1), zigzag(x), zigzag(y)}
+}
+
+// mvtRingGeometry кодирует внешнее кольцо полигона. Кольцо передаётся без замыкающей точки;
+// по спецификации внешнее кольцо обходится по часовой стрелке в координатах тайла (ось Y вниз).
+func mvtRingGeometry(ring [][2]int) []uint32 {
+	if ringArea(ring) < 0 {
+		for i, j := 0, len(ring)-1; i < j; i, j = i+1, j-1 {
+			ring[i], ring[j] = ring[j], ring[i]
+		}
+	}
+
+	geometry := make([]uint32, 0, 2*len(ring)+3)
+	geometry = append(geometry, mvtCommand(mvtCmdMoveTo, 1), zigzag(ring[0][0]), zigzag(ring[0][1]))
+	geometry = append(geometry, mvtCommand(mvtCmdLineTo, len(ring)-1))
+	for i := 1; i < len(ring); i++ {
+		geometry = append(geometry, zigzag(ring[i][0]-ring[i-1][0]), zigzag(ring[i][1]-ring[i-1][1]))
+	}
+	return append(geometry, mvtCommand(mvtCmdClosePath, 1))
+}
+
+// ringArea удвоенная ориентированная площадь кольца (положительна при обходе по часовой стрелке в тайле)
+func ringArea(ring [][2]int) int {
+	area := 0
+	for i := range ring {
+		j := (i + 1) % len(ring)
+		area += ring[i][0]*ring[j][1] - ring[j][0]*ring[i][1]
+	}
+	return area
+}
+
+// clipRing отсекает выпуклое кольцо прямоугольником [min, max] по обеим осям (Сазерленд — Ходжман)
+func clipRing(ring [][2]float64, min, max float64) [][2]float64 {
+	bounds := []struct {
+		axis  int
+		value float64
+		upper bool
+	}{{0, min, false}, {0, max, true}, {1, min, false}, {1, max, true}}
+
+	for _, b := range bounds {
+		if len(ring) == 0 {
+			return ring
+		}
+		inside := func(p [2]float64) bool {
+			if b.upper {
+				return p[b.axis] <= b.value
+			}
+			return p[b.axis] >= b.value
+		}
+
+		clipped := make([][2]float64, 0, len(ring)+4)
+		prev := ring[len(ring)-1]
+		for _, cur := range ring {
+			if inside(cur) != inside(prev) {
+				t := (b.value - prev[b.axis]) / (cur[b.axis] - prev[b.axis])
+				var p [2]float64
+				p[b.axis] = b.value
+				p[1-b.axis] = prev[1-b.axis] + t*(cur[1-b.axis]-prev[1-b.axis])
+				clipped = append(clipped, p)
+			}
+			if inside(cur) {
+				clipped = append(clipped, cur)
+			}
+			prev = cur
+		}
+		ring = clipped
+	}
+	return ring
+}
+
+// roundRing округляет координаты и убирает повторяющиеся подряд точки
+func roundRing(ring [][2]float64) [][2]int {
+	rounded := make([][2]int, 0, len(ring))
+	for _, p := range ring {
+		point := [2]int{int(math.Round(p[0])), int(math.Round(p[1]))}
+		if len(rounded) > 0 && rounded[len(rounded)-1] == point {
+			continue
+		}
+		rounded = append(rounded, point)
+	}
+	for len(rounded) > 1 && rounded[0] == rounded[len(rounded)-1] {
+		rounded = rounded[:len(rounded)-1]
+	}
+	return rounded
+}
+
+func mvtCommand(id, count int) uint32 {
+	return uint32(id&0x7) | uint32(count)<<3
+}
+
+func zigzag(v int) uint32 {
+	return uint32(int32(v)<<1) ^ uint32(int32(v)>>31)
+}
+
+func pbAppendVarint(buf []byte, field int, v uint64) []byte {
+	buf = binary.AppendUvarint(buf, uint64(field)<<3|pbVarint)
+	return binary.AppendUvarint(buf, v)
+}
+
+func pbAppendBytes(buf []byte, field int, data []byte) []byte {
+	buf = binary.AppendUvarint(buf, uint64(field)<<3|pbBytes)
+	buf = binary.AppendUvarint(buf, uint64(len(data)))
+	return append(buf, data...)
+}
+
+func pbAppendPacked(buf []byte, field int, values []uint32) []byte {
+	var packed []byte
+	for _, v := range values {
+		packed = binary.AppendUvarint(packed, uint64(v))
+	}
+	return pbAppendBytes(buf, field, packed)
+}
@@ -0,0 +1,100 @@
+package service
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/ruslanuskembaev/geo-alerts-system/internal/domain"
+	"github.com/ruslanuskembaev/geo-alerts-system/internal/repository"
+)
+
+// Значения ретранслятора outbox по умолчанию
+const (
+	defaultOutboxRelayInterval  = time.Second
+	defaultOutboxRelayBatchSize = 100
+	outboxPruneInterval         = time.Hour
+)
+
+// OutboxRelay переносит задачи вебхуков из outbox в очередь доставки и отмечает их
+// переданными. Реплики не мешают друг другу: заблокированные строки пропускаются.
+// Переданные задачи хранятся retention (0 — бессрочно) и затем удаляются.
+type OutboxRelay struct {
+	repo      repository.WebhookOutboxRepository
+	queue     repository.WebhookQueue
+	interval  time.Duration
+	batchSize int
+	retention time.Duration
+	lastPrune time.Time
+}
+
+// NewOutboxRelay создаёт ретранслятор; ретранслятор нельзя выключить, так как без него
+// оповещения не отправляются, поэтому неположительные значения заменяются значениями по умолчанию
+func NewOutboxRelay(repo repository.WebhookOutboxRepository, queue repository.WebhookQueue, interval time.Duration, batchSize int, retention time.Duration) *OutboxRelay {
+	if interval <= 0 {
+		interval = defaultOutboxRelayInterval
+	}
+	if batchSize <= 0 {
+		batchSize = defaultOutboxRelayBatchSize
+	}
+	return &OutboxRelay{
+		repo:      repo,
+		queue:     queue,
+		interval:  interval,
+		batchSize: batchSize,
+		retention: retention,
+	}
+}
+
+// RunOnce передаёт в очередь ожидающие задачи, пока выборки заполняются целиком,
+// и возвращает число переданных задач
+func (r *OutboxRelay) RunOnce(ctx context.Context) (int, error) {
+	total := 0
+	for {
+		dispatched, err := r.repo.Dispatch(ctx, r.batchSize, func(job domain.WebhookJob) error {
+			return r.queue.Enqueue(ctx, job)
+		})
+		total += dispatched
+		if err != nil || dispatched < r.batchSize {
+			return total, err
+		}
+	}
+}
+
+// Prune удаляет задачи, переданные раньше now-retention
+func (r *OutboxRelay) Prune(ctx context.Context) (int, error) {
+	if r.retention <= 0 {
+		return 0, nil
+	}
+	return r.repo.PruneDispatched(ctx, time.Now().UTC().Add(-r.retention))
+}
+
+func (r *OutboxRelay) Start(ctx context.Context) {
+	log.Println("Webhook outbox relay started")
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+
+	for {
+		if dispatched, err := r.RunOnce(ctx); err != nil {
+			log.Printf("Webhook outbox relay error: %v\n", err)
+		} else if dispatched > 0 {
+			log.Printf("Relayed %d webhook jobs from outbox\n", dispatched)
+		}
+
+		if now := time.Now(); now.Sub(r.lastPrune) >= outboxPruneInterval {
+			r.lastPrune = now
+			if pruned, err := r.Prune(ctx); err != nil {
+				log.Printf("Webhook outbox prune error: %v\n", err)
+			} else if pruned > 0 {
+				log.Printf("Pruned %d dispatched webhook outbox jobs\n", pruned)
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			log.Println("Webhook outbox relay stopped")
+			return
+		case <-ticker.C:
+		}
+	}
+}
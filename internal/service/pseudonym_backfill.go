@@ -0,0 +1,81 @@
+package service
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/ruslanuskembaev/geo-alerts-system/internal/domain"
+	"github.com/ruslanuskembaev/geo-alerts-system/internal/repository"
+)
+
+// PseudonymBackfill переводит на псевдонимы проверки и отметки статистики, сохранённые под
+// исходным user_id до включения псевдонимизации. Новые проверки сразу сохраняются под
+// псевдонимом, поэтому задача завершается, когда исходных user_id не остаётся; при ошибке
+// повторяет проход через interval.
+type PseudonymBackfill struct {
+	repo      repository.UserPseudonymRepository
+	privacy   domain.LocationPrivacy
+	interval  time.Duration
+	batchSize int
+}
+
+func NewPseudonymBackfill(repo repository.UserPseudonymRepository, privacy domain.LocationPrivacy, interval time.Duration, batchSize int) *PseudonymBackfill {
+	return &PseudonymBackfill{
+		repo:      repo,
+		privacy:   privacy,
+		interval:  interval,
+		batchSize: batchSize,
+	}
+}
+
+// RunOnce переводит пользователей пачками по batchSize, пока исходных user_id не останется.
+// Возвращает число переведённых пользователей.
+func (b *PseudonymBackfill) RunOnce(ctx context.Context) (int, error) {
+	converted := 0
+	for {
+		users, err := b.repo.RawUsers(ctx, b.batchSize)
+		if err != nil {
+			return converted, err
+		}
+		for _, user := range users {
+			if err := b.repo.Pseudonymize(ctx, user, b.privacy.UserIDLookups(user.TenantID, user.UserID)); err != nil {
+				return converted, err
+			}
+			converted++
+		}
+		if len(users) < b.batchSize || ctx.Err() != nil {
+			return converted, ctx.Err()
+		}
+	}
+}
+
+func (b *PseudonymBackfill) Start(ctx context.Context) {
+	if len(b.privacy.Keys) == 0 || b.interval <= 0 || b.batchSize <= 0 {
+		log.Println("Pseudonym backfill disabled")
+		return
+	}
+
+	log.Println("Pseudonym backfill started")
+	ticker := time.NewTicker(b.interval)
+	defer ticker.Stop()
+
+	for {
+		converted, err := b.RunOnce(ctx)
+		if converted > 0 {
+			log.Printf("Pseudonymized stored data of %d users\n", converted)
+		}
+		if err == nil {
+			log.Println("Pseudonym backfill completed")
+			return
+		}
+		log.Printf("Pseudonym backfill error: %v\n", err)
+
+		select {
+		case <-ctx.Done():
+			log.Println("Pseudonym backfill stopped")
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,57 @@
+package service
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/ruslanuskembaev/geo-alerts-system/internal/domain"
+	"github.com/ruslanuskembaev/geo-alerts-system/internal/repository"
+)
+
+// IncidentPurger периодически удаляет инциденты, деактивированные дольше retention
+type IncidentPurger struct {
+	repo      repository.IncidentRepository
+	retention time.Duration
+	interval  time.Duration
+}
+
+func NewIncidentPurger(repo repository.IncidentRepository, retention, interval time.Duration) *IncidentPurger {
+	return &IncidentPurger{
+		repo:      repo,
+		retention: retention,
+		interval:  interval,
+	}
+}
+
+// RunOnce удаляет инциденты, деактивированные раньше now-retention
+func (p *IncidentPurger) RunOnce(ctx context.Context) (int, error) {
+	ctx = domain.WithActor(ctx, domain.SystemActor)
+	return p.repo.PurgeDeactivatedBefore(ctx, time.Now().UTC().Add(-p.retention))
+}
+
+func (p *IncidentPurger) Start(ctx context.Context) {
+	if p.retention <= 0 || p.interval <= 0 {
+		log.Println("Incident purger disabled")
+		return
+	}
+
+	log.Println("Incident purger started")
+	ticker := time.NewTicker(p.interval)
+	defer ticker.Stop()
+
+	for {
+		if purged, err := p.RunOnce(ctx); err != nil {
+			log.Printf("Incident purge error: %v\n", err)
+		} else if purged > 0 {
+			log.Printf("Purged %d deactivated incidents\n", purged)
+		}
+
+		select {
+		case <-ctx.Done():
+			log.Println("Incident purger stopped")
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,61 @@
+package service
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/ruslanuskembaev/geo-alerts-system/internal/domain"
+	"github.com/ruslanuskembaev/geo-alerts-system/internal/repository"
+)
+
+// RateLimitService применяет лимиты запросов на пользователя и IP
+type RateLimitService struct {
+	limiter repository.RateLimiter
+	perUser int
+	perIP   int
+	window  time.Duration
+}
+
+func NewRateLimitService(limiter repository.RateLimiter, perUser, perIP int, window time.Duration) *RateLimitService {
+	return &RateLimitService{
+		limiter: limiter,
+		perUser: perUser,
+		perIP:   perIP,
+		window:  window,
+	}
+}
+
+// AllowUser проверяет лимит для user_id арендатора из контекста.
+// Возвращает время ожидания при превышении.
+func (s *RateLimitService) AllowUser(ctx context.Context, userID string) (bool, time.Duration) {
+	return s.allow(ctx, userRateLimitKey(ctx, userID), s.perUser)
+}
+
+// ResetUser удаляет счётчик запросов user_id арендатора из контекста
+func (s *RateLimitService) ResetUser(ctx context.Context, userID string) (bool, error) {
+	return s.limiter.Reset(ctx, userRateLimitKey(ctx, userID))
+}
+
+func userRateLimitKey(ctx context.Context, userID string) string {
+	return "user:" + domain.TenantFromContext(ctx) + ":" + userID
+}
+
+// AllowIP проверяет лимит для IP-адреса клиента.
+func (s *RateLimitService) AllowIP(ctx context.Context, ip string) (bool, time.Duration) {
+	return s.allow(ctx, "ip:"+ip, s.perIP)
+}
+
+func (s *RateLimitService) allow(ctx context.Context, key string, limit int) (bool, time.Duration) {
+	if limit <= 0 || s.window <= 0 {
+		return true, 0
+	}
+
+	allowed, retryAfter, err := s.limiter.Allow(ctx, key, limit, s.window)
+	if err != nil {
+		// Недоступность Redis не должна блокировать проверку опасных зон
+		log.Printf("Rate limiter error: %v\n", err)
+		return true, 0
+	}
+	return allowed, retryAfter
+}
@@ -0,0 +1,208 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/ruslanuskembaev/geo-alerts-system/internal/domain"
+	"github.com/ruslanuskembaev/geo-alerts-system/internal/repository"
+)
+
+// Значения обработчика ретроактивных оповещений
+const (
+	defaultRetroactiveAlertInterval = time.Second
+	retroactiveAlertBatchSize       = 20
+	// Аренда запроса: реплика, упавшая посреди обработки, не блокирует его дольше
+	retroactiveAlertLease      = time.Minute
+	retroactiveAlertPruneEvery = time.Hour
+)
+
+// WithRetroactiveAlerts включает оповещение пользователей, чья последняя проверка за lookback
+// попадает в новую или изменённую зону (по флагу notify_recent_users запроса). Запрос только
+// ставится в очередь, поиск проверок и запись вебхуков в outbox выполняет RetroactiveAlerter.
+// Для оповещения нужны точные user_id и координаты всех проверок: при псевдонимизации, режиме
+// координат none или хранении только проверок в зонах (danger only) оповещения выключены.
+// В режиме grid зона сравнивается с центром ячейки, а не с исходной точкой.
+func (s *IncidentService) WithRetroactiveAlerts(retro repository.RetroactiveAlertRepository, lookback time.Duration, privacy domain.LocationPrivacy) *IncidentService {
+	if lookback > 0 {
+		switch {
+		case len(privacy.Keys) > 0 || !privacy.StoresCoordinates():
+			log.Println("Retroactive alerts disabled: location privacy does not keep user IDs and coordinates")
+			lookback = 0
+		case privacy.DangerOnly:
+			log.Println("Retroactive alerts disabled: checks outside danger zones are not stored")
+			lookback = 0
+		case privacy.Coordinates == domain.CoordinatesGrid:
+			log.Printf("Retroactive alerts match grid-snapped coordinates (%g m cells): users near the zone edge may be missed or alerted\n", privacy.GridMeters)
+		}
+	}
+	s.retro = retro
+	s.retroLookback = lookback
+	return s
+}
+
+// RetroactiveLookback возвращает окно поиска проверок для ретроактивных оповещений
+// (0 — оповещения выключены настройкой или режимом приватности)
+func (s *IncidentService) RetroactiveLookback() time.Duration {
+	return s.retroLookback
+}
+
+// requestRecentUserAlerts ставит в очередь ретроактивные оповещения о зоне инцидента.
+// Инцидент к этому моменту уже сохранён, поэтому ошибки только логируются.
+func (s *IncidentService) requestRecentUserAlerts(ctx context.Context, incident *domain.Incident) {
+	if s.retro == nil || s.retroLookback <= 0 || !incident.IsActive {
+		return
+	}
+	now := time.Now().UTC()
+	if incident.ExpiresAt != nil && !incident.ExpiresAt.After(now) {
+		return
+	}
+
+	if err := s.retro.Request(ctx, incident.ID, now.Add(-s.retroLookback)); err != nil {
+		log.Printf("Retroactive alerts request for incident %s failed: %v\n", incident.ID, err)
+	}
+}
+
+// RetroactiveAlerter обрабатывает запросы ретроактивных оповещений: находит пользователей,
+// чья последняя проверка попадает в зону, и пишет вебхуки в outbox. По каждой проверке
+// об инциденте оповещают не больше одного раза. Реплики делят запросы через аренду.
+type RetroactiveAlerter struct {
+	repo      repository.RetroactiveAlertRepository
+	incidents repository.IncidentRepository
+	checks    repository.LocationCheckRepository
+	lookback  time.Duration
+	interval  time.Duration
+	lastPrune time.Time
+}
+
+func NewRetroactiveAlerter(
+	repo repository.RetroactiveAlertRepository,
+	incidents repository.IncidentRepository,
+	checks repository.LocationCheckRepository,
+	lookback, interval time.Duration,
+) *RetroactiveAlerter {
+	if interval <= 0 {
+		interval = defaultRetroactiveAlertInterval
+	}
+	return &RetroactiveAlerter{
+		repo:      repo,
+		incidents: incidents,
+		checks:    checks,
+		lookback:  lookback,
+		interval:  interval,
+	}
+}
+
+// RunOnce обрабатывает ожидающие запросы, пока выборки заполняются целиком, и возвращает
+// число записанных оповещений. Запрос, обработка которого не удалась, выдаётся повторно
+// после истечения аренды.
+func (a *RetroactiveAlerter) RunOnce(ctx context.Context) (int, error) {
+	ctx = domain.WithActor(ctx, domain.SystemActor)
+	total := 0
+	for {
+		requests, err := a.repo.Claim(ctx, retroactiveAlertBatchSize, retroactiveAlertLease)
+		if err != nil {
+			return total, err
+		}
+		var lastErr error
+		for _, req := range requests {
+			alerted, err := a.process(domain.WithTenant(ctx, req.TenantID), req)
+			if err != nil {
+				log.Printf("Retroactive alerts for incident %s failed: %v\n", req.IncidentID, err)
+				lastErr = err
+				continue
+			}
+			if alerted > 0 {
+				log.Printf("Retroactive alerts for incident %s: %d users\n", req.IncidentID, alerted)
+			}
+			total += alerted
+		}
+		if lastErr != nil || len(requests) < retroactiveAlertBatchSize {
+			return total, lastErr
+		}
+	}
+}
+
+func (a *RetroactiveAlerter) process(ctx context.Context, req domain.RetroactiveAlertRequest) (int, error) {
+	incident, err := a.incidents.GetByID(ctx, req.IncidentID)
+	if err != nil && !errors.Is(err, repository.ErrNotFound) {
+		return 0, err
+	}
+	now := time.Now().UTC()
+	// Удалённый, деактивированный или истёкший к моменту обработки инцидент не оповещает
+	if incident == nil || !incident.IsActive || (incident.ExpiresAt != nil && !incident.ExpiresAt.After(now)) {
+		return a.repo.Complete(ctx, req, nil)
+	}
+
+	checks, err := a.checks.LatestChecksInZone(ctx, *incident, req.Since)
+	if err != nil {
+		return 0, err
+	}
+
+	jobs := make([]domain.WebhookJob, 0, len(checks))
+	for _, check := range checks {
+		jobs = append(jobs, domain.WebhookJob{
+			TenantID: incident.TenantID,
+			Payload: domain.WebhookPayload{
+				CheckID:        check.ID,
+				UserID:         check.UserID,
+				Latitude:       check.Latitude,
+				Longitude:      check.Longitude,
+				IsInDangerZone: true,
+				CheckedAt:      check.CheckedAt,
+				Incidents: []domain.NearbyIncident{{
+					ID:             incident.ID,
+					Title:          incident.Title,
+					Severity:       incident.Severity,
+					Latitude:       incident.Latitude,
+					Longitude:      incident.Longitude,
+					RadiusMeters:   incident.RadiusMeters,
+					DistanceMeters: distanceMeters(check.Latitude, check.Longitude, incident.Latitude, incident.Longitude),
+				}},
+				Retroactive: true,
+			},
+			CreatedAt: now,
+		})
+	}
+	return a.repo.Complete(ctx, req, jobs)
+}
+
+// Prune удаляет отметки оповещений старше окна поиска
+func (a *RetroactiveAlerter) Prune(ctx context.Context) (int, error) {
+	return a.repo.PruneAlerts(ctx, time.Now().UTC().Add(-a.lookback))
+}
+
+func (a *RetroactiveAlerter) Start(ctx context.Context) {
+	if a.lookback <= 0 {
+		log.Println("Retroactive alerter disabled")
+		return
+	}
+
+	log.Println("Retroactive alerter started")
+	ticker := time.NewTicker(a.interval)
+	defer ticker.Stop()
+
+	for {
+		if _, err := a.RunOnce(ctx); err != nil {
+			log.Printf("Retroactive alerter error: %v\n", err)
+		}
+
+		if now := time.Now(); now.Sub(a.lastPrune) >= retroactiveAlertPruneEvery {
+			a.lastPrune = now
+			if pruned, err := a.Prune(ctx); err != nil {
+				log.Printf("Retroactive alerts prune error: %v\n", err)
+			} else if pruned > 0 {
+				log.Printf("Pruned %d retroactive alert marks\n", pruned)
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			log.Println("Retroactive alerter stopped")
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,58 @@
+package service
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/ruslanuskembaev/geo-alerts-system/internal/repository"
+)
+
+// statsBucketUsersRetention срок хранения отметок пользователей корзин: проверки пишутся
+// текущим временем, поэтому корзины старше суток (самая крупная — день) уже закрыты
+const statsBucketUsersRetention = 48 * time.Hour
+
+// StatsRollupPruner периодически удаляет отметки пользователей закрытых корзин статистики.
+// Сами агрегаты incident_stats_rollup не удаляются.
+type StatsRollupPruner struct {
+	repo     repository.LocationCheckRepository
+	interval time.Duration
+}
+
+func NewStatsRollupPruner(repo repository.LocationCheckRepository, interval time.Duration) *StatsRollupPruner {
+	return &StatsRollupPruner{
+		repo:     repo,
+		interval: interval,
+	}
+}
+
+// RunOnce удаляет отметки корзин, начавшихся раньше now-statsBucketUsersRetention
+func (p *StatsRollupPruner) RunOnce(ctx context.Context) (int, error) {
+	return p.repo.PruneStatsBucketUsers(ctx, time.Now().UTC().Add(-statsBucketUsersRetention))
+}
+
+func (p *StatsRollupPruner) Start(ctx context.Context) {
+	if p.interval <= 0 {
+		log.Println("Stats rollup pruner disabled")
+		return
+	}
+
+	log.Println("Stats rollup pruner started")
+	ticker := time.NewTicker(p.interval)
+	defer ticker.Stop()
+
+	for {
+		if pruned, err := p.RunOnce(ctx); err != nil {
+			log.Printf("Stats rollup prune error: %v\n", err)
+		} else if pruned > 0 {
+			log.Printf("Pruned %d stats bucket user marks\n", pruned)
+		}
+
+		select {
+		case <-ctx.Done():
+			log.Println("Stats rollup pruner stopped")
+			return
+		case <-ticker.C:
+		}
+	}
+}
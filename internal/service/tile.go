@@ -0,0 +1,140 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"math"
+	"time"
+
+	"github.com/ruslanuskembaev/geo-alerts-system/internal/domain"
+	"github.com/ruslanuskembaev/geo-alerts-system/internal/repository"
+)
+
+// ErrInvalidTile тайл вне допустимого диапазона z/x/y
+var ErrInvalidTile = errors.New("invalid tile coordinates")
+
+const (
+	incidentTileLayer = "incidents"
+	// tileCircleSegments число вершин многоугольника, аппроксимирующего зону
+	tileCircleSegments = 64
+	// maxMercatorLat граница проекции Web Mercator
+	maxMercatorLat = 85.05112878
+)
+
+// TileService отдаёт зоны активных инцидентов векторными тайлами (Mapbox Vector Tile)
+type TileService struct {
+	repo  repository.IncidentRepository
+	cache repository.IncidentCache
+	tiles repository.TileCache
+}
+
+func NewTileService(repo repository.IncidentRepository, cache repository.IncidentCache, tiles repository.TileCache) *TileService {
+	return &TileService{
+		repo:  repo,
+		cache: cache,
+		tiles: tiles,
+	}
+}
+
+// IncidentTile возвращает MVT-тайл со слоем incidents. Отрисованный тайл кешируется
+// под текущим поколением арендатора, которое меняется при любом изменении инцидентов.
+func (s *TileService) IncidentTile(ctx context.Context, coord domain.TileCoord) ([]byte, error) {
+	if !coord.Valid() {
+		return nil, ErrInvalidTile
+	}
+
+	// Поколение читается до инцидентов: изменение между чтениями оставит тайл под старым поколением
+	generation, err := s.tiles.Generation(ctx)
+	if err != nil {
+		return nil, err
+	}
+	tile, ok, err := s.tiles.Get(ctx, generation, coord)
+	if err != nil {
+		return nil, err
+	}
+	if ok {
+		return tile, nil
+	}
+
+	incidents, err := activeIncidents(ctx, s.repo, s.cache)
+	if err != nil {
+		return nil, err
+	}
+	tile = renderIncidentTile(incidents, coord, time.Now().UTC())
+	_ = s.tiles.Set(ctx, generation, coord, tile)
+	return tile, nil
+}
+
+// renderIncidentTile рисует зоны многоугольниками в координатах тайла. Зона, которая на этом
+// масштабе занимает лишь несколько единиц сетки тайла, выводится точкой в своём центре.
+func renderIncidentTile(incidents []*domain.Incident, coord domain.TileCoord, now time.Time) []byte {
+	layer := newMVTLayer(incidentTileLayer)
+	for _, incident := range incidents {
+		// Кеш может пережить expires_at до ближайшего прохода IncidentExpirer
+		if incident.ExpiresAt != nil && !incident.ExpiresAt.After(now) {
+			continue
+		}
+
+		ring := circleRing(incident.Latitude, incident.Longitude, float64(incident.RadiusMeters), tileCircleSegments)
+		projected := make([][2]float64, 0, len(ring)-1)
+		for _, point := range ring[:len(ring)-1] {
+			// Зона через антимеридиан остаётся непрерывной: долгота берётся рядом с центром
+			lon := point[0]
+			if lon-incident.Longitude > 180 {
+				lon -= 360
+			} else if incident.Longitude-lon > 180 {
+				lon += 360
+			}
+			projected = append(projected, tilePixel(coord, point[1], lon))
+		}
+
+		clipped := clipRing(projected, -mvtBuffer, mvtExtent+mvtBuffer)
+		if len(clipped) == 0 {
+			continue
+		}
+
+		attributes := []mvtAttribute{
+			{key: "id", value: mvtValue{str: incident.ID}},
+			{key: "title", value: mvtValue{str: incident.Title}},
+			{key: "severity", value: mvtValue{str: string(incident.Severity)}},
+			{key: "radius_meters", value: mvtValue{num: uint64(incident.RadiusMeters), isNum: true}},
+		}
+
+		rounded := roundRing(clipped)
+		if !tooSmallForPolygon(clipped) && len(rounded) >= 3 && ringArea(rounded) != 0 {
+			layer.addFeature(mvtGeomPolygon, mvtRingGeometry(rounded), attributes)
+			continue
+		}
+		center := tilePixel(coord, incident.Latitude, incident.Longitude)
+		if center[0] >= 0 && center[0] < mvtExtent && center[1] >= 0 && center[1] < mvtExtent {
+			layer.addFeature(mvtGeomPoint, mvtPointGeometry(int(center[0]), int(center[1])), attributes)
+		}
+	}
+	return encodeMVT(layer)
+}
+
+// tooSmallForPolygon сообщает, что кольцо укладывается в пару единиц сетки тайла
+// и после округления превратится в вырожденный многоугольник
+func tooSmallForPolygon(ring [][2]float64) bool {
+	minX, minY := math.Inf(1), math.Inf(1)
+	maxX, maxY := math.Inf(-1), math.Inf(-1)
+	for _, p := range ring {
+		minX, maxX = math.Min(minX, p[0]), math.Max(maxX, p[0])
+		minY, maxY = math.Min(minY, p[1]), math.Max(maxY, p[1])
+	}
+	return maxX-minX < 4 && maxY-minY < 4
+}
+
+// tilePixel проецирует точку в Web Mercator и переводит в координаты тайла [0, mvtExtent)
+func tilePixel(coord domain.TileCoord, lat, lon float64) [2]float64 {
+	lat = math.Max(-maxMercatorLat, math.Min(maxMercatorLat, lat))
+	n := float64(int(1) << coord.Z)
+	latRad := lat * math.Pi / 180
+
+	worldX := (lon + 180) / 360 * n
+	worldY := (1 - math.Log(math.Tan(latRad)+1/math.Cos(latRad))/math.Pi) / 2 * n
+	return [2]float64{
+		(worldX - float64(coord.X)) * mvtExtent,
+		(worldY - float64(coord.Y)) * mvtExtent,
+	}
+}
@@ -0,0 +1,109 @@
+package service
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/ruslanuskembaev/geo-alerts-system/internal/domain"
+)
+
+var (
+	ErrInvalidToken   = errors.New("invalid token")
+	ErrTokenExpired   = errors.New("token expired")
+	ErrTokensDisabled = errors.New("user tokens are not configured")
+)
+
+const tokenPartsSeparator = "."
+
+var tokenEncoding = base64.RawURLEncoding
+
+// TokenService выпускает и проверяет короткоживущие токены пользователей.
+// Формат: base64url(claims).base64url(HMAC-SHA256(claims)).
+type TokenService struct {
+	secret []byte
+	ttl    time.Duration
+}
+
+func NewTokenService(secret string, ttl time.Duration) *TokenService {
+	return &TokenService{
+		secret: []byte(secret),
+		ttl:    ttl,
+	}
+}
+
+// Enabled сообщает, настроен ли секрет для подписи токенов
+func (s *TokenService) Enabled() bool {
+	return len(s.secret) > 0
+}
+
+// Issue выпускает токен для user_id арендатора из контекста
+func (s *TokenService) Issue(ctx context.Context, userID string) (*domain.IssueTokenResponse, error) {
+	if !s.Enabled() {
+		return nil, ErrTokensDisabled
+	}
+
+	expiresAt := time.Now().Add(s.ttl).UTC().Truncate(time.Second)
+	raw, err := json.Marshal(domain.UserTokenClaims{
+		TenantID:  domain.TenantFromContext(ctx),
+		UserID:    userID,
+		ExpiresAt: expiresAt.Unix(),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	payload := tokenEncoding.EncodeToString(raw)
+	token := payload + tokenPartsSeparator + tokenEncoding.EncodeToString(s.sign(payload))
+
+	return &domain.IssueTokenResponse{
+		Token:     token,
+		ExpiresAt: expiresAt,
+	}, nil
+}
+
+func (s *TokenService) Verify(token string) (*domain.UserTokenClaims, error) {
+	if !s.Enabled() {
+		return nil, ErrTokensDisabled
+	}
+
+	payload, signature, ok := strings.Cut(token, tokenPartsSeparator)
+	if !ok {
+		return nil, ErrInvalidToken
+	}
+	gotSig, err := tokenEncoding.DecodeString(signature)
+	if err != nil {
+		return nil, ErrInvalidToken
+	}
+	if !hmac.Equal(gotSig, s.sign(payload)) {
+		return nil, ErrInvalidToken
+	}
+
+	raw, err := tokenEncoding.DecodeString(payload)
+	if err != nil {
+		return nil, ErrInvalidToken
+	}
+	var claims domain.UserTokenClaims
+	if err := json.Unmarshal(raw, &claims); err != nil || claims.UserID == "" {
+		return nil, ErrInvalidToken
+	}
+	if claims.TenantID == "" {
+		claims.TenantID = domain.DefaultTenantID
+	}
+	if time.Now().Unix() >= claims.ExpiresAt {
+		return nil, ErrTokenExpired
+	}
+
+	return &claims, nil
+}
+
+func (s *TokenService) sign(payload string) []byte {
+	mac := hmac.New(sha256.New, s.secret)
+	mac.Write([]byte(payload))
+	return mac.Sum(nil)
+}
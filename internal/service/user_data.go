@@ -0,0 +1,100 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"github.com/ruslanuskembaev/geo-alerts-system/internal/domain"
+	"github.com/ruslanuskembaev/geo-alerts-system/internal/repository"
+)
+
+// UserDataService выгрузка и удаление данных пользователя по запросам субъектов данных.
+// Проверки ищутся под исходным user_id и под псевдонимами всех ключей политики приватности.
+type UserDataService struct {
+	repo      repository.UserDataRepository
+	queue     repository.WebhookQueue
+	rateLimit *RateLimitService
+	privacy   domain.LocationPrivacy
+	auditKey  domain.UserIDKey
+}
+
+// NewUserDataService создаёт сервис; auditKey — ключ HMAC для user_id в журнале аудита удалений,
+// общий для всех реплик, чтобы запись пользователя находилась по хешу
+func NewUserDataService(
+	repo repository.UserDataRepository,
+	queue repository.WebhookQueue,
+	rateLimit *RateLimitService,
+	privacy domain.LocationPrivacy,
+	auditKey domain.UserIDKey,
+) *UserDataService {
+	return &UserDataService{
+		repo:      repo,
+		queue:     queue,
+		rateLimit: rateLimit,
+		privacy:   privacy,
+		auditKey:  auditKey,
+	}
+}
+
+// Export возвращает проверки, совпавшие зоны, журнал доставок и ожидающие вебхуки пользователя:
+// задачи outbox, очереди и отложенных повторов — всё, что удаляет Erase. Outbox читается раньше
+// очереди, поэтому задача, переданная ретранслятором между чтениями, попадает в выгрузку дважды,
+// но не пропадает.
+func (s *UserDataService) Export(ctx context.Context, userID string) (*domain.UserDataExport, error) {
+	tenantID := domain.TenantFromContext(ctx)
+
+	export, err := s.repo.Export(ctx, s.privacy.StoredUserIDs(tenantID, userID))
+	if err != nil {
+		return nil, err
+	}
+	queued, err := s.queue.UserJobs(ctx, tenantID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	export.TenantID = tenantID
+	export.UserID = userID
+	export.ExportedAt = time.Now().UTC()
+	export.QueuedWebhooks = append(export.QueuedWebhooks, queued...)
+	return export, nil
+}
+
+// Erase удаляет данные пользователя. Сначала очищается состояние в Redis (очередь и отложенные
+// повторы), чтобы воркер не отправил вебхук по уже удалённой проверке; запись аудита создаётся
+// вместе с удалением в Postgres (включая outbox вебхуков), содержит итоговые счётчики и вместо
+// user_id — его ключевой хеш. После удаления очередь
+// очищается повторно: ретранслятор мог передать в неё задачи, пока удаление ждало блокировки.
+func (s *UserDataService) Erase(ctx context.Context, userID string) (*domain.UserErasure, error) {
+	tenantID := domain.TenantFromContext(ctx)
+	erasure := &domain.UserErasure{
+		TenantID:   tenantID,
+		UserID:     userID,
+		UserIDHash: domain.PseudonymizeUserID(s.auditKey, tenantID, userID),
+		ErasedAt:   time.Now().UTC(),
+	}
+
+	queued, err := s.queue.RemoveUserJobs(ctx, tenantID, userID)
+	if err != nil {
+		return nil, err
+	}
+	erasure.QueuedWebhooks = queued
+
+	reset, err := s.rateLimit.ResetUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if reset {
+		erasure.RateLimitKeys = 1
+	}
+
+	if err := s.repo.Erase(ctx, s.privacy.StoredUserIDs(tenantID, userID), erasure); err != nil {
+		return nil, err
+	}
+
+	relayed, err := s.queue.RemoveUserJobs(ctx, tenantID, userID)
+	if err != nil {
+		return nil, err
+	}
+	erasure.QueuedWebhooks += relayed
+	return erasure, nil
+}
@@ -0,0 +1,42 @@
+package service
+
+import (
+	"context"
+
+	"github.com/ruslanuskembaev/geo-alerts-system/internal/domain"
+	"github.com/ruslanuskembaev/geo-alerts-system/internal/repository"
+)
+
+// LocationHistoryService чтение истории проверок для расследования инцидентов.
+// История пользователя ищется и под псевдонимами всех ключей политики приватности.
+type LocationHistoryService struct {
+	incidentRepo repository.IncidentRepository
+	checkRepo    repository.LocationCheckRepository
+	privacy      domain.LocationPrivacy
+}
+
+func NewLocationHistoryService(
+	incidentRepo repository.IncidentRepository,
+	checkRepo repository.LocationCheckRepository,
+	privacy domain.LocationPrivacy,
+) *LocationHistoryService {
+	return &LocationHistoryService{
+		incidentRepo: incidentRepo,
+		checkRepo:    checkRepo,
+		privacy:      privacy,
+	}
+}
+
+// UserChecks возвращает проверки пользователя арендатора из контекста, новые первыми
+func (s *LocationHistoryService) UserChecks(ctx context.Context, userID string, filter domain.UserChecksFilter, page domain.PageRequest) ([]domain.UserCheck, domain.PageInfo, error) {
+	userIDs := s.privacy.StoredUserIDs(domain.TenantFromContext(ctx), userID)
+	return s.checkRepo.ListByUser(ctx, userIDs, filter, page)
+}
+
+// IncidentUsers возвращает пользователей, попадавших в зону инцидента за интервал
+func (s *LocationHistoryService) IncidentUsers(ctx context.Context, incidentID string, query domain.IncidentUsersQuery, page domain.PageRequest) ([]domain.IncidentUser, domain.PageInfo, error) {
+	if _, err := s.incidentRepo.GetByID(ctx, incidentID); err != nil {
+		return nil, domain.PageInfo{}, err
+	}
+	return s.checkRepo.IncidentUsers(ctx, incidentID, query, page)
+}
@@ -13,15 +13,18 @@ import (
 	"github.com/ruslanuskembaev/geo-alerts-system/internal/repository"
 )
 
-// WebhookSender отправляет вебхуки
+// WebhookSender отправляет вебхуки.
+// URL выбирается по арендатору из контекста, иначе используется URL по умолчанию.
 type WebhookSender struct {
-	url    string
-	client *http.Client
+	url        string
+	tenantURLs map[string]string
+	client     *http.Client
 }
 
-func NewWebhookSender(url string, timeout time.Duration) *WebhookSender {
+func NewWebhookSender(url string, tenantURLs map[string]string, timeout time.Duration) *WebhookSender {
 	return &WebhookSender{
-		url: url,
+		url:        url,
+		tenantURLs: tenantURLs,
 		client: &http.Client{
 			Timeout: timeout,
 		},
@@ -34,7 +37,12 @@ func (s *WebhookSender) Send(ctx context.Context, payload domain.WebhookPayload)
 		return err
 	}
 
-	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.url, bytes.NewReader(body))
+	url := s.url
+	if tenantURL, ok := s.tenantURLs[domain.TenantFromContext(ctx)]; ok {
+		url = tenantURL
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
 	if err != nil {
 		return err
 	}
@@ -60,6 +68,7 @@ type WebhookWorker struct {
 	retryAttempts int
 	retryDelay    time.Duration
 	popTimeout    time.Duration
+	deliveries    repository.WebhookDeliveryRepository
 }
 
 func NewWebhookWorker(
@@ -77,6 +86,12 @@ func NewWebhookWorker(
 	}
 }
 
+// WithDeliveryLog включает запись каждой попытки доставки в журнал
+func (w *WebhookWorker) WithDeliveryLog(deliveries repository.WebhookDeliveryRepository) *WebhookWorker {
+	w.deliveries = deliveries
+	return w
+}
+
 func (w *WebhookWorker) Start(ctx context.Context) {
 	log.Println("Webhook worker started")
 	for {
@@ -97,21 +112,51 @@ func (w *WebhookWorker) Start(ctx context.Context) {
 			continue
 		}
 
-		if err := w.sender.Send(ctx, job.Payload); err != nil {
+		err = w.sender.Send(domain.WithTenant(ctx, job.TenantID), job.Payload)
+		w.recordDelivery(ctx, *job, err)
+		if err != nil {
 			attempt := job.Attempt + 1
 			if attempt <= w.retryAttempts {
 				job.Attempt = attempt
 				delay := w.retryDelay * time.Duration(attempt)
 				log.Printf("Webhook failed (attempt %d/%d). Retrying in %s: %v\n", attempt, w.retryAttempts, delay, err)
-				go func(job domain.WebhookJob, delay time.Duration) {
-					time.Sleep(delay)
-					if enqueueErr := w.queue.Enqueue(context.Background(), job); enqueueErr != nil {
-						log.Printf("Failed to requeue webhook job: %v\n", enqueueErr)
-					}
-				}(*job, delay)
+				// Повтор откладывается в очереди, а не в памяти воркера: удаление данных пользователя
+				// находит его, и он не теряется при остановке
+				if retryErr := w.queue.Retry(context.WithoutCancel(ctx), *job, time.Now().Add(delay)); retryErr != nil {
+					log.Printf("Failed to schedule webhook retry: %v\n", retryErr)
+				}
 			} else {
 				log.Printf("Webhook permanently failed after %d attempts: %v\n", w.retryAttempts, err)
 			}
 		}
 	}
 }
+
+// recordDelivery пишет попытку в журнал доставок; ошибка журнала не влияет на отправку
+func (w *WebhookWorker) recordDelivery(ctx context.Context, job domain.WebhookJob, sendErr error) {
+	if w.deliveries == nil {
+		return
+	}
+
+	delivery := domain.WebhookDelivery{
+		TenantID:  job.TenantID,
+		CheckID:   job.Payload.CheckID,
+		Attempt:   job.Attempt + 1,
+		Status:    domain.WebhookDelivered,
+		CreatedAt: time.Now().UTC(),
+	}
+	if delivery.TenantID == "" {
+		delivery.TenantID = domain.DefaultTenantID
+	}
+	if sendErr != nil {
+		delivery.Error = sendErr.Error()
+		delivery.Status = domain.WebhookFailed
+		if job.Attempt < w.retryAttempts {
+			delivery.Status = domain.WebhookRetrying
+		}
+	}
+
+	if err := w.deliveries.Record(ctx, delivery); err != nil {
+		log.Printf("Failed to record webhook delivery: %v\n", err)
+	}
+}
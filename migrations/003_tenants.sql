@@ -0,0 +1,5 @@
+ALTER TABLE incidents ADD COLUMN IF NOT EXISTS tenant_id TEXT NOT NULL DEFAULT 'default';
+ALTER TABLE location_checks ADD COLUMN IF NOT EXISTS tenant_id TEXT NOT NULL DEFAULT 'default';
+
+CREATE INDEX IF NOT EXISTS idx_incidents_tenant_active ON incidents (tenant_id, is_active);
+CREATE INDEX IF NOT EXISTS idx_location_checks_tenant_checked_at ON location_checks (tenant_id, checked_at);
@@ -0,0 +1,5 @@
+ALTER TABLE incidents ADD COLUMN IF NOT EXISTS deactivated_at TIMESTAMPTZ;
+
+UPDATE incidents SET deactivated_at = updated_at WHERE is_active = false AND deactivated_at IS NULL;
+
+CREATE INDEX IF NOT EXISTS idx_incidents_deactivated_at ON incidents (deactivated_at) WHERE is_active = false;
@@ -0,0 +1,17 @@
+-- Расстояние по формуле гаверсинусов в метрах
+CREATE OR REPLACE FUNCTION geo_distance_meters(
+    lat1 DOUBLE PRECISION, lon1 DOUBLE PRECISION,
+    lat2 DOUBLE PRECISION, lon2 DOUBLE PRECISION
+) RETURNS DOUBLE PRECISION AS $$
+    SELECT 2 * 6371000 * asin(least(1, sqrt(
+        power(sin(radians(lat2 - lat1) / 2), 2) +
+        cos(radians(lat1)) * cos(radians(lat2)) * power(sin(radians(lon2 - lon1) / 2), 2)
+    )))
+$$ LANGUAGE sql IMMUTABLE STRICT PARALLEL SAFE;
+
+CREATE INDEX IF NOT EXISTS idx_incidents_tenant_created ON incidents (tenant_id, created_at DESC, id DESC);
+CREATE INDEX IF NOT EXISTS idx_incidents_tenant_updated ON incidents (tenant_id, updated_at DESC, id DESC);
+CREATE INDEX IF NOT EXISTS idx_incidents_tenant_severity ON incidents (tenant_id, severity);
+CREATE INDEX IF NOT EXISTS idx_incidents_tenant_location ON incidents (tenant_id, latitude, longitude);
+CREATE INDEX IF NOT EXISTS idx_incidents_search ON incidents
+    USING GIN (to_tsvector('simple', title || ' ' || description));
@@ -0,0 +1,16 @@
+ALTER TABLE incidents ADD COLUMN IF NOT EXISTS expires_at TIMESTAMPTZ;
+
+CREATE INDEX IF NOT EXISTS idx_incidents_expires_at ON incidents (expires_at) WHERE is_active = true AND expires_at IS NOT NULL;
+
+-- Принятые CAP-сообщения: по (sender, identifier) находятся инциденты для Update/Cancel,
+-- повторно полученное сообщение не применяется
+CREATE TABLE IF NOT EXISTS cap_alerts (
+    tenant_id TEXT NOT NULL,
+    sender TEXT NOT NULL,
+    identifier TEXT NOT NULL,
+    sent TIMESTAMPTZ NOT NULL,
+    msg_type TEXT NOT NULL,
+    incident_ids UUID[] NOT NULL DEFAULT '{}',
+    received_at TIMESTAMPTZ NOT NULL,
+    PRIMARY KEY (tenant_id, sender, identifier)
+);
@@ -0,0 +1,64 @@
+-- Агрегаты проверок по инцидентам в корзинах minute/hour/day, обновляются при каждой проверке
+CREATE TABLE IF NOT EXISTS incident_stats_rollup (
+    incident_id UUID NOT NULL REFERENCES incidents(id) ON DELETE CASCADE,
+    tenant_id TEXT NOT NULL,
+    bucket TEXT NOT NULL,
+    bucket_start TIMESTAMPTZ NOT NULL,
+    checks BIGINT NOT NULL DEFAULT 0,
+    users BIGINT NOT NULL DEFAULT 0,
+    new_users BIGINT NOT NULL DEFAULT 0,
+    PRIMARY KEY (incident_id, bucket, bucket_start)
+);
+
+CREATE INDEX IF NOT EXISTS idx_incident_stats_rollup_tenant ON incident_stats_rollup (tenant_id, bucket, bucket_start);
+
+-- Первое попадание пользователя в зону инцидента (для new_users)
+CREATE TABLE IF NOT EXISTS incident_stats_users (
+    incident_id UUID NOT NULL REFERENCES incidents(id) ON DELETE CASCADE,
+    user_id TEXT NOT NULL,
+    first_seen_at TIMESTAMPTZ NOT NULL,
+    PRIMARY KEY (incident_id, user_id)
+);
+
+-- Пользователи, уже учтённые в users открытой корзины. Закрытые корзины больше не меняются,
+-- поэтому строки старше суток удаляются фоновой задачей.
+CREATE TABLE IF NOT EXISTS incident_stats_bucket_users (
+    incident_id UUID NOT NULL REFERENCES incidents(id) ON DELETE CASCADE,
+    bucket TEXT NOT NULL,
+    bucket_start TIMESTAMPTZ NOT NULL,
+    user_id TEXT NOT NULL,
+    PRIMARY KEY (incident_id, bucket, bucket_start, user_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_incident_stats_bucket_users_start ON incident_stats_bucket_users (bucket_start);
+
+-- Заполнение агрегатов по уже накопленным проверкам
+INSERT INTO incident_stats_users (incident_id, user_id, first_seen_at)
+SELECT lci.incident_id, lc.user_id, MIN(lc.checked_at)
+FROM location_check_incidents lci
+JOIN location_checks lc ON lc.id = lci.check_id
+GROUP BY lci.incident_id, lc.user_id
+ON CONFLICT DO NOTHING;
+
+INSERT INTO incident_stats_rollup (incident_id, tenant_id, bucket, bucket_start, checks, users, new_users)
+SELECT lci.incident_id,
+       lc.tenant_id,
+       b.bucket,
+       date_trunc(b.bucket, lc.checked_at, 'UTC') AS bucket_start,
+       COUNT(*),
+       COUNT(DISTINCT lc.user_id),
+       COUNT(DISTINCT lc.user_id) FILTER (WHERE date_trunc(b.bucket, su.first_seen_at, 'UTC') = date_trunc(b.bucket, lc.checked_at, 'UTC'))
+FROM location_check_incidents lci
+JOIN location_checks lc ON lc.id = lci.check_id
+JOIN incident_stats_users su ON su.incident_id = lci.incident_id AND su.user_id = lc.user_id
+CROSS JOIN (VALUES ('minute'), ('hour'), ('day')) AS b (bucket)
+GROUP BY lci.incident_id, lc.tenant_id, b.bucket, bucket_start
+ON CONFLICT DO NOTHING;
+
+INSERT INTO incident_stats_bucket_users (incident_id, bucket, bucket_start, user_id)
+SELECT DISTINCT lci.incident_id, b.bucket, date_trunc(b.bucket, lc.checked_at, 'UTC'), lc.user_id
+FROM location_check_incidents lci
+JOIN location_checks lc ON lc.id = lci.check_id
+CROSS JOIN (VALUES ('minute'), ('hour'), ('day')) AS b (bucket)
+WHERE lc.checked_at >= NOW() - INTERVAL '2 days'
+ON CONFLICT DO NOTHING;
@@ -0,0 +1,66 @@
+-- Помесячное секционирование location_checks и location_check_incidents по checked_at.
+-- Связь проверки и инцидентов хранит checked_at, чтобы секции обеих таблиц совпадали и удалялись
+-- вместе; внешний ключ на location_checks заменяется записью в одной транзакции.
+-- Секции на будущие месяцы создаёт фоновая задача, DEFAULT-секции принимают проверки вне созданных секций.
+DO $$
+DECLARE
+    month_start TIMESTAMP;
+    last_month TIMESTAMP;
+    suffix TEXT;
+BEGIN
+    IF EXISTS (SELECT 1 FROM pg_class WHERE relname = 'location_checks' AND relkind = 'p') THEN
+        RETURN;
+    END IF;
+
+    ALTER TABLE location_check_incidents RENAME TO location_check_incidents_legacy;
+    ALTER TABLE location_checks RENAME TO location_checks_legacy;
+
+    CREATE TABLE location_checks (
+        id UUID NOT NULL,
+        tenant_id TEXT NOT NULL DEFAULT 'default',
+        user_id TEXT NOT NULL,
+        latitude DOUBLE PRECISION NOT NULL,
+        longitude DOUBLE PRECISION NOT NULL,
+        is_in_danger_zone BOOLEAN NOT NULL,
+        checked_at TIMESTAMPTZ NOT NULL,
+        PRIMARY KEY (id, checked_at)
+    ) PARTITION BY RANGE (checked_at);
+
+    CREATE TABLE location_check_incidents (
+        check_id UUID NOT NULL,
+        incident_id UUID NOT NULL REFERENCES incidents(id) ON DELETE CASCADE,
+        checked_at TIMESTAMPTZ NOT NULL,
+        PRIMARY KEY (check_id, incident_id, checked_at)
+    ) PARTITION BY RANGE (checked_at);
+
+    CREATE TABLE location_checks_default PARTITION OF location_checks DEFAULT;
+    CREATE TABLE location_check_incidents_default PARTITION OF location_check_incidents DEFAULT;
+
+    month_start := date_trunc('month', COALESCE((SELECT MIN(checked_at) FROM location_checks_legacy), NOW()) AT TIME ZONE 'UTC');
+    last_month := date_trunc('month', NOW() AT TIME ZONE 'UTC') + INTERVAL '3 months';
+    WHILE month_start <= last_month LOOP
+        suffix := to_char(month_start, 'YYYYMM');
+        EXECUTE format('CREATE TABLE %I PARTITION OF location_checks FOR VALUES FROM (%L) TO (%L)',
+            'location_checks_p' || suffix, month_start AT TIME ZONE 'UTC', (month_start + INTERVAL '1 month') AT TIME ZONE 'UTC');
+        EXECUTE format('CREATE TABLE %I PARTITION OF location_check_incidents FOR VALUES FROM (%L) TO (%L)',
+            'location_check_incidents_p' || suffix, month_start AT TIME ZONE 'UTC', (month_start + INTERVAL '1 month') AT TIME ZONE 'UTC');
+        month_start := month_start + INTERVAL '1 month';
+    END LOOP;
+
+    INSERT INTO location_checks (id, tenant_id, user_id, latitude, longitude, is_in_danger_zone, checked_at)
+    SELECT id, tenant_id, user_id, latitude, longitude, is_in_danger_zone, checked_at
+    FROM location_checks_legacy;
+
+    INSERT INTO location_check_incidents (check_id, incident_id, checked_at)
+    SELECT lci.check_id, lci.incident_id, lc.checked_at
+    FROM location_check_incidents_legacy lci
+    JOIN location_checks_legacy lc ON lc.id = lci.check_id;
+
+    DROP TABLE location_check_incidents_legacy;
+    DROP TABLE location_checks_legacy;
+END $$;
+
+CREATE INDEX IF NOT EXISTS idx_location_checks_checked_at ON location_checks (checked_at);
+CREATE INDEX IF NOT EXISTS idx_location_checks_user_id ON location_checks (user_id);
+CREATE INDEX IF NOT EXISTS idx_location_checks_tenant_checked_at ON location_checks (tenant_id, checked_at);
+CREATE INDEX IF NOT EXISTS idx_location_check_incidents_incident ON location_check_incidents (incident_id);
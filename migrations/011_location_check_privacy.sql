@@ -0,0 +1,3 @@
+-- Координаты проверки необязательны: в режиме приватности none сохраняются только совпавшие инциденты
+ALTER TABLE location_checks ALTER COLUMN latitude DROP NOT NULL;
+ALTER TABLE location_checks ALTER COLUMN longitude DROP NOT NULL;
@@ -0,0 +1,15 @@
+-- Журнал попыток доставки вебхуков; пользователь определяется через проверку (check_id)
+CREATE TABLE IF NOT EXISTS webhook_deliveries (
+    id BIGSERIAL PRIMARY KEY,
+    tenant_id TEXT NOT NULL,
+    check_id UUID NOT NULL,
+    attempt INTEGER NOT NULL,
+    status TEXT NOT NULL,
+    error TEXT,
+    created_at TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_check ON webhook_deliveries (tenant_id, check_id);
+
+-- Поиск проверок пользователя при выгрузке и удалении данных
+CREATE INDEX IF NOT EXISTS idx_location_checks_tenant_user ON location_checks (tenant_id, user_id, checked_at DESC);
@@ -0,0 +1,3 @@
+-- Попадания в зону за интервал для списка пользователей инцидента
+CREATE INDEX IF NOT EXISTS idx_location_check_incidents_incident_checked_at
+    ON location_check_incidents (incident_id, checked_at DESC);
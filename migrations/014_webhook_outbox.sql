@@ -0,0 +1,15 @@
+-- Outbox вебхуков: задача пишется в одной транзакции с проверкой, ретранслятор переносит её в очередь
+CREATE TABLE IF NOT EXISTS webhook_outbox (
+    id BIGSERIAL PRIMARY KEY,
+    tenant_id TEXT NOT NULL,
+    check_id UUID NOT NULL,
+    job JSONB NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL,
+    dispatched_at TIMESTAMPTZ
+);
+
+-- Очередь ещё не переданных задач
+CREATE INDEX IF NOT EXISTS idx_webhook_outbox_pending ON webhook_outbox (id) WHERE dispatched_at IS NULL;
+-- Очистка переданных задач и удаление данных пользователя
+CREATE INDEX IF NOT EXISTS idx_webhook_outbox_dispatched ON webhook_outbox (dispatched_at) WHERE dispatched_at IS NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_webhook_outbox_check ON webhook_outbox (tenant_id, check_id);
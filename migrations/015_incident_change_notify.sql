@@ -0,0 +1,20 @@
+-- Уведомление о любом изменении incidents (в том числе в обход API) для сброса кешей;
+-- payload — арендатор. Одинаковые уведомления одной транзакции Postgres объединяет.
+CREATE OR REPLACE FUNCTION incidents_notify_change() RETURNS trigger AS $$
+BEGIN
+    IF TG_OP = 'DELETE' THEN
+        PERFORM pg_notify('incident_changes', OLD.tenant_id);
+    ELSE
+        PERFORM pg_notify('incident_changes', NEW.tenant_id);
+        IF TG_OP = 'UPDATE' AND OLD.tenant_id IS DISTINCT FROM NEW.tenant_id THEN
+            PERFORM pg_notify('incident_changes', OLD.tenant_id);
+        END IF;
+    END IF;
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS incidents_notify_change ON incidents;
+CREATE TRIGGER incidents_notify_change
+    AFTER INSERT OR UPDATE OR DELETE ON incidents
+    FOR EACH ROW EXECUTE FUNCTION incidents_notify_change();
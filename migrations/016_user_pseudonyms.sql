@@ -0,0 +1,21 @@
+-- Стабильные псевдонимы пользователей: user_id проверок не меняется при смене ключа псевдонимизации.
+-- lookup — HMAC пользователя под одним из ключей ("<key id>:<hmac>"), pseudonym — сохраняемый user_id.
+CREATE TABLE IF NOT EXISTS user_pseudonyms (
+    tenant_id TEXT NOT NULL,
+    lookup TEXT NOT NULL,
+    pseudonym TEXT NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL,
+    PRIMARY KEY (tenant_id, lookup)
+);
+
+-- Выгрузка и удаление данных пользователя
+CREATE INDEX IF NOT EXISTS idx_user_pseudonyms_pseudonym ON user_pseudonyms (tenant_id, pseudonym);
+
+-- Псевдонимы, сохранённые до появления таблицы, остаются стабильными: HMAC под ключом,
+-- которым они были выданы, совпадает с самим псевдонимом
+INSERT INTO user_pseudonyms (tenant_id, lookup, pseudonym, created_at)
+SELECT tenant_id, user_id, user_id, MIN(checked_at)
+FROM location_checks
+WHERE user_id ~ '^[^:]+:[0-9a-f]{32}$'
+GROUP BY tenant_id, user_id
+ON CONFLICT DO NOTHING;
@@ -0,0 +1,20 @@
+-- Запросы ретроактивных оповещений: создаются при изменении зоны и обрабатываются фоновой задачей.
+-- locked_until — аренда запроса репликой, чтобы одну зону не обрабатывали одновременно.
+CREATE TABLE IF NOT EXISTS retroactive_alert_requests (
+    id BIGSERIAL PRIMARY KEY,
+    tenant_id TEXT NOT NULL,
+    incident_id UUID NOT NULL REFERENCES incidents(id) ON DELETE CASCADE,
+    since TIMESTAMPTZ NOT NULL,
+    requested_at TIMESTAMPTZ NOT NULL,
+    locked_until TIMESTAMPTZ
+);
+
+-- Отправленные ретроактивные оповещения: по проверке об инциденте оповещают один раз
+CREATE TABLE IF NOT EXISTS retroactive_alerts (
+    incident_id UUID NOT NULL REFERENCES incidents(id) ON DELETE CASCADE,
+    check_id UUID NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL,
+    PRIMARY KEY (incident_id, check_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_retroactive_alerts_created ON retroactive_alerts (created_at);
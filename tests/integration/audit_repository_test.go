@@ -0,0 +1,83 @@
+//go:build integration
+
+package integration
+
+import (
+	"context"
+	"testing"
+
+	"github.com/ruslanuskembaev/geo-alerts-system/internal/domain"
+	"github.com/ruslanuskembaev/geo-alerts-system/internal/repository"
+)
+
+func TestAuditRepository_IncidentRevisions(t *testing.T) {
+	pool := testDB(t)
+	defer pool.Close()
+	truncateTables(t, pool)
+
+	repo := repository.NewIncidentRepository(pool)
+	auditRepo := repository.NewAuditRepository(pool)
+
+	ctx := domain.WithActor(context.Background(), "operator-1")
+
+	created, err := repo.Create(ctx, domain.CreateIncidentRequest{
+		Title:        "Audit Incident",
+		Severity:     domain.SeverityLow,
+		Latitude:     10,
+		Longitude:    10,
+		RadiusMeters: 500,
+	})
+	if err != nil {
+		t.Fatalf("create failed: %v", err)
+	}
+
+	radius := 900
+	if _, err := repo.Update(ctx, created.ID, domain.UpdateIncidentRequest{RadiusMeters: &radius}, nil); err != nil {
+		t.Fatalf("update failed: %v", err)
+	}
+	if err := repo.Deactivate(domain.WithActor(context.Background(), "operator-2"), created.ID, nil); err != nil {
+		t.Fatalf("deactivate failed: %v", err)
+	}
+
+	history, info, err := auditRepo.List(context.Background(), domain.AuditFilter{
+		EntityType: domain.AuditEntityIncident,
+		EntityID:   created.ID,
+	}, domain.PageRequest{Limit: 10, IncludeTotal: true})
+	if err != nil {
+		t.Fatalf("list history failed: %v", err)
+	}
+	if info.Total == nil || *info.Total != 3 || len(history) != 3 {
+		t.Fatalf("expected 3 revisions, got total=%v len=%d", info.Total, len(history))
+	}
+
+	// Новые записи первыми
+	if history[0].Action != domain.AuditActionDeactivate || history[0].Actor != "operator-2" {
+		t.Fatalf("unexpected latest revision: %+v", history[0])
+	}
+	update := history[1]
+	if update.Action != domain.AuditActionUpdate || update.Actor != "operator-1" {
+		t.Fatalf("unexpected update revision: %+v", update)
+	}
+	change, ok := update.Diff["radius_meters"]
+	if !ok || change.Old != float64(500) || change.New != float64(900) {
+		t.Fatalf("expected radius diff 500 -> 900, got %+v", update.Diff)
+	}
+	if len(update.Before) == 0 || len(update.After) == 0 {
+		t.Fatalf("expected before/after snapshots on update")
+	}
+	if history[2].Action != domain.AuditActionCreate || len(history[2].Before) != 0 {
+		t.Fatalf("expected create revision without before snapshot")
+	}
+
+	filtered, _, err := auditRepo.List(context.Background(), domain.AuditFilter{Actor: "operator-2"}, domain.PageRequest{Limit: 10})
+	if err != nil {
+		t.Fatalf("filtered list failed: %v", err)
+	}
+	if len(filtered) != 1 {
+		t.Fatalf("expected one entry for operator-2, got %d", len(filtered))
+	}
+
+	if _, err := pool.Exec(context.Background(), `UPDATE audit_log SET actor = 'tampered'`); err == nil {
+		t.Fatalf("expected audit log to reject updates")
+	}
+}
@@ -0,0 +1,196 @@
+//go:build integration
+
+package integration
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/ruslanuskembaev/geo-alerts-system/internal/domain"
+	"github.com/ruslanuskembaev/geo-alerts-system/internal/repository"
+)
+
+func TestCAPRepository_AlertUpdateCancel(t *testing.T) {
+	pool := testDB(t)
+	defer pool.Close()
+	truncateTables(t, pool)
+
+	capRepo := repository.NewCAPRepository(pool)
+	incidentRepo := repository.NewIncidentRepository(pool)
+	ctx := domain.WithActor(context.Background(), "cap_feed")
+	expires := time.Now().Add(time.Hour).UTC().Truncate(time.Second)
+
+	zone := func(title string, lat float64) domain.CreateIncidentRequest {
+		return domain.CreateIncidentRequest{
+			Title: title, Severity: domain.SeverityHigh, Latitude: lat, Longitude: 37.61, RadiusMeters: 1000, ExpiresAt: &expires,
+		}
+	}
+	sent := time.Date(2026, 10, 19, 7, 0, 0, 0, time.UTC)
+
+	alert := domain.CAPAlert{
+		Identifier: "alert-1", Sender: "mchs", Sent: sent, MsgType: domain.CAPMsgTypeAlert,
+		Incidents: []domain.CreateIncidentRequest{zone("Zone A", 55.75), zone("Zone B", 55.76)},
+	}
+	result, err := capRepo.Apply(ctx, alert)
+	if err != nil {
+		t.Fatalf("apply alert failed: %v", err)
+	}
+	if result.Status != domain.CAPIngestApplied || len(result.Created) != 2 {
+		t.Fatalf("unexpected alert result: %+v", result)
+	}
+
+	duplicate, err := capRepo.Apply(ctx, alert)
+	if err != nil {
+		t.Fatalf("apply duplicate failed: %v", err)
+	}
+	if duplicate.Status != domain.CAPIngestDuplicate {
+		t.Fatalf("expected duplicate, got %+v", duplicate)
+	}
+
+	// Update с одной зоной обновляет первую и деактивирует вторую
+	update, err := capRepo.Apply(ctx, domain.CAPAlert{
+		Identifier: "alert-2", Sender: "mchs", Sent: sent.Add(time.Hour), MsgType: domain.CAPMsgTypeUpdate,
+		References: []domain.CAPReference{{Sender: "mchs", Identifier: "alert-1"}},
+		Incidents:  []domain.CreateIncidentRequest{zone("Zone A moved", 55.80)},
+	})
+	if err != nil {
+		t.Fatalf("apply update failed: %v", err)
+	}
+	if len(update.Updated) != 1 || update.Updated[0] != result.Created[0] || len(update.Deactivated) != 1 || update.Deactivated[0] != result.Created[1] {
+		t.Fatalf("unexpected update result: %+v", update)
+	}
+	moved, err := incidentRepo.GetByID(ctx, result.Created[0])
+	if err != nil {
+		t.Fatalf("get failed: %v", err)
+	}
+	if moved.Title != "Zone A moved" || moved.Latitude != 55.80 || moved.Version != 2 || moved.ExpiresAt == nil {
+		t.Fatalf("unexpected updated incident: %+v", moved)
+	}
+
+	// Update без expires снимает срок действия
+	unlimited := zone("Zone A moved", 55.80)
+	unlimited.ExpiresAt = nil
+	if _, err := capRepo.Apply(ctx, domain.CAPAlert{
+		Identifier: "alert-2b", Sender: "mchs", Sent: sent.Add(90 * time.Minute), MsgType: domain.CAPMsgTypeUpdate,
+		References: []domain.CAPReference{{Sender: "mchs", Identifier: "alert-2"}},
+		Incidents:  []domain.CreateIncidentRequest{unlimited},
+	}); err != nil {
+		t.Fatalf("apply update without expires failed: %v", err)
+	}
+	if moved, err = incidentRepo.GetByID(ctx, result.Created[0]); err != nil || moved.ExpiresAt != nil {
+		t.Fatalf("expected update without expires to clear expires_at, got %+v (%v)", moved, err)
+	}
+
+	cancel, err := capRepo.Apply(ctx, domain.CAPAlert{
+		Identifier: "alert-3", Sender: "mchs", Sent: sent.Add(2 * time.Hour), MsgType: domain.CAPMsgTypeCancel,
+		References: []domain.CAPReference{{Sender: "mchs", Identifier: "alert-2b"}},
+	})
+	if err != nil {
+		t.Fatalf("apply cancel failed: %v", err)
+	}
+	if len(cancel.Deactivated) != 1 || cancel.Deactivated[0] != result.Created[0] {
+		t.Fatalf("unexpected cancel result: %+v", cancel)
+	}
+
+	active, err := incidentRepo.ListActive(ctx)
+	if err != nil {
+		t.Fatalf("list active failed: %v", err)
+	}
+	if len(active) != 0 {
+		t.Fatalf("expected no active incidents after cancel, got %d", len(active))
+	}
+}
+
+func TestCAPRepository_PurgeRemovesAlertReferences(t *testing.T) {
+	pool := testDB(t)
+	defer pool.Close()
+	truncateTables(t, pool)
+
+	capRepo := repository.NewCAPRepository(pool)
+	incidentRepo := repository.NewIncidentRepository(pool)
+	ctx := domain.WithActor(context.Background(), "cap_feed")
+	sent := time.Date(2026, 10, 19, 7, 0, 0, 0, time.UTC)
+
+	zone := func(title string, lat float64) domain.CreateIncidentRequest {
+		return domain.CreateIncidentRequest{Title: title, Severity: domain.SeverityHigh, Latitude: lat, Longitude: 37.61, RadiusMeters: 1000}
+	}
+	result, err := capRepo.Apply(ctx, domain.CAPAlert{
+		Identifier: "alert-1", Sender: "mchs", Sent: sent, MsgType: domain.CAPMsgTypeAlert,
+		Incidents: []domain.CreateIncidentRequest{zone("Zone A", 55.75), zone("Zone B", 55.76)},
+	})
+	if err != nil || len(result.Created) != 2 {
+		t.Fatalf("apply alert failed: %+v (%v)", result, err)
+	}
+
+	if err := incidentRepo.Purge(ctx, result.Created[0]); err != nil {
+		t.Fatalf("purge failed: %v", err)
+	}
+
+	var refs []string
+	if err := pool.QueryRow(ctx, `
+		SELECT ARRAY(SELECT unnest(incident_ids)::text) FROM cap_alerts WHERE identifier = 'alert-1'
+	`).Scan(&refs); err != nil {
+		t.Fatalf("query cap alert failed: %v", err)
+	}
+	if len(refs) != 1 || refs[0] != result.Created[1] {
+		t.Fatalf("expected only the remaining incident referenced, got %v", refs)
+	}
+
+	// Cancel затрагивает только оставшуюся зону
+	cancel, err := capRepo.Apply(ctx, domain.CAPAlert{
+		Identifier: "alert-2", Sender: "mchs", Sent: sent.Add(time.Hour), MsgType: domain.CAPMsgTypeCancel,
+		References: []domain.CAPReference{{Sender: "mchs", Identifier: "alert-1"}},
+	})
+	if err != nil || len(cancel.Deactivated) != 1 || cancel.Deactivated[0] != result.Created[1] {
+		t.Fatalf("unexpected cancel result: %+v (%v)", cancel, err)
+	}
+}
+
+func TestIncidentRepository_DeactivateExpired(t *testing.T) {
+	pool := testDB(t)
+	defer pool.Close()
+	truncateTables(t, pool)
+
+	repo := repository.NewIncidentRepository(pool)
+	auditRepo := repository.NewAuditRepository(pool)
+	ctx := context.Background()
+
+	past := time.Now().Add(-time.Minute).UTC()
+	future := time.Now().Add(time.Hour).UTC()
+	var ids []string
+	for _, expiresAt := range []*time.Time{&past, &future, nil} {
+		incident, err := repo.Create(ctx, domain.CreateIncidentRequest{
+			Title: "Zone", Severity: domain.SeverityLow, Latitude: 55.75, Longitude: 37.61, RadiusMeters: 100, ExpiresAt: expiresAt,
+		})
+		if err != nil {
+			t.Fatalf("create failed: %v", err)
+		}
+		ids = append(ids, incident.ID)
+	}
+
+	// Истёкший инцидент не попадает в проверки ещё до деактивации
+	active, err := repo.ListActive(ctx)
+	if err != nil {
+		t.Fatalf("list active failed: %v", err)
+	}
+	if len(active) != 2 {
+		t.Fatalf("expected 2 unexpired active incidents, got %d", len(active))
+	}
+
+	expired, err := repo.DeactivateExpired(ctx, time.Now().UTC())
+	if err != nil {
+		t.Fatalf("deactivate expired failed: %v", err)
+	}
+	if len(expired) != 1 || expired[0].ID != ids[0] || expired[0].IsActive || expired[0].DeactivatedAt == nil {
+		t.Fatalf("unexpected expired incidents: %+v", expired)
+	}
+
+	entries, _, err := auditRepo.List(ctx, domain.AuditFilter{EntityID: ids[0], Action: domain.AuditActionDeactivate}, domain.PageRequest{Limit: 10})
+	if err != nil {
+		t.Fatalf("audit list failed: %v", err)
+	}
+	if len(entries) != 1 || entries[0].Actor != domain.SystemActor {
+		t.Fatalf("expected system deactivate revision, got %+v", entries)
+	}
+}
@@ -0,0 +1,171 @@
+//go:build integration
+
+package integration
+
+import (
+	"context"
+	"testing"
+
+	"github.com/ruslanuskembaev/geo-alerts-system/internal/domain"
+	"github.com/ruslanuskembaev/geo-alerts-system/internal/repository"
+)
+
+func TestIncidentRepository_ListFilters(t *testing.T) {
+	pool := testDB(t)
+	defer pool.Close()
+	truncateTables(t, pool)
+
+	repo := repository.NewIncidentRepository(pool)
+	ctx := context.Background()
+
+	create := func(title, description string, severity domain.Severity, lat, lon float64, radius int) *domain.Incident {
+		incident, err := repo.Create(ctx, domain.CreateIncidentRequest{
+			Title:        title,
+			Description:  description,
+			Severity:     severity,
+			Latitude:     lat,
+			Longitude:    lon,
+			RadiusMeters: radius,
+		})
+		if err != nil {
+			t.Fatalf("create failed: %v", err)
+		}
+		return incident
+	}
+
+	kremlin := create("Пожар у Кремля", "Сильное задымление", domain.SeverityHigh, 55.7520, 37.6175, 500)
+	arbat := create("Flood on Arbat", "Water main burst", domain.SeverityMedium, 55.7494, 37.5916, 800)
+	spb := create("Storm in Saint Petersburg", "Strong wind", domain.SeverityLow, 59.9343, 30.3351, 3000)
+	if err := repo.Deactivate(ctx, spb.ID, nil); err != nil {
+		t.Fatalf("deactivate failed: %v", err)
+	}
+
+	list := func(filter domain.IncidentFilter) []*domain.Incident {
+		incidents, info, err := repo.List(ctx, filter, domain.PageRequest{Limit: 10, IncludeTotal: true})
+		if err != nil {
+			t.Fatalf("list failed: %v", err)
+		}
+		if info.Total == nil || *info.Total != len(incidents) {
+			t.Fatalf("expected total %v to match page size %d", info.Total, len(incidents))
+		}
+		return incidents
+	}
+
+	active := true
+	if got := list(domain.IncidentFilter{IsActive: &active}); len(got) != 2 {
+		t.Fatalf("expected 2 active incidents, got %d", len(got))
+	}
+
+	if got := list(domain.IncidentFilter{Severities: []domain.Severity{domain.SeverityHigh, domain.SeverityLow}}); len(got) != 2 {
+		t.Fatalf("expected 2 high/low incidents, got %d", len(got))
+	}
+
+	if got := list(domain.IncidentFilter{Query: "задымление"}); len(got) != 1 || got[0].ID != kremlin.ID {
+		t.Fatalf("expected full-text match on description, got %v", got)
+	}
+	if got := list(domain.IncidentFilter{Query: "flood"}); len(got) != 1 || got[0].ID != arbat.ID {
+		t.Fatalf("expected full-text match on title, got %v", got)
+	}
+
+	moscow := &domain.BoundingBox{MinLatitude: 55.5, MinLongitude: 37.3, MaxLatitude: 56.0, MaxLongitude: 37.9}
+	if got := list(domain.IncidentFilter{BBox: moscow}); len(got) != 2 {
+		t.Fatalf("expected 2 incidents inside Moscow bbox, got %d", len(got))
+	}
+
+	near := &domain.GeoCircle{Latitude: 55.7520, Longitude: 37.6175, RadiusMeters: 1000}
+	if got := list(domain.IncidentFilter{Near: near}); len(got) != 1 || got[0].ID != kremlin.ID {
+		t.Fatalf("expected only Kremlin incident within 1km, got %d", len(got))
+	}
+
+	near.RadiusMeters = 5000
+	got := list(domain.IncidentFilter{Near: near, SortBy: domain.IncidentSortDistance})
+	if len(got) != 2 || got[0].ID != kremlin.ID || got[1].ID != arbat.ID {
+		t.Fatalf("expected incidents sorted by distance")
+	}
+
+	got = list(domain.IncidentFilter{SortBy: domain.IncidentSortSeverity, SortDesc: true})
+	if got[0].ID != kremlin.ID || got[2].ID != spb.ID {
+		t.Fatalf("expected incidents sorted by severity desc")
+	}
+
+	got = list(domain.IncidentFilter{SortBy: domain.IncidentSortRadiusMeters})
+	if got[0].ID != kremlin.ID || got[2].ID != spb.ID {
+		t.Fatalf("expected incidents sorted by radius asc")
+	}
+}
+
+func TestIncidentRepository_ListNearAcrossAntimeridian(t *testing.T) {
+	pool := testDB(t)
+	defer pool.Close()
+	truncateTables(t, pool)
+
+	repo := repository.NewIncidentRepository(pool)
+	ctx := context.Background()
+
+	create := func(title string, lon float64) *domain.Incident {
+		incident, err := repo.Create(ctx, domain.CreateIncidentRequest{
+			Title:        title,
+			Severity:     domain.SeverityMedium,
+			Latitude:     -16.5,
+			Longitude:    lon,
+			RadiusMeters: 500,
+		})
+		if err != nil {
+			t.Fatalf("create failed: %v", err)
+		}
+		return incident
+	}
+
+	east := create("East of antimeridian", 179.95)
+	west := create("West of antimeridian", -179.95)
+	create("Far away", 178.0)
+
+	// Обе зоны в ~5 км от центра по разные стороны ±180
+	for _, lon := range []float64{179.999, -179.999} {
+		near := &domain.GeoCircle{Latitude: -16.5, Longitude: lon, RadiusMeters: 20000}
+		incidents, _, err := repo.List(ctx, domain.IncidentFilter{Near: near, SortBy: domain.IncidentSortDistance}, domain.PageRequest{Limit: 10})
+		if err != nil {
+			t.Fatalf("list failed: %v", err)
+		}
+		ids := map[string]bool{}
+		for _, incident := range incidents {
+			ids[incident.ID] = true
+		}
+		if len(incidents) != 2 || !ids[east.ID] || !ids[west.ID] {
+			t.Fatalf("expected zones on both sides of the antimeridian near lon %v, got %d", lon, len(incidents))
+		}
+	}
+}
+
+func TestIncidentRepository_StreamAppliesFilter(t *testing.T) {
+	pool := testDB(t)
+	defer pool.Close()
+	truncateTables(t, pool)
+
+	repo := repository.NewIncidentRepository(pool)
+	ctx := context.Background()
+
+	for _, severity := range []domain.Severity{domain.SeverityLow, domain.SeverityHigh, domain.SeverityHigh} {
+		if _, err := repo.Create(ctx, domain.CreateIncidentRequest{
+			Title:        "Zone " + string(severity),
+			Severity:     severity,
+			Latitude:     55.75,
+			Longitude:    37.61,
+			RadiusMeters: 100,
+		}); err != nil {
+			t.Fatalf("create failed: %v", err)
+		}
+	}
+
+	var streamed []*domain.Incident
+	err := repo.Stream(ctx, domain.IncidentFilter{Severities: []domain.Severity{domain.SeverityHigh}}, func(incident *domain.Incident) error {
+		streamed = append(streamed, incident)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("stream failed: %v", err)
+	}
+	if len(streamed) != 2 || streamed[0].Severity != domain.SeverityHigh {
+		t.Fatalf("expected 2 high incidents, got %+v", streamed)
+	}
+}
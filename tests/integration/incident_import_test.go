@@ -0,0 +1,58 @@
+//go:build integration
+
+package integration
+
+import (
+	"context"
+	"testing"
+
+	"github.com/ruslanuskembaev/geo-alerts-system/internal/domain"
+	"github.com/ruslanuskembaev/geo-alerts-system/internal/repository"
+)
+
+func TestIncidentRepository_CreateBatch(t *testing.T) {
+	pool := testDB(t)
+	defer pool.Close()
+	truncateTables(t, pool)
+
+	repo := repository.NewIncidentRepository(pool)
+	auditRepo := repository.NewAuditRepository(pool)
+	ctx := domain.WithActor(context.Background(), "cli")
+
+	reqs := []domain.CreateIncidentRequest{
+		{Title: "Zone A", Severity: domain.SeverityLow, Latitude: 55.75, Longitude: 37.61, RadiusMeters: 100},
+		{Title: "Zone B", Severity: domain.SeverityHigh, Latitude: 55.76, Longitude: 37.62, RadiusMeters: 200},
+	}
+	created, err := repo.CreateBatch(ctx, reqs)
+	if err != nil {
+		t.Fatalf("create batch failed: %v", err)
+	}
+	if len(created) != 2 || created[1].Title != "Zone B" || created[1].Version != 1 {
+		t.Fatalf("unexpected created incidents: %+v", created)
+	}
+
+	entries, _, err := auditRepo.List(ctx, domain.AuditFilter{Action: domain.AuditActionCreate, Actor: "cli"}, domain.PageRequest{Limit: 10})
+	if err != nil {
+		t.Fatalf("audit list failed: %v", err)
+	}
+	if len(entries) != 2 {
+		t.Fatalf("expected create revision per imported incident, got %d", len(entries))
+	}
+
+	// Ошибка в любой строке откатывает всю пачку
+	broken := []domain.CreateIncidentRequest{
+		{Title: "Zone C", Severity: domain.SeverityLow, Latitude: 55.75, Longitude: 37.61, RadiusMeters: 100},
+		{Title: "bad\x00title", Severity: domain.SeverityLow, Latitude: 55.75, Longitude: 37.61, RadiusMeters: 100},
+	}
+	if _, err := repo.CreateBatch(ctx, broken); err == nil {
+		t.Fatalf("expected batch with invalid row to fail")
+	}
+
+	_, info, err := repo.List(ctx, domain.IncidentFilter{}, domain.PageRequest{Limit: 10, IncludeTotal: true})
+	if err != nil {
+		t.Fatalf("list failed: %v", err)
+	}
+	if *info.Total != 2 {
+		t.Fatalf("expected failed batch to be rolled back, got %d incidents", *info.Total)
+	}
+}
@@ -0,0 +1,190 @@
+//go:build integration
+
+package integration
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/ruslanuskembaev/geo-alerts-system/internal/domain"
+	"github.com/ruslanuskembaev/geo-alerts-system/internal/repository"
+	"github.com/ruslanuskembaev/geo-alerts-system/internal/service"
+)
+
+// invalidationRecorder кеш, который только сообщает о сбросах по арендаторам
+type invalidationRecorder struct {
+	invalidated chan string
+}
+
+func (r *invalidationRecorder) GetActive(ctx context.Context) ([]*domain.Incident, bool, error) {
+	return nil, false, nil
+}
+
+func (r *invalidationRecorder) SetActive(ctx context.Context, incidents []*domain.Incident) error {
+	return nil
+}
+
+func (r *invalidationRecorder) Invalidate(ctx context.Context) error {
+	r.invalidated <- domain.TenantFromContext(ctx)
+	return nil
+}
+
+func (r *invalidationRecorder) expect(t *testing.T, tenants ...string) {
+	t.Helper()
+	pending := make(map[string]bool, len(tenants))
+	for _, tenant := range tenants {
+		pending[tenant] = true
+	}
+	deadline := time.After(10 * time.Second)
+	for len(pending) > 0 {
+		select {
+		case tenant := <-r.invalidated:
+			delete(pending, tenant)
+		case <-deadline:
+			t.Fatalf("expected invalidations for %v", pending)
+		}
+	}
+}
+
+// drain отбрасывает уже полученные сбросы, чтобы следующая проверка не засчитала их
+func (r *invalidationRecorder) drain() {
+	for {
+		select {
+		case <-r.invalidated:
+		default:
+			return
+		}
+	}
+}
+
+func TestIncidentChangeWatcher_InvalidatesOnDatabaseChanges(t *testing.T) {
+	pool := testDB(t)
+	defer pool.Close()
+	truncateTables(t, pool)
+
+	repo := repository.NewIncidentRepository(pool)
+	create := func(tenantID string) *domain.Incident {
+		incident, err := repo.Create(domain.WithTenant(context.Background(), tenantID), domain.CreateIncidentRequest{
+			Title:        "Flood",
+			Severity:     domain.SeverityHigh,
+			Latitude:     10,
+			Longitude:    10,
+			RadiusMeters: 1000,
+		})
+		if err != nil {
+			t.Fatalf("create incident failed: %v", err)
+		}
+		return incident
+	}
+	incidentA := create("city-a")
+
+	recorder := &invalidationRecorder{invalidated: make(chan string, 64)}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		service.NewIncidentChangeWatcher(repository.NewIncidentChangeListener(pool), recorder).
+			WithRetryDelay(50*time.Millisecond, 200*time.Millisecond).
+			Start(ctx)
+	}()
+	defer func() {
+		cancel()
+		<-done
+	}()
+
+	// После подписки сбрасываются все арендаторы с инцидентами
+	recorder.expect(t, "city-a")
+
+	// Правка в обход API
+	recorder.drain()
+	if _, err := pool.Exec(context.Background(), `UPDATE incidents SET title = 'Manual fix' WHERE id = $1`, incidentA.ID); err != nil {
+		t.Fatalf("manual update failed: %v", err)
+	}
+	recorder.expect(t, "city-a")
+
+	create("city-b")
+	recorder.expect(t, "city-b")
+
+	// Обрыв соединения подписки: слушатель переподключается и сбрасывает все арендаторы
+	var terminated int
+	if err := pool.QueryRow(context.Background(), `
+		SELECT COUNT(*) FROM (
+			SELECT pg_terminate_backend(pid) FROM pg_stat_activity WHERE application_name = $1
+		) t
+	`, repository.IncidentListenerApplicationName).Scan(&terminated); err != nil || terminated == 0 {
+		t.Fatalf("terminate listener failed: %d, %v", terminated, err)
+	}
+	recorder.expect(t, "city-a", "city-b")
+
+	recorder.drain()
+	if _, err := pool.Exec(context.Background(), `DELETE FROM incidents WHERE id = $1`, incidentA.ID); err != nil {
+		t.Fatalf("manual delete failed: %v", err)
+	}
+	recorder.expect(t, "city-a")
+}
+
+func TestIncidentChangeWatcher_OnlyLeaderInvalidates(t *testing.T) {
+	pool := testDB(t)
+	defer pool.Close()
+	truncateTables(t, pool)
+
+	repo := repository.NewIncidentRepository(pool)
+	incident, err := repo.Create(domain.WithTenant(context.Background(), "city-a"), domain.CreateIncidentRequest{
+		Title:        "Flood",
+		Severity:     domain.SeverityHigh,
+		Latitude:     10,
+		Longitude:    10,
+		RadiusMeters: 1000,
+	})
+	if err != nil {
+		t.Fatalf("create incident failed: %v", err)
+	}
+
+	start := func(recorder *invalidationRecorder) (context.CancelFunc, chan struct{}) {
+		ctx, cancel := context.WithCancel(context.Background())
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			listener := repository.NewIncidentChangeListener(pool).WithLockRetry(50 * time.Millisecond)
+			service.NewIncidentChangeWatcher(listener, recorder).
+				WithRetryDelay(50*time.Millisecond, 200*time.Millisecond).
+				Start(ctx)
+		}()
+		return cancel, done
+	}
+	update := func() {
+		if _, err := pool.Exec(context.Background(), `UPDATE incidents SET title = title || '!' WHERE id = $1`, incident.ID); err != nil {
+			t.Fatalf("manual update failed: %v", err)
+		}
+	}
+
+	leader := &invalidationRecorder{invalidated: make(chan string, 64)}
+	cancelLeader, leaderDone := start(leader)
+	leader.expect(t, "city-a")
+
+	standby := &invalidationRecorder{invalidated: make(chan string, 64)}
+	cancelStandby, standbyDone := start(standby)
+	defer func() {
+		cancelStandby()
+		<-standbyDone
+	}()
+
+	// Изменение обрабатывает только ведущая реплика
+	update()
+	leader.expect(t, "city-a")
+	time.Sleep(300 * time.Millisecond)
+	select {
+	case tenant := <-standby.invalidated:
+		t.Fatalf("expected standby to stay idle, got invalidation for %q", tenant)
+	default:
+	}
+
+	// После остановки ведущей резервная получает блокировку и сбрасывает всех арендаторов
+	cancelLeader()
+	<-leaderDone
+	standby.expect(t, "city-a")
+
+	update()
+	standby.expect(t, "city-a")
+}
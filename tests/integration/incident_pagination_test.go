@@ -0,0 +1,88 @@
+//go:build integration
+
+package integration
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/ruslanuskembaev/geo-alerts-system/internal/domain"
+	"github.com/ruslanuskembaev/geo-alerts-system/internal/repository"
+)
+
+func TestIncidentRepository_KeysetPagination(t *testing.T) {
+	pool := testDB(t)
+	defer pool.Close()
+	truncateTables(t, pool)
+
+	repo := repository.NewIncidentRepository(pool)
+	ctx := context.Background()
+
+	for i := 0; i < 5; i++ {
+		if _, err := repo.Create(ctx, domain.CreateIncidentRequest{
+			Title:        fmt.Sprintf("Incident %d", i),
+			Severity:     domain.SeverityLow,
+			Latitude:     55.75,
+			Longitude:    37.61,
+			RadiusMeters: 100 + i,
+		}); err != nil {
+			t.Fatalf("create failed: %v", err)
+		}
+	}
+
+	filter := domain.IncidentFilter{SortBy: domain.IncidentSortRadiusMeters}
+	first, info, err := repo.List(ctx, filter, domain.PageRequest{Limit: 2})
+	if err != nil {
+		t.Fatalf("first page failed: %v", err)
+	}
+	if len(first) != 2 || first[0].RadiusMeters != 100 || info.NextCursor == "" || info.PrevCursor != "" || info.Total != nil {
+		t.Fatalf("unexpected first page: len=%d info=%+v", len(first), info)
+	}
+
+	// Инцидент, созданный между запросами страниц, не сдвигает следующую страницу
+	if _, err := repo.Create(ctx, domain.CreateIncidentRequest{
+		Title: "Inserted", Severity: domain.SeverityLow, Latitude: 55.75, Longitude: 37.61, RadiusMeters: 50,
+	}); err != nil {
+		t.Fatalf("create failed: %v", err)
+	}
+
+	next, err := domain.DecodeCursor(info.NextCursor)
+	if err != nil {
+		t.Fatalf("decode cursor: %v", err)
+	}
+	second, info, err := repo.List(ctx, filter, domain.PageRequest{Limit: 2, Cursor: next})
+	if err != nil {
+		t.Fatalf("second page failed: %v", err)
+	}
+	if len(second) != 2 || second[0].RadiusMeters != 102 || second[1].RadiusMeters != 103 {
+		t.Fatalf("unexpected second page: %+v", second)
+	}
+
+	next, _ = domain.DecodeCursor(info.NextCursor)
+	last, lastInfo, err := repo.List(ctx, filter, domain.PageRequest{Limit: 2, Cursor: next})
+	if err != nil {
+		t.Fatalf("last page failed: %v", err)
+	}
+	if len(last) != 1 || last[0].RadiusMeters != 104 || lastInfo.NextCursor != "" {
+		t.Fatalf("unexpected last page: len=%d info=%+v", len(last), lastInfo)
+	}
+
+	prev, _ := domain.DecodeCursor(info.PrevCursor)
+	back, backInfo, err := repo.List(ctx, filter, domain.PageRequest{Limit: 2, Cursor: prev})
+	if err != nil {
+		t.Fatalf("previous page failed: %v", err)
+	}
+	if len(back) != 2 || back[0].RadiusMeters != 100 || back[1].RadiusMeters != 101 {
+		t.Fatalf("unexpected previous page: %+v", back)
+	}
+	// Перед первой страницей оказался вставленный инцидент
+	if backInfo.PrevCursor == "" || backInfo.NextCursor == "" {
+		t.Fatalf("expected both cursors on previous page, got %+v", backInfo)
+	}
+
+	// Курсор другой сортировки отклоняется
+	if _, _, err := repo.List(ctx, domain.IncidentFilter{}, domain.PageRequest{Limit: 2, Cursor: next}); err != domain.ErrInvalidCursor {
+		t.Fatalf("expected ErrInvalidCursor, got %v", err)
+	}
+}
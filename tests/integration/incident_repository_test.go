@@ -4,9 +4,12 @@ package integration
 
 import (
 	"context"
+	"errors"
 	"testing"
 	"time"
 
+	"github.com/google/uuid"
+
 	"github.com/ruslanuskembaev/geo-alerts-system/internal/domain"
 	"github.com/ruslanuskembaev/geo-alerts-system/internal/repository"
 )
@@ -38,11 +41,11 @@ func TestIncidentRepository_CRUD(t *testing.T) {
 		t.Fatalf("unexpected title")
 	}
 
-	list, total, err := repo.List(context.Background(), 10, 0)
+	list, info, err := repo.List(context.Background(), domain.IncidentFilter{}, domain.PageRequest{Limit: 10, IncludeTotal: true})
 	if err != nil {
 		t.Fatalf("list failed: %v", err)
 	}
-	if total != 1 || len(list) != 1 {
+	if info.Total == nil || *info.Total != 1 || len(list) != 1 {
 		t.Fatalf("expected total=1 list=1")
 	}
 
@@ -51,7 +54,7 @@ func TestIncidentRepository_CRUD(t *testing.T) {
 	updated, err := repo.Update(context.Background(), created.ID, domain.UpdateIncidentRequest{
 		Title:        &newTitle,
 		RadiusMeters: &newRadius,
-	})
+	}, nil)
 	if err != nil {
 		t.Fatalf("update failed: %v", err)
 	}
@@ -59,7 +62,7 @@ func TestIncidentRepository_CRUD(t *testing.T) {
 		t.Fatalf("update values not applied")
 	}
 
-	if err := repo.Deactivate(context.Background(), created.ID); err != nil {
+	if err := repo.Deactivate(context.Background(), created.ID, nil); err != nil {
 		t.Fatalf("deactivate failed: %v", err)
 	}
 
@@ -82,3 +85,218 @@ func TestIncidentRepository_CRUD(t *testing.T) {
 		t.Fatalf("expected updated_at to be recent")
 	}
 }
+
+func TestIncidentRepository_OptimisticConcurrency(t *testing.T) {
+	pool := testDB(t)
+	defer pool.Close()
+	truncateTables(t, pool)
+
+	repo := repository.NewIncidentRepository(pool)
+	ctx := context.Background()
+
+	created, err := repo.Create(ctx, domain.CreateIncidentRequest{
+		Title:        "Versioned",
+		Severity:     domain.SeverityMedium,
+		Latitude:     55.7,
+		Longitude:    37.6,
+		RadiusMeters: 500,
+	})
+	if err != nil {
+		t.Fatalf("create failed: %v", err)
+	}
+	if created.Version != 1 {
+		t.Fatalf("expected initial version 1, got %d", created.Version)
+	}
+
+	staleVersion := created.Version
+	radius := 800
+	updated, err := repo.Update(ctx, created.ID, domain.UpdateIncidentRequest{RadiusMeters: &radius}, &staleVersion)
+	if err != nil {
+		t.Fatalf("conditional update failed: %v", err)
+	}
+	if updated.Version != 2 || updated.RadiusMeters != radius || updated.Title != created.Title {
+		t.Fatalf("unexpected updated incident: %+v", updated)
+	}
+
+	title := "Lost update"
+	if _, err := repo.Update(ctx, created.ID, domain.UpdateIncidentRequest{Title: &title}, &staleVersion); !errors.Is(err, repository.ErrVersionConflict) {
+		t.Fatalf("expected version conflict, got %v", err)
+	}
+	if err := repo.Deactivate(ctx, created.ID, &staleVersion); !errors.Is(err, repository.ErrVersionConflict) {
+		t.Fatalf("expected version conflict on deactivate, got %v", err)
+	}
+
+	missing := 1
+	if _, err := repo.Update(ctx, "00000000-0000-0000-0000-000000000000", domain.UpdateIncidentRequest{Title: &title}, &missing); !errors.Is(err, repository.ErrNotFound) {
+		t.Fatalf("expected not found for missing incident, got %v", err)
+	}
+
+	current, err := repo.GetByID(ctx, created.ID)
+	if err != nil {
+		t.Fatalf("get failed: %v", err)
+	}
+	if current.Title != created.Title || current.Version != 2 {
+		t.Fatalf("expected stale writes to be rejected, got %+v", current)
+	}
+	if err := repo.Deactivate(ctx, created.ID, &current.Version); err != nil {
+		t.Fatalf("conditional deactivate failed: %v", err)
+	}
+}
+
+func TestIncidentRepository_ReactivateAndPurge(t *testing.T) {
+	pool := testDB(t)
+	defer pool.Close()
+	truncateTables(t, pool)
+
+	repo := repository.NewIncidentRepository(pool)
+	checkRepo := repository.NewLocationCheckRepository(pool)
+	ctx := context.Background()
+
+	created, err := repo.Create(ctx, domain.CreateIncidentRequest{
+		Title:        "Purge me",
+		Severity:     domain.SeverityLow,
+		Latitude:     1,
+		Longitude:    1,
+		RadiusMeters: 100,
+	})
+	if err != nil {
+		t.Fatalf("create failed: %v", err)
+	}
+
+	if _, err := repo.Reactivate(ctx, created.ID, nil); !errors.Is(err, repository.ErrNotFound) {
+		t.Fatalf("expected reactivate of active incident to fail with not found, got %v", err)
+	}
+	if err := repo.Deactivate(ctx, created.ID, nil); err != nil {
+		t.Fatalf("deactivate failed: %v", err)
+	}
+	deactivated, err := repo.GetByID(ctx, created.ID)
+	if err != nil {
+		t.Fatalf("get failed: %v", err)
+	}
+	if deactivated.DeactivatedAt == nil {
+		t.Fatalf("expected deactivated_at to be set")
+	}
+
+	reactivated, err := repo.Reactivate(ctx, created.ID, &deactivated.Version)
+	if err != nil {
+		t.Fatalf("reactivate failed: %v", err)
+	}
+	if !reactivated.IsActive || reactivated.DeactivatedAt != nil {
+		t.Fatalf("expected incident active without deactivated_at, got %+v", reactivated)
+	}
+
+	check := domain.LocationCheck{
+		ID:             uuid.New().String(),
+		UserID:         "user-1",
+		Latitude:       1,
+		Longitude:      1,
+		IsInDangerZone: true,
+		CheckedAt:      time.Now().UTC(),
+	}
+	if err := checkRepo.Create(ctx, check, []string{created.ID}, nil); err != nil {
+		t.Fatalf("create check failed: %v", err)
+	}
+
+	if err := repo.Purge(ctx, created.ID); err != nil {
+		t.Fatalf("purge failed: %v", err)
+	}
+	if _, err := repo.GetByID(ctx, created.ID); !errors.Is(err, repository.ErrNotFound) {
+		t.Fatalf("expected purged incident to be gone, got %v", err)
+	}
+	var links int
+	if err := pool.QueryRow(ctx, `SELECT COUNT(*) FROM location_check_incidents WHERE incident_id = $1`, created.ID).Scan(&links); err != nil {
+		t.Fatalf("count links failed: %v", err)
+	}
+	if links != 0 {
+		t.Fatalf("expected links removed, got %d", links)
+	}
+	if err := repo.Purge(ctx, created.ID); !errors.Is(err, repository.ErrNotFound) {
+		t.Fatalf("expected second purge to fail with not found, got %v", err)
+	}
+}
+
+func TestIncidentRepository_PurgeDeactivatedBefore(t *testing.T) {
+	pool := testDB(t)
+	defer pool.Close()
+	truncateTables(t, pool)
+
+	repo := repository.NewIncidentRepository(pool)
+	ctx := context.Background()
+
+	create := func(title string) *domain.Incident {
+		incident, err := repo.Create(ctx, domain.CreateIncidentRequest{
+			Title:        title,
+			Severity:     domain.SeverityLow,
+			Latitude:     1,
+			Longitude:    1,
+			RadiusMeters: 100,
+		})
+		if err != nil {
+			t.Fatalf("create failed: %v", err)
+		}
+		return incident
+	}
+
+	old := create("Old")
+	recent := create("Recent")
+	active := create("Active")
+	for _, incident := range []*domain.Incident{old, recent} {
+		if err := repo.Deactivate(ctx, incident.ID, nil); err != nil {
+			t.Fatalf("deactivate failed: %v", err)
+		}
+	}
+	if _, err := pool.Exec(ctx, `UPDATE incidents SET deactivated_at = now() - interval '40 days' WHERE id = $1`, old.ID); err != nil {
+		t.Fatalf("backdate failed: %v", err)
+	}
+
+	purged, err := repo.PurgeDeactivatedBefore(ctx, time.Now().Add(-30*24*time.Hour))
+	if err != nil {
+		t.Fatalf("purge failed: %v", err)
+	}
+	if purged != 1 {
+		t.Fatalf("expected one purged incident, got %d", purged)
+	}
+	if _, err := repo.GetByID(ctx, old.ID); !errors.Is(err, repository.ErrNotFound) {
+		t.Fatalf("expected old incident purged")
+	}
+	for _, incident := range []*domain.Incident{recent, active} {
+		if _, err := repo.GetByID(ctx, incident.ID); err != nil {
+			t.Fatalf("expected incident %s to remain: %v", incident.Title, err)
+		}
+	}
+}
+
+func TestIncidentRepository_LastUpdatedAtIncludesDeactivated(t *testing.T) {
+	pool := testDB(t)
+	defer pool.Close()
+	truncateTables(t, pool)
+
+	repo := repository.NewIncidentRepository(pool)
+	ctx := context.Background()
+
+	last, err := repo.LastUpdatedAt(ctx)
+	if err != nil {
+		t.Fatalf("last updated failed: %v", err)
+	}
+	if !last.IsZero() {
+		t.Fatalf("expected zero time without incidents, got %s", last)
+	}
+
+	incident, err := repo.Create(ctx, domain.CreateIncidentRequest{
+		Title: "Zone", Severity: domain.SeverityLow, Latitude: 55.75, Longitude: 37.61, RadiusMeters: 100,
+	})
+	if err != nil {
+		t.Fatalf("create failed: %v", err)
+	}
+	if err := repo.Deactivate(ctx, incident.ID, nil); err != nil {
+		t.Fatalf("deactivate failed: %v", err)
+	}
+
+	last, err = repo.LastUpdatedAt(ctx)
+	if err != nil {
+		t.Fatalf("last updated failed: %v", err)
+	}
+	if !last.After(incident.UpdatedAt) {
+		t.Fatalf("expected deactivation to advance last update, got %s <= %s", last, incident.UpdatedAt)
+	}
+}
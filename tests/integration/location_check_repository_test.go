@@ -41,7 +41,7 @@ func TestLocationCheckRepository_Stats(t *testing.T) {
 		IsInDangerZone: true,
 		CheckedAt:      time.Now().UTC(),
 	}
-	if err := checkRepo.Create(context.Background(), check, []string{incident.ID}); err != nil {
+	if err := checkRepo.Create(context.Background(), check, []string{incident.ID}, nil); err != nil {
 		t.Fatalf("create check failed: %v", err)
 	}
 
@@ -53,11 +53,11 @@ func TestLocationCheckRepository_Stats(t *testing.T) {
 		IsInDangerZone: true,
 		CheckedAt:      time.Now().UTC(),
 	}
-	if err := checkRepo.Create(context.Background(), another, []string{incident.ID}); err != nil {
+	if err := checkRepo.Create(context.Background(), another, []string{incident.ID}, nil); err != nil {
 		t.Fatalf("create second check failed: %v", err)
 	}
 
-	stats, err := checkRepo.StatsByIncident(context.Background(), time.Now().Add(-time.Hour))
+	stats, err := checkRepo.StatsByIncident(context.Background(), lastHourStats())
 	if err != nil {
 		t.Fatalf("stats failed: %v", err)
 	}
@@ -68,3 +68,675 @@ func TestLocationCheckRepository_Stats(t *testing.T) {
 		t.Fatalf("expected unique user_count=1")
 	}
 }
+
+func TestLocationCheckRepository_Heatmap(t *testing.T) {
+	pool := testDB(t)
+	defer pool.Close()
+	truncateTables(t, pool)
+
+	checkRepo := repository.NewLocationCheckRepository(pool)
+	ctx := context.Background()
+	now := time.Now().UTC()
+
+	checks := []struct {
+		user     string
+		lat, lon float64
+		danger   bool
+		age      time.Duration
+		tenantID string
+	}{
+		{"user-1", 55.7480, 37.6100, true, time.Minute, ""},
+		{"user-1", 55.7481, 37.6101, true, 2 * time.Minute, ""},
+		{"user-2", 55.7482, 37.6102, false, 3 * time.Minute, ""},
+		{"user-3", 59.9300, 30.3300, false, time.Minute, ""},
+		// Вне окна и чужой арендатор не учитываются
+		{"user-4", 55.7500, 37.6100, true, 3 * time.Hour, ""},
+		{"user-5", 55.7500, 37.6100, true, time.Minute, "city-b"},
+	}
+	for _, item := range checks {
+		check := domain.LocationCheck{
+			ID:             uuid.New().String(),
+			TenantID:       item.tenantID,
+			UserID:         item.user,
+			Latitude:       item.lat,
+			Longitude:      item.lon,
+			IsInDangerZone: item.danger,
+			CheckedAt:      now.Add(-item.age),
+		}
+		if err := checkRepo.Create(ctx, check, nil, nil); err != nil {
+			t.Fatalf("create check failed: %v", err)
+		}
+	}
+
+	query := domain.HeatmapQuery{Precision: 6, Since: now.Add(-time.Hour), Until: now.Add(time.Second), Limit: 10}
+	cells, err := checkRepo.Heatmap(ctx, query)
+	if err != nil {
+		t.Fatalf("heatmap failed: %v", err)
+	}
+	if len(cells) != 2 {
+		t.Fatalf("expected 2 cells, got %+v", cells)
+	}
+	moscow := cells[0]
+	if moscow.Geohash != "ucftpu" || moscow.Checks != 3 || moscow.Users != 2 || moscow.DangerHits != 2 {
+		t.Fatalf("unexpected busiest cell: %+v", moscow)
+	}
+
+	query.BBox = &domain.BoundingBox{MinLatitude: 59, MinLongitude: 30, MaxLatitude: 60, MaxLongitude: 31}
+	query.Limit = 1
+	cells, err = checkRepo.Heatmap(ctx, query)
+	if err != nil {
+		t.Fatalf("heatmap with bbox failed: %v", err)
+	}
+	if len(cells) != 1 || cells[0].Checks != 1 || cells[0].DangerHits != 0 {
+		t.Fatalf("expected only the bbox cell, got %+v", cells)
+	}
+}
+
+func TestLocationCheckRepository_StatsRollupMaintainedIncrementally(t *testing.T) {
+	pool := testDB(t)
+	defer pool.Close()
+	truncateTables(t, pool)
+
+	incidentRepo := repository.NewIncidentRepository(pool)
+	checkRepo := repository.NewLocationCheckRepository(pool)
+	ctx := context.Background()
+
+	incident, err := incidentRepo.Create(ctx, domain.CreateIncidentRequest{
+		Title:        "Rollup",
+		Severity:     domain.SeverityHigh,
+		Latitude:     10,
+		Longitude:    10,
+		RadiusMeters: 1000,
+	})
+	if err != nil {
+		t.Fatalf("create incident failed: %v", err)
+	}
+
+	day := time.Date(2026, 10, 19, 0, 0, 0, 0, time.UTC)
+	checks := []struct {
+		user string
+		at   time.Time
+	}{
+		{"user-1", day.Add(10*time.Hour + 5*time.Minute)},
+		{"user-1", day.Add(10*time.Hour + 6*time.Minute)},
+		{"user-2", day.Add(10*time.Hour + 30*time.Minute)},
+		{"user-1", day.Add(11 * time.Hour)},
+		{"user-3", day.Add(11*time.Hour + time.Minute)},
+	}
+	for _, item := range checks {
+		check := domain.LocationCheck{
+			ID:             uuid.New().String(),
+			UserID:         item.user,
+			Latitude:       10,
+			Longitude:      10,
+			IsInDangerZone: true,
+			CheckedAt:      item.at,
+		}
+		if err := checkRepo.Create(ctx, check, []string{incident.ID}, nil); err != nil {
+			t.Fatalf("create check failed: %v", err)
+		}
+	}
+
+	hourly, err := checkRepo.StatsSeries(ctx, domain.StatsSeriesQuery{
+		From:   day,
+		To:     day.Add(24 * time.Hour),
+		Bucket: domain.StatsBucketHour,
+	})
+	if err != nil {
+		t.Fatalf("hourly series failed: %v", err)
+	}
+	if len(hourly) != 1 || len(hourly[0].Points) != 2 {
+		t.Fatalf("expected one series with two hourly points, got %+v", hourly)
+	}
+	ten, eleven := hourly[0].Points[0], hourly[0].Points[1]
+	if ten.Checks != 3 || ten.Users != 2 || ten.NewUsers != 2 {
+		t.Fatalf("unexpected 10:00 bucket: %+v", ten)
+	}
+	if eleven.Checks != 2 || eleven.Users != 2 || eleven.NewUsers != 1 {
+		t.Fatalf("unexpected 11:00 bucket: %+v", eleven)
+	}
+
+	daily, err := checkRepo.StatsSeries(ctx, domain.StatsSeriesQuery{
+		From:        day,
+		To:          day.Add(24 * time.Hour),
+		Bucket:      domain.StatsBucketDay,
+		IncidentIDs: []string{incident.ID},
+	})
+	if err != nil {
+		t.Fatalf("daily series failed: %v", err)
+	}
+	if point := daily[0].Points[0]; point.Checks != 5 || point.Users != 3 || point.NewUsers != 3 {
+		t.Fatalf("unexpected daily bucket: %+v", point)
+	}
+
+	pruned, err := checkRepo.PruneStatsBucketUsers(ctx, day.Add(48*time.Hour))
+	if err != nil {
+		t.Fatalf("prune failed: %v", err)
+	}
+	if pruned == 0 {
+		t.Fatalf("expected bucket user marks to be pruned")
+	}
+	daily, err = checkRepo.StatsSeries(ctx, domain.StatsSeriesQuery{From: day, To: day.Add(24 * time.Hour), Bucket: domain.StatsBucketDay})
+	if err != nil || daily[0].Points[0].Users != 3 {
+		t.Fatalf("expected rollups to survive pruning, got %+v / %v", daily, err)
+	}
+}
+
+// lastHourStats интервал статистики за последний час
+func lastHourStats() domain.IncidentStatsQuery {
+	now := time.Now().UTC()
+	return domain.IncidentStatsQuery{Since: now.Add(-time.Hour), Until: now.Add(time.Second)}
+}
+
+func TestLocationCheckRepository_StatsMetricsAndFilters(t *testing.T) {
+	pool := testDB(t)
+	defer pool.Close()
+	truncateTables(t, pool)
+
+	incidentRepo := repository.NewIncidentRepository(pool)
+	checkRepo := repository.NewLocationCheckRepository(pool)
+	ctx := context.Background()
+
+	high, err := incidentRepo.Create(ctx, domain.CreateIncidentRequest{
+		Title: "High", Severity: domain.SeverityHigh, Latitude: 10, Longitude: 10, RadiusMeters: 5000,
+	})
+	if err != nil {
+		t.Fatalf("create incident failed: %v", err)
+	}
+	low, err := incidentRepo.Create(ctx, domain.CreateIncidentRequest{
+		Title: "Low", Severity: domain.SeverityLow, Latitude: 20, Longitude: 20, RadiusMeters: 1000,
+	})
+	if err != nil {
+		t.Fatalf("create incident failed: %v", err)
+	}
+
+	now := time.Now().UTC()
+	// Смещения по широте ~0, 1113 и 2226 м от центра
+	hits := []struct {
+		user string
+		lat  float64
+		age  time.Duration
+	}{
+		{"user-1", 10, 30 * time.Minute},
+		{"user-2", 10.01, 20 * time.Minute},
+		{"user-1", 10.02, 10 * time.Minute},
+		{"user-3", 10, 3 * time.Hour},
+	}
+	for _, hit := range hits {
+		check := domain.LocationCheck{
+			ID: uuid.New().String(), UserID: hit.user, Latitude: hit.lat, Longitude: 10,
+			IsInDangerZone: true, CheckedAt: now.Add(-hit.age),
+		}
+		if err := checkRepo.Create(ctx, check, []string{high.ID}, nil); err != nil {
+			t.Fatalf("create check failed: %v", err)
+		}
+	}
+
+	stats, err := checkRepo.StatsByIncident(ctx, lastHourStats())
+	if err != nil {
+		t.Fatalf("stats failed: %v", err)
+	}
+	if len(stats) != 2 {
+		t.Fatalf("expected stats for both active incidents, got %+v", stats)
+	}
+	var highStats, lowStats domain.IncidentStats
+	for _, item := range stats {
+		if item.IncidentID == high.ID {
+			highStats = item
+		} else if item.IncidentID == low.ID {
+			lowStats = item
+		}
+	}
+	if highStats.Checks != 3 || highStats.UserCount != 2 || highStats.Severity != domain.SeverityHigh {
+		t.Fatalf("unexpected counts: %+v", highStats)
+	}
+	if highStats.FirstHitAt == nil || highStats.LastHitAt == nil || !highStats.FirstHitAt.Before(*highStats.LastHitAt) {
+		t.Fatalf("expected first and last hit times, got %+v", highStats)
+	}
+	if highStats.AvgDistanceMeters == nil || *highStats.AvgDistanceMeters < 1100 || *highStats.AvgDistanceMeters > 1130 {
+		t.Fatalf("unexpected average distance: %v", highStats.AvgDistanceMeters)
+	}
+	if *highStats.P50DistanceMeters < 1100 || *highStats.P50DistanceMeters > 1130 || *highStats.MaxDistanceMeters < 2200 {
+		t.Fatalf("unexpected distance percentiles: %+v", highStats)
+	}
+	if lowStats.Checks != 0 || lowStats.AvgDistanceMeters != nil || lowStats.FirstHitAt != nil {
+		t.Fatalf("expected empty metrics for incident without hits, got %+v", lowStats)
+	}
+
+	query := domain.IncidentStatsQuery{Since: now.Add(-4 * time.Hour), Until: now.Add(time.Second), Severities: []domain.Severity{domain.SeverityHigh}}
+	stats, err = checkRepo.StatsByIncident(ctx, query)
+	if err != nil {
+		t.Fatalf("stats with severity failed: %v", err)
+	}
+	if len(stats) != 1 || stats[0].Checks != 4 || stats[0].UserCount != 3 {
+		t.Fatalf("expected only high incident over wider window, got %+v", stats)
+	}
+
+	// Деактивированный инцидент доступен по incident_id
+	if err := incidentRepo.Deactivate(ctx, high.ID, nil); err != nil {
+		t.Fatalf("deactivate failed: %v", err)
+	}
+	query = lastHourStats()
+	query.IncidentIDs = []string{high.ID}
+	stats, err = checkRepo.StatsByIncident(ctx, query)
+	if err != nil {
+		t.Fatalf("stats by id failed: %v", err)
+	}
+	if len(stats) != 1 || stats[0].IsActive || stats[0].Checks != 3 {
+		t.Fatalf("expected deactivated incident stats, got %+v", stats)
+	}
+}
+
+func TestLocationCheckRepository_PrivacyModes(t *testing.T) {
+	pool := testDB(t)
+	defer pool.Close()
+	truncateTables(t, pool)
+
+	ctx := context.Background()
+	incidentRepo := repository.NewIncidentRepository(pool)
+	checkRepo := repository.NewLocationCheckRepository(pool).WithPrivacy(domain.LocationPrivacy{
+		Coordinates: domain.CoordinatesNone,
+		DangerOnly:  true,
+		Keys:        []domain.UserIDKey{{ID: "k1", Secret: "secret"}},
+	})
+
+	incident, err := incidentRepo.Create(ctx, domain.CreateIncidentRequest{
+		Title:        "Private",
+		Severity:     domain.SeverityHigh,
+		Latitude:     10,
+		Longitude:    10,
+		RadiusMeters: 1000,
+	})
+	if err != nil {
+		t.Fatalf("create incident failed: %v", err)
+	}
+
+	now := time.Now().UTC()
+	checks := []struct {
+		userID   string
+		inDanger bool
+	}{
+		{"user-1", true},
+		{"user-1", true},
+		{"user-2", true},
+		{"user-3", false},
+	}
+	for _, item := range checks {
+		var ids []string
+		if item.inDanger {
+			ids = []string{incident.ID}
+		}
+		check := domain.LocationCheck{
+			ID:             uuid.New().String(),
+			UserID:         item.userID,
+			Latitude:       10,
+			Longitude:      10,
+			IsInDangerZone: item.inDanger,
+			CheckedAt:      now,
+		}
+		if err := checkRepo.Create(ctx, check, ids, nil); err != nil {
+			t.Fatalf("create check failed: %v", err)
+		}
+	}
+
+	var stored, withCoordinates, rawUsers int
+	if err := pool.QueryRow(ctx, `
+		SELECT COUNT(*),
+		       COUNT(*) FILTER (WHERE latitude IS NOT NULL OR longitude IS NOT NULL),
+		       COUNT(*) FILTER (WHERE user_id LIKE 'user-%')
+		FROM location_checks
+	`).Scan(&stored, &withCoordinates, &rawUsers); err != nil {
+		t.Fatalf("query checks failed: %v", err)
+	}
+	if stored != 3 || withCoordinates != 0 || rawUsers != 0 {
+		t.Fatalf("expected 3 pseudonymised checks without coordinates, got %d/%d/%d", stored, withCoordinates, rawUsers)
+	}
+
+	stats, err := checkRepo.StatsByIncident(ctx, lastHourStats())
+	if err != nil {
+		t.Fatalf("stats failed: %v", err)
+	}
+	if len(stats) != 1 || stats[0].Checks != 3 || stats[0].UserCount != 2 {
+		t.Fatalf("expected 3 checks from 2 users, got %+v", stats)
+	}
+	if stats[0].AvgDistanceMeters != nil {
+		t.Fatalf("expected no distances without coordinates, got %v", *stats[0].AvgDistanceMeters)
+	}
+
+	heatmap, err := checkRepo.Heatmap(ctx, domain.HeatmapQuery{
+		Precision: domain.DefaultHeatmapPrecision,
+		Since:     now.Add(-time.Hour),
+		Until:     now.Add(time.Hour),
+		Limit:     domain.DefaultHeatmapLimit,
+	})
+	if err != nil {
+		t.Fatalf("heatmap failed: %v", err)
+	}
+	if len(heatmap) != 0 {
+		t.Fatalf("expected checks without coordinates outside heatmap, got %+v", heatmap)
+	}
+}
+
+func TestLocationCheckRepository_PseudonymStableAcrossKeyChange(t *testing.T) {
+	pool := testDB(t)
+	defer pool.Close()
+	truncateTables(t, pool)
+
+	ctx := context.Background()
+	incidentRepo := repository.NewIncidentRepository(pool)
+	incident, err := incidentRepo.Create(ctx, domain.CreateIncidentRequest{
+		Title: "Rotation", Severity: domain.SeverityHigh, Latitude: 10, Longitude: 10, RadiusMeters: 1000,
+	})
+	if err != nil {
+		t.Fatalf("create incident failed: %v", err)
+	}
+
+	k1 := domain.UserIDKey{ID: "k1", Secret: "old-secret"}
+	k2 := domain.UserIDKey{ID: "k2", Secret: "new-secret"}
+	raw := domain.LocationPrivacy{}
+	beforeRotation := domain.LocationPrivacy{Keys: []domain.UserIDKey{k1}}
+	rotated := domain.LocationPrivacy{Keys: []domain.UserIDKey{k2, k1}}
+	oldKeyRemoved := domain.LocationPrivacy{Keys: []domain.UserIDKey{k2}}
+
+	// Окно захватывает включение псевдонимизации, смену ключа и удаление старого
+	day := time.Now().UTC().Truncate(24 * time.Hour)
+	steps := []struct {
+		privacy domain.LocationPrivacy
+		userID  string
+	}{
+		{raw, "user-2"},
+		{beforeRotation, "user-1"},
+		{beforeRotation, "user-2"},
+		{rotated, "user-1"},
+		{oldKeyRemoved, "user-1"},
+	}
+	for i, step := range steps {
+		check := domain.LocationCheck{
+			ID: uuid.New().String(), UserID: step.userID, Latitude: 10, Longitude: 10,
+			IsInDangerZone: true, CheckedAt: day.Add(time.Duration(i+1) * time.Minute),
+		}
+		checkRepo := repository.NewLocationCheckRepository(pool).WithPrivacy(step.privacy)
+		if err := checkRepo.Create(ctx, check, []string{incident.ID}, nil); err != nil {
+			t.Fatalf("create check %d failed: %v", i, err)
+		}
+	}
+
+	// Проверка user-2 до включения псевдонимизации переводится фоновой задачей
+	pseudonymRepo := repository.NewUserPseudonymRepository(pool)
+	pending, err := pseudonymRepo.RawUsers(ctx, 10)
+	if err != nil {
+		t.Fatalf("raw users failed: %v", err)
+	}
+	if len(pending) != 1 || pending[0].UserID != "user-2" {
+		t.Fatalf("expected only user-2 stored under raw id, got %+v", pending)
+	}
+	if err := pseudonymRepo.Pseudonymize(ctx, pending[0], rotated.UserIDLookups(domain.DefaultTenantID, "user-2")); err != nil {
+		t.Fatalf("pseudonymize failed: %v", err)
+	}
+	if pending, err := pseudonymRepo.RawUsers(ctx, 10); err != nil || len(pending) != 0 {
+		t.Fatalf("expected no raw users left, got %+v (%v)", pending, err)
+	}
+
+	var users, rawUsers int
+	if err := pool.QueryRow(ctx, `
+		SELECT COUNT(DISTINCT user_id), COUNT(*) FILTER (WHERE user_id LIKE 'user-%')
+		FROM location_checks
+	`).Scan(&users, &rawUsers); err != nil {
+		t.Fatalf("query checks failed: %v", err)
+	}
+	if users != 2 || rawUsers != 0 {
+		t.Fatalf("expected 2 stable pseudonyms and no raw ids, got %d / %d", users, rawUsers)
+	}
+
+	checkRepo := repository.NewLocationCheckRepository(pool).WithPrivacy(oldKeyRemoved)
+	stats, err := checkRepo.StatsByIncident(ctx, domain.IncidentStatsQuery{
+		Since: day, Until: day.Add(time.Hour), IncidentIDs: []string{incident.ID},
+	})
+	if err != nil {
+		t.Fatalf("stats failed: %v", err)
+	}
+	if len(stats) != 1 || stats[0].Checks != 5 || stats[0].UserCount != 2 {
+		t.Fatalf("expected 5 checks from 2 users across key change, got %+v", stats)
+	}
+
+	series, err := checkRepo.StatsSeries(ctx, domain.StatsSeriesQuery{
+		From: day, To: day.Add(24 * time.Hour), Bucket: domain.StatsBucketDay,
+	})
+	if err != nil {
+		t.Fatalf("stats series failed: %v", err)
+	}
+	if len(series) != 1 || len(series[0].Points) != 1 {
+		t.Fatalf("expected one day bucket, got %+v", series)
+	}
+	if point := series[0].Points[0]; point.Checks != 5 || point.Users != 2 || point.NewUsers != 2 {
+		t.Fatalf("expected rollup to count each user once, got %+v", point)
+	}
+
+	// После удаления старого ключа история находится по HMAC под текущим
+	history, _, err := checkRepo.ListByUser(ctx, oldKeyRemoved.StoredUserIDs(domain.DefaultTenantID, "user-1"),
+		domain.UserChecksFilter{}, domain.PageRequest{Limit: 10})
+	if err != nil {
+		t.Fatalf("list by user failed: %v", err)
+	}
+	if len(history) != 3 {
+		t.Fatalf("expected all 3 checks of user-1, got %d", len(history))
+	}
+}
+
+func TestLocationCheckRepository_UserHistoryAndIncidentUsers(t *testing.T) {
+	pool := testDB(t)
+	defer pool.Close()
+	truncateTables(t, pool)
+
+	ctx := context.Background()
+	incidentRepo := repository.NewIncidentRepository(pool)
+	checkRepo := repository.NewLocationCheckRepository(pool)
+
+	incident, err := incidentRepo.Create(ctx, domain.CreateIncidentRequest{
+		Title:        "Fire",
+		Severity:     domain.SeverityHigh,
+		Latitude:     10,
+		Longitude:    10,
+		RadiusMeters: 1000,
+	})
+	if err != nil {
+		t.Fatalf("create incident failed: %v", err)
+	}
+
+	base := time.Now().UTC().Add(-time.Hour).Truncate(time.Second)
+	create := func(userID string, minute int, lat float64, inDanger bool) {
+		var ids []string
+		if inDanger {
+			ids = []string{incident.ID}
+		}
+		check := domain.LocationCheck{
+			ID:             uuid.New().String(),
+			UserID:         userID,
+			Latitude:       lat,
+			Longitude:      10,
+			IsInDangerZone: inDanger,
+			CheckedAt:      base.Add(time.Duration(minute) * time.Minute),
+		}
+		if err := checkRepo.Create(ctx, check, ids, nil); err != nil {
+			t.Fatalf("create check failed: %v", err)
+		}
+	}
+	create("user-1", 0, 10, true)
+	create("user-1", 10, 10, true)
+	create("user-1", 20, 11, false) // вышел из зоны
+	create("user-2", 5, 10, true)
+	create("user-3", 15, 12, false)
+
+	page := domain.PageRequest{Limit: 2}
+	checks, info, err := checkRepo.ListByUser(ctx, []string{"user-1"}, domain.UserChecksFilter{}, page)
+	if err != nil {
+		t.Fatalf("list by user failed: %v", err)
+	}
+	if len(checks) != 2 || checks[0].Latitude == nil || *checks[0].Latitude != 11 || info.NextCursor == "" {
+		t.Fatalf("expected newest 2 checks with next cursor, got %+v / %+v", checks, info)
+	}
+	cursor, err := domain.DecodeCursor(info.NextCursor)
+	if err != nil {
+		t.Fatalf("decode cursor failed: %v", err)
+	}
+	rest, _, err := checkRepo.ListByUser(ctx, []string{"user-1"}, domain.UserChecksFilter{}, domain.PageRequest{Limit: 2, Cursor: cursor})
+	if err != nil || len(rest) != 1 || !rest[0].CheckedAt.Equal(base) || len(rest[0].Incidents) != 1 {
+		t.Fatalf("expected oldest check with its zone on next page, got %+v (%v)", rest, err)
+	}
+
+	dangerOnly, _, err := checkRepo.ListByUser(ctx, []string{"user-1"}, domain.UserChecksFilter{DangerOnly: true}, domain.PageRequest{Limit: 10, IncludeTotal: true})
+	if err != nil || len(dangerOnly) != 2 {
+		t.Fatalf("expected 2 danger checks, got %+v (%v)", dangerOnly, err)
+	}
+
+	users, usersInfo, err := checkRepo.IncidentUsers(ctx, incident.ID, domain.IncidentUsersQuery{
+		Since: base.Add(-time.Minute),
+		Until: base.Add(time.Hour),
+	}, domain.PageRequest{Limit: 10, IncludeTotal: true})
+	if err != nil {
+		t.Fatalf("incident users failed: %v", err)
+	}
+	if len(users) != 2 || usersInfo.Total == nil || *usersInfo.Total != 2 {
+		t.Fatalf("expected 2 users in zone, got %+v", users)
+	}
+	first := users[0]
+	if first.UserID != "user-1" || first.Checks != 2 || !first.LastHitAt.Equal(base.Add(10*time.Minute)) {
+		t.Fatalf("unexpected first user: %+v", first)
+	}
+	if first.LastPosition.IsInDangerZone || first.LastPosition.Latitude == nil || *first.LastPosition.Latitude != 11 {
+		t.Fatalf("expected last known position outside zone, got %+v", first.LastPosition)
+	}
+
+	// Последняя позиция берётся на момент until
+	users, _, err = checkRepo.IncidentUsers(ctx, incident.ID, domain.IncidentUsersQuery{
+		Since: base.Add(-time.Minute),
+		Until: base.Add(15 * time.Minute),
+	}, domain.PageRequest{Limit: 10})
+	if err != nil || len(users) != 2 || !users[0].LastPosition.CheckedAt.Equal(base.Add(10*time.Minute)) {
+		t.Fatalf("expected position as of until, got %+v (%v)", users, err)
+	}
+}
+
+func TestLocationCheckRepository_LatestChecksInZone(t *testing.T) {
+	pool := testDB(t)
+	defer pool.Close()
+	truncateTables(t, pool)
+
+	ctx := context.Background()
+	incidentRepo := repository.NewIncidentRepository(pool)
+	checkRepo := repository.NewLocationCheckRepository(pool)
+
+	existing, err := incidentRepo.Create(ctx, domain.CreateIncidentRequest{
+		Title:        "Existing",
+		Severity:     domain.SeverityLow,
+		Latitude:     10,
+		Longitude:    10,
+		RadiusMeters: 1000,
+	})
+	if err != nil {
+		t.Fatalf("create incident failed: %v", err)
+	}
+
+	now := time.Now().UTC()
+	create := func(userID string, ago time.Duration, lat float64, incidentIDs ...string) {
+		check := domain.LocationCheck{
+			ID:             uuid.New().String(),
+			UserID:         userID,
+			Latitude:       lat,
+			Longitude:      10,
+			IsInDangerZone: len(incidentIDs) > 0,
+			CheckedAt:      now.Add(-ago),
+		}
+		if err := checkRepo.Create(ctx, check, incidentIDs, nil); err != nil {
+			t.Fatalf("create check failed: %v", err)
+		}
+	}
+	create("inside", 5*time.Minute, 10.001)
+	create("moved-away", 10*time.Minute, 10.001)
+	create("moved-away", 2*time.Minute, 11) // последняя проверка уже вне зоны
+	create("stale", 2*time.Hour, 10.001)
+	create("already-alerted", 3*time.Minute, 10.001, existing.ID)
+
+	zone := *existing
+	found, err := checkRepo.LatestChecksInZone(ctx, zone, now.Add(-15*time.Minute))
+	if err != nil {
+		t.Fatalf("latest checks failed: %v", err)
+	}
+	if len(found) != 1 || found[0].UserID != "inside" || found[0].Latitude != 10.001 {
+		t.Fatalf("expected only user inside, got %+v", found)
+	}
+
+	// Для новой зоны пользователь, оповещённый о другой, тоже попадает в выборку
+	other, err := incidentRepo.Create(ctx, domain.CreateIncidentRequest{
+		Title:        "New",
+		Severity:     domain.SeverityHigh,
+		Latitude:     10,
+		Longitude:    10,
+		RadiusMeters: 1000,
+	})
+	if err != nil {
+		t.Fatalf("create incident failed: %v", err)
+	}
+	found, err = checkRepo.LatestChecksInZone(ctx, *other, now.Add(-15*time.Minute))
+	if err != nil || len(found) != 2 || found[0].UserID != "already-alerted" {
+		t.Fatalf("expected 2 users for new zone, got %+v (%v)", found, err)
+	}
+}
+
+func TestImpactPreview_ZoneImpactAndOverlapping(t *testing.T) {
+	pool := testDB(t)
+	defer pool.Close()
+	truncateTables(t, pool)
+
+	ctx := context.Background()
+	incidentRepo := repository.NewIncidentRepository(pool)
+	checkRepo := repository.NewLocationCheckRepository(pool)
+
+	now := time.Now().UTC()
+	create := func(userID string, ago time.Duration, lat float64) {
+		check := domain.LocationCheck{
+			ID:        uuid.New().String(),
+			UserID:    userID,
+			Latitude:  lat,
+			Longitude: 10,
+			CheckedAt: now.Add(-ago),
+		}
+		if err := checkRepo.Create(ctx, check, nil, nil); err != nil {
+			t.Fatalf("create check failed: %v", err)
+		}
+	}
+	create("user-1", 30*time.Minute, 10)
+	create("user-1", 20*time.Minute, 10.001)
+	create("user-2", 40*time.Minute, 10.002)
+	create("user-2", 10*time.Minute, 10.5) // ушёл из зоны
+	create("user-3", 10*time.Minute, 11)
+	create("user-4", 3*time.Hour, 10) // вне интервала
+
+	zone := domain.ImpactZone{Latitude: 10, Longitude: 10, RadiusMeters: 1000}
+	impact, err := checkRepo.ZoneImpact(ctx, zone, now.Add(-time.Hour), now)
+	if err != nil {
+		t.Fatalf("zone impact failed: %v", err)
+	}
+	if impact != (domain.ImpactCounts{Checks: 3, Users: 2, CurrentUsers: 1}) {
+		t.Fatalf("unexpected impact: %+v", impact)
+	}
+
+	for _, req := range []domain.CreateIncidentRequest{
+		{Title: "Wide", Severity: domain.SeverityHigh, Latitude: 10.005, Longitude: 10, RadiusMeters: 5000},
+		{Title: "Touching", Severity: domain.SeverityLow, Latitude: 10.015, Longitude: 10, RadiusMeters: 1000},
+		{Title: "Far", Severity: domain.SeverityLow, Latitude: 12, Longitude: 10, RadiusMeters: 1000},
+	} {
+		if _, err := incidentRepo.Create(ctx, req); err != nil {
+			t.Fatalf("create incident failed: %v", err)
+		}
+	}
+
+	overlapping, err := incidentRepo.ListOverlapping(ctx, zone)
+	if err != nil {
+		t.Fatalf("list overlapping failed: %v", err)
+	}
+	if len(overlapping) != 2 || overlapping[0].Title != "Wide" || !overlapping[0].Contains ||
+		overlapping[1].Title != "Touching" || overlapping[1].Contains {
+		t.Fatalf("unexpected overlapping incidents: %+v", overlapping)
+	}
+}
@@ -0,0 +1,177 @@
+//go:build integration
+
+package integration
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/ruslanuskembaev/geo-alerts-system/internal/domain"
+	"github.com/ruslanuskembaev/geo-alerts-system/internal/repository"
+)
+
+func TestLocationCheckPartitionRepository_EnsureAndDrop(t *testing.T) {
+	pool := testDB(t)
+	defer pool.Close()
+	truncateTables(t, pool)
+
+	ctx := context.Background()
+	partitions := repository.NewLocationCheckPartitionRepository(pool)
+	incidentRepo := repository.NewIncidentRepository(pool)
+	checkRepo := repository.NewLocationCheckRepository(pool)
+
+	// Давние месяцы не пересекаются с секциями, созданными миграцией
+	month := time.Date(2001, 1, 1, 0, 0, 0, 0, time.UTC)
+	if _, err := partitions.DropPartitionsBefore(ctx, month.AddDate(0, 2, 0)); err != nil {
+		t.Fatalf("cleanup failed: %v", err)
+	}
+	defer func() {
+		_, _ = partitions.DropPartitionsBefore(ctx, month.AddDate(0, 2, 0))
+	}()
+
+	created, err := partitions.EnsurePartitions(ctx, month.AddDate(0, 0, 14), month.AddDate(0, 1, 2))
+	if err != nil {
+		t.Fatalf("ensure partitions failed: %v", err)
+	}
+	if len(created) != 2 || created[0] != "location_checks_p200101" || created[1] != "location_checks_p200102" {
+		t.Fatalf("unexpected created partitions: %v", created)
+	}
+	if created, err = partitions.EnsurePartitions(ctx, month, month.AddDate(0, 1, 0)); err != nil || len(created) != 0 {
+		t.Fatalf("expected ensure to be idempotent, got %v / %v", created, err)
+	}
+
+	incident, err := incidentRepo.Create(ctx, domain.CreateIncidentRequest{
+		Title: "Old", Severity: domain.SeverityLow, Latitude: 10, Longitude: 10, RadiusMeters: 1000,
+	})
+	if err != nil {
+		t.Fatalf("create incident failed: %v", err)
+	}
+	inPartition := domain.LocationCheck{
+		ID: uuid.New().String(), UserID: "user-1", Latitude: 10, Longitude: 10,
+		IsInDangerZone: true, CheckedAt: month.AddDate(0, 0, 19),
+	}
+	inDefault := domain.LocationCheck{
+		ID: uuid.New().String(), UserID: "user-2", Latitude: 10, Longitude: 10,
+		IsInDangerZone: true, CheckedAt: month.Add(-time.Hour),
+	}
+	for _, check := range []domain.LocationCheck{inPartition, inDefault} {
+		if err := checkRepo.Create(ctx, check, []string{incident.ID}, nil); err != nil {
+			t.Fatalf("create check failed: %v", err)
+		}
+	}
+
+	var table string
+	if err := pool.QueryRow(ctx, `SELECT tableoid::regclass::text FROM location_check_incidents WHERE check_id = $1`, inPartition.ID).Scan(&table); err != nil {
+		t.Fatalf("lookup partition failed: %v", err)
+	}
+	if table != "location_check_incidents_p200101" {
+		t.Fatalf("expected link in monthly partition, got %s", table)
+	}
+
+	stats, err := checkRepo.StatsByIncident(ctx, domain.IncidentStatsQuery{
+		Since: month.AddDate(0, 0, -1), Until: month.AddDate(0, 1, 0), IncidentIDs: []string{incident.ID},
+	})
+	if err != nil {
+		t.Fatalf("stats failed: %v", err)
+	}
+	if len(stats) != 1 || stats[0].Checks != 2 || stats[0].UserCount != 2 {
+		t.Fatalf("expected stats across partitions, got %+v", stats)
+	}
+
+	dropped, err := partitions.DropPartitionsBefore(ctx, month.AddDate(0, 1, 0))
+	if err != nil {
+		t.Fatalf("drop partitions failed: %v", err)
+	}
+	if len(dropped) != 1 || dropped[0] != "location_checks_p200101" {
+		t.Fatalf("expected only January partition dropped, got %v", dropped)
+	}
+
+	var checks, links int
+	if err := pool.QueryRow(ctx, `SELECT COUNT(*) FROM location_checks WHERE checked_at < $1`, month.AddDate(0, 1, 0)).Scan(&checks); err != nil {
+		t.Fatalf("count checks failed: %v", err)
+	}
+	if err := pool.QueryRow(ctx, `SELECT COUNT(*) FROM location_check_incidents WHERE incident_id = $1`, incident.ID).Scan(&links); err != nil {
+		t.Fatalf("count links failed: %v", err)
+	}
+	if checks != 0 || links != 0 {
+		t.Fatalf("expected expired checks removed from partitions and default, got %d checks / %d links", checks, links)
+	}
+
+	// Агрегаты переживают удаление сырых проверок
+	series, err := checkRepo.StatsSeries(ctx, domain.StatsSeriesQuery{
+		From: month.AddDate(0, 0, -1), To: month.AddDate(0, 1, 0), Bucket: domain.StatsBucketDay,
+	})
+	if err != nil {
+		t.Fatalf("stats series failed: %v", err)
+	}
+	if len(series) != 1 || len(series[0].Points) != 2 {
+		t.Fatalf("expected rollups to be kept after dropping partitions, got %+v", series)
+	}
+}
+
+func TestLocationCheckPartitionRepository_EnsureMovesRowsFromDefault(t *testing.T) {
+	pool := testDB(t)
+	defer pool.Close()
+	truncateTables(t, pool)
+
+	ctx := context.Background()
+	partitions := repository.NewLocationCheckPartitionRepository(pool)
+	incidentRepo := repository.NewIncidentRepository(pool)
+	checkRepo := repository.NewLocationCheckRepository(pool)
+
+	month := time.Date(2002, 3, 1, 0, 0, 0, 0, time.UTC)
+	if _, err := partitions.DropPartitionsBefore(ctx, month.AddDate(0, 1, 0)); err != nil {
+		t.Fatalf("cleanup failed: %v", err)
+	}
+	defer func() {
+		_, _ = partitions.DropPartitionsBefore(ctx, month.AddDate(0, 1, 0))
+	}()
+
+	incident, err := incidentRepo.Create(ctx, domain.CreateIncidentRequest{
+		Title: "Stranded", Severity: domain.SeverityLow, Latitude: 10, Longitude: 10, RadiusMeters: 1000,
+	})
+	if err != nil {
+		t.Fatalf("create incident failed: %v", err)
+	}
+
+	// Секции месяца нет: проверка попадает в DEFAULT
+	check := domain.LocationCheck{
+		ID: uuid.New().String(), UserID: "user-1", Latitude: 10, Longitude: 10,
+		IsInDangerZone: true, CheckedAt: month.AddDate(0, 0, 9),
+	}
+	if err := checkRepo.Create(ctx, check, []string{incident.ID}, nil); err != nil {
+		t.Fatalf("create check failed: %v", err)
+	}
+
+	created, err := partitions.EnsurePartitions(ctx, month, month)
+	if err != nil {
+		t.Fatalf("ensure partitions with stranded rows failed: %v", err)
+	}
+	if len(created) != 1 || created[0] != "location_checks_p200203" {
+		t.Fatalf("unexpected created partitions: %v", created)
+	}
+
+	var checkTable, linkTable string
+	if err := pool.QueryRow(ctx, `SELECT tableoid::regclass::text FROM location_checks WHERE id = $1`, check.ID).Scan(&checkTable); err != nil {
+		t.Fatalf("lookup check partition failed: %v", err)
+	}
+	if err := pool.QueryRow(ctx, `SELECT tableoid::regclass::text FROM location_check_incidents WHERE check_id = $1`, check.ID).Scan(&linkTable); err != nil {
+		t.Fatalf("lookup link partition failed: %v", err)
+	}
+	if checkTable != "location_checks_p200203" || linkTable != "location_check_incidents_p200203" {
+		t.Fatalf("expected rows moved into the new partitions, got %s / %s", checkTable, linkTable)
+	}
+
+	// Новые проверки месяца ложатся в созданную секцию
+	next := check
+	next.ID = uuid.New().String()
+	if err := checkRepo.Create(ctx, next, []string{incident.ID}, nil); err != nil {
+		t.Fatalf("create check after attach failed: %v", err)
+	}
+	if err := pool.QueryRow(ctx, `SELECT tableoid::regclass::text FROM location_checks WHERE id = $1`, next.ID).Scan(&checkTable); err != nil || checkTable != "location_checks_p200203" {
+		t.Fatalf("expected new check in attached partition, got %s (%v)", checkTable, err)
+	}
+}
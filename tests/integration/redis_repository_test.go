@@ -78,3 +78,224 @@ func TestRedisQueueAndCache(t *testing.T) {
 		t.Fatalf("unexpected dequeued job payload")
 	}
 }
+
+func TestRedisRateLimiter(t *testing.T) {
+	client := testRedis(t)
+	defer func() {
+		_ = client.Close()
+	}()
+
+	limiter := repository.NewRateLimiter(client)
+	ctx := context.Background()
+
+	for i := 0; i < 2; i++ {
+		allowed, _, err := limiter.Allow(ctx, "user:user-1", 2, time.Minute)
+		if err != nil {
+			t.Fatalf("allow failed: %v", err)
+		}
+		if !allowed {
+			t.Fatalf("expected request %d to be allowed", i+1)
+		}
+	}
+
+	allowed, retryAfter, err := limiter.Allow(ctx, "user:user-1", 2, time.Minute)
+	if err != nil {
+		t.Fatalf("allow failed: %v", err)
+	}
+	if allowed {
+		t.Fatalf("expected third request to be limited")
+	}
+	if retryAfter <= 0 || retryAfter > time.Minute {
+		t.Fatalf("unexpected retry after: %s", retryAfter)
+	}
+}
+
+func TestRedisIncidentCache_TenantIsolation(t *testing.T) {
+	client := testRedis(t)
+	defer func() {
+		_ = client.Close()
+	}()
+
+	cache := repository.NewIncidentCache(client, time.Minute)
+	ctxA := domain.WithTenant(context.Background(), "city-a")
+	ctxB := domain.WithTenant(context.Background(), "city-b")
+
+	if err := cache.SetActive(ctxA, []*domain.Incident{{ID: "incident-a", TenantID: "city-a"}}); err != nil {
+		t.Fatalf("cache set failed: %v", err)
+	}
+
+	if _, ok, err := cache.GetActive(ctxB); err != nil || ok {
+		t.Fatalf("expected cache miss for tenant B, ok=%v err=%v", ok, err)
+	}
+
+	if err := cache.Invalidate(ctxB); err != nil {
+		t.Fatalf("invalidate failed: %v", err)
+	}
+	got, ok, err := cache.GetActive(ctxA)
+	if err != nil || !ok || len(got) != 1 {
+		t.Fatalf("expected tenant A cache to survive tenant B invalidation")
+	}
+}
+
+func TestRedisTileCache_InvalidatedWithIncidentCache(t *testing.T) {
+	client := testRedis(t)
+	defer func() {
+		_ = client.Close()
+	}()
+
+	cache := repository.NewIncidentCache(client, time.Minute)
+	tiles := repository.NewTileCache(client, time.Minute)
+	ctx := domain.WithTenant(context.Background(), "city-a")
+	other := domain.WithTenant(context.Background(), "city-b")
+	coord := domain.TileCoord{Z: 10, X: 618, Y: 320}
+
+	generation, err := tiles.Generation(ctx)
+	if err != nil {
+		t.Fatalf("generation failed: %v", err)
+	}
+	if err := tiles.Set(ctx, generation, coord, []byte{0x1a, 0x00}); err != nil {
+		t.Fatalf("tile set failed: %v", err)
+	}
+	if data, ok, err := tiles.Get(ctx, generation, coord); err != nil || !ok || len(data) != 2 {
+		t.Fatalf("expected cached tile, got %v / %v / %v", data, ok, err)
+	}
+	if _, ok, _ := tiles.Get(other, generation, coord); ok {
+		t.Fatalf("expected tiles to be isolated per tenant")
+	}
+
+	if err := cache.Invalidate(ctx); err != nil {
+		t.Fatalf("invalidate failed: %v", err)
+	}
+	next, err := tiles.Generation(ctx)
+	if err != nil {
+		t.Fatalf("generation failed: %v", err)
+	}
+	if next == generation {
+		t.Fatalf("expected generation to change after invalidation")
+	}
+	if _, ok, _ := tiles.Get(ctx, next, coord); ok {
+		t.Fatalf("expected no tile under new generation")
+	}
+	if otherGeneration, _ := tiles.Generation(other); otherGeneration != 0 {
+		t.Fatalf("expected other tenant generation to be untouched, got %d", otherGeneration)
+	}
+}
+
+func TestRedisIdempotencyStore_ReserveSaveRelease(t *testing.T) {
+	client := testRedis(t)
+	defer func() {
+		_ = client.Close()
+	}()
+
+	ctx := context.Background()
+	store := repository.NewIdempotencyStore(client)
+	key := "city-a:" + time.Now().Format(time.RFC3339Nano)
+	defer func() {
+		_ = store.Release(ctx, key)
+	}()
+
+	pending := domain.IdempotencyRecord{Fingerprint: "fp-1", CreatedAt: time.Now().UTC()}
+	if existing, reserved, err := store.Reserve(ctx, key, pending, time.Minute); err != nil || !reserved || existing != nil {
+		t.Fatalf("expected key reserved, got %+v / %v / %v", existing, reserved, err)
+	}
+	existing, reserved, err := store.Reserve(ctx, key, domain.IdempotencyRecord{Fingerprint: "fp-2"}, time.Minute)
+	if err != nil || reserved || existing == nil || existing.Fingerprint != "fp-1" || existing.Completed() {
+		t.Fatalf("expected pending record returned, got %+v / %v / %v", existing, reserved, err)
+	}
+
+	completed := domain.IdempotencyRecord{
+		Fingerprint: "fp-1",
+		Status:      201,
+		Headers:     map[string]string{"Content-Type": "application/json"},
+		Body:        []byte(`{"id":"1"}`),
+	}
+	if err := store.Save(ctx, key, completed, time.Hour); err != nil {
+		t.Fatalf("save failed: %v", err)
+	}
+	if ttl := client.TTL(ctx, "geoalerts:idempotency:"+key).Val(); ttl <= time.Minute {
+		t.Fatalf("expected ttl extended on save, got %s", ttl)
+	}
+	existing, _, err = store.Reserve(ctx, key, pending, time.Minute)
+	if err != nil || existing == nil || existing.Status != 201 || string(existing.Body) != `{"id":"1"}` {
+		t.Fatalf("expected completed record returned, got %+v / %v", existing, err)
+	}
+
+	if err := store.Release(ctx, key); err != nil {
+		t.Fatalf("release failed: %v", err)
+	}
+	if _, reserved, err := store.Reserve(ctx, key, pending, time.Minute); err != nil || !reserved {
+		t.Fatalf("expected key reserved again after release, got %v / %v", reserved, err)
+	}
+}
+
+func TestRedisIncidentCache_VersionsAndInvalidationMessages(t *testing.T) {
+	client := testRedis(t)
+	defer func() {
+		_ = client.Close()
+	}()
+
+	cache := repository.NewIncidentCache(client, time.Minute)
+	replica := repository.NewIncidentCache(client, time.Minute)
+	tenantID := "city-" + time.Now().Format("150405.000000000")
+	ctx := domain.WithTenant(context.Background(), tenantID)
+
+	watchCtx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	subscribed := make(chan bool, 4)
+	invalidated := make(chan string, 4)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		replica.WatchInvalidations(watchCtx, func(tenant string) { invalidated <- tenant }, func(ok bool) { subscribed <- ok })
+	}()
+	select {
+	case ok := <-subscribed:
+		if !ok {
+			t.Fatalf("expected subscription")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("subscription timed out")
+	}
+
+	version, err := cache.Version(ctx)
+	if err != nil {
+		t.Fatalf("version failed: %v", err)
+	}
+	if err := cache.SetVersion(ctx, version, []*domain.Incident{{ID: "incident-1"}}); err != nil {
+		t.Fatalf("set failed: %v", err)
+	}
+	if err := cache.Invalidate(ctx); err != nil {
+		t.Fatalf("invalidate failed: %v", err)
+	}
+
+	select {
+	case tenant := <-invalidated:
+		if tenant != tenantID {
+			t.Fatalf("expected invalidation for %s, got %s", tenantID, tenant)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("invalidation message not received")
+	}
+
+	next, err := replica.Version(ctx)
+	if err != nil || next != version+1 {
+		t.Fatalf("expected version bumped to %d, got %d, %v", version+1, next, err)
+	}
+	if _, ok, _ := replica.GetActive(ctx); ok {
+		t.Fatalf("expected miss under the new version")
+	}
+	// Загрузка, начатая до инвалидации, пишется под старой версией и не читается
+	if err := cache.SetVersion(ctx, version, []*domain.Incident{{ID: "stale"}}); err != nil {
+		t.Fatalf("set failed: %v", err)
+	}
+	if _, ok, _ := replica.GetActive(ctx); ok {
+		t.Fatalf("expected stale write under old version to stay invisible")
+	}
+
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatalf("watcher did not stop")
+	}
+}
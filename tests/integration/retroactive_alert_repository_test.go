@@ -0,0 +1,106 @@
+//go:build integration
+
+package integration
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/ruslanuskembaev/geo-alerts-system/internal/domain"
+	"github.com/ruslanuskembaev/geo-alerts-system/internal/repository"
+)
+
+func TestRetroactiveAlertRepository_AlertsEachCheckOnce(t *testing.T) {
+	pool := testDB(t)
+	defer pool.Close()
+	truncateTables(t, pool)
+
+	ctx := domain.WithActor(context.Background(), "operator")
+	incidentRepo := repository.NewIncidentRepository(pool)
+	checkRepo := repository.NewLocationCheckRepository(pool)
+	outboxRepo := repository.NewWebhookOutboxRepository(pool)
+	retroRepo := repository.NewRetroactiveAlertRepository(pool)
+
+	incident, err := incidentRepo.Create(ctx, domain.CreateIncidentRequest{
+		Title: "Gas leak", Severity: domain.SeverityHigh, Latitude: 10, Longitude: 10, RadiusMeters: 1000,
+	})
+	if err != nil {
+		t.Fatalf("create incident failed: %v", err)
+	}
+
+	now := time.Now().UTC()
+	check := domain.LocationCheck{ID: uuid.New().String(), UserID: "user-1", Latitude: 10, Longitude: 10.001, CheckedAt: now}
+	if err := checkRepo.Create(ctx, check, nil, nil); err != nil {
+		t.Fatalf("create check failed: %v", err)
+	}
+
+	since := now.Add(-15 * time.Minute)
+	for i := 0; i < 2; i++ {
+		if err := retroRepo.Request(ctx, incident.ID, since); err != nil {
+			t.Fatalf("request failed: %v", err)
+		}
+	}
+
+	requests, err := retroRepo.Claim(ctx, 1, time.Minute)
+	if err != nil || len(requests) != 1 || requests[0].IncidentID != incident.ID ||
+		requests[0].TenantID != domain.DefaultTenantID || !requests[0].Since.Equal(since.Truncate(time.Microsecond)) {
+		t.Fatalf("expected first request claimed, got %+v (%v)", requests, err)
+	}
+	second, err := retroRepo.Claim(ctx, 10, time.Minute)
+	if err != nil || len(second) != 1 || second[0].ID == requests[0].ID {
+		t.Fatalf("expected only the unleased request claimed, got %+v (%v)", second, err)
+	}
+	if more, err := retroRepo.Claim(ctx, 10, time.Minute); err != nil || len(more) != 0 {
+		t.Fatalf("expected leased requests skipped, got %+v (%v)", more, err)
+	}
+
+	matches, err := checkRepo.LatestChecksInZone(ctx, *incident, since)
+	if err != nil || len(matches) != 1 {
+		t.Fatalf("expected one check in zone, got %+v (%v)", matches, err)
+	}
+	job := domain.WebhookJob{
+		TenantID:  domain.DefaultTenantID,
+		Payload:   domain.WebhookPayload{CheckID: check.ID, UserID: "user-1", Retroactive: true},
+		CreatedAt: now,
+	}
+
+	// Оба запроса нашли одну и ту же проверку: оповещение пишется один раз
+	if written, err := retroRepo.Complete(ctx, requests[0], []domain.WebhookJob{job}); err != nil || written != 1 {
+		t.Fatalf("expected one alert written, got %d (%v)", written, err)
+	}
+	if written, err := retroRepo.Complete(ctx, second[0], []domain.WebhookJob{job}); err != nil || written != 0 {
+		t.Fatalf("expected repeated alert skipped, got %d (%v)", written, err)
+	}
+	if matches, err := checkRepo.LatestChecksInZone(ctx, *incident, since); err != nil || len(matches) != 0 {
+		t.Fatalf("expected alerted check excluded from later lookups, got %+v (%v)", matches, err)
+	}
+
+	var jobs []domain.WebhookJob
+	if _, err := outboxRepo.Dispatch(ctx, 10, func(job domain.WebhookJob) error {
+		jobs = append(jobs, job)
+		return nil
+	}); err != nil {
+		t.Fatalf("dispatch failed: %v", err)
+	}
+	if len(jobs) != 1 || jobs[0].Payload.CheckID != check.ID {
+		t.Fatalf("expected one outbox job, got %+v", jobs)
+	}
+
+	var pending int
+	if err := pool.QueryRow(ctx, `SELECT COUNT(*) FROM retroactive_alert_requests`).Scan(&pending); err != nil {
+		t.Fatalf("count requests failed: %v", err)
+	}
+	if pending != 0 {
+		t.Fatalf("expected completed requests removed, got %d", pending)
+	}
+
+	if pruned, err := retroRepo.PruneAlerts(ctx, now.Add(-time.Hour)); err != nil || pruned != 0 {
+		t.Fatalf("expected fresh marks kept, got %d (%v)", pruned, err)
+	}
+	if pruned, err := retroRepo.PruneAlerts(ctx, time.Now().UTC().Add(time.Minute)); err != nil || pruned != 1 {
+		t.Fatalf("expected old marks pruned, got %d (%v)", pruned, err)
+	}
+}
@@ -65,6 +65,21 @@ func applyMigrations(pool *pgxpool.Pool) error {
 	files := []string{
 		filepath.Join(root, "migrations", "001_init.sql"),
 		filepath.Join(root, "migrations", "002_indexes.sql"),
+		filepath.Join(root, "migrations", "003_tenants.sql"),
+		filepath.Join(root, "migrations", "004_audit_log.sql"),
+		filepath.Join(root, "migrations", "005_incident_version.sql"),
+		filepath.Join(root, "migrations", "006_incident_deactivated_at.sql"),
+		filepath.Join(root, "migrations", "007_incident_search.sql"),
+		filepath.Join(root, "migrations", "008_cap_alerts.sql"),
+		filepath.Join(root, "migrations", "009_incident_stats_rollup.sql"),
+		filepath.Join(root, "migrations", "010_location_check_partitions.sql"),
+		filepath.Join(root, "migrations", "011_location_check_privacy.sql"),
+		filepath.Join(root, "migrations", "012_webhook_deliveries.sql"),
+		filepath.Join(root, "migrations", "013_location_check_history_indexes.sql"),
+		filepath.Join(root, "migrations", "014_webhook_outbox.sql"),
+		filepath.Join(root, "migrations", "015_incident_change_notify.sql"),
+		filepath.Join(root, "migrations", "016_user_pseudonyms.sql"),
+		filepath.Join(root, "migrations", "017_retroactive_alerts.sql"),
 	}
 
 	for _, path := range files {
@@ -79,18 +94,14 @@ func applyMigrations(pool *pgxpool.Pool) error {
 	return nil
 }
 
+// execSQL выполняет файл миграции целиком: без параметров pgx использует simple protocol,
+// поэтому несколько выражений и тела функций с ";" выполняются как есть
 func execSQL(ctx context.Context, pool *pgxpool.Pool, sql string) error {
-	statements := strings.Split(sql, ";")
-	for _, stmt := range statements {
-		trimmed := strings.TrimSpace(stmt)
-		if trimmed == "" {
-			continue
-		}
-		if _, err := pool.Exec(ctx, trimmed); err != nil {
-			return err
-		}
+	if strings.TrimSpace(sql) == "" {
+		return nil
 	}
-	return nil
+	_, err := pool.Exec(ctx, sql)
+	return err
 }
 
 func truncateTables(t *testing.T, pool *pgxpool.Pool) {
@@ -99,7 +110,9 @@ func truncateTables(t *testing.T, pool *pgxpool.Pool) {
 	defer cancel()
 
 	if _, err := pool.Exec(ctx, `
-		TRUNCATE TABLE location_check_incidents, location_checks, incidents CASCADE
+		TRUNCATE TABLE location_check_incidents, location_checks, incidents, audit_log, cap_alerts,
+			incident_stats_rollup, incident_stats_users, incident_stats_bucket_users, webhook_deliveries, webhook_outbox,
+			user_pseudonyms, retroactive_alert_requests, retroactive_alerts CASCADE
 	`); err != nil {
 		t.Fatalf("failed to truncate tables: %v", err)
 	}
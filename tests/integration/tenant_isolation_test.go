@@ -0,0 +1,104 @@
+//go:build integration
+
+package integration
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/ruslanuskembaev/geo-alerts-system/internal/domain"
+	"github.com/ruslanuskembaev/geo-alerts-system/internal/repository"
+)
+
+func TestTenantIsolation_Incidents(t *testing.T) {
+	pool := testDB(t)
+	defer pool.Close()
+	truncateTables(t, pool)
+
+	repo := repository.NewIncidentRepository(pool)
+	checkRepo := repository.NewLocationCheckRepository(pool)
+
+	ctxA := domain.WithTenant(context.Background(), "city-a")
+	ctxB := domain.WithTenant(context.Background(), "city-b")
+
+	incidentA, err := repo.Create(ctxA, domain.CreateIncidentRequest{
+		Title:        "Tenant A",
+		Severity:     domain.SeverityHigh,
+		Latitude:     10,
+		Longitude:    10,
+		RadiusMeters: 500,
+	})
+	if err != nil {
+		t.Fatalf("create A failed: %v", err)
+	}
+	if incidentA.TenantID != "city-a" {
+		t.Fatalf("expected tenant city-a, got %s", incidentA.TenantID)
+	}
+	if _, err := repo.Create(ctxB, domain.CreateIncidentRequest{
+		Title:        "Tenant B",
+		Severity:     domain.SeverityLow,
+		Latitude:     10,
+		Longitude:    10,
+		RadiusMeters: 500,
+	}); err != nil {
+		t.Fatalf("create B failed: %v", err)
+	}
+
+	activeB, err := repo.ListActive(ctxB)
+	if err != nil {
+		t.Fatalf("list active B failed: %v", err)
+	}
+	if len(activeB) != 1 || activeB[0].Title != "Tenant B" {
+		t.Fatalf("expected tenant B to see only its incident")
+	}
+
+	_, infoB, err := repo.List(ctxB, domain.IncidentFilter{}, domain.PageRequest{Limit: 10, IncludeTotal: true})
+	if err != nil {
+		t.Fatalf("list B failed: %v", err)
+	}
+	if infoB.Total == nil || *infoB.Total != 1 {
+		t.Fatalf("expected tenant B total=1, got %v", infoB.Total)
+	}
+
+	if _, err := repo.GetByID(ctxB, incidentA.ID); !errors.Is(err, repository.ErrNotFound) {
+		t.Fatalf("expected tenant B to get not found for tenant A incident, got %v", err)
+	}
+	title := "Hijacked"
+	if _, err := repo.Update(ctxB, incidentA.ID, domain.UpdateIncidentRequest{Title: &title}, nil); !errors.Is(err, repository.ErrNotFound) {
+		t.Fatalf("expected tenant B update of tenant A incident to fail, got %v", err)
+	}
+	if err := repo.Deactivate(ctxB, incidentA.ID, nil); !errors.Is(err, repository.ErrNotFound) {
+		t.Fatalf("expected tenant B deactivate of tenant A incident to fail, got %v", err)
+	}
+
+	check := domain.LocationCheck{
+		ID:             uuid.New().String(),
+		UserID:         "user-1",
+		Latitude:       10,
+		Longitude:      10,
+		IsInDangerZone: true,
+		CheckedAt:      time.Now().UTC(),
+	}
+	if err := checkRepo.Create(ctxA, check, []string{incidentA.ID}, nil); err != nil {
+		t.Fatalf("create check failed: %v", err)
+	}
+
+	statsA, err := checkRepo.StatsByIncident(ctxA, lastHourStats())
+	if err != nil {
+		t.Fatalf("stats A failed: %v", err)
+	}
+	if len(statsA) != 1 || statsA[0].IncidentID != incidentA.ID || statsA[0].UserCount != 1 {
+		t.Fatalf("unexpected tenant A stats: %+v", statsA)
+	}
+	statsB, err := checkRepo.StatsByIncident(ctxB, lastHourStats())
+	if err != nil {
+		t.Fatalf("stats B failed: %v", err)
+	}
+	if len(statsB) != 1 || statsB[0].UserCount != 0 {
+		t.Fatalf("expected tenant B stats to exclude tenant A checks: %+v", statsB)
+	}
+}
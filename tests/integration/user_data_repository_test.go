@@ -0,0 +1,235 @@
+//go:build integration
+
+package integration
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/ruslanuskembaev/geo-alerts-system/internal/domain"
+	"github.com/ruslanuskembaev/geo-alerts-system/internal/repository"
+)
+
+func TestUserDataRepository_ExportAndErase(t *testing.T) {
+	pool := testDB(t)
+	defer pool.Close()
+	truncateTables(t, pool)
+
+	ctx := domain.WithActor(context.Background(), "dpo")
+	incidentRepo := repository.NewIncidentRepository(pool)
+	checkRepo := repository.NewLocationCheckRepository(pool)
+	deliveryRepo := repository.NewWebhookDeliveryRepository(pool)
+	userDataRepo := repository.NewUserDataRepository(pool)
+
+	incident, err := incidentRepo.Create(ctx, domain.CreateIncidentRequest{
+		Title:        "Flood",
+		Severity:     domain.SeverityHigh,
+		Latitude:     10,
+		Longitude:    10,
+		RadiusMeters: 1000,
+	})
+	if err != nil {
+		t.Fatalf("create incident failed: %v", err)
+	}
+
+	now := time.Now().UTC()
+	create := func(userID string, inDanger bool) string {
+		check := domain.LocationCheck{
+			ID:             uuid.New().String(),
+			UserID:         userID,
+			Latitude:       10,
+			Longitude:      10,
+			IsInDangerZone: inDanger,
+			CheckedAt:      now,
+		}
+		// Попадание в зону оставляет ожидающую задачу вебхука в outbox
+		var ids []string
+		var job *domain.WebhookJob
+		if inDanger {
+			ids = []string{incident.ID}
+			job = &domain.WebhookJob{Payload: domain.WebhookPayload{CheckID: check.ID, UserID: userID}, CreatedAt: now}
+		}
+		if err := checkRepo.Create(ctx, check, ids, job); err != nil {
+			t.Fatalf("create check failed: %v", err)
+		}
+		return check.ID
+	}
+	dangerCheck := create("user-1", true)
+	create("user-1", false)
+	otherCheck := create("user-2", true)
+
+	for _, checkID := range []string{dangerCheck, otherCheck} {
+		if err := deliveryRepo.Record(ctx, domain.WebhookDelivery{
+			TenantID: domain.DefaultTenantID,
+			CheckID:  checkID,
+			Attempt:  1,
+			Status:   domain.WebhookDelivered,
+		}); err != nil {
+			t.Fatalf("record delivery failed: %v", err)
+		}
+	}
+
+	export, err := userDataRepo.Export(ctx, []string{"user-1", "unused-pseudonym"})
+	if err != nil {
+		t.Fatalf("export failed: %v", err)
+	}
+	if len(export.Checks) != 2 || len(export.WebhookDeliveries) != 1 || export.WebhookDeliveries[0].CheckID != dangerCheck {
+		t.Fatalf("expected 2 checks and 1 delivery, got %+v / %+v", export.Checks, export.WebhookDeliveries)
+	}
+	if len(export.QueuedWebhooks) != 1 || export.QueuedWebhooks[0].Payload.CheckID != dangerCheck {
+		t.Fatalf("expected the pending outbox job, got %+v", export.QueuedWebhooks)
+	}
+	matched := 0
+	for _, check := range export.Checks {
+		matched += len(check.Incidents)
+		if check.Latitude == nil || *check.Latitude != 10 {
+			t.Fatalf("expected stored coordinates, got %+v", check)
+		}
+	}
+	if matched != 1 {
+		t.Fatalf("expected one zone match, got %d", matched)
+	}
+
+	otherTenant := domain.WithTenant(ctx, "city-b")
+	if export, err := userDataRepo.Export(otherTenant, []string{"user-1"}); err != nil || len(export.Checks) != 0 || len(export.QueuedWebhooks) != 0 {
+		t.Fatalf("expected no data in other tenant, got %+v (%v)", export, err)
+	}
+
+	erasure := &domain.UserErasure{
+		TenantID: domain.DefaultTenantID, UserID: "user-1", UserIDHash: "audit:0123", ErasedAt: now, QueuedWebhooks: 1,
+	}
+	if err := userDataRepo.Erase(ctx, []string{"user-1"}, erasure); err != nil {
+		t.Fatalf("erase failed: %v", err)
+	}
+	if erasure.Checks != 2 || erasure.IncidentMatches != 1 || erasure.WebhookDeliveries != 1 || erasure.StatsMarks == 0 {
+		t.Fatalf("unexpected erasure counts: %+v", erasure)
+	}
+
+	export, err = userDataRepo.Export(ctx, []string{"user-1"})
+	if err != nil || len(export.Checks) != 0 || len(export.WebhookDeliveries) != 0 || len(export.QueuedWebhooks) != 0 {
+		t.Fatalf("expected user data erased, got %+v (%v)", export, err)
+	}
+	if export, err := userDataRepo.Export(ctx, []string{"user-2"}); err != nil || len(export.Checks) != 1 || len(export.WebhookDeliveries) != 1 || len(export.QueuedWebhooks) != 1 {
+		t.Fatalf("expected other user untouched, got %+v (%v)", export, err)
+	}
+
+	var remainingMarks int
+	if err := pool.QueryRow(ctx, `
+		SELECT (SELECT COUNT(*) FROM incident_stats_users WHERE user_id = 'user-1')
+		     + (SELECT COUNT(*) FROM incident_stats_bucket_users WHERE user_id = 'user-1')
+	`).Scan(&remainingMarks); err != nil {
+		t.Fatalf("query stats marks failed: %v", err)
+	}
+	if remainingMarks != 0 {
+		t.Fatalf("expected stats marks erased, got %d", remainingMarks)
+	}
+
+	entries, _, err := repository.NewAuditRepository(pool).List(ctx, domain.AuditFilter{
+		EntityType: domain.AuditEntityUser,
+		EntityID:   "audit:0123",
+	}, domain.PageRequest{Limit: 10})
+	if err != nil {
+		t.Fatalf("audit list failed: %v", err)
+	}
+	if len(entries) != 1 || entries[0].Action != domain.AuditActionErase || entries[0].Actor != "dpo" {
+		t.Fatalf("expected erase audit record, got %+v", entries)
+	}
+	if strings.Contains(string(entries[0].After), "user-1") {
+		t.Fatalf("expected raw user id kept out of audit log, got %s", entries[0].After)
+	}
+}
+
+func TestRedisWebhookQueue_UserJobs(t *testing.T) {
+	client := testRedis(t)
+	defer func() {
+		_ = client.Close()
+	}()
+
+	queue := repository.NewWebhookQueue(client)
+	ctx := context.Background()
+
+	jobs := []domain.WebhookJob{
+		{TenantID: "city-a", Payload: domain.WebhookPayload{CheckID: "c1", UserID: "user-1"}},
+		{TenantID: "city-a", Payload: domain.WebhookPayload{CheckID: "c2", UserID: "user-2"}},
+		{TenantID: "city-b", Payload: domain.WebhookPayload{CheckID: "c3", UserID: "user-1"}},
+		{TenantID: "city-a", Payload: domain.WebhookPayload{CheckID: "c4", UserID: "user-1"}},
+	}
+	for _, job := range jobs {
+		if err := queue.Enqueue(ctx, job); err != nil {
+			t.Fatalf("enqueue failed: %v", err)
+		}
+	}
+
+	// Отложенные повторы видны и удаляются вместе с очередью
+	delayed := []domain.WebhookJob{
+		{TenantID: "city-a", Payload: domain.WebhookPayload{CheckID: "c5", UserID: "user-1"}, Attempt: 1},
+		{TenantID: "city-a", Payload: domain.WebhookPayload{CheckID: "c6", UserID: "user-2"}, Attempt: 1},
+	}
+	for _, job := range delayed {
+		if err := queue.Retry(ctx, job, time.Now().Add(time.Hour)); err != nil {
+			t.Fatalf("retry failed: %v", err)
+		}
+	}
+
+	found, err := queue.UserJobs(ctx, "city-a", "user-1")
+	if err != nil || len(found) != 3 {
+		t.Fatalf("expected 2 queued jobs and 1 retry, got %+v (%v)", found, err)
+	}
+
+	removed, err := queue.RemoveUserJobs(ctx, "city-a", "user-1")
+	if err != nil || removed != 3 {
+		t.Fatalf("expected 3 removed jobs, got %d (%v)", removed, err)
+	}
+	if found, err := queue.UserJobs(ctx, "city-a", "user-2"); err != nil || len(found) != 2 {
+		t.Fatalf("expected other user's job and retry kept, got %+v (%v)", found, err)
+	}
+
+	remaining := map[string]bool{}
+	for {
+		job, ok, err := queue.Dequeue(ctx, 100*time.Millisecond)
+		if err != nil {
+			t.Fatalf("dequeue failed: %v", err)
+		}
+		if !ok {
+			break
+		}
+		remaining[job.Payload.CheckID] = true
+	}
+	if len(remaining) != 2 || !remaining["c2"] || !remaining["c3"] {
+		t.Fatalf("expected other jobs kept, got %v", remaining)
+	}
+}
+
+func TestRedisWebhookQueue_RetryPromotedWhenDue(t *testing.T) {
+	client := testRedis(t)
+	defer func() {
+		_ = client.Close()
+	}()
+
+	queue := repository.NewWebhookQueue(client)
+	ctx := context.Background()
+
+	due := domain.WebhookJob{TenantID: "city-a", Payload: domain.WebhookPayload{CheckID: "due", UserID: "user-1"}, Attempt: 1}
+	later := domain.WebhookJob{TenantID: "city-a", Payload: domain.WebhookPayload{CheckID: "later", UserID: "user-1"}, Attempt: 1}
+	if err := queue.Retry(ctx, due, time.Now().Add(-time.Second)); err != nil {
+		t.Fatalf("retry failed: %v", err)
+	}
+	if err := queue.Retry(ctx, later, time.Now().Add(time.Hour)); err != nil {
+		t.Fatalf("retry failed: %v", err)
+	}
+
+	job, ok, err := queue.Dequeue(ctx, 100*time.Millisecond)
+	if err != nil || !ok || job.Payload.CheckID != "due" || job.Attempt != 1 {
+		t.Fatalf("expected due retry dequeued, got %+v / %v (%v)", job, ok, err)
+	}
+	if job, ok, err := queue.Dequeue(ctx, 100*time.Millisecond); err != nil || ok {
+		t.Fatalf("expected future retry to wait, got %+v (%v)", job, err)
+	}
+	if found, err := queue.UserJobs(ctx, "city-a", "user-1"); err != nil || len(found) != 1 || found[0].Payload.CheckID != "later" {
+		t.Fatalf("expected future retry kept, got %+v (%v)", found, err)
+	}
+}
@@ -0,0 +1,201 @@
+//go:build integration
+
+package integration
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/ruslanuskembaev/geo-alerts-system/internal/domain"
+	"github.com/ruslanuskembaev/geo-alerts-system/internal/repository"
+)
+
+func TestWebhookOutboxRepository_DispatchAndPrune(t *testing.T) {
+	pool := testDB(t)
+	defer pool.Close()
+	truncateTables(t, pool)
+
+	ctx := domain.WithTenant(context.Background(), "city-a")
+	checkRepo := repository.NewLocationCheckRepository(pool)
+	outboxRepo := repository.NewWebhookOutboxRepository(pool)
+
+	now := time.Now().UTC()
+	checkIDs := make([]string, 0, 3)
+	for i := 0; i < 3; i++ {
+		check := domain.LocationCheck{
+			ID:             uuid.New().String(),
+			UserID:         "user-1",
+			Latitude:       10,
+			Longitude:      10,
+			IsInDangerZone: true,
+			CheckedAt:      now,
+		}
+		job := &domain.WebhookJob{
+			Payload:   domain.WebhookPayload{CheckID: check.ID, UserID: check.UserID, IsInDangerZone: true},
+			CreatedAt: now,
+		}
+		if err := checkRepo.Create(ctx, check, nil, job); err != nil {
+			t.Fatalf("create check failed: %v", err)
+		}
+		checkIDs = append(checkIDs, check.ID)
+	}
+
+	// Задача не записывается, если проверка не сохранилась
+	if err := checkRepo.Create(ctx, domain.LocationCheck{ID: "not-a-uuid", CheckedAt: now}, nil,
+		&domain.WebhookJob{CreatedAt: now}); err == nil {
+		t.Fatalf("expected invalid check to fail")
+	}
+
+	sendErr := errors.New("redis down")
+	sent := make([]domain.WebhookJob, 0)
+	dispatched, err := outboxRepo.Dispatch(context.Background(), 10, func(job domain.WebhookJob) error {
+		if len(sent) == 2 {
+			return sendErr
+		}
+		sent = append(sent, job)
+		return nil
+	})
+	if !errors.Is(err, sendErr) || dispatched != 2 {
+		t.Fatalf("expected 2 jobs dispatched before failure, got %d, %v", dispatched, err)
+	}
+	if sent[0].Payload.CheckID != checkIDs[0] || sent[1].Payload.CheckID != checkIDs[1] || sent[0].TenantID != "city-a" {
+		t.Fatalf("expected jobs in outbox order with tenant, got %+v", sent)
+	}
+
+	sent = sent[:0]
+	dispatched, err = outboxRepo.Dispatch(context.Background(), 10, func(job domain.WebhookJob) error {
+		sent = append(sent, job)
+		return nil
+	})
+	if err != nil || dispatched != 1 || sent[0].Payload.CheckID != checkIDs[2] {
+		t.Fatalf("expected only remaining job dispatched, got %d %+v, %v", dispatched, sent, err)
+	}
+
+	// После передачи в очередь user_id и координаты в outbox не остаются
+	var withPayload int
+	if err := pool.QueryRow(ctx, `SELECT COUNT(*) FROM webhook_outbox WHERE job->'payload' IS NOT NULL`).Scan(&withPayload); err != nil {
+		t.Fatalf("query outbox failed: %v", err)
+	}
+	if withPayload != 0 {
+		t.Fatalf("expected payload erased on dispatch, got %d rows with payload", withPayload)
+	}
+
+	if pruned, err := outboxRepo.PruneDispatched(context.Background(), now.Add(-time.Hour)); err != nil || pruned != 0 {
+		t.Fatalf("expected nothing pruned before dispatch time, got %d, %v", pruned, err)
+	}
+	if pruned, err := outboxRepo.PruneDispatched(context.Background(), time.Now().UTC().Add(time.Minute)); err != nil || pruned != 3 {
+		t.Fatalf("expected dispatched jobs pruned, got %d, %v", pruned, err)
+	}
+}
+
+func TestWebhookOutboxRepository_ConcurrentDispatchSkipsLockedRows(t *testing.T) {
+	pool := testDB(t)
+	defer pool.Close()
+	truncateTables(t, pool)
+
+	ctx := context.Background()
+	checkRepo := repository.NewLocationCheckRepository(pool)
+	outboxRepo := repository.NewWebhookOutboxRepository(pool)
+
+	now := time.Now().UTC()
+	for i := 0; i < 2; i++ {
+		check := domain.LocationCheck{ID: uuid.New().String(), UserID: "user-1", IsInDangerZone: true, CheckedAt: now}
+		job := &domain.WebhookJob{Payload: domain.WebhookPayload{CheckID: check.ID}, CreatedAt: now}
+		if err := checkRepo.Create(ctx, check, nil, job); err != nil {
+			t.Fatalf("create check failed: %v", err)
+		}
+	}
+
+	// Пока первая реплика держит строку, вторая забирает следующую
+	var inner []string
+	outer, err := outboxRepo.Dispatch(ctx, 1, func(job domain.WebhookJob) error {
+		_, err := outboxRepo.Dispatch(ctx, 10, func(other domain.WebhookJob) error {
+			inner = append(inner, other.Payload.CheckID)
+			return nil
+		})
+		if len(inner) == 1 && inner[0] == job.Payload.CheckID {
+			t.Fatalf("expected locked job skipped")
+		}
+		return err
+	})
+	if err != nil || outer != 1 || len(inner) != 1 {
+		t.Fatalf("expected one job per replica, got outer=%d inner=%v, %v", outer, inner, err)
+	}
+}
+
+func TestUserDataRepository_EraseRemovesOutbox(t *testing.T) {
+	pool := testDB(t)
+	defer pool.Close()
+	truncateTables(t, pool)
+
+	ctx := domain.WithActor(context.Background(), "dpo")
+	checkRepo := repository.NewLocationCheckRepository(pool)
+	outboxRepo := repository.NewWebhookOutboxRepository(pool)
+	userDataRepo := repository.NewUserDataRepository(pool)
+
+	now := time.Now().UTC()
+	for _, userID := range []string{"user-1", "user-1", "user-2"} {
+		check := domain.LocationCheck{ID: uuid.New().String(), UserID: userID, IsInDangerZone: true, CheckedAt: now}
+		job := &domain.WebhookJob{Payload: domain.WebhookPayload{CheckID: check.ID, UserID: userID}, CreatedAt: now}
+		if err := checkRepo.Create(ctx, check, nil, job); err != nil {
+			t.Fatalf("create check failed: %v", err)
+		}
+	}
+	if _, err := outboxRepo.Dispatch(ctx, 1, func(domain.WebhookJob) error { return nil }); err != nil {
+		t.Fatalf("dispatch failed: %v", err)
+	}
+
+	// Задачи без сохранённой проверки находятся по user_id нагрузки: проверка вне зон
+	// в режиме danger only и ретроактивное оповещение
+	dangerOnly := repository.NewLocationCheckRepository(pool).WithPrivacy(domain.LocationPrivacy{DangerOnly: true})
+	safe := domain.LocationCheck{ID: uuid.New().String(), UserID: "user-1", CheckedAt: now}
+	if err := dangerOnly.Create(ctx, safe, nil, &domain.WebhookJob{
+		Payload: domain.WebhookPayload{CheckID: safe.ID, UserID: "user-1"}, CreatedAt: now,
+	}); err != nil {
+		t.Fatalf("create unstored check failed: %v", err)
+	}
+	incident, err := repository.NewIncidentRepository(pool).Create(ctx, domain.CreateIncidentRequest{
+		Title: "Flood", Severity: domain.SeverityHigh, Latitude: 10, Longitude: 10, RadiusMeters: 1000,
+	})
+	if err != nil {
+		t.Fatalf("create incident failed: %v", err)
+	}
+	retroRepo := repository.NewRetroactiveAlertRepository(pool)
+	if err := retroRepo.Request(ctx, incident.ID, now.Add(-time.Hour)); err != nil {
+		t.Fatalf("request retroactive alerts failed: %v", err)
+	}
+	requests, err := retroRepo.Claim(ctx, 10, time.Minute)
+	if err != nil || len(requests) != 1 {
+		t.Fatalf("expected one claimed request, got %+v (%v)", requests, err)
+	}
+	if _, err := retroRepo.Complete(ctx, requests[0], []domain.WebhookJob{{
+		TenantID:  domain.DefaultTenantID,
+		Payload:   domain.WebhookPayload{CheckID: uuid.New().String(), UserID: "user-1", Retroactive: true},
+		CreatedAt: now,
+	}}); err != nil {
+		t.Fatalf("complete retroactive request failed: %v", err)
+	}
+
+	erasure := &domain.UserErasure{TenantID: domain.DefaultTenantID, UserID: "user-1", ErasedAt: now}
+	if err := userDataRepo.Erase(ctx, []string{"user-1"}, erasure); err != nil {
+		t.Fatalf("erase failed: %v", err)
+	}
+	if erasure.QueuedWebhooks != 3 {
+		t.Fatalf("expected three pending outbox jobs counted, got %+v", erasure)
+	}
+
+	var left []string
+	if _, err := outboxRepo.Dispatch(ctx, 10, func(job domain.WebhookJob) error {
+		left = append(left, job.Payload.UserID)
+		return nil
+	}); err != nil {
+		t.Fatalf("dispatch failed: %v", err)
+	}
+	if len(left) != 1 || left[0] != "user-2" {
+		t.Fatalf("expected only other user's job left, got %v", left)
+	}
+}
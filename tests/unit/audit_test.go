@@ -0,0 +1,79 @@
+package unit
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/ruslanuskembaev/geo-alerts-system/internal/domain"
+	"github.com/ruslanuskembaev/geo-alerts-system/internal/handler"
+)
+
+func TestDiffIncidents_OnlyChangedFields(t *testing.T) {
+	before := &domain.Incident{Title: "Fire", Severity: domain.SeverityLow, RadiusMeters: 500, IsActive: true}
+	after := *before
+	after.Severity = domain.SeverityHigh
+	after.RadiusMeters = 1200
+
+	diff := domain.DiffIncidents(before, &after)
+	if len(diff) != 2 {
+		t.Fatalf("expected 2 changed fields, got %v", diff)
+	}
+	if diff["severity"].Old != domain.SeverityLow || diff["severity"].New != domain.SeverityHigh {
+		t.Fatalf("unexpected severity change: %+v", diff["severity"])
+	}
+	if diff["radius_meters"].Old != 500 || diff["radius_meters"].New != 1200 {
+		t.Fatalf("unexpected radius change: %+v", diff["radius_meters"])
+	}
+}
+
+func TestDiffIncidents_CreateHasAllSetFields(t *testing.T) {
+	diff := domain.DiffIncidents(nil, &domain.Incident{Title: "Fire", RadiusMeters: 100, IsActive: true})
+	if _, ok := diff["title"]; !ok {
+		t.Fatalf("expected title in create diff")
+	}
+	if _, ok := diff["is_active"]; !ok {
+		t.Fatalf("expected is_active in create diff")
+	}
+}
+
+func TestActorFromContext_DefaultsToSystem(t *testing.T) {
+	if actor := domain.ActorFromContext(context.Background()); actor != domain.SystemActor {
+		t.Fatalf("expected system actor, got %s", actor)
+	}
+	if actor := domain.ActorFromContext(domain.WithActor(context.Background(), "operator")); actor != "operator" {
+		t.Fatalf("expected operator actor, got %s", actor)
+	}
+}
+
+func TestAuthMiddleware_ActorDerivedFromKey(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	var actor string
+	r := gin.New()
+	r.GET("/admin", handler.AuthMiddleware(map[string]string{"city-a": "key-a"}), func(c *gin.Context) {
+		actor = domain.ActorFromContext(c.Request.Context())
+		c.Status(http.StatusNoContent)
+	})
+
+	tests := map[string]string{
+		"":               "api_key:city-a",
+		"  ":             "api_key:city-a",
+		"operator-1":     "api_key:city-a/operator-1",
+		"api_key:city-b": "api_key:city-a/api_key:city-b",
+	}
+	for header, expected := range tests {
+		req := httptest.NewRequest(http.MethodGet, "/admin", nil)
+		req.Header.Set("X-API-Key", "key-a")
+		if header != "" {
+			req.Header.Set("X-Actor", header)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		if w.Code != http.StatusNoContent || actor != expected {
+			t.Fatalf("X-Actor %q: expected actor %q, got %q (%d)", header, expected, actor, w.Code)
+		}
+	}
+}
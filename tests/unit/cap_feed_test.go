@@ -0,0 +1,140 @@
+package unit
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ruslanuskembaev/geo-alerts-system/internal/domain"
+	svc "github.com/ruslanuskembaev/geo-alerts-system/internal/service"
+)
+
+// capFeedServer локальная Atom-лента: одна запись со встроенным CAP, другая со ссылкой на документ
+type capFeedServer struct {
+	mu            sync.Mutex
+	linkedUpdated string
+	linkedFetches int
+}
+
+func (s *capFeedServer) handler(t *testing.T) http.Handler {
+	area := `<area><areaDesc>Zone</areaDesc><circle>55.75,37.61 1</circle></area>`
+	mux := http.NewServeMux()
+	mux.HandleFunc("/feed", func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		updated := s.linkedUpdated
+		s.mu.Unlock()
+
+		embedded := capDocument("embedded-1", "Alert", "", "Actual", futureCAPTime(), area)
+		embedded = embedded[strings.Index(embedded, "<alert"):]
+		w.Header().Set("Content-Type", "application/atom+xml")
+		fmt.Fprintf(w, `<?xml version="1.0" encoding="UTF-8"?>
+<feed xmlns="http://www.w3.org/2005/Atom">
+  <title>Civil defence alerts</title>
+  <entry>
+    <id>urn:embedded-1</id>
+    <updated>2026-10-19T07:00:00Z</updated>
+    <content type="text/xml">%s</content>
+  </entry>
+  <entry>
+    <id>urn:linked-1</id>
+    <updated>%s</updated>
+    <link rel="alternate" type="text/html" href="/alerts/linked-1.html"/>
+    <link rel="related" type="application/cap+xml" href="/alerts/linked-1.xml"/>
+  </entry>
+</feed>`, embedded, updated)
+	})
+	mux.HandleFunc("/alerts/linked-1.xml", func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		s.linkedFetches++
+		s.mu.Unlock()
+		w.Header().Set("Content-Type", "application/cap+xml")
+		fmt.Fprint(w, capDocument("linked-1", "Alert", "", "Actual", futureCAPTime(), area))
+	})
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		t.Errorf("unexpected request %s", r.URL.Path)
+		http.NotFound(w, r)
+	})
+	return mux
+}
+
+func TestCAPFeedPoller_IngestsNewAndUpdatedEntries(t *testing.T) {
+	feed := &capFeedServer{linkedUpdated: "2026-10-19T07:00:00Z"}
+	server := httptest.NewServer(feed.handler(t))
+	defer server.Close()
+
+	var tenants, actors []string
+	repo := &fakeCAPRepo{
+		applyFn: func(ctx context.Context, alert domain.CAPAlert) (*domain.CAPIngestResult, error) {
+			tenants = append(tenants, domain.TenantFromContext(ctx))
+			actors = append(actors, domain.ActorFromContext(ctx))
+			return &domain.CAPIngestResult{Identifier: alert.Identifier, Status: domain.CAPIngestApplied}, nil
+		},
+	}
+	poller := svc.NewCAPFeedPoller(svc.NewCAPService(repo, &fakeIncidentCache{}), server.URL+"/feed", "city-a", time.Minute, time.Second)
+
+	results, err := poller.RunOnce(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected poll error: %v", err)
+	}
+	if len(results) != 2 || results[0].Identifier != "embedded-1" || results[1].Identifier != "linked-1" {
+		t.Fatalf("expected embedded and linked alerts, got %+v", results)
+	}
+	if tenants[0] != "city-a" || actors[0] != "cap_feed" {
+		t.Fatalf("expected feed tenant and actor, got %v / %v", tenants, actors)
+	}
+
+	// Без изменений в ленте ничего не принимается и ссылки не загружаются повторно
+	results, err = poller.RunOnce(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected poll error: %v", err)
+	}
+	if len(results) != 0 || feed.linkedFetches != 1 {
+		t.Fatalf("expected no new entries, got %d results and %d fetches", len(results), feed.linkedFetches)
+	}
+
+	feed.mu.Lock()
+	feed.linkedUpdated = "2026-10-19T08:00:00Z"
+	feed.mu.Unlock()
+	results, err = poller.RunOnce(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected poll error: %v", err)
+	}
+	if len(results) != 1 || results[0].Identifier != "linked-1" || feed.linkedFetches != 2 {
+		t.Fatalf("expected updated entry to be re-ingested, got %+v", results)
+	}
+}
+
+func TestCAPFeedPoller_AcceptsPlainCAPDocument(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, capDocument("single-1", "Alert", "", "Actual", futureCAPTime(),
+			`<area><areaDesc>Zone</areaDesc><circle>55.75,37.61 1</circle></area>`))
+	}))
+	defer server.Close()
+
+	repo := &fakeCAPRepo{}
+	poller := svc.NewCAPFeedPoller(svc.NewCAPService(repo, &fakeIncidentCache{}), server.URL, domain.DefaultTenantID, time.Minute, time.Second)
+	results, err := poller.RunOnce(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected poll error: %v", err)
+	}
+	if len(results) != 1 || len(repo.applied) != 1 || repo.applied[0].Identifier != "single-1" {
+		t.Fatalf("expected single CAP document to be ingested, got %+v", results)
+	}
+}
+
+func TestCAPFeedPoller_FeedErrors(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "unavailable", http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	poller := svc.NewCAPFeedPoller(svc.NewCAPService(&fakeCAPRepo{}, &fakeIncidentCache{}), server.URL, domain.DefaultTenantID, time.Minute, time.Second)
+	if _, err := poller.RunOnce(context.Background()); err == nil {
+		t.Fatalf("expected error for unavailable feed")
+	}
+}
@@ -0,0 +1,227 @@
+package unit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/ruslanuskembaev/geo-alerts-system/internal/domain"
+	"github.com/ruslanuskembaev/geo-alerts-system/internal/handler"
+	svc "github.com/ruslanuskembaev/geo-alerts-system/internal/service"
+)
+
+// capDocument собирает CAP 1.2 документ с одним <info>
+func capDocument(identifier, msgType, references, status, expires, areas string) string {
+	return fmt.Sprintf(`<?xml version="1.0" encoding="UTF-8"?>
+<alert xmlns="urn:oasis:names:tc:emergency:cap:1.2">
+  <identifier>%s</identifier>
+  <sender>mchs@example.org</sender>
+  <sent>2026-10-19T10:00:00+03:00</sent>
+  <status>%s</status>
+  <msgType>%s</msgType>
+  <scope>Public</scope>
+  <references>%s</references>
+  <info>
+    <category>Met</category>
+    <event>Storm</event>
+    <urgency>Immediate</urgency>
+    <severity>Severe</severity>
+    <certainty>Observed</certainty>
+    <expires>%s</expires>
+    <headline>Storm warning</headline>
+    <description>Wind gusts up to 30 m/s</description>
+    %s
+  </info>
+</alert>`, identifier, status, msgType, references, expires, areas)
+}
+
+func futureCAPTime() string {
+	return time.Now().Add(6 * time.Hour).UTC().Format(time.RFC3339)
+}
+
+func TestCAPIngest_MapsCirclesAndPolygons(t *testing.T) {
+	repo := &fakeCAPRepo{}
+	cache := &fakeIncidentCache{}
+	service := svc.NewCAPService(repo, cache)
+
+	areas := `<area><areaDesc>Moscow centre</areaDesc><circle>55.75,37.61 2.5</circle></area>
+    <area><areaDesc>Lake</areaDesc><polygon>55.0,37.0 55.0,37.1 55.1,37.1 55.1,37.0 55.0,37.0</polygon></area>`
+	doc := capDocument("alert-1", "Alert", "", "Actual", futureCAPTime(), areas)
+
+	result, err := service.Ingest(context.Background(), strings.NewReader(doc))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result.Status != domain.CAPIngestApplied || cache.invalidateCalls != 1 {
+		t.Fatalf("expected applied alert and cache invalidation, got %+v / %d", result, cache.invalidateCalls)
+	}
+
+	alert := repo.applied[0]
+	if alert.Identifier != "alert-1" || alert.Sender != "mchs@example.org" || len(alert.Incidents) != 2 {
+		t.Fatalf("unexpected alert: %+v", alert)
+	}
+	circle, polygon := alert.Incidents[0], alert.Incidents[1]
+	if circle.RadiusMeters != 2500 || circle.Latitude != 55.75 || circle.Severity != domain.SeverityHigh {
+		t.Fatalf("unexpected circle incident: %+v", circle)
+	}
+	if circle.Title != "Storm warning — Moscow centre" || circle.ExpiresAt == nil {
+		t.Fatalf("expected title with area and expiry, got %+v", circle)
+	}
+	if polygon.Latitude < 55.04 || polygon.Latitude > 55.06 || polygon.RadiusMeters < 6000 || polygon.RadiusMeters > 8000 {
+		t.Fatalf("expected polygon centroid and enclosing radius, got %+v", polygon)
+	}
+}
+
+func TestCAPIngest_RejectsOversizedArea(t *testing.T) {
+	repo := &fakeCAPRepo{}
+	service := svc.NewCAPService(repo, &fakeIncidentCache{})
+
+	areas := `<area><areaDesc>Region</areaDesc><circle>55.75,37.61 250</circle></area>
+    <area><areaDesc>City</areaDesc><circle>55.75,37.61 5</circle></area>`
+	_, err := service.Ingest(context.Background(), strings.NewReader(capDocument("alert-big", "Alert", "", "Actual", futureCAPTime(), areas)))
+	if !errors.Is(err, svc.ErrCAPAreaTooLarge) || !errors.Is(err, svc.ErrInvalidCAPAlert) {
+		t.Fatalf("expected oversized area rejected, got %v", err)
+	}
+	if !strings.Contains(err.Error(), "area 1") || !strings.Contains(err.Error(), "250000") {
+		t.Fatalf("expected the oversized area named in the error, got %v", err)
+	}
+	if len(repo.applied) != 0 {
+		t.Fatalf("expected nothing applied, got %+v", repo.applied)
+	}
+}
+
+func TestCAPHandler_OversizedAreaIsUnprocessable(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.POST("/incidents/cap", handler.NewCAPHandler(svc.NewCAPService(&fakeCAPRepo{}, &fakeIncidentCache{})).Ingest)
+
+	for doc, expected := range map[string]int{
+		capDocument("alert-big", "Alert", "", "Actual", futureCAPTime(),
+			`<area><areaDesc>Region</areaDesc><circle>55.75,37.61 250</circle></area>`): http.StatusUnprocessableEntity,
+		capDocument("alert-bad", "Alert", "", "Actual", futureCAPTime(),
+			`<area><areaDesc>Zone</areaDesc><circle>95,37.61 1</circle></area>`): http.StatusBadRequest,
+	} {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/incidents/cap", strings.NewReader(doc)))
+		if w.Code != expected {
+			t.Fatalf("expected %d, got %d: %s", expected, w.Code, w.Body.String())
+		}
+	}
+}
+
+func TestCAPIngest_PolygonAcrossAntimeridian(t *testing.T) {
+	repo := &fakeCAPRepo{}
+	service := svc.NewCAPService(repo, &fakeIncidentCache{})
+
+	area := `<area><areaDesc>Bering Strait</areaDesc><polygon>65.0,179.9 65.0,-179.9 65.1,-179.9 65.1,179.9 65.0,179.9</polygon></area>`
+	if _, err := service.Ingest(context.Background(), strings.NewReader(capDocument("alert-date-line", "Alert", "", "Actual", futureCAPTime(), area))); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	zone := repo.applied[0].Incidents[0]
+	if math.Abs(math.Abs(zone.Longitude)-180) > 0.01 || zone.Latitude < 65.04 || zone.Latitude > 65.06 {
+		t.Fatalf("expected centre on the antimeridian, got %.4f,%.4f", zone.Latitude, zone.Longitude)
+	}
+	if zone.RadiusMeters > 10000 {
+		t.Fatalf("expected a small enclosing radius, got %d", zone.RadiusMeters)
+	}
+}
+
+func TestCAPIngest_SkipsNonActualAndExpired(t *testing.T) {
+	repo := &fakeCAPRepo{}
+	service := svc.NewCAPService(repo, &fakeIncidentCache{})
+	area := `<area><areaDesc>Zone</areaDesc><circle>55.75,37.61 1</circle></area>`
+
+	for name, doc := range map[string]string{
+		"exercise": capDocument("alert-2", "Alert", "", "Exercise", futureCAPTime(), area),
+		"expired":  capDocument("alert-3", "Alert", "", "Actual", "2020-01-01T00:00:00Z", area),
+		"ack":      capDocument("alert-4", "Ack", "", "Actual", futureCAPTime(), area),
+	} {
+		result, err := service.Ingest(context.Background(), strings.NewReader(doc))
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", name, err)
+		}
+		if result.Status != domain.CAPIngestIgnored || result.Reason == "" {
+			t.Fatalf("%s: expected ignored result with reason, got %+v", name, result)
+		}
+	}
+	if len(repo.applied) != 0 {
+		t.Fatalf("expected nothing applied, got %d", len(repo.applied))
+	}
+}
+
+func TestCAPIngest_UpdateAndCancelCarryReferences(t *testing.T) {
+	repo := &fakeCAPRepo{}
+	service := svc.NewCAPService(repo, &fakeIncidentCache{})
+	references := "mchs@example.org,alert-1,2026-10-19T10:00:00+03:00"
+
+	update := capDocument("alert-5", "Update", references, "Actual", futureCAPTime(),
+		`<area><areaDesc>Zone</areaDesc><circle>55.75,37.61 1</circle></area>`)
+	if _, err := service.Ingest(context.Background(), strings.NewReader(update)); err != nil {
+		t.Fatalf("unexpected update error: %v", err)
+	}
+	// Cancel не обязан содержать area
+	cancel := capDocument("alert-6", "Cancel", references, "Actual", futureCAPTime(), "")
+	if _, err := service.Ingest(context.Background(), strings.NewReader(cancel)); err != nil {
+		t.Fatalf("unexpected cancel error: %v", err)
+	}
+
+	if len(repo.applied) != 2 {
+		t.Fatalf("expected 2 applied messages, got %d", len(repo.applied))
+	}
+	for _, alert := range repo.applied {
+		if len(alert.References) != 1 || alert.References[0].Identifier != "alert-1" {
+			t.Fatalf("unexpected references: %+v", alert.References)
+		}
+	}
+	if repo.applied[1].MsgType != domain.CAPMsgTypeCancel || len(repo.applied[1].Incidents) != 0 {
+		t.Fatalf("unexpected cancel: %+v", repo.applied[1])
+	}
+}
+
+func TestCAPIngest_InvalidDocuments(t *testing.T) {
+	service := svc.NewCAPService(&fakeCAPRepo{}, &fakeIncidentCache{})
+
+	for name, doc := range map[string]string{
+		"not xml": "{}",
+		"update without references": capDocument("alert-7", "Update", "", "Actual", futureCAPTime(),
+			`<area><areaDesc>Zone</areaDesc><circle>55.75,37.61 1</circle></area>`),
+		"geocode only": capDocument("alert-8", "Alert", "", "Actual", futureCAPTime(),
+			`<area><areaDesc>Region</areaDesc><geocode><valueName>OKATO</valueName><value>45</value></geocode></area>`),
+		"bad circle": capDocument("alert-9", "Alert", "", "Actual", futureCAPTime(),
+			`<area><areaDesc>Zone</areaDesc><circle>95,37.61 1</circle></area>`),
+	} {
+		_, err := service.Ingest(context.Background(), strings.NewReader(doc))
+		if !errors.Is(err, svc.ErrInvalidCAPAlert) {
+			t.Fatalf("%s: expected ErrInvalidCAPAlert, got %v", name, err)
+		}
+	}
+}
+
+func TestCAPIngest_DuplicateDoesNotInvalidateCache(t *testing.T) {
+	repo := &fakeCAPRepo{
+		applyFn: func(ctx context.Context, alert domain.CAPAlert) (*domain.CAPIngestResult, error) {
+			return &domain.CAPIngestResult{Identifier: alert.Identifier, Status: domain.CAPIngestDuplicate}, nil
+		},
+	}
+	cache := &fakeIncidentCache{}
+	service := svc.NewCAPService(repo, cache)
+
+	doc := capDocument("alert-1", "Alert", "", "Actual", futureCAPTime(),
+		`<area><areaDesc>Zone</areaDesc><circle>55.75,37.61 1</circle></area>`)
+	result, err := service.Ingest(context.Background(), strings.NewReader(doc))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result.Status != domain.CAPIngestDuplicate || cache.invalidateCalls != 0 {
+		t.Fatalf("expected duplicate without invalidation, got %+v / %d", result, cache.invalidateCalls)
+	}
+}
@@ -3,24 +3,35 @@ package unit
 import (
 	"context"
 	"errors"
+	"fmt"
+	"sync"
 	"time"
 
 	"github.com/ruslanuskembaev/geo-alerts-system/internal/domain"
 )
 
 type fakeIncidentRepo struct {
-	createFn        func(context.Context, domain.CreateIncidentRequest) (*domain.Incident, error)
-	getByIDFn       func(context.Context, string) (*domain.Incident, error)
-	listFn          func(context.Context, int, int) ([]*domain.Incident, int, error)
-	updateFn        func(context.Context, string, domain.UpdateIncidentRequest) (*domain.Incident, error)
-	deactivateFn    func(context.Context, string) error
-	listActiveFn    func(context.Context) ([]*domain.Incident, error)
-	createCalls     int
-	getByIDCalls    int
-	listCalls       int
-	updateCalls     int
-	deactivateCalls int
-	listActiveCalls int
+	createFn         func(context.Context, domain.CreateIncidentRequest) (*domain.Incident, error)
+	createBatchFn    func(context.Context, []domain.CreateIncidentRequest) ([]*domain.Incident, error)
+	getByIDFn        func(context.Context, string) (*domain.Incident, error)
+	listFn           func(context.Context, domain.IncidentFilter, domain.PageRequest) ([]*domain.Incident, domain.PageInfo, error)
+	streamFn         func(context.Context, domain.IncidentFilter, func(*domain.Incident) error) error
+	updateFn         func(context.Context, string, domain.UpdateIncidentRequest, *int) (*domain.Incident, error)
+	deactivateFn     func(context.Context, string, *int) error
+	listActiveFn     func(context.Context) ([]*domain.Incident, error)
+	reactivateFn     func(context.Context, string, *int) (*domain.Incident, error)
+	purgeFn          func(context.Context, string) error
+	purgeBeforeFn    func(context.Context, time.Time) (int, error)
+	expireFn         func(context.Context, time.Time) ([]*domain.Incident, error)
+	lastUpdatedFn    func(context.Context) (time.Time, error)
+	overlappingFn    func(context.Context, domain.ImpactZone) ([]domain.OverlappingIncident, error)
+	createCalls      int
+	createBatchCalls int
+	getByIDCalls     int
+	listCalls        int
+	updateCalls      int
+	deactivateCalls  int
+	listActiveCalls  int
 }
 
 func (f *fakeIncidentRepo) Create(ctx context.Context, req domain.CreateIncidentRequest) (*domain.Incident, error) {
@@ -31,6 +42,14 @@ func (f *fakeIncidentRepo) Create(ctx context.Context, req domain.CreateIncident
 	return nil, errors.New("Create not implemented")
 }
 
+func (f *fakeIncidentRepo) CreateBatch(ctx context.Context, reqs []domain.CreateIncidentRequest) ([]*domain.Incident, error) {
+	f.createBatchCalls++
+	if f.createBatchFn != nil {
+		return f.createBatchFn(ctx, reqs)
+	}
+	return nil, errors.New("CreateBatch not implemented")
+}
+
 func (f *fakeIncidentRepo) GetByID(ctx context.Context, id string) (*domain.Incident, error) {
 	f.getByIDCalls++
 	if f.getByIDFn != nil {
@@ -39,30 +58,79 @@ func (f *fakeIncidentRepo) GetByID(ctx context.Context, id string) (*domain.Inci
 	return nil, errors.New("GetByID not implemented")
 }
 
-func (f *fakeIncidentRepo) List(ctx context.Context, limit, offset int) ([]*domain.Incident, int, error) {
+func (f *fakeIncidentRepo) List(ctx context.Context, filter domain.IncidentFilter, page domain.PageRequest) ([]*domain.Incident, domain.PageInfo, error) {
 	f.listCalls++
 	if f.listFn != nil {
-		return f.listFn(ctx, limit, offset)
+		return f.listFn(ctx, filter, page)
+	}
+	return nil, domain.PageInfo{}, errors.New("List not implemented")
+}
+
+func (f *fakeIncidentRepo) Stream(ctx context.Context, filter domain.IncidentFilter, fn func(*domain.Incident) error) error {
+	if f.streamFn != nil {
+		return f.streamFn(ctx, filter, fn)
 	}
-	return nil, 0, errors.New("List not implemented")
+	return errors.New("Stream not implemented")
 }
 
-func (f *fakeIncidentRepo) Update(ctx context.Context, id string, req domain.UpdateIncidentRequest) (*domain.Incident, error) {
+func (f *fakeIncidentRepo) Update(ctx context.Context, id string, req domain.UpdateIncidentRequest, expectedVersion *int) (*domain.Incident, error) {
 	f.updateCalls++
 	if f.updateFn != nil {
-		return f.updateFn(ctx, id, req)
+		return f.updateFn(ctx, id, req, expectedVersion)
 	}
 	return nil, errors.New("Update not implemented")
 }
 
-func (f *fakeIncidentRepo) Deactivate(ctx context.Context, id string) error {
+func (f *fakeIncidentRepo) Deactivate(ctx context.Context, id string, expectedVersion *int) error {
 	f.deactivateCalls++
 	if f.deactivateFn != nil {
-		return f.deactivateFn(ctx, id)
+		return f.deactivateFn(ctx, id, expectedVersion)
 	}
 	return errors.New("Deactivate not implemented")
 }
 
+func (f *fakeIncidentRepo) Reactivate(ctx context.Context, id string, expectedVersion *int) (*domain.Incident, error) {
+	if f.reactivateFn != nil {
+		return f.reactivateFn(ctx, id, expectedVersion)
+	}
+	return nil, errors.New("Reactivate not implemented")
+}
+
+func (f *fakeIncidentRepo) Purge(ctx context.Context, id string) error {
+	if f.purgeFn != nil {
+		return f.purgeFn(ctx, id)
+	}
+	return errors.New("Purge not implemented")
+}
+
+func (f *fakeIncidentRepo) PurgeDeactivatedBefore(ctx context.Context, before time.Time) (int, error) {
+	if f.purgeBeforeFn != nil {
+		return f.purgeBeforeFn(ctx, before)
+	}
+	return 0, errors.New("PurgeDeactivatedBefore not implemented")
+}
+
+func (f *fakeIncidentRepo) DeactivateExpired(ctx context.Context, now time.Time) ([]*domain.Incident, error) {
+	if f.expireFn != nil {
+		return f.expireFn(ctx, now)
+	}
+	return nil, errors.New("DeactivateExpired not implemented")
+}
+
+func (f *fakeIncidentRepo) LastUpdatedAt(ctx context.Context) (time.Time, error) {
+	if f.lastUpdatedFn != nil {
+		return f.lastUpdatedFn(ctx)
+	}
+	return time.Time{}, errors.New("LastUpdatedAt not implemented")
+}
+
+func (f *fakeIncidentRepo) ListOverlapping(ctx context.Context, zone domain.ImpactZone) ([]domain.OverlappingIncident, error) {
+	if f.overlappingFn != nil {
+		return f.overlappingFn(ctx, zone)
+	}
+	return nil, errors.New("ListOverlapping not implemented")
+}
+
 func (f *fakeIncidentRepo) ListActive(ctx context.Context) ([]*domain.Incident, error) {
 	f.listActiveCalls++
 	if f.listActiveFn != nil {
@@ -104,37 +172,115 @@ func (f *fakeIncidentCache) Invalidate(ctx context.Context) error {
 	return nil
 }
 
+type fakeCAPRepo struct {
+	applyFn func(context.Context, domain.CAPAlert) (*domain.CAPIngestResult, error)
+	applied []domain.CAPAlert
+}
+
+func (f *fakeCAPRepo) Apply(ctx context.Context, alert domain.CAPAlert) (*domain.CAPIngestResult, error) {
+	f.applied = append(f.applied, alert)
+	if f.applyFn != nil {
+		return f.applyFn(ctx, alert)
+	}
+	return &domain.CAPIngestResult{
+		Identifier: alert.Identifier,
+		Sender:     alert.Sender,
+		MsgType:    alert.MsgType,
+		Status:     domain.CAPIngestApplied,
+	}, nil
+}
+
 type fakeCheckRepo struct {
-	createFn        func(context.Context, domain.LocationCheck, []string) error
-	statsFn         func(context.Context, time.Time) ([]domain.IncidentStats, error)
+	createFn        func(context.Context, domain.LocationCheck, []string, *domain.WebhookJob) error
+	statsFn         func(context.Context, domain.IncidentStatsQuery) ([]domain.IncidentStats, error)
+	heatmapFn       func(context.Context, domain.HeatmapQuery) ([]domain.HeatmapCell, error)
+	statsSeriesFn   func(context.Context, domain.StatsSeriesQuery) ([]domain.IncidentStatsSeries, error)
+	pruneFn         func(context.Context, time.Time) (int, error)
+	listByUserFn    func(context.Context, []string, domain.UserChecksFilter, domain.PageRequest) ([]domain.UserCheck, domain.PageInfo, error)
+	incidentUsersFn func(context.Context, string, domain.IncidentUsersQuery, domain.PageRequest) ([]domain.IncidentUser, domain.PageInfo, error)
+	latestInZoneFn  func(context.Context, domain.Incident, time.Time) ([]domain.LocationCheck, error)
+	zoneImpactFn    func(context.Context, domain.ImpactZone, time.Time, time.Time) (domain.ImpactCounts, error)
 	createCalls     int
 	statsCalls      int
 	lastCheck       domain.LocationCheck
 	lastIncidentIDs []string
+	lastJob         *domain.WebhookJob
 }
 
-func (f *fakeCheckRepo) Create(ctx context.Context, check domain.LocationCheck, incidentIDs []string) error {
+func (f *fakeCheckRepo) Create(ctx context.Context, check domain.LocationCheck, incidentIDs []string, job *domain.WebhookJob) error {
 	f.createCalls++
 	f.lastCheck = check
 	f.lastIncidentIDs = append([]string(nil), incidentIDs...)
+	f.lastJob = job
 	if f.createFn != nil {
-		return f.createFn(ctx, check, incidentIDs)
+		return f.createFn(ctx, check, incidentIDs, job)
 	}
 	return nil
 }
 
-func (f *fakeCheckRepo) StatsByIncident(ctx context.Context, since time.Time) ([]domain.IncidentStats, error) {
+func (f *fakeCheckRepo) StatsByIncident(ctx context.Context, query domain.IncidentStatsQuery) ([]domain.IncidentStats, error) {
 	f.statsCalls++
 	if f.statsFn != nil {
-		return f.statsFn(ctx, since)
+		return f.statsFn(ctx, query)
+	}
+	return nil, nil
+}
+
+func (f *fakeCheckRepo) Heatmap(ctx context.Context, query domain.HeatmapQuery) ([]domain.HeatmapCell, error) {
+	if f.heatmapFn != nil {
+		return f.heatmapFn(ctx, query)
+	}
+	return nil, nil
+}
+
+func (f *fakeCheckRepo) StatsSeries(ctx context.Context, query domain.StatsSeriesQuery) ([]domain.IncidentStatsSeries, error) {
+	if f.statsSeriesFn != nil {
+		return f.statsSeriesFn(ctx, query)
+	}
+	return nil, nil
+}
+
+func (f *fakeCheckRepo) PruneStatsBucketUsers(ctx context.Context, before time.Time) (int, error) {
+	if f.pruneFn != nil {
+		return f.pruneFn(ctx, before)
+	}
+	return 0, nil
+}
+
+func (f *fakeCheckRepo) ListByUser(ctx context.Context, userIDs []string, filter domain.UserChecksFilter, page domain.PageRequest) ([]domain.UserCheck, domain.PageInfo, error) {
+	if f.listByUserFn != nil {
+		return f.listByUserFn(ctx, userIDs, filter, page)
+	}
+	return nil, domain.PageInfo{}, nil
+}
+
+func (f *fakeCheckRepo) IncidentUsers(ctx context.Context, incidentID string, query domain.IncidentUsersQuery, page domain.PageRequest) ([]domain.IncidentUser, domain.PageInfo, error) {
+	if f.incidentUsersFn != nil {
+		return f.incidentUsersFn(ctx, incidentID, query, page)
+	}
+	return nil, domain.PageInfo{}, nil
+}
+
+func (f *fakeCheckRepo) LatestChecksInZone(ctx context.Context, incident domain.Incident, since time.Time) ([]domain.LocationCheck, error) {
+	if f.latestInZoneFn != nil {
+		return f.latestInZoneFn(ctx, incident, since)
 	}
 	return nil, nil
 }
 
+func (f *fakeCheckRepo) ZoneImpact(ctx context.Context, zone domain.ImpactZone, since, until time.Time) (domain.ImpactCounts, error) {
+	if f.zoneImpactFn != nil {
+		return f.zoneImpactFn(ctx, zone, since, until)
+	}
+	return domain.ImpactCounts{}, nil
+}
+
 type fakeQueue struct {
 	enqueueFn func(context.Context, domain.WebhookJob) error
 	dequeueFn func(context.Context, time.Duration) (*domain.WebhookJob, bool, error)
 	enqueued  []domain.WebhookJob
+	retried   []domain.WebhookJob
+	retryAt   []time.Time
 }
 
 func (f *fakeQueue) Enqueue(ctx context.Context, job domain.WebhookJob) error {
@@ -151,3 +297,361 @@ func (f *fakeQueue) Dequeue(ctx context.Context, timeout time.Duration) (*domain
 	}
 	return nil, false, nil
 }
+
+func (f *fakeQueue) Retry(ctx context.Context, job domain.WebhookJob, at time.Time) error {
+	f.retried = append(f.retried, job)
+	f.retryAt = append(f.retryAt, at)
+	return nil
+}
+
+func (f *fakeQueue) UserJobs(ctx context.Context, tenantID, userID string) ([]domain.WebhookJob, error) {
+	jobs := make([]domain.WebhookJob, 0)
+	for _, job := range append(append([]domain.WebhookJob{}, f.enqueued...), f.retried...) {
+		if job.TenantID == tenantID && job.Payload.UserID == userID {
+			jobs = append(jobs, job)
+		}
+	}
+	return jobs, nil
+}
+
+func (f *fakeQueue) RemoveUserJobs(ctx context.Context, tenantID, userID string) (int, error) {
+	removed := 0
+	keep := func(jobs []domain.WebhookJob) []domain.WebhookJob {
+		kept := jobs[:0]
+		for _, job := range jobs {
+			if job.TenantID != tenantID || job.Payload.UserID != userID {
+				kept = append(kept, job)
+			}
+		}
+		removed += len(jobs) - len(kept)
+		return kept
+	}
+	f.enqueued = keep(f.enqueued)
+	f.retried = keep(f.retried)
+	return removed, nil
+}
+
+type fakeRateLimiter struct {
+	allowFn func(context.Context, string, int, time.Duration) (bool, time.Duration, error)
+	keys    []string
+	reset   []string
+}
+
+func (f *fakeRateLimiter) Allow(ctx context.Context, key string, limit int, window time.Duration) (bool, time.Duration, error) {
+	f.keys = append(f.keys, key)
+	if f.allowFn != nil {
+		return f.allowFn(ctx, key, limit, window)
+	}
+	return true, 0, nil
+}
+
+func (f *fakeRateLimiter) Reset(ctx context.Context, key string) (bool, error) {
+	f.reset = append(f.reset, key)
+	return true, nil
+}
+
+type fakeTileCache struct {
+	generation int64
+	tiles      map[string][]byte
+	getCalls   int
+	setCalls   int
+}
+
+func (f *fakeTileCache) Generation(ctx context.Context) (int64, error) {
+	return f.generation, nil
+}
+
+func (f *fakeTileCache) Get(ctx context.Context, generation int64, tile domain.TileCoord) ([]byte, bool, error) {
+	f.getCalls++
+	data, ok := f.tiles[fakeTileKey(generation, tile)]
+	return data, ok, nil
+}
+
+func (f *fakeTileCache) Set(ctx context.Context, generation int64, tile domain.TileCoord, data []byte) error {
+	f.setCalls++
+	if f.tiles == nil {
+		f.tiles = make(map[string][]byte)
+	}
+	f.tiles[fakeTileKey(generation, tile)] = data
+	return nil
+}
+
+func fakeTileKey(generation int64, tile domain.TileCoord) string {
+	return fmt.Sprintf("%d:%d/%d/%d", generation, tile.Z, tile.X, tile.Y)
+}
+
+type fakePartitionRepo struct {
+	ensureFn func(context.Context, time.Time, time.Time) ([]string, error)
+	dropFn   func(context.Context, time.Time) ([]string, error)
+}
+
+func (f *fakePartitionRepo) EnsurePartitions(ctx context.Context, from, to time.Time) ([]string, error) {
+	if f.ensureFn != nil {
+		return f.ensureFn(ctx, from, to)
+	}
+	return nil, nil
+}
+
+func (f *fakePartitionRepo) DropPartitionsBefore(ctx context.Context, before time.Time) ([]string, error) {
+	if f.dropFn != nil {
+		return f.dropFn(ctx, before)
+	}
+	return nil, nil
+}
+
+type fakeUserDataRepo struct {
+	checks     []domain.UserCheck
+	deliveries []domain.WebhookDelivery
+	outbox     []domain.WebhookJob
+	exportIDs  []string
+	erased     []string
+	eraseFn    func(context.Context, []string, *domain.UserErasure) error
+}
+
+func (f *fakeUserDataRepo) Export(ctx context.Context, userIDs []string) (*domain.UserDataExport, error) {
+	f.exportIDs = userIDs
+	return &domain.UserDataExport{
+		Checks:            f.checks,
+		WebhookDeliveries: f.deliveries,
+		QueuedWebhooks:    append([]domain.WebhookJob{}, f.outbox...),
+	}, nil
+}
+
+func (f *fakeUserDataRepo) Erase(ctx context.Context, userIDs []string, erasure *domain.UserErasure) error {
+	f.erased = userIDs
+	if f.eraseFn != nil {
+		return f.eraseFn(ctx, userIDs, erasure)
+	}
+	erasure.Checks = len(f.checks)
+	erasure.WebhookDeliveries = len(f.deliveries)
+	return nil
+}
+
+type fakePseudonymRepo struct {
+	raw       []domain.StoredUser
+	converted map[domain.StoredUser][]string
+	err       error
+}
+
+func (f *fakePseudonymRepo) RawUsers(ctx context.Context, limit int) ([]domain.StoredUser, error) {
+	return f.raw[:min(limit, len(f.raw))], nil
+}
+
+func (f *fakePseudonymRepo) Pseudonymize(ctx context.Context, user domain.StoredUser, lookups []string) error {
+	if f.err != nil {
+		return f.err
+	}
+	if f.converted == nil {
+		f.converted = make(map[domain.StoredUser][]string)
+	}
+	f.converted[user] = lookups
+	f.raw = f.raw[1:]
+	return nil
+}
+
+type fakeDeliveryRepo struct {
+	recorded []domain.WebhookDelivery
+}
+
+func (f *fakeDeliveryRepo) Record(ctx context.Context, delivery domain.WebhookDelivery) error {
+	f.recorded = append(f.recorded, delivery)
+	return nil
+}
+
+type fakeOutboxRepo struct {
+	pending       []domain.WebhookJob
+	dispatched    []domain.WebhookJob
+	dispatchCalls int
+	pruneBefore   *time.Time
+}
+
+func (f *fakeOutboxRepo) Dispatch(ctx context.Context, limit int, send func(domain.WebhookJob) error) (int, error) {
+	f.dispatchCalls++
+	count := 0
+	for len(f.pending) > 0 && count < limit {
+		if err := send(f.pending[0]); err != nil {
+			return count, err
+		}
+		f.dispatched = append(f.dispatched, f.pending[0])
+		f.pending = f.pending[1:]
+		count++
+	}
+	return count, nil
+}
+
+func (f *fakeOutboxRepo) PruneDispatched(ctx context.Context, before time.Time) (int, error) {
+	f.pruneBefore = &before
+	return len(f.dispatched), nil
+}
+
+type fakeIdempotencyStore struct {
+	records  map[string]domain.IdempotencyRecord
+	ttls     map[string]time.Duration
+	err      error
+	released int
+}
+
+func newFakeIdempotencyStore() *fakeIdempotencyStore {
+	return &fakeIdempotencyStore{
+		records: make(map[string]domain.IdempotencyRecord),
+		ttls:    make(map[string]time.Duration),
+	}
+}
+
+func (f *fakeIdempotencyStore) Reserve(ctx context.Context, key string, record domain.IdempotencyRecord, ttl time.Duration) (*domain.IdempotencyRecord, bool, error) {
+	if f.err != nil {
+		return nil, false, f.err
+	}
+	if existing, ok := f.records[key]; ok {
+		return &existing, false, nil
+	}
+	f.records[key] = record
+	f.ttls[key] = ttl
+	return nil, true, nil
+}
+
+func (f *fakeIdempotencyStore) Save(ctx context.Context, key string, record domain.IdempotencyRecord, ttl time.Duration) error {
+	f.records[key] = record
+	f.ttls[key] = ttl
+	return nil
+}
+
+func (f *fakeIdempotencyStore) Release(ctx context.Context, key string) error {
+	delete(f.records, key)
+	f.released++
+	return nil
+}
+
+type fakeVersionedCache struct {
+	mu              sync.Mutex
+	versions        map[string]int64
+	blobs           map[string][]*domain.Incident
+	versionCalls    int
+	getVersionCalls int
+	onInvalidate    func(string)
+	onSubscribed    func(bool)
+}
+
+func newFakeVersionedCache() *fakeVersionedCache {
+	return &fakeVersionedCache{
+		versions: make(map[string]int64),
+		blobs:    make(map[string][]*domain.Incident),
+	}
+}
+
+func (f *fakeVersionedCache) blobKey(ctx context.Context, version int64) string {
+	return fmt.Sprintf("%s:%d", domain.TenantFromContext(ctx), version)
+}
+
+func (f *fakeVersionedCache) GetActive(ctx context.Context) ([]*domain.Incident, bool, error) {
+	version, _ := f.Version(ctx)
+	return f.GetVersion(ctx, version)
+}
+
+func (f *fakeVersionedCache) SetActive(ctx context.Context, incidents []*domain.Incident) error {
+	version, _ := f.Version(ctx)
+	return f.SetVersion(ctx, version, incidents)
+}
+
+// Invalidate как в Redis: новая версия и сообщение всем подписчикам
+func (f *fakeVersionedCache) Invalidate(ctx context.Context) error {
+	tenantID := domain.TenantFromContext(ctx)
+	f.mu.Lock()
+	f.versions[tenantID]++
+	onInvalidate := f.onInvalidate
+	f.mu.Unlock()
+	if onInvalidate != nil {
+		onInvalidate(tenantID)
+	}
+	return nil
+}
+
+func (f *fakeVersionedCache) Version(ctx context.Context) (int64, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.versionCalls++
+	return f.versions[domain.TenantFromContext(ctx)], nil
+}
+
+func (f *fakeVersionedCache) GetVersion(ctx context.Context, version int64) ([]*domain.Incident, bool, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.getVersionCalls++
+	incidents, ok := f.blobs[f.blobKey(ctx, version)]
+	return incidents, ok, nil
+}
+
+func (f *fakeVersionedCache) SetVersion(ctx context.Context, version int64, incidents []*domain.Incident) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.blobs[f.blobKey(ctx, version)] = incidents
+	return nil
+}
+
+// WatchInvalidations сразу сообщает о подписке и запоминает обработчики для теста
+func (f *fakeVersionedCache) WatchInvalidations(ctx context.Context, onInvalidate func(string), onSubscribed func(bool)) {
+	f.mu.Lock()
+	f.onInvalidate, f.onSubscribed = onInvalidate, onSubscribed
+	f.mu.Unlock()
+	onSubscribed(true)
+}
+
+// fakeChangeListener выполняет сценарии подключений по очереди; после них ждёт отмены ctx
+type fakeChangeListener struct {
+	attempts []func(onChange func(string)) error
+	calls    int
+}
+
+func (f *fakeChangeListener) Listen(ctx context.Context, onChange func(tenantID string)) error {
+	f.calls++
+	if len(f.attempts) == 0 {
+		<-ctx.Done()
+		return ctx.Err()
+	}
+	attempt := f.attempts[0]
+	f.attempts = f.attempts[1:]
+	return attempt(onChange)
+}
+
+type fakeRetroactiveRepo struct {
+	requestFn func(context.Context, string, time.Time) error
+	requests  []domain.RetroactiveAlertRequest
+	completed []domain.RetroactiveAlertRequest
+	outbox    []domain.WebhookJob
+	batches   int
+}
+
+func (f *fakeRetroactiveRepo) Request(ctx context.Context, incidentID string, since time.Time) error {
+	if f.requestFn != nil {
+		if err := f.requestFn(ctx, incidentID, since); err != nil {
+			return err
+		}
+	}
+	f.requests = append(f.requests, domain.RetroactiveAlertRequest{
+		ID:         int64(len(f.requests) + 1),
+		TenantID:   domain.TenantFromContext(ctx),
+		IncidentID: incidentID,
+		Since:      since,
+	})
+	return nil
+}
+
+func (f *fakeRetroactiveRepo) Claim(ctx context.Context, limit int, lease time.Duration) ([]domain.RetroactiveAlertRequest, error) {
+	if limit > len(f.requests) {
+		limit = len(f.requests)
+	}
+	claimed := f.requests[:limit]
+	f.requests = f.requests[limit:]
+	return claimed, nil
+}
+
+func (f *fakeRetroactiveRepo) Complete(ctx context.Context, req domain.RetroactiveAlertRequest, jobs []domain.WebhookJob) (int, error) {
+	f.completed = append(f.completed, req)
+	f.outbox = append(f.outbox, jobs...)
+	f.batches++
+	return len(jobs), nil
+}
+
+func (f *fakeRetroactiveRepo) PruneAlerts(ctx context.Context, before time.Time) (int, error) {
+	return 0, nil
+}
@@ -0,0 +1,193 @@
+package unit
+
+import (
+	"context"
+	"encoding/xml"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/ruslanuskembaev/geo-alerts-system/internal/domain"
+	"github.com/ruslanuskembaev/geo-alerts-system/internal/handler"
+	svc "github.com/ruslanuskembaev/geo-alerts-system/internal/service"
+)
+
+var feedUpdatedAt = time.Date(2026, 10, 19, 7, 30, 0, 0, time.UTC)
+
+func feedIncidents() []*domain.Incident {
+	expired := time.Now().Add(-time.Minute).UTC()
+	return []*domain.Incident{
+		{ID: "a", TenantID: "default", Title: "Flood on Arbat", Severity: domain.SeverityMedium, Latitude: 55.7494, Longitude: 37.5916, RadiusMeters: 800, IsActive: true, Version: 1, CreatedAt: feedUpdatedAt, UpdatedAt: feedUpdatedAt},
+		{ID: "b", TenantID: "default", Title: "Storm", Severity: domain.SeverityHigh, Latitude: 59.93, Longitude: 30.33, RadiusMeters: 3000, IsActive: true, Version: 3, CreatedAt: feedUpdatedAt.Add(-time.Hour), UpdatedAt: feedUpdatedAt.Add(-time.Minute)},
+		{ID: "c", TenantID: "default", Title: "Expired", Severity: domain.SeverityLow, Latitude: 55, Longitude: 37, RadiusMeters: 100, IsActive: true, Version: 1, CreatedAt: feedUpdatedAt, UpdatedAt: feedUpdatedAt, ExpiresAt: &expired},
+	}
+}
+
+func newFeedRouter(incidents *[]*domain.Incident, lastUpdated *time.Time) *gin.Engine {
+	return newFeedRouterWithProxies(incidents, lastUpdated, nil)
+}
+
+func newFeedRouterWithProxies(incidents *[]*domain.Incident, lastUpdated *time.Time, proxies handler.TrustedProxies) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	repo := &fakeIncidentRepo{
+		listActiveFn: func(ctx context.Context) ([]*domain.Incident, error) {
+			return *incidents, nil
+		},
+		lastUpdatedFn: func(ctx context.Context) (time.Time, error) {
+			return *lastUpdated, nil
+		},
+	}
+	service := svc.NewIncidentService(repo, &fakeIncidentCache{}, &fakeCheckRepo{})
+	h := handler.NewFeedHandler(service, map[string]string{"default": "key"}, "mchs@example.org").
+		WithTrustedProxies(proxies)
+
+	r := gin.New()
+	r.GET("/feeds/:tenant/atom", h.Atom)
+	r.GET("/feeds/:tenant/cap", h.CAP)
+	return r
+}
+
+func getFeed(r http.Handler, path string, headers map[string]string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, path, nil)
+	for name, value := range headers {
+		req.Header.Set(name, value)
+	}
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestFeedHandler_AtomWithGeoRSS(t *testing.T) {
+	incidents := feedIncidents()
+	lastUpdated := feedUpdatedAt
+	rec := getFeed(newFeedRouter(&incidents, &lastUpdated), "/feeds/default/atom", nil)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", rec.Code)
+	}
+
+	var feed struct {
+		Entries []struct {
+			ID     string `xml:"id"`
+			Point  string `xml:"http://www.georss.org/georss point"`
+			Radius int    `xml:"http://www.georss.org/georss radius"`
+		} `xml:"http://www.w3.org/2005/Atom entry"`
+	}
+	if err := xml.Unmarshal(rec.Body.Bytes(), &feed); err != nil {
+		t.Fatalf("invalid atom: %v", err)
+	}
+	if len(feed.Entries) != 2 {
+		t.Fatalf("expected expired incident to be excluded, got %d entries", len(feed.Entries))
+	}
+	if feed.Entries[0].Point != "55.7494 37.5916" || feed.Entries[0].Radius != 800 {
+		t.Fatalf("unexpected georss geometry: %+v", feed.Entries[0])
+	}
+	if rec.Header().Get("ETag") == "" || rec.Header().Get("Last-Modified") == "" {
+		t.Fatalf("expected ETag and Last-Modified, got %v", rec.Header())
+	}
+}
+
+func TestFeedHandler_ConditionalGet(t *testing.T) {
+	incidents := feedIncidents()
+	lastUpdated := feedUpdatedAt
+	router := newFeedRouter(&incidents, &lastUpdated)
+
+	first := getFeed(router, "/feeds/default/cap", nil)
+	etag, lastModified := first.Header().Get("ETag"), first.Header().Get("Last-Modified")
+
+	if rec := getFeed(router, "/feeds/default/cap", map[string]string{"If-None-Match": etag}); rec.Code != http.StatusNotModified || rec.Body.Len() != 0 {
+		t.Fatalf("expected 304 for matching ETag, got %d", rec.Code)
+	}
+	if rec := getFeed(router, "/feeds/default/cap", map[string]string{"If-Modified-Since": lastModified}); rec.Code != http.StatusNotModified {
+		t.Fatalf("expected 304 for If-Modified-Since, got %d", rec.Code)
+	}
+
+	// Деактивация: инцидент исчезает из набора, updated_at арендатора растёт
+	incidents = incidents[1:]
+	lastUpdated = feedUpdatedAt.Add(time.Minute)
+	rec := getFeed(router, "/feeds/default/cap", map[string]string{"If-None-Match": etag})
+	if rec.Code != http.StatusOK || rec.Header().Get("ETag") == etag {
+		t.Fatalf("expected new representation after change, got %d", rec.Code)
+	}
+	if rec := getFeed(router, "/feeds/default/cap", map[string]string{"If-Modified-Since": lastModified}); rec.Code != http.StatusOK {
+		t.Fatalf("expected 200 for stale If-Modified-Since, got %d", rec.Code)
+	}
+}
+
+func TestFeedHandler_SelfLinkTrustsForwardedProtoOnlyFromProxies(t *testing.T) {
+	incidents := feedIncidents()
+	lastUpdated := feedUpdatedAt
+	proxies, err := handler.ParseTrustedProxies([]string{"10.0.0.0/8"})
+	if err != nil {
+		t.Fatalf("parse proxies: %v", err)
+	}
+	router := newFeedRouterWithProxies(&incidents, &lastUpdated, proxies)
+
+	selfLink := func(remoteAddr string) string {
+		req := httptest.NewRequest(http.MethodGet, "http://feeds.example.org/feeds/default/atom", nil)
+		req.RemoteAddr = remoteAddr
+		req.Header.Set("X-Forwarded-Proto", "https")
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+
+		var feed struct {
+			Links []struct {
+				Rel  string `xml:"rel,attr"`
+				Href string `xml:"href,attr"`
+			} `xml:"http://www.w3.org/2005/Atom link"`
+		}
+		if err := xml.Unmarshal(rec.Body.Bytes(), &feed); err != nil {
+			t.Fatalf("invalid atom: %v", err)
+		}
+		for _, link := range feed.Links {
+			if link.Rel == "self" {
+				return link.Href
+			}
+		}
+		return ""
+	}
+
+	if got := selfLink("203.0.113.5:1234"); got != "http://feeds.example.org/feeds/default/atom" {
+		t.Fatalf("expected X-Forwarded-Proto from client to be ignored, got %q", got)
+	}
+	if got := selfLink("10.1.2.3:1234"); got != "https://feeds.example.org/feeds/default/atom" {
+		t.Fatalf("expected X-Forwarded-Proto from trusted proxy to be honoured, got %q", got)
+	}
+}
+
+func TestFeedHandler_UnknownTenant(t *testing.T) {
+	incidents := feedIncidents()
+	lastUpdated := feedUpdatedAt
+	if rec := getFeed(newFeedRouter(&incidents, &lastUpdated), "/feeds/unknown/atom", nil); rec.Code != http.StatusNotFound {
+		t.Fatalf("expected 404 for unknown tenant, got %d", rec.Code)
+	}
+}
+
+func TestFeedHandler_CAPFeedIsConsumableByCAPPoller(t *testing.T) {
+	incidents := feedIncidents()
+	lastUpdated := feedUpdatedAt
+	server := httptest.NewServer(newFeedRouter(&incidents, &lastUpdated))
+	defer server.Close()
+
+	repo := &fakeCAPRepo{}
+	poller := svc.NewCAPFeedPoller(svc.NewCAPService(repo, &fakeIncidentCache{}), server.URL+"/feeds/default/cap", "partner", time.Minute, time.Second)
+	if _, err := poller.RunOnce(context.Background()); err != nil {
+		t.Fatalf("unexpected poll error: %v", err)
+	}
+
+	if len(repo.applied) != 2 {
+		t.Fatalf("expected 2 CAP alerts, got %d", len(repo.applied))
+	}
+	first, second := repo.applied[0], repo.applied[1]
+	if first.MsgType != domain.CAPMsgTypeAlert || first.Sender != "mchs@example.org" || first.Incidents[0].RadiusMeters != 800 {
+		t.Fatalf("unexpected alert: %+v", first)
+	}
+	if second.MsgType != domain.CAPMsgTypeUpdate || len(second.References) != 1 || second.References[0].Identifier != "urn:geoalerts:default:incident:b:1" {
+		t.Fatalf("expected update referencing first version, got %+v", second)
+	}
+	if second.Incidents[0].Severity != domain.SeverityHigh || !second.Sent.Equal(feedUpdatedAt.Add(-time.Minute)) {
+		t.Fatalf("unexpected update mapping: %+v", second)
+	}
+}
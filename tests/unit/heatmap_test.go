@@ -0,0 +1,129 @@
+package unit
+
+import (
+	"context"
+	"encoding/json"
+	"math"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/ruslanuskembaev/geo-alerts-system/internal/domain"
+	"github.com/ruslanuskembaev/geo-alerts-system/internal/handler"
+	svc "github.com/ruslanuskembaev/geo-alerts-system/internal/service"
+)
+
+func TestGeohashCell_MatchesStandardGeohash(t *testing.T) {
+	cases := []struct {
+		lat, lon  float64
+		precision int
+		geohash   string
+	}{
+		{55.75, 37.61, 6, "ucftpu"},
+		{-33.8688, 151.2093, 9, "r3gx2f77b"},
+		{40.6892, -74.0445, 5, "dr5r7"},
+	}
+
+	for _, tc := range cases {
+		lonStep, latStep := domain.GeohashCellSize(tc.precision)
+		x := int64(math.Floor((tc.lon + 180) / lonStep))
+		y := int64(math.Floor((tc.lat + 90) / latStep))
+
+		cell := domain.GeohashCell(tc.precision, x, y)
+		if cell.Geohash != tc.geohash {
+			t.Fatalf("expected geohash %s for %v,%v, got %s", tc.geohash, tc.lat, tc.lon, cell.Geohash)
+		}
+		box := cell.Bounds
+		if tc.lat < box.MinLatitude || tc.lat >= box.MaxLatitude || tc.lon < box.MinLongitude || tc.lon >= box.MaxLongitude {
+			t.Fatalf("point %v,%v outside of cell bounds %+v", tc.lat, tc.lon, box)
+		}
+	}
+}
+
+func newHeatmapRouter(checkRepo *fakeCheckRepo) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	service := svc.NewIncidentService(&fakeIncidentRepo{}, &fakeIncidentCache{}, checkRepo)
+	h := handler.NewIncidentHandler(service, time.Hour)
+
+	r := gin.New()
+	r.GET("/incidents/stats/heatmap", h.Heatmap)
+	return r
+}
+
+func TestIncidentHandler_HeatmapQueryAndTruncation(t *testing.T) {
+	var got domain.HeatmapQuery
+	checkRepo := &fakeCheckRepo{
+		heatmapFn: func(ctx context.Context, query domain.HeatmapQuery) ([]domain.HeatmapCell, error) {
+			got = query
+			cells := make([]domain.HeatmapCell, 0, query.Limit+1)
+			for i := 0; i <= query.Limit; i++ {
+				cell := domain.GeohashCell(query.Precision, int64(i), 0)
+				cell.Checks = 10 - i
+				cells = append(cells, cell)
+			}
+			return cells, nil
+		},
+	}
+
+	rec := getFeed(newHeatmapRouter(checkRepo),
+		"/incidents/stats/heatmap?precision=5&since=2026-10-18T00:00:00Z&until=2026-10-19T00:00:00Z&bbox=37,55,38,56&limit=2", nil)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if got.Precision != 5 || got.Limit != 2 || got.BBox == nil || got.BBox.MinLatitude != 55 || got.BBox.MaxLongitude != 38 {
+		t.Fatalf("unexpected query: %+v", got)
+	}
+	if !got.Since.Equal(time.Date(2026, 10, 18, 0, 0, 0, 0, time.UTC)) || !got.Until.Equal(time.Date(2026, 10, 19, 0, 0, 0, 0, time.UTC)) {
+		t.Fatalf("unexpected time window: %s - %s", got.Since, got.Until)
+	}
+
+	var heatmap domain.Heatmap
+	if err := json.Unmarshal(rec.Body.Bytes(), &heatmap); err != nil {
+		t.Fatalf("invalid response: %v", err)
+	}
+	if !heatmap.Truncated || len(heatmap.Cells) != 2 || heatmap.Cells[0].Checks != 10 || len(heatmap.Cells[0].Geohash) != 5 {
+		t.Fatalf("expected two heaviest cells and truncation flag, got %+v", heatmap)
+	}
+}
+
+func TestIncidentHandler_HeatmapDefaultsToStatsWindow(t *testing.T) {
+	var got domain.HeatmapQuery
+	checkRepo := &fakeCheckRepo{
+		heatmapFn: func(ctx context.Context, query domain.HeatmapQuery) ([]domain.HeatmapCell, error) {
+			got = query
+			return nil, nil
+		},
+	}
+
+	rec := getFeed(newHeatmapRouter(checkRepo), "/incidents/stats/heatmap", nil)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", rec.Code)
+	}
+	if got.Precision != domain.DefaultHeatmapPrecision || got.Limit != domain.DefaultHeatmapLimit || got.BBox != nil {
+		t.Fatalf("unexpected defaults: %+v", got)
+	}
+	if got.Until.Sub(got.Since) != time.Hour || time.Since(got.Until) > time.Minute {
+		t.Fatalf("expected last stats window, got %s - %s", got.Since, got.Until)
+	}
+}
+
+func TestIncidentHandler_HeatmapValidation(t *testing.T) {
+	router := newHeatmapRouter(&fakeCheckRepo{})
+	for _, query := range []string{
+		"precision=0",
+		"precision=10",
+		"limit=0",
+		"limit=20000",
+		"since=yesterday",
+		"since=2026-10-19T00:00:00Z&until=2026-10-18T00:00:00Z",
+		"since=1970-01-01T00:00:00Z",
+		"since=2026-09-01T00:00:00Z&until=2026-10-19T00:00:00Z",
+		"bbox=37,55,38",
+	} {
+		if rec := getFeed(router, "/incidents/stats/heatmap?"+query, nil); rec.Code != http.StatusBadRequest {
+			t.Fatalf("%s: expected 400, got %d", query, rec.Code)
+		}
+	}
+}
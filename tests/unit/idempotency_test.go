@@ -0,0 +1,150 @@
+package unit
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/ruslanuskembaev/geo-alerts-system/internal/domain"
+	"github.com/ruslanuskembaev/geo-alerts-system/internal/handler"
+	svc "github.com/ruslanuskembaev/geo-alerts-system/internal/service"
+)
+
+const checkBody = `{"user_id": "user-1", "latitude": 55.75, "longitude": 37.61}`
+
+// newIdempotencyRouter возвращает роутер, чей обработчик отвечает статусами из statuses по очереди
+func newIdempotencyRouter(store *fakeIdempotencyStore, calls *int, statuses ...int) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	idempotency := svc.NewIdempotencyService(store, 24*time.Hour)
+
+	r := gin.New()
+	r.POST("/location/check",
+		handler.ClientAuthMiddleware(svc.NewTokenService("secret", time.Minute), nil, false),
+		handler.IdempotencyMiddleware(idempotency),
+		func(c *gin.Context) {
+			*calls++
+			status := http.StatusOK
+			if len(statuses) > 0 {
+				status, statuses = statuses[0], statuses[1:]
+			}
+			c.Header("ETag", `"v1"`)
+			c.JSON(status, gin.H{"call": *calls})
+		},
+	)
+	return r
+}
+
+func postIdempotent(r http.Handler, key, tenant, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, "/location/check", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	if key != "" {
+		req.Header.Set("Idempotency-Key", key)
+	}
+	if tenant != "" {
+		req.Header.Set("X-Tenant-ID", tenant)
+	}
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestIdempotencyMiddleware_ReplaysStoredResponse(t *testing.T) {
+	store := newFakeIdempotencyStore()
+	calls := 0
+	r := newIdempotencyRouter(store, &calls, http.StatusOK)
+
+	first := postIdempotent(r, "key-1", "", checkBody)
+	second := postIdempotent(r, "key-1", "", checkBody)
+
+	if calls != 1 {
+		t.Fatalf("expected handler executed once, got %d", calls)
+	}
+	if second.Code != http.StatusOK || second.Body.String() != first.Body.String() {
+		t.Fatalf("expected original response replayed, got %d %s", second.Code, second.Body.String())
+	}
+	if second.Header().Get("Idempotent-Replayed") != "true" || second.Header().Get("ETag") != `"v1"` ||
+		!strings.HasPrefix(second.Header().Get("Content-Type"), "application/json") {
+		t.Fatalf("expected replay headers, got %v", second.Header())
+	}
+	if first.Header().Get("Idempotent-Replayed") != "" {
+		t.Fatalf("expected first response not marked as replay")
+	}
+	for key, ttl := range store.ttls {
+		if ttl != 24*time.Hour || !store.records[key].Completed() {
+			t.Fatalf("expected completed record stored for ttl, got %s %+v", ttl, store.records[key])
+		}
+	}
+
+	// Тот же ключ у другого арендатора — отдельный запрос
+	if postIdempotent(r, "key-1", "city-b", checkBody); calls != 2 {
+		t.Fatalf("expected keys scoped to tenant, got %d calls", calls)
+	}
+}
+
+func TestIdempotencyMiddleware_RejectsReuseAndConcurrentRequests(t *testing.T) {
+	store := newFakeIdempotencyStore()
+	calls := 0
+	r := newIdempotencyRouter(store, &calls)
+
+	postIdempotent(r, "key-1", "", checkBody)
+	rec := postIdempotent(r, "key-1", "", `{"user_id": "user-1", "latitude": 10, "longitude": 10}`)
+	if rec.Code != http.StatusUnprocessableEntity || calls != 1 {
+		t.Fatalf("expected 422 for a different body, got %d (calls %d)", rec.Code, calls)
+	}
+
+	if rec := postIdempotent(r, strings.Repeat("k", domain.MaxIdempotencyKeyLength+1), "", checkBody); rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for a too long key, got %d", rec.Code)
+	}
+
+	// Первый запрос ещё выполняется: запись без статуса
+	pending := newFakeIdempotencyStore()
+	r = newIdempotencyRouter(pending, &calls)
+	postIdempotent(r, "key-2", "", checkBody)
+	for key, record := range pending.records {
+		pending.records[key] = domain.IdempotencyRecord{Fingerprint: record.Fingerprint}
+	}
+	rec = postIdempotent(r, "key-2", "", checkBody)
+	if rec.Code != http.StatusConflict || rec.Header().Get("Retry-After") == "" || calls != 2 {
+		t.Fatalf("expected 409 while the first request runs, got %d (calls %d)", rec.Code, calls)
+	}
+}
+
+func TestIdempotencyMiddleware_ServerErrorsAreNotStored(t *testing.T) {
+	store := newFakeIdempotencyStore()
+	calls := 0
+	r := newIdempotencyRouter(store, &calls, http.StatusInternalServerError, http.StatusTooManyRequests, http.StatusOK)
+
+	for i := 0; i < 3; i++ {
+		postIdempotent(r, "key-1", "", checkBody)
+	}
+	if calls != 3 || store.released != 2 {
+		t.Fatalf("expected 5xx and 429 released for retry, got %d calls, %d released", calls, store.released)
+	}
+	if rec := postIdempotent(r, "key-1", "", checkBody); rec.Code != http.StatusOK || calls != 3 {
+		t.Fatalf("expected successful response replayed, got %d (calls %d)", rec.Code, calls)
+	}
+}
+
+func TestIdempotencyMiddleware_WithoutKeyOrStore(t *testing.T) {
+	store := newFakeIdempotencyStore()
+	calls := 0
+	r := newIdempotencyRouter(store, &calls)
+
+	postIdempotent(r, "", "", checkBody)
+	postIdempotent(r, "", "", checkBody)
+	if calls != 2 || len(store.records) != 0 {
+		t.Fatalf("expected requests without key to pass through, got %d calls", calls)
+	}
+
+	store.err = errors.New("redis down")
+	postIdempotent(r, "key-1", "", checkBody)
+	postIdempotent(r, "key-1", "", checkBody)
+	if calls != 4 {
+		t.Fatalf("expected store errors to fail open, got %d calls", calls)
+	}
+}
@@ -0,0 +1,93 @@
+package unit
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/ruslanuskembaev/geo-alerts-system/internal/domain"
+	"github.com/ruslanuskembaev/geo-alerts-system/internal/handler"
+	svc "github.com/ruslanuskembaev/geo-alerts-system/internal/service"
+)
+
+const previewBody = `{"title": "Draft", "severity": "high", "latitude": 55.75, "longitude": 37.61, "radius_meters": 800}`
+
+func newPreviewRouter(repo *fakeIncidentRepo, checkRepo *fakeCheckRepo) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	service := svc.NewIncidentService(repo, &fakeIncidentCache{}, checkRepo)
+	h := handler.NewIncidentHandler(service, time.Hour)
+
+	r := gin.New()
+	r.POST("/incidents/preview", h.Preview)
+	return r
+}
+
+func postPreview(r http.Handler, path, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestIncidentHandler_PreviewDoesNotCreate(t *testing.T) {
+	var gotZone domain.ImpactZone
+	var gotSince, gotUntil time.Time
+	checkRepo := &fakeCheckRepo{
+		zoneImpactFn: func(ctx context.Context, zone domain.ImpactZone, since, until time.Time) (domain.ImpactCounts, error) {
+			gotZone, gotSince, gotUntil = zone, since, until
+			return domain.ImpactCounts{Checks: 12, Users: 5, CurrentUsers: 3}, nil
+		},
+	}
+	repo := &fakeIncidentRepo{
+		overlappingFn: func(ctx context.Context, zone domain.ImpactZone) ([]domain.OverlappingIncident, error) {
+			return []domain.OverlappingIncident{{ID: "incident-1", DistanceMeters: 300, RadiusMeters: 2000, Contains: true}}, nil
+		},
+	}
+	r := newPreviewRouter(repo, checkRepo)
+
+	rec := postPreview(r, "/incidents/preview?window=30m&until=2026-10-19T12:00:00Z", previewBody)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if repo.createCalls != 0 {
+		t.Fatalf("preview must not create incidents")
+	}
+
+	until := time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC)
+	if gotZone != (domain.ImpactZone{Latitude: 55.75, Longitude: 37.61, RadiusMeters: 800}) ||
+		!gotUntil.Equal(until) || !gotSince.Equal(until.Add(-30*time.Minute)) {
+		t.Fatalf("unexpected zone %+v or interval %s..%s", gotZone, gotSince, gotUntil)
+	}
+
+	var preview domain.ImpactPreview
+	if err := json.Unmarshal(rec.Body.Bytes(), &preview); err != nil {
+		t.Fatalf("decode failed: %v", err)
+	}
+	if preview.Impact.Users != 5 || preview.Impact.Checks != 12 || preview.Impact.CurrentUsers != 3 {
+		t.Fatalf("unexpected impact: %+v", preview.Impact)
+	}
+	if len(preview.Overlapping) != 1 || !preview.Overlapping[0].Contains {
+		t.Fatalf("unexpected overlapping incidents: %+v", preview.Overlapping)
+	}
+}
+
+func TestIncidentHandler_PreviewValidatesDraft(t *testing.T) {
+	r := newPreviewRouter(&fakeIncidentRepo{}, &fakeCheckRepo{})
+
+	if rec := postPreview(r, "/incidents/preview", `{"title": "Draft", "severity": "high", "latitude": 55.75, "longitude": 37.61, "radius_meters": 5}`); rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for invalid radius, got %d", rec.Code)
+	}
+	if rec := postPreview(r, "/incidents/preview?window=-1h", previewBody); rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for invalid window, got %d", rec.Code)
+	}
+	if rec := postPreview(r, "/incidents/preview?since=2020-01-01T00:00:00Z", previewBody); rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for interval beyond the maximum, got %d", rec.Code)
+	}
+}
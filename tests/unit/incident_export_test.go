@@ -0,0 +1,179 @@
+package unit
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ruslanuskembaev/geo-alerts-system/internal/domain"
+	svc "github.com/ruslanuskembaev/geo-alerts-system/internal/service"
+)
+
+func newExportService(incidents []*domain.Incident, streamErr error) *svc.IncidentService {
+	repo := &fakeIncidentRepo{
+		streamFn: func(ctx context.Context, filter domain.IncidentFilter, fn func(*domain.Incident) error) error {
+			if streamErr != nil {
+				return streamErr
+			}
+			for _, incident := range incidents {
+				if err := fn(incident); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	}
+	return svc.NewIncidentService(repo, &fakeIncidentCache{}, &fakeCheckRepo{})
+}
+
+func exportFixture() []*domain.Incident {
+	now := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	return []*domain.Incident{
+		{ID: "a", Title: "Flood, Arbat", Severity: domain.SeverityMedium, Latitude: 55.7494, Longitude: 37.5916, RadiusMeters: 800, IsActive: true, Version: 1, CreatedAt: now, UpdatedAt: now},
+		{ID: "b", Title: "Storm <north>", Severity: domain.SeverityHigh, Latitude: 59.93, Longitude: 30.33, RadiusMeters: 3000, Version: 2, CreatedAt: now, UpdatedAt: now},
+	}
+}
+
+func TestIncidentExport_CSV(t *testing.T) {
+	var buf bytes.Buffer
+	if err := newExportService(exportFixture(), nil).Export(context.Background(), domain.ExportFormatCSV, domain.IncidentFilter{}, &buf); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	records, err := csv.NewReader(&buf).ReadAll()
+	if err != nil {
+		t.Fatalf("invalid csv: %v", err)
+	}
+	if len(records) != 3 || records[0][0] != "id" {
+		t.Fatalf("expected header and two rows, got %v", records)
+	}
+	if records[1][1] != "Flood, Arbat" || records[2][3] != "high" || records[2][7] != "false" {
+		t.Fatalf("unexpected rows: %v", records[1:])
+	}
+}
+
+func TestIncidentExport_CSVEscapesFormulas(t *testing.T) {
+	now := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	incidents := []*domain.Incident{
+		{ID: "a", Title: "=HYPERLINK(\"http://evil\")", Description: "@SUM(A1)", Severity: domain.SeverityLow, Latitude: 55, Longitude: 37, RadiusMeters: 100, CreatedAt: now, UpdatedAt: now},
+		{ID: "b", Title: "-5 degrees", Description: "+1 zone", Severity: domain.SeverityLow, Latitude: 55, Longitude: 37, RadiusMeters: 100, CreatedAt: now, UpdatedAt: now},
+		{ID: "c", Title: "Flood = danger", Severity: domain.SeverityLow, Latitude: 55, Longitude: 37, RadiusMeters: 100, CreatedAt: now, UpdatedAt: now},
+	}
+
+	var buf bytes.Buffer
+	if err := newExportService(incidents, nil).Export(context.Background(), domain.ExportFormatCSV, domain.IncidentFilter{}, &buf); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	records, err := csv.NewReader(bytes.NewReader(buf.Bytes())).ReadAll()
+	if err != nil {
+		t.Fatalf("invalid csv: %v", err)
+	}
+	if records[1][1] != "'=HYPERLINK(\"http://evil\")" || records[1][2] != "'@SUM(A1)" {
+		t.Fatalf("expected formulas to be escaped, got %v", records[1])
+	}
+	if records[2][1] != "'-5 degrees" || records[2][2] != "'+1 zone" || records[3][1] != "Flood = danger" {
+		t.Fatalf("unexpected escaping: %v", records[2:])
+	}
+
+	// Выгрузка остаётся совместимой с импортом: апостроф снимается
+	var created []domain.CreateIncidentRequest
+	importer, _, _ := newImportService(&created)
+	if _, err := importer.Import(context.Background(), domain.ImportFormatCSV, &buf, false); err != nil {
+		t.Fatalf("import failed: %v", err)
+	}
+	if len(created) != 3 || created[0].Title != incidents[0].Title || created[1].Description != "+1 zone" {
+		t.Fatalf("expected escaped cells to round-trip, got %+v", created)
+	}
+}
+
+func TestIncidentExport_GeoJSON(t *testing.T) {
+	var buf bytes.Buffer
+	if err := newExportService(exportFixture(), nil).Export(context.Background(), domain.ExportFormatGeoJSON, domain.IncidentFilter{}, &buf); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var collection struct {
+		Type     string `json:"type"`
+		Features []struct {
+			Geometry struct {
+				Coordinates []float64 `json:"coordinates"`
+			} `json:"geometry"`
+			Properties domain.Incident `json:"properties"`
+		} `json:"features"`
+	}
+	if err := json.Unmarshal(buf.Bytes(), &collection); err != nil {
+		t.Fatalf("invalid geojson: %v", err)
+	}
+	if collection.Type != "FeatureCollection" || len(collection.Features) != 2 {
+		t.Fatalf("unexpected collection: %s", buf.String())
+	}
+	first := collection.Features[0]
+	if first.Geometry.Coordinates[0] != 37.5916 || first.Properties.ID != "a" {
+		t.Fatalf("expected [lon, lat] point with properties, got %+v", first)
+	}
+}
+
+func TestIncidentExport_EmptyGeoJSONIsValid(t *testing.T) {
+	var buf bytes.Buffer
+	if err := newExportService(nil, nil).Export(context.Background(), domain.ExportFormatGeoJSON, domain.IncidentFilter{}, &buf); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !json.Valid(buf.Bytes()) || !strings.Contains(buf.String(), `"features":[]`) {
+		t.Fatalf("expected empty feature collection, got %s", buf.String())
+	}
+}
+
+func TestIncidentExport_KMLStyledBySeverity(t *testing.T) {
+	var buf bytes.Buffer
+	if err := newExportService(exportFixture(), nil).Export(context.Background(), domain.ExportFormatKML, domain.IncidentFilter{}, &buf); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var doc struct {
+		Styles []struct {
+			ID string `xml:"id,attr"`
+		} `xml:"Document>Style"`
+		Placemarks []struct {
+			Name     string `xml:"name"`
+			StyleURL string `xml:"styleUrl"`
+			Polygon  string `xml:"MultiGeometry>Polygon>outerBoundaryIs>LinearRing>coordinates"`
+		} `xml:"Document>Placemark"`
+	}
+	if err := xml.Unmarshal(buf.Bytes(), &doc); err != nil {
+		t.Fatalf("invalid kml: %v", err)
+	}
+	if len(doc.Styles) != 3 || len(doc.Placemarks) != 2 {
+		t.Fatalf("expected 3 styles and 2 placemarks, got %d/%d", len(doc.Styles), len(doc.Placemarks))
+	}
+	if doc.Placemarks[1].Name != "Storm <north>" || doc.Placemarks[1].StyleURL != "#severity-high" {
+		t.Fatalf("unexpected placemark: %+v", doc.Placemarks[1])
+	}
+	if points := strings.Fields(doc.Placemarks[0].Polygon); len(points) != 65 || points[0] != points[64] {
+		t.Fatalf("expected closed ring of 65 points, got %d", len(points))
+	}
+}
+
+func TestIncidentExport_StreamErrorBeforeFirstRowWritesNothing(t *testing.T) {
+	var buf bytes.Buffer
+	streamErr := errors.New("db down")
+	err := newExportService(nil, streamErr).Export(context.Background(), domain.ExportFormatKML, domain.IncidentFilter{}, &buf)
+	if !errors.Is(err, streamErr) {
+		t.Fatalf("expected stream error, got %v", err)
+	}
+	if buf.Len() != 0 {
+		t.Fatalf("expected nothing written, got %q", buf.String())
+	}
+}
+
+func TestIncidentExport_UnsupportedFormat(t *testing.T) {
+	err := newExportService(nil, nil).Export(context.Background(), domain.ExportFormat("shp"), domain.IncidentFilter{}, &bytes.Buffer{})
+	if !errors.Is(err, svc.ErrUnsupportedExportFormat) {
+		t.Fatalf("expected ErrUnsupportedExportFormat, got %v", err)
+	}
+}
@@ -0,0 +1,343 @@
+package unit
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/ruslanuskembaev/geo-alerts-system/internal/domain"
+	"github.com/ruslanuskembaev/geo-alerts-system/internal/handler"
+	"github.com/ruslanuskembaev/geo-alerts-system/internal/repository"
+	svc "github.com/ruslanuskembaev/geo-alerts-system/internal/service"
+)
+
+func newIncidentRouter(repo *fakeIncidentRepo) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	service := svc.NewIncidentService(repo, &fakeIncidentCache{}, &fakeCheckRepo{})
+	h := handler.NewIncidentHandler(service, time.Hour)
+
+	r := gin.New()
+	r.GET("/incidents", h.List)
+	r.GET("/incidents/:id", h.GetByID)
+	r.PUT("/incidents/:id", h.Update)
+	r.DELETE("/incidents/:id", h.Delete)
+	return r
+}
+
+func TestIncidentHandler_GetByID_SetsETag(t *testing.T) {
+	repo := &fakeIncidentRepo{
+		getByIDFn: func(ctx context.Context, id string) (*domain.Incident, error) {
+			return &domain.Incident{ID: id, Version: 3}, nil
+		},
+	}
+
+	w := httptest.NewRecorder()
+	newIncidentRouter(repo).ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/incidents/incident-1", nil))
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", w.Code)
+	}
+	if etag := w.Header().Get("ETag"); etag != `"3"` {
+		t.Fatalf("expected ETag \"3\", got %q", etag)
+	}
+}
+
+func TestIncidentHandler_Update_IfMatch(t *testing.T) {
+	var gotVersion *int
+	repo := &fakeIncidentRepo{
+		updateFn: func(ctx context.Context, id string, req domain.UpdateIncidentRequest, expectedVersion *int) (*domain.Incident, error) {
+			gotVersion = expectedVersion
+			if expectedVersion != nil && *expectedVersion != 2 {
+				return nil, repository.ErrVersionConflict
+			}
+			return &domain.Incident{ID: id, Version: 3}, nil
+		},
+	}
+	router := newIncidentRouter(repo)
+
+	req := httptest.NewRequest(http.MethodPut, "/incidents/incident-1", strings.NewReader(`{"radius_meters": 1500}`))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("If-Match", `"2"`)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	if gotVersion == nil || *gotVersion != 2 {
+		t.Fatalf("expected If-Match version passed to repository")
+	}
+	if etag := w.Header().Get("ETag"); etag != `"3"` {
+		t.Fatalf("expected new ETag \"3\", got %q", etag)
+	}
+
+	req = httptest.NewRequest(http.MethodPut, "/incidents/incident-1", strings.NewReader(`{"radius_meters": 1500}`))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("If-Match", `"1"`)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusPreconditionFailed {
+		t.Fatalf("expected 412 on stale If-Match, got %d", w.Code)
+	}
+}
+
+func TestIncidentHandler_Update_ClearExpiresAt(t *testing.T) {
+	var got domain.UpdateIncidentRequest
+	repo := &fakeIncidentRepo{
+		updateFn: func(ctx context.Context, id string, req domain.UpdateIncidentRequest, expectedVersion *int) (*domain.Incident, error) {
+			got = req
+			return &domain.Incident{ID: id, Version: 2}, nil
+		},
+	}
+	router := newIncidentRouter(repo)
+
+	put := func(body string) int {
+		req := httptest.NewRequest(http.MethodPut, "/incidents/incident-1", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	if code := put(`{"clear_expires_at": true}`); code != http.StatusOK || !got.ClearExpiresAt {
+		t.Fatalf("expected clear_expires_at passed to repository, got %d / %+v", code, got)
+	}
+	if code := put(`{"clear_expires_at": true, "expires_at": "2030-01-01T00:00:00Z"}`); code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for conflicting expiry fields, got %d", code)
+	}
+}
+
+func TestIncidentHandler_Delete_MalformedIfMatch(t *testing.T) {
+	repo := &fakeIncidentRepo{}
+
+	req := httptest.NewRequest(http.MethodDelete, "/incidents/incident-1", nil)
+	req.Header.Set("If-Match", "not-a-version")
+	w := httptest.NewRecorder()
+	newIncidentRouter(repo).ServeHTTP(w, req)
+
+	if w.Code != http.StatusPreconditionFailed {
+		t.Fatalf("expected 412, got %d", w.Code)
+	}
+	if repo.deactivateCalls != 0 {
+		t.Fatalf("expected repository not called on malformed If-Match")
+	}
+}
+
+func TestIncidentHandler_Update_WeakIfMatchFails(t *testing.T) {
+	calls := 0
+	repo := &fakeIncidentRepo{
+		updateFn: func(ctx context.Context, id string, req domain.UpdateIncidentRequest, expectedVersion *int) (*domain.Incident, error) {
+			calls++
+			return &domain.Incident{ID: id, Version: 4}, nil
+		},
+	}
+
+	req := httptest.NewRequest(http.MethodPut, "/incidents/incident-1", strings.NewReader(`{"radius_meters": 1500}`))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("If-Match", `W/"3"`)
+	w := httptest.NewRecorder()
+	newIncidentRouter(repo).ServeHTTP(w, req)
+
+	if w.Code != http.StatusPreconditionFailed || calls != 0 {
+		t.Fatalf("expected 412 without update on weak If-Match, got %d (%d calls)", w.Code, calls)
+	}
+}
+
+func TestIncidentHandler_Update_IfMatchList(t *testing.T) {
+	var gotVersion *int
+	repo := &fakeIncidentRepo{
+		getByIDFn: func(ctx context.Context, id string) (*domain.Incident, error) {
+			return &domain.Incident{ID: id, Version: 3}, nil
+		},
+		updateFn: func(ctx context.Context, id string, req domain.UpdateIncidentRequest, expectedVersion *int) (*domain.Incident, error) {
+			gotVersion = expectedVersion
+			if expectedVersion != nil && *expectedVersion != 3 {
+				return nil, repository.ErrVersionConflict
+			}
+			return &domain.Incident{ID: id, Version: 4}, nil
+		},
+	}
+	router := newIncidentRouter(repo)
+
+	tests := map[string]int{
+		`"2", "3"`:      http.StatusOK,
+		`W/"3", "3"`:    http.StatusOK,
+		`"1", "2"`:      http.StatusPreconditionFailed,
+		`W/"3", "oops"`: http.StatusPreconditionFailed,
+	}
+	for header, expected := range tests {
+		gotVersion = nil
+		req := httptest.NewRequest(http.MethodPut, "/incidents/incident-1", strings.NewReader(`{"radius_meters": 1500}`))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("If-Match", header)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		if w.Code != expected {
+			t.Fatalf("If-Match %s: expected %d, got %d", header, expected, w.Code)
+		}
+		if expected == http.StatusOK && (gotVersion == nil || *gotVersion != 3) {
+			t.Fatalf("If-Match %s: expected current version 3 passed to repository, got %v", header, gotVersion)
+		}
+	}
+}
+
+func TestIncidentHandler_List_ParsesFilters(t *testing.T) {
+	var got domain.IncidentFilter
+	repo := &fakeIncidentRepo{
+		listFn: func(ctx context.Context, filter domain.IncidentFilter, page domain.PageRequest) ([]*domain.Incident, domain.PageInfo, error) {
+			got = filter
+			return []*domain.Incident{}, domain.PageInfo{}, nil
+		},
+	}
+	router := newIncidentRouter(repo)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet,
+		"/incidents?is_active=true&severity=high,medium&q=fire&near=55.75,37.61&radius_meters=2000&sort=distance&created_from=2025-01-01T00:00:00Z", nil))
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	if got.IsActive == nil || !*got.IsActive {
+		t.Fatalf("expected is_active=true filter")
+	}
+	if len(got.Severities) != 2 || got.Severities[0] != domain.SeverityHigh {
+		t.Fatalf("unexpected severities: %v", got.Severities)
+	}
+	if got.Query != "fire" {
+		t.Fatalf("unexpected query: %q", got.Query)
+	}
+	if got.Near == nil || got.Near.Latitude != 55.75 || got.Near.RadiusMeters != 2000 {
+		t.Fatalf("unexpected near filter: %+v", got.Near)
+	}
+	if got.SortBy != domain.IncidentSortDistance || got.SortDesc {
+		t.Fatalf("expected ascending distance sort, got %s desc=%v", got.SortBy, got.SortDesc)
+	}
+	if got.CreatedFrom == nil || got.CreatedFrom.Year() != 2025 {
+		t.Fatalf("expected created_from parsed")
+	}
+}
+
+func TestIncidentHandler_List_RejectsInvalidFilters(t *testing.T) {
+	repo := &fakeIncidentRepo{}
+	router := newIncidentRouter(repo)
+
+	for _, query := range []string{
+		"severity=extreme",
+		"sort=distance",
+		"sort=-password",
+		"bbox=1,2,3",
+		"near=55.75,37.61",
+		"updated_to=yesterday",
+		"cursor=not-a-cursor",
+		"include_total=maybe",
+	} {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/incidents?"+query, nil))
+		if w.Code != http.StatusBadRequest {
+			t.Fatalf("expected 400 for %q, got %d", query, w.Code)
+		}
+	}
+	if repo.listCalls != 0 {
+		t.Fatalf("expected repository not called for invalid filters")
+	}
+}
+
+func TestIncidentHandler_List_CursorMode(t *testing.T) {
+	var got domain.PageRequest
+	total := 42
+	repo := &fakeIncidentRepo{
+		listFn: func(ctx context.Context, filter domain.IncidentFilter, page domain.PageRequest) ([]*domain.Incident, domain.PageInfo, error) {
+			got = page
+			info := domain.PageInfo{NextCursor: "next", PrevCursor: "prev"}
+			if page.IncludeTotal {
+				info.Total = &total
+			}
+			return []*domain.Incident{}, info, nil
+		},
+	}
+	router := newIncidentRouter(repo)
+
+	cursor := domain.Cursor{Sort: domain.IncidentSortCreatedAt, Desc: true, Value: []byte(`"2025-01-01T00:00:00Z"`), ID: "abc"}.Encode()
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/incidents?page_size=5&page=3&cursor="+cursor, nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	if got.Cursor == nil || got.Cursor.ID != "abc" || got.Limit != 5 || got.Offset != 0 || got.IncludeTotal {
+		t.Fatalf("unexpected page request in cursor mode: %+v", got)
+	}
+
+	var body map[string]any
+	if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+	if body["next_cursor"] != "next" || body["prev_cursor"] != "prev" {
+		t.Fatalf("expected cursors in response, got %v", body)
+	}
+	if _, ok := body["total"]; ok {
+		t.Fatalf("expected total omitted in cursor mode")
+	}
+	if _, ok := body["page"]; ok {
+		t.Fatalf("expected page omitted in cursor mode")
+	}
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/incidents?page=2&page_size=10", nil))
+	if got.Cursor != nil || got.Offset != 10 || !got.IncludeTotal {
+		t.Fatalf("expected offset mode with total, got %+v", got)
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+	if body["total"] != float64(42) || body["page"] != float64(2) {
+		t.Fatalf("expected legacy page fields, got %v", body)
+	}
+}
+
+func TestIncidentHandler_ExportOutlivesServerWriteTimeout(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	repo := &fakeIncidentRepo{
+		streamFn: func(ctx context.Context, filter domain.IncidentFilter, fn func(*domain.Incident) error) error {
+			for i := 0; i < 3; i++ {
+				time.Sleep(50 * time.Millisecond)
+				if err := fn(&domain.Incident{ID: fmt.Sprintf("zone-%d", i), Severity: domain.SeverityLow}); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	}
+	h := handler.NewIncidentHandler(svc.NewIncidentService(repo, &fakeIncidentCache{}, &fakeCheckRepo{}), time.Hour).
+		WithExportTimeout(5 * time.Second)
+	r := gin.New()
+	r.GET("/incidents/export", h.Export)
+
+	server := httptest.NewUnstartedServer(r)
+	server.Config.WriteTimeout = 50 * time.Millisecond
+	server.Start()
+	defer server.Close()
+
+	resp, err := http.Get(server.URL + "/incidents/export?format=csv")
+	if err != nil {
+		t.Fatalf("export request failed: %v", err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("export truncated: %v", err)
+	}
+	if lines := strings.Count(string(body), "\n"); lines != 4 {
+		t.Fatalf("expected header and 3 rows, got %d lines: %q", lines, body)
+	}
+}
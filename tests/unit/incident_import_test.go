@@ -0,0 +1,117 @@
+package unit
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/ruslanuskembaev/geo-alerts-system/internal/domain"
+	svc "github.com/ruslanuskembaev/geo-alerts-system/internal/service"
+)
+
+func newImportService(created *[]domain.CreateIncidentRequest) (*svc.IncidentService, *fakeIncidentRepo, *fakeIncidentCache) {
+	repo := &fakeIncidentRepo{
+		createBatchFn: func(ctx context.Context, reqs []domain.CreateIncidentRequest) ([]*domain.Incident, error) {
+			*created = reqs
+			incidents := make([]*domain.Incident, len(reqs))
+			for i, req := range reqs {
+				incidents[i] = &domain.Incident{Title: req.Title}
+			}
+			return incidents, nil
+		},
+	}
+	cache := &fakeIncidentCache{}
+	return svc.NewIncidentService(repo, cache, &fakeCheckRepo{}), repo, cache
+}
+
+func TestIncidentImport_CSV(t *testing.T) {
+	var created []domain.CreateIncidentRequest
+	service, repo, cache := newImportService(&created)
+
+	csv := "title,description,severity,latitude,longitude,radius_meters\n" +
+		"Flood on Arbat,Water main burst,medium,55.7494,37.5916,800\n" +
+		"Storm,,HIGH,59.93,30.33,3000\n"
+
+	result, err := service.Import(context.Background(), domain.ImportFormatCSV, strings.NewReader(csv), false)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result.Total != 2 || result.Imported != 2 || len(result.Errors) != 0 {
+		t.Fatalf("unexpected result: %+v", result)
+	}
+	if repo.createBatchCalls != 1 || cache.invalidateCalls != 1 {
+		t.Fatalf("expected one batch insert and one invalidation, got %d/%d", repo.createBatchCalls, cache.invalidateCalls)
+	}
+	if created[1].Severity != domain.SeverityHigh || created[0].RadiusMeters != 800 {
+		t.Fatalf("unexpected parsed rows: %+v", created)
+	}
+}
+
+func TestIncidentImport_RowErrorsAbortImport(t *testing.T) {
+	var created []domain.CreateIncidentRequest
+	service, repo, cache := newImportService(&created)
+
+	csv := "title,severity,latitude,longitude,radius_meters\n" +
+		"Valid zone,low,55.75,37.61,100\n" +
+		"No,extreme,95,37.61,5\n" +
+		"Bad coords,low,abc,37.61,100\n"
+
+	result, err := service.Import(context.Background(), domain.ImportFormatCSV, strings.NewReader(csv), false)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result.Valid != 1 || result.Imported != 0 || len(result.Errors) != 2 {
+		t.Fatalf("unexpected result: %+v", result)
+	}
+	if result.Errors[0].Row != 2 || len(result.Errors[0].Errors) != 4 {
+		t.Fatalf("expected 4 validation errors for row 2, got %+v", result.Errors[0])
+	}
+	if !strings.HasPrefix(result.Errors[0].Errors[0], "title: failed on min") {
+		t.Fatalf("expected json field names in errors, got %v", result.Errors[0].Errors)
+	}
+	if result.Errors[1].Row != 3 || result.Errors[1].Errors[0] != "latitude: must be a number" {
+		t.Fatalf("unexpected parse error: %+v", result.Errors[1])
+	}
+	if repo.createBatchCalls != 0 || cache.invalidateCalls != 0 {
+		t.Fatalf("expected nothing created when rows are invalid")
+	}
+}
+
+func TestIncidentImport_GeoJSONDryRun(t *testing.T) {
+	var created []domain.CreateIncidentRequest
+	service, repo, _ := newImportService(&created)
+
+	geojson := `{"type":"FeatureCollection","features":[
+		{"type":"Feature","geometry":{"type":"Point","coordinates":[37.6175,55.752]},
+		 "properties":{"title":"Fire near Kremlin","severity":"high","radius_meters":500}},
+		{"type":"Feature","geometry":{"type":"LineString","coordinates":[[0,0],[1,1]]},
+		 "properties":{"title":"Line","severity":"low","radius_meters":100}}
+	]}`
+
+	result, err := service.Import(context.Background(), domain.ImportFormatGeoJSON, strings.NewReader(geojson), true)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !result.DryRun || result.Total != 2 || result.Valid != 1 || len(result.Errors) != 1 || result.Errors[0].Row != 2 {
+		t.Fatalf("unexpected dry-run result: %+v", result)
+	}
+	if repo.createBatchCalls != 0 {
+		t.Fatalf("expected dry-run not to insert")
+	}
+}
+
+func TestIncidentImport_InvalidFile(t *testing.T) {
+	var created []domain.CreateIncidentRequest
+	service, _, _ := newImportService(&created)
+
+	if _, err := service.Import(context.Background(), domain.ImportFormatCSV, strings.NewReader("name,lat\n"), false); !errors.Is(err, svc.ErrInvalidImportFile) {
+		t.Fatalf("expected ErrInvalidImportFile for missing columns, got %v", err)
+	}
+	if _, err := service.Import(context.Background(), domain.ImportFormatGeoJSON, strings.NewReader(`{"type":"Feature"}`), false); !errors.Is(err, svc.ErrInvalidImportFile) {
+		t.Fatalf("expected ErrInvalidImportFile for non-collection, got %v", err)
+	}
+	if _, err := service.Import(context.Background(), "kml", strings.NewReader(""), false); !errors.Is(err, svc.ErrUnsupportedImportFormat) {
+		t.Fatalf("expected ErrUnsupportedImportFormat, got %v", err)
+	}
+}
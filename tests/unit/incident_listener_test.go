@@ -0,0 +1,61 @@
+package unit
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ruslanuskembaev/geo-alerts-system/internal/domain"
+	svc "github.com/ruslanuskembaev/geo-alerts-system/internal/service"
+)
+
+func TestIncidentChangeWatcher_InvalidatesTenantAndReconnects(t *testing.T) {
+	var mu sync.Mutex
+	invalidated := make([]string, 0)
+	cache := &fakeIncidentCache{invalidateFn: func(ctx context.Context) error {
+		mu.Lock()
+		defer mu.Unlock()
+		invalidated = append(invalidated, domain.TenantFromContext(ctx))
+		return nil
+	}}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	listener := &fakeChangeListener{attempts: []func(func(string)) error{
+		func(onChange func(string)) error {
+			return errors.New("connection refused")
+		},
+		func(onChange func(string)) error {
+			onChange("city-a")
+			onChange("city-b")
+			return errors.New("connection reset")
+		},
+		func(onChange func(string)) error {
+			onChange("city-a")
+			cancel()
+			return context.Canceled
+		},
+	}}
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		svc.NewIncidentChangeWatcher(listener, cache).WithRetryDelay(time.Millisecond, 4*time.Millisecond).Start(ctx)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatalf("watcher did not stop")
+	}
+
+	if listener.calls != 3 {
+		t.Fatalf("expected reconnects after failures, got %d attempts", listener.calls)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if len(invalidated) != 3 || invalidated[0] != "city-a" || invalidated[1] != "city-b" || invalidated[2] != "city-a" {
+		t.Fatalf("expected tenant-scoped invalidations, got %v", invalidated)
+	}
+}
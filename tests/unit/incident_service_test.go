@@ -15,10 +15,10 @@ func TestIncidentService_InvalidatesCache(t *testing.T) {
 		createFn: func(ctx context.Context, req domain.CreateIncidentRequest) (*domain.Incident, error) {
 			return &domain.Incident{ID: "incident-1"}, nil
 		},
-		updateFn: func(ctx context.Context, id string, req domain.UpdateIncidentRequest) (*domain.Incident, error) {
+		updateFn: func(ctx context.Context, id string, req domain.UpdateIncidentRequest, expectedVersion *int) (*domain.Incident, error) {
 			return &domain.Incident{ID: id}, nil
 		},
-		deactivateFn: func(ctx context.Context, id string) error {
+		deactivateFn: func(ctx context.Context, id string, expectedVersion *int) error {
 			return nil
 		},
 	}
@@ -40,11 +40,11 @@ func TestIncidentService_InvalidatesCache(t *testing.T) {
 	title := "Updated"
 	if _, err := service.Update(context.Background(), "incident-1", domain.UpdateIncidentRequest{
 		Title: &title,
-	}); err != nil {
+	}, nil); err != nil {
 		t.Fatalf("unexpected update error: %v", err)
 	}
 
-	if err := service.Deactivate(context.Background(), "incident-1"); err != nil {
+	if err := service.Deactivate(context.Background(), "incident-1", nil); err != nil {
 		t.Fatalf("unexpected deactivate error: %v", err)
 	}
 
@@ -59,14 +59,14 @@ func TestIncidentService_StatsByIncident(t *testing.T) {
 	}
 
 	checkRepo := &fakeCheckRepo{
-		statsFn: func(ctx context.Context, since time.Time) ([]domain.IncidentStats, error) {
+		statsFn: func(ctx context.Context, query domain.IncidentStatsQuery) ([]domain.IncidentStats, error) {
 			return expected, nil
 		},
 	}
 
 	service := svc.NewIncidentService(&fakeIncidentRepo{}, &fakeIncidentCache{}, checkRepo)
 
-	stats, err := service.StatsByIncident(context.Background(), time.Now().Add(-time.Hour))
+	stats, err := service.StatsByIncident(context.Background(), domain.IncidentStatsQuery{Since: time.Now().Add(-time.Hour), Until: time.Now()})
 	if err != nil {
 		t.Fatalf("unexpected stats error: %v", err)
 	}
@@ -0,0 +1,109 @@
+package unit
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/ruslanuskembaev/geo-alerts-system/internal/domain"
+	"github.com/ruslanuskembaev/geo-alerts-system/internal/handler"
+	"github.com/ruslanuskembaev/geo-alerts-system/internal/repository"
+	svc "github.com/ruslanuskembaev/geo-alerts-system/internal/service"
+)
+
+func newStatsRouter(repo *fakeIncidentRepo, checkRepo *fakeCheckRepo) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	service := svc.NewIncidentService(repo, &fakeIncidentCache{}, checkRepo)
+	h := handler.NewIncidentHandler(service, time.Hour)
+
+	r := gin.New()
+	r.GET("/incidents/stats", h.Stats)
+	r.GET("/incidents/:id/stats", h.IncidentStats)
+	return r
+}
+
+func TestIncidentHandler_StatsQueryParameters(t *testing.T) {
+	var got domain.IncidentStatsQuery
+	checkRepo := &fakeCheckRepo{
+		statsFn: func(ctx context.Context, query domain.IncidentStatsQuery) ([]domain.IncidentStats, error) {
+			got = query
+			return []domain.IncidentStats{}, nil
+		},
+	}
+	router := newStatsRouter(&fakeIncidentRepo{}, checkRepo)
+
+	rec := getFeed(router, "/incidents/stats?window=24h&until=2026-10-19T12:00:00Z&severity=high,medium&incident_id="+seriesIncidentID, nil)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	until := time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC)
+	if !got.Until.Equal(until) || !got.Since.Equal(until.Add(-24*time.Hour)) {
+		t.Fatalf("unexpected interval: %s - %s", got.Since, got.Until)
+	}
+	if len(got.Severities) != 2 || got.Severities[0] != domain.SeverityHigh || len(got.IncidentIDs) != 1 {
+		t.Fatalf("unexpected filters: %+v", got)
+	}
+
+	rec = getFeed(router, "/incidents/stats?since=2026-10-19T00:00:00Z&until=2026-10-19T06:00:00Z", nil)
+	if rec.Code != http.StatusOK || !got.Since.Equal(time.Date(2026, 10, 19, 0, 0, 0, 0, time.UTC)) {
+		t.Fatalf("expected explicit since, got %d / %s", rec.Code, got.Since)
+	}
+
+	rec = getFeed(router, "/incidents/stats", nil)
+	if rec.Code != http.StatusOK || got.Until.Sub(got.Since) != time.Hour || got.Severities != nil || got.IncidentIDs != nil {
+		t.Fatalf("expected default stats window without filters, got %+v", got)
+	}
+}
+
+func TestIncidentHandler_StatsValidation(t *testing.T) {
+	router := newStatsRouter(&fakeIncidentRepo{}, &fakeCheckRepo{})
+	for _, query := range []string{
+		"window=-1h",
+		"window=day",
+		"window=1h&since=2026-10-19T00:00:00Z",
+		"since=2026-10-19T10:00:00Z&until=2026-10-19T09:00:00Z",
+		"window=745h",
+		"since=2020-01-01T00:00:00Z",
+		"severity=critical",
+		"incident_id=42",
+	} {
+		if rec := getFeed(router, "/incidents/stats?"+query, nil); rec.Code != http.StatusBadRequest {
+			t.Fatalf("%s: expected 400, got %d", query, rec.Code)
+		}
+	}
+}
+
+func TestIncidentHandler_SingleIncidentStats(t *testing.T) {
+	var got domain.IncidentStatsQuery
+	avg := 250.0
+	repo := &fakeIncidentRepo{
+		getByIDFn: func(ctx context.Context, id string) (*domain.Incident, error) {
+			if id != seriesIncidentID {
+				return nil, repository.ErrNotFound
+			}
+			return &domain.Incident{ID: id}, nil
+		},
+	}
+	checkRepo := &fakeCheckRepo{
+		statsFn: func(ctx context.Context, query domain.IncidentStatsQuery) ([]domain.IncidentStats, error) {
+			got = query
+			return []domain.IncidentStats{{IncidentID: seriesIncidentID, Checks: 4, UserCount: 2, AvgDistanceMeters: &avg}}, nil
+		},
+	}
+	router := newStatsRouter(repo, checkRepo)
+
+	rec := getFeed(router, "/incidents/"+seriesIncidentID+"/stats?window=2h", nil)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if len(got.IncidentIDs) != 1 || got.IncidentIDs[0] != seriesIncidentID || got.Until.Sub(got.Since) != 2*time.Hour {
+		t.Fatalf("unexpected query: %+v", got)
+	}
+
+	if rec := getFeed(router, "/incidents/6f1c2f8e-0000-4a5c-9e7f-0a1b2c3d4e5f/stats", nil); rec.Code != http.StatusNotFound {
+		t.Fatalf("expected 404 for unknown incident, got %d", rec.Code)
+	}
+}
@@ -0,0 +1,170 @@
+package unit
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/ruslanuskembaev/geo-alerts-system/internal/domain"
+	"github.com/ruslanuskembaev/geo-alerts-system/internal/repository"
+	svc "github.com/ruslanuskembaev/geo-alerts-system/internal/service"
+)
+
+func TestLocalIncidentCache_CoalescesConcurrentMisses(t *testing.T) {
+	remote := newFakeVersionedCache()
+	cache := repository.NewLocalIncidentCache(remote, time.Minute)
+
+	var loads int32
+	release := make(chan struct{})
+	load := func(ctx context.Context) ([]*domain.Incident, error) {
+		atomic.AddInt32(&loads, 1)
+		<-release
+		return []*domain.Incident{{ID: "incident-1"}}, nil
+	}
+
+	const requests = 20
+	var started, done sync.WaitGroup
+	started.Add(requests)
+	done.Add(requests)
+	results := make([][]*domain.Incident, requests)
+	for i := 0; i < requests; i++ {
+		go func(i int) {
+			defer done.Done()
+			started.Done()
+			incidents, err := cache.LoadActive(context.Background(), load)
+			if err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+			results[i] = incidents
+		}(i)
+	}
+	started.Wait()
+	time.Sleep(20 * time.Millisecond)
+	close(release)
+	done.Wait()
+
+	if loads != 1 {
+		t.Fatalf("expected one database load for concurrent misses, got %d", loads)
+	}
+	for _, incidents := range results {
+		if len(incidents) != 1 {
+			t.Fatalf("expected every request to get the loaded incidents, got %+v", incidents)
+		}
+	}
+	if blob, ok, _ := remote.GetActive(context.Background()); !ok || len(blob) != 1 {
+		t.Fatalf("expected redis warmed under the current version")
+	}
+}
+
+func TestLocalIncidentCache_RefreshesOnlyWhenVersionChanges(t *testing.T) {
+	remote := newFakeVersionedCache()
+	cache := repository.NewLocalIncidentCache(remote, time.Minute)
+	ctx := context.Background()
+	loads := 0
+	load := func(ctx context.Context) ([]*domain.Incident, error) {
+		loads++
+		return []*domain.Incident{{ID: "incident-1"}}, nil
+	}
+
+	// Без подписки версия сверяется на каждом чтении, но запись из Redis читается один раз
+	for i := 0; i < 3; i++ {
+		if _, err := cache.LoadActive(ctx, load); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	if loads != 1 || remote.versionCalls != 3 || remote.getVersionCalls != 1 {
+		t.Fatalf("expected local copy reused, got loads=%d version=%d get=%d", loads, remote.versionCalls, remote.getVersionCalls)
+	}
+
+	// Другая реплика инвалидировала кеш и уже прогрела новую версию
+	remote.versions[domain.DefaultTenantID] = 1
+	_ = remote.SetVersion(ctx, 1, []*domain.Incident{{ID: "incident-1"}, {ID: "incident-2"}})
+	incidents, err := cache.LoadActive(ctx, load)
+	if err != nil || len(incidents) != 2 || loads != 1 {
+		t.Fatalf("expected new version read from redis, got %+v (loads %d), %v", incidents, loads, err)
+	}
+}
+
+func TestLocalIncidentCache_SubscribedCopySkipsRedisUntilInvalidated(t *testing.T) {
+	remote := newFakeVersionedCache()
+	cache := repository.NewLocalIncidentCache(remote, time.Minute)
+	cache.Start(context.Background())
+	ctx := domain.WithTenant(context.Background(), "city-a")
+	other := domain.WithTenant(context.Background(), "city-b")
+
+	loads := map[string]int{}
+	load := func(ctx context.Context) ([]*domain.Incident, error) {
+		loads[domain.TenantFromContext(ctx)]++
+		return []*domain.Incident{{ID: "incident-1"}}, nil
+	}
+
+	for i := 0; i < 3; i++ {
+		_, _ = cache.LoadActive(ctx, load)
+		_, _ = cache.LoadActive(other, load)
+	}
+	if remote.versionCalls != 2 || loads["city-a"] != 1 || loads["city-b"] != 1 {
+		t.Fatalf("expected subscribed copies served from memory, got version=%d loads=%v", remote.versionCalls, loads)
+	}
+
+	// Другая реплика инвалидировала city-a: новая версия и сообщение подписчикам
+	_ = remote.Invalidate(ctx)
+	_, _ = cache.LoadActive(ctx, load)
+	_, _ = cache.LoadActive(other, load)
+	if loads["city-a"] != 2 || loads["city-b"] != 1 {
+		t.Fatalf("expected only invalidated tenant reloaded, got %v", loads)
+	}
+
+	// Обрыв подписки: сообщения могли потеряться, копии сбрасываются и версия сверяется
+	remote.onSubscribed(false)
+	calls := remote.versionCalls
+	_, _ = cache.LoadActive(other, load)
+	_, _ = cache.LoadActive(other, load)
+	if remote.versionCalls != calls+2 || loads["city-b"] != 1 {
+		t.Fatalf("expected version checks without subscription, got %d calls, loads %v", remote.versionCalls-calls, loads)
+	}
+}
+
+func TestLocalIncidentCache_InvalidationDuringLoadIsNotCached(t *testing.T) {
+	remote := newFakeVersionedCache()
+	cache := repository.NewLocalIncidentCache(remote, time.Minute)
+	cache.Start(context.Background())
+	ctx := context.Background()
+
+	loads := 0
+	load := func(ctx context.Context) ([]*domain.Incident, error) {
+		loads++
+		if loads == 1 {
+			// Инцидент изменили, пока список читался из БД
+			_ = cache.Invalidate(ctx)
+		}
+		return []*domain.Incident{{ID: "incident-1"}}, nil
+	}
+
+	_, _ = cache.LoadActive(ctx, load)
+	_, _ = cache.LoadActive(ctx, load)
+	if loads != 2 {
+		t.Fatalf("expected stale load not cached locally, got %d loads", loads)
+	}
+}
+
+func TestActiveIncidents_UsesCacheLoader(t *testing.T) {
+	repo := &fakeIncidentRepo{
+		listActiveFn: func(ctx context.Context) ([]*domain.Incident, error) {
+			return []*domain.Incident{{ID: "incident-1", RadiusMeters: 1000, IsActive: true}}, nil
+		},
+	}
+	cache := repository.NewLocalIncidentCache(newFakeVersionedCache(), time.Minute)
+	service := svc.NewLocationService(repo, cache, &fakeCheckRepo{})
+
+	for i := 0; i < 2; i++ {
+		resp, err := service.CheckLocation(context.Background(), domain.LocationCheckRequest{UserID: "user-1"})
+		if err != nil || !resp.IsInDangerZone {
+			t.Fatalf("unexpected response %+v, %v", resp, err)
+		}
+	}
+	if repo.listActiveCalls != 1 {
+		t.Fatalf("expected one database load, got %d", repo.listActiveCalls)
+	}
+}
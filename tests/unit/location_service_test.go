@@ -2,6 +2,7 @@ package unit
 
 import (
 	"context"
+	"errors"
 	"testing"
 	"time"
 
@@ -9,7 +10,7 @@ import (
 	svc "github.com/ruslanuskembaev/geo-alerts-system/internal/service"
 )
 
-func TestLocationService_CheckLocation_CacheHit_EnqueuesWebhook(t *testing.T) {
+func TestLocationService_CheckLocation_CacheHit_StoresWebhookJob(t *testing.T) {
 	incidents := []*domain.Incident{
 		{
 			ID:           "incident-1",
@@ -42,9 +43,8 @@ func TestLocationService_CheckLocation_CacheHit_EnqueuesWebhook(t *testing.T) {
 		},
 	}
 	checkRepo := &fakeCheckRepo{}
-	queue := &fakeQueue{}
 
-	service := svc.NewLocationService(repo, cache, checkRepo, queue)
+	service := svc.NewLocationService(repo, cache, checkRepo)
 
 	resp, err := service.CheckLocation(context.Background(), domain.LocationCheckRequest{
 		UserID:    "user-1",
@@ -78,10 +78,10 @@ func TestLocationService_CheckLocation_CacheHit_EnqueuesWebhook(t *testing.T) {
 	if len(checkRepo.lastIncidentIDs) != 2 {
 		t.Fatalf("expected incident IDs stored for check")
 	}
-	if len(queue.enqueued) != 1 {
-		t.Fatalf("expected webhook job enqueued")
+	if checkRepo.lastJob == nil {
+		t.Fatalf("expected webhook job stored with the check")
 	}
-	job := queue.enqueued[0]
+	job := checkRepo.lastJob
 	if job.Payload.CheckID != resp.CheckID {
 		t.Fatalf("expected webhook payload to include check ID")
 	}
@@ -123,14 +123,8 @@ func TestLocationService_CheckLocation_CacheMiss_NoMatches(t *testing.T) {
 		},
 	}
 	checkRepo := &fakeCheckRepo{}
-	queue := &fakeQueue{
-		enqueueFn: func(ctx context.Context, job domain.WebhookJob) error {
-			t.Fatalf("expected no webhook enqueue when no matches")
-			return nil
-		},
-	}
 
-	service := svc.NewLocationService(repo, cache, checkRepo, queue)
+	service := svc.NewLocationService(repo, cache, checkRepo)
 
 	resp, err := service.CheckLocation(context.Background(), domain.LocationCheckRequest{
 		UserID:    "user-2",
@@ -155,10 +149,69 @@ func TestLocationService_CheckLocation_CacheMiss_NoMatches(t *testing.T) {
 	if checkRepo.createCalls != 1 {
 		t.Fatalf("expected location check to be stored")
 	}
-	if len(queue.enqueued) != 0 {
-		t.Fatalf("expected no webhook jobs enqueued")
+	if checkRepo.lastJob != nil {
+		t.Fatalf("expected no webhook job when no matches")
 	}
 	if checkRepo.lastCheck.CheckedAt.After(time.Now().UTC().Add(1 * time.Second)) {
 		t.Fatalf("unexpected check timestamp")
 	}
 }
+
+func TestLocationService_CheckLocation_TenantScoped(t *testing.T) {
+	var cacheTenant, repoTenant string
+	repo := &fakeIncidentRepo{
+		listActiveFn: func(ctx context.Context) ([]*domain.Incident, error) {
+			repoTenant = domain.TenantFromContext(ctx)
+			return []*domain.Incident{
+				{ID: "incident-1", TenantID: "city-a", Latitude: 0, Longitude: 0, RadiusMeters: 1000, IsActive: true},
+			}, nil
+		},
+	}
+	cache := &fakeIncidentCache{
+		getFn: func(ctx context.Context) ([]*domain.Incident, bool, error) {
+			cacheTenant = domain.TenantFromContext(ctx)
+			return nil, false, nil
+		},
+	}
+	checkRepo := &fakeCheckRepo{}
+
+	service := svc.NewLocationService(repo, cache, checkRepo)
+
+	ctx := domain.WithTenant(context.Background(), "city-a")
+	if _, err := service.CheckLocation(ctx, domain.LocationCheckRequest{UserID: "user-1"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if cacheTenant != "city-a" || repoTenant != "city-a" {
+		t.Fatalf("expected cache and repo lookups scoped to tenant, got cache=%q repo=%q", cacheTenant, repoTenant)
+	}
+	if checkRepo.lastCheck.TenantID != "city-a" {
+		t.Fatalf("expected check stored for tenant, got %q", checkRepo.lastCheck.TenantID)
+	}
+	if checkRepo.lastJob == nil || checkRepo.lastJob.TenantID != "city-a" {
+		t.Fatalf("expected webhook job tagged with tenant")
+	}
+}
+
+func TestLocationService_CheckLocation_StoreFailureFailsWithoutJob(t *testing.T) {
+	cache := &fakeIncidentCache{
+		getFn: func(ctx context.Context) ([]*domain.Incident, bool, error) {
+			return []*domain.Incident{{ID: "incident-1", RadiusMeters: 1000, IsActive: true}}, true, nil
+		},
+	}
+	storeErr := errors.New("db down")
+	checkRepo := &fakeCheckRepo{
+		createFn: func(ctx context.Context, check domain.LocationCheck, incidentIDs []string, job *domain.WebhookJob) error {
+			if job == nil || job.Payload.CheckID != check.ID {
+				t.Fatalf("expected webhook job passed with its check, got %+v", job)
+			}
+			return storeErr
+		},
+	}
+
+	service := svc.NewLocationService(&fakeIncidentRepo{}, cache, checkRepo)
+
+	if _, err := service.CheckLocation(context.Background(), domain.LocationCheckRequest{UserID: "user-1"}); !errors.Is(err, storeErr) {
+		t.Fatalf("expected store error, got %v", err)
+	}
+}
@@ -0,0 +1,77 @@
+package unit
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/ruslanuskembaev/geo-alerts-system/internal/domain"
+	svc "github.com/ruslanuskembaev/geo-alerts-system/internal/service"
+)
+
+func outboxJobs(checkIDs ...string) []domain.WebhookJob {
+	jobs := make([]domain.WebhookJob, 0, len(checkIDs))
+	for _, id := range checkIDs {
+		jobs = append(jobs, domain.WebhookJob{TenantID: "city-a", Payload: domain.WebhookPayload{CheckID: id}})
+	}
+	return jobs
+}
+
+func TestOutboxRelay_DrainsAllBatchesInOrder(t *testing.T) {
+	jobs := outboxJobs("c1", "c2", "c3", "c4", "c5")
+	repo := &fakeOutboxRepo{pending: jobs}
+	queue := &fakeQueue{}
+	relay := svc.NewOutboxRelay(repo, queue, time.Second, 2, 0)
+
+	dispatched, err := relay.RunOnce(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if dispatched != 5 || repo.dispatchCalls != 3 {
+		t.Fatalf("expected 5 jobs in 3 batches, got %d in %d", dispatched, repo.dispatchCalls)
+	}
+	for i, job := range queue.enqueued {
+		if job.Payload.CheckID != jobs[i].Payload.CheckID {
+			t.Fatalf("expected outbox order kept, got %s at %d", job.Payload.CheckID, i)
+		}
+	}
+}
+
+func TestOutboxRelay_QueueFailureKeepsRemainingJobs(t *testing.T) {
+	repo := &fakeOutboxRepo{pending: outboxJobs("c1", "c2", "c3")}
+	queueErr := errors.New("redis down")
+	queue := &fakeQueue{enqueueFn: func(ctx context.Context, job domain.WebhookJob) error {
+		if job.Payload.CheckID == "c2" {
+			return queueErr
+		}
+		return nil
+	}}
+	relay := svc.NewOutboxRelay(repo, queue, time.Second, 10, 0)
+
+	dispatched, err := relay.RunOnce(context.Background())
+	if !errors.Is(err, queueErr) {
+		t.Fatalf("expected queue error, got %v", err)
+	}
+	if dispatched != 1 || len(repo.pending) != 2 || repo.pending[0].Payload.CheckID != "c2" {
+		t.Fatalf("expected only c1 dispatched, got %d, pending %+v", dispatched, repo.pending)
+	}
+}
+
+func TestOutboxRelay_PruneRespectsRetention(t *testing.T) {
+	repo := &fakeOutboxRepo{}
+	if _, err := svc.NewOutboxRelay(repo, &fakeQueue{}, 0, 0, 0).Prune(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if repo.pruneBefore != nil {
+		t.Fatalf("expected no pruning with zero retention")
+	}
+
+	before := time.Now().UTC().Add(-24 * time.Hour)
+	if _, err := svc.NewOutboxRelay(repo, &fakeQueue{}, 0, 0, 24*time.Hour).Prune(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if repo.pruneBefore == nil || repo.pruneBefore.Before(before) || repo.pruneBefore.After(before.Add(time.Minute)) {
+		t.Fatalf("expected prune before now-retention, got %v", repo.pruneBefore)
+	}
+}
@@ -0,0 +1,203 @@
+package unit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ruslanuskembaev/geo-alerts-system/internal/domain"
+	svc "github.com/ruslanuskembaev/geo-alerts-system/internal/service"
+)
+
+func privacyCheck(inDanger bool) domain.LocationCheck {
+	return domain.LocationCheck{
+		ID:             "check-1",
+		TenantID:       "city-a",
+		UserID:         "user-1",
+		Latitude:       55.751244,
+		Longitude:      37.618423,
+		IsInDangerZone: inDanger,
+		CheckedAt:      time.Now().UTC(),
+	}
+}
+
+func TestLocationPrivacy_DefaultKeepsCheck(t *testing.T) {
+	check := privacyCheck(false)
+
+	stored, ok := domain.LocationPrivacy{}.Apply(check)
+	if !ok {
+		t.Fatalf("expected check to be stored")
+	}
+	if stored != check {
+		t.Fatalf("expected check unchanged, got %+v", stored)
+	}
+}
+
+func TestLocationPrivacy_DangerOnlySkipsSafeChecks(t *testing.T) {
+	privacy := domain.LocationPrivacy{DangerOnly: true}
+
+	if _, ok := privacy.Apply(privacyCheck(false)); ok {
+		t.Fatalf("expected safe check to be skipped")
+	}
+	if _, ok := privacy.Apply(privacyCheck(true)); !ok {
+		t.Fatalf("expected danger check to be stored")
+	}
+}
+
+func TestLocationPrivacy_GridSnapsToCellCentre(t *testing.T) {
+	privacy := domain.LocationPrivacy{Coordinates: domain.CoordinatesGrid, GridMeters: 500}
+
+	first, _ := privacy.Apply(privacyCheck(true))
+	nearby := privacyCheck(true)
+	nearby.Latitude += 0.0001
+	nearby.Longitude += 0.0001
+	second, _ := privacy.Apply(nearby)
+
+	if first.Latitude != second.Latitude || first.Longitude != second.Longitude {
+		t.Fatalf("expected nearby points in one cell, got %v,%v and %v,%v",
+			first.Latitude, first.Longitude, second.Latitude, second.Longitude)
+	}
+	if first.Latitude == 55.751244 || first.Longitude == 37.618423 {
+		t.Fatalf("expected coordinates to be coarsened, got %v,%v", first.Latitude, first.Longitude)
+	}
+	// Центр ячейки не дальше половины диагонали от исходной точки
+	dLat := (first.Latitude - 55.751244) * 111320
+	dLon := (first.Longitude - 37.618423) * 111320 * math.Cos(first.Latitude*math.Pi/180)
+	if dist := math.Hypot(dLat, dLon); dist > 500*math.Sqrt2/2+1 {
+		t.Fatalf("expected snapped point within cell, got %.0fm away", dist)
+	}
+}
+
+func TestSnapToGrid_StaysInRangeNearPolesAndAntimeridian(t *testing.T) {
+	for _, point := range [][2]float64{{90, 180}, {-90, -180}, {89.9999, 179.9999}, {0, 180}} {
+		lat, lon := domain.SnapToGrid(point[0], point[1], 1000)
+		if lat < -90 || lat > 90 || lon < -180 || lon > 180 {
+			t.Fatalf("snap of %v out of range: %v,%v", point, lat, lon)
+		}
+	}
+}
+
+func TestLocationPrivacy_NoneDropsCoordinates(t *testing.T) {
+	privacy := domain.LocationPrivacy{Coordinates: domain.CoordinatesNone}
+
+	stored, ok := privacy.Apply(privacyCheck(true))
+	if !ok || privacy.StoresCoordinates() {
+		t.Fatalf("expected check stored without coordinates")
+	}
+	if stored.Latitude != 0 || stored.Longitude != 0 {
+		t.Fatalf("expected coordinates dropped, got %v,%v", stored.Latitude, stored.Longitude)
+	}
+}
+
+func TestLocationPrivacy_PseudonymStableWithinKey(t *testing.T) {
+	current := domain.UserIDKey{ID: "k2", Secret: "new-secret"}
+	previous := domain.UserIDKey{ID: "k1", Secret: "old-secret"}
+	privacy := domain.LocationPrivacy{Keys: []domain.UserIDKey{current, previous}}
+
+	first, _ := privacy.Apply(privacyCheck(true))
+	second, _ := privacy.Apply(privacyCheck(false))
+	if first.UserID != second.UserID {
+		t.Fatalf("expected same pseudonym for one user, got %q and %q", first.UserID, second.UserID)
+	}
+	if first.UserID == "user-1" || !strings.HasPrefix(first.UserID, "k2:") {
+		t.Fatalf("expected pseudonym signed by current key, got %q", first.UserID)
+	}
+
+	other := privacyCheck(true)
+	other.TenantID = "city-b"
+	if stored, _ := privacy.Apply(other); stored.UserID == first.UserID {
+		t.Fatalf("expected pseudonyms to differ between tenants")
+	}
+
+	rotated, _ := domain.LocationPrivacy{Keys: []domain.UserIDKey{previous}}.Apply(privacyCheck(true))
+	if rotated.UserID == first.UserID {
+		t.Fatalf("expected pseudonym to change with key")
+	}
+
+	ids := privacy.StoredUserIDs("city-a", "user-1")
+	want := []string{"user-1", first.UserID, rotated.UserID}
+	if len(ids) != len(want) {
+		t.Fatalf("expected %v, got %v", want, ids)
+	}
+	for i := range want {
+		if ids[i] != want[i] {
+			t.Fatalf("expected %v, got %v", want, ids)
+		}
+	}
+}
+
+func TestLocationPrivacy_UserIDLookupsCurrentKeyFirst(t *testing.T) {
+	current := domain.UserIDKey{ID: "k2", Secret: "new-secret"}
+	previous := domain.UserIDKey{ID: "k1", Secret: "old-secret"}
+	privacy := domain.LocationPrivacy{Keys: []domain.UserIDKey{current, previous}}
+
+	lookups := privacy.UserIDLookups("city-a", "user-1")
+	if len(lookups) != 2 ||
+		lookups[0] != domain.PseudonymizeUserID(current, "city-a", "user-1") ||
+		lookups[1] != domain.PseudonymizeUserID(previous, "city-a", "user-1") {
+		t.Fatalf("expected HMAC under current key then previous, got %v", lookups)
+	}
+	if stored, _ := privacy.Apply(privacyCheck(true)); stored.UserID != lookups[0] {
+		t.Fatalf("expected Apply to use current key lookup, got %q", stored.UserID)
+	}
+	if got := (domain.LocationPrivacy{}).UserIDLookups("city-a", "user-1"); len(got) != 0 {
+		t.Fatalf("expected no lookups without keys, got %v", got)
+	}
+}
+
+func TestLocationPrivacy_ValidateRejectsUnknownModes(t *testing.T) {
+	for _, privacy := range []domain.LocationPrivacy{
+		{Coordinates: domain.CoordinatesExact},
+		{Coordinates: domain.CoordinatesNone},
+		{Coordinates: domain.CoordinatesGrid, GridMeters: 500},
+	} {
+		if err := privacy.Validate(); err != nil {
+			t.Fatalf("expected %+v to be valid, got %v", privacy, err)
+		}
+	}
+	for _, privacy := range []domain.LocationPrivacy{
+		{Coordinates: "rounded"},
+		{Coordinates: "Grid", GridMeters: 500},
+		{Coordinates: ""},
+		{Coordinates: domain.CoordinatesGrid, GridMeters: 0},
+	} {
+		if err := privacy.Validate(); err == nil {
+			t.Fatalf("expected %+v to be rejected", privacy)
+		}
+	}
+}
+
+func TestPseudonymBackfill_RunOnceConvertsAllRawUsers(t *testing.T) {
+	privacy := domain.LocationPrivacy{Keys: []domain.UserIDKey{{ID: "k2", Secret: "new"}, {ID: "k1", Secret: "old"}}}
+	repo := &fakePseudonymRepo{}
+	for i := 0; i < 5; i++ {
+		repo.raw = append(repo.raw, domain.StoredUser{TenantID: "city-a", UserID: fmt.Sprintf("user-%d", i)})
+	}
+
+	converted, err := svc.NewPseudonymBackfill(repo, privacy, time.Minute, 2).RunOnce(context.Background())
+	if err != nil || converted != 5 || len(repo.raw) != 0 {
+		t.Fatalf("expected all 5 users converted in batches, got %d (%v), %d left", converted, err, len(repo.raw))
+	}
+	lookups := repo.converted[domain.StoredUser{TenantID: "city-a", UserID: "user-3"}]
+	if !slices.Equal(lookups, privacy.UserIDLookups("city-a", "user-3")) {
+		t.Fatalf("expected lookups under all keys, got %v", lookups)
+	}
+}
+
+func TestPseudonymBackfill_RunOnceStopsOnError(t *testing.T) {
+	repo := &fakePseudonymRepo{
+		raw: []domain.StoredUser{{TenantID: "city-a", UserID: "user-1"}},
+		err: errors.New("db down"),
+	}
+	privacy := domain.LocationPrivacy{Keys: []domain.UserIDKey{{ID: "k1", Secret: "secret"}}}
+
+	converted, err := svc.NewPseudonymBackfill(repo, privacy, time.Minute, 10).RunOnce(context.Background())
+	if err == nil || converted != 0 || len(repo.raw) != 1 {
+		t.Fatalf("expected error with the user left for retry, got %d (%v)", converted, err)
+	}
+}
@@ -0,0 +1,138 @@
+package unit
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/ruslanuskembaev/geo-alerts-system/internal/domain"
+	svc "github.com/ruslanuskembaev/geo-alerts-system/internal/service"
+)
+
+func TestIncidentPurger_RunOnce_UsesRetentionCutoff(t *testing.T) {
+	var cutoff time.Time
+	var actor string
+	repo := &fakeIncidentRepo{
+		purgeBeforeFn: func(ctx context.Context, before time.Time) (int, error) {
+			cutoff = before
+			actor = domain.ActorFromContext(ctx)
+			return 2, nil
+		},
+	}
+
+	purger := svc.NewIncidentPurger(repo, 30*24*time.Hour, time.Hour)
+	purged, err := purger.RunOnce(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected purge error: %v", err)
+	}
+	if purged != 2 {
+		t.Fatalf("expected 2 purged incidents, got %d", purged)
+	}
+
+	expected := time.Now().UTC().Add(-30 * 24 * time.Hour)
+	if cutoff.Sub(expected) > time.Second || expected.Sub(cutoff) > time.Second {
+		t.Fatalf("unexpected purge cutoff %s, expected about %s", cutoff, expected)
+	}
+	if actor != domain.SystemActor {
+		t.Fatalf("expected system actor for scheduled purge, got %s", actor)
+	}
+}
+
+func TestIncidentService_ReactivateAndPurge_InvalidateCache(t *testing.T) {
+	repo := &fakeIncidentRepo{
+		reactivateFn: func(ctx context.Context, id string, expectedVersion *int) (*domain.Incident, error) {
+			return &domain.Incident{ID: id, IsActive: true}, nil
+		},
+		purgeFn: func(ctx context.Context, id string) error {
+			return nil
+		},
+	}
+	cache := &fakeIncidentCache{}
+	service := svc.NewIncidentService(repo, cache, &fakeCheckRepo{})
+
+	incident, err := service.Reactivate(context.Background(), "incident-1", nil)
+	if err != nil {
+		t.Fatalf("unexpected reactivate error: %v", err)
+	}
+	if !incident.IsActive {
+		t.Fatalf("expected reactivated incident to be active")
+	}
+	if err := service.Purge(context.Background(), "incident-1"); err != nil {
+		t.Fatalf("unexpected purge error: %v", err)
+	}
+	if cache.invalidateCalls != 2 {
+		t.Fatalf("expected cache invalidation on reactivate and purge, got %d", cache.invalidateCalls)
+	}
+}
+
+func TestIncidentExpirer_RunOnce_InvalidatesAffectedTenants(t *testing.T) {
+	var actor string
+	repo := &fakeIncidentRepo{
+		expireFn: func(ctx context.Context, now time.Time) ([]*domain.Incident, error) {
+			actor = domain.ActorFromContext(ctx)
+			return []*domain.Incident{
+				{ID: "a", TenantID: "city-a"},
+				{ID: "b", TenantID: "city-a"},
+				{ID: "c", TenantID: "city-b"},
+			}, nil
+		},
+	}
+	var tenants []string
+	cache := &fakeIncidentCache{
+		invalidateFn: func(ctx context.Context) error {
+			tenants = append(tenants, domain.TenantFromContext(ctx))
+			return nil
+		},
+	}
+
+	expired, err := svc.NewIncidentExpirer(repo, cache, time.Minute).RunOnce(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected expiry error: %v", err)
+	}
+	if expired != 3 || len(tenants) != 2 {
+		t.Fatalf("expected 3 expired incidents and 2 invalidated tenants, got %d / %v", expired, tenants)
+	}
+	if actor != domain.SystemActor {
+		t.Fatalf("expected system actor for expiry, got %s", actor)
+	}
+}
+
+func TestLocationCheckRetention_RunOnce(t *testing.T) {
+	var ensureFrom, ensureTo, dropBefore time.Time
+	dropCalls := 0
+	repo := &fakePartitionRepo{
+		ensureFn: func(ctx context.Context, from, to time.Time) ([]string, error) {
+			ensureFrom, ensureTo = from, to
+			return []string{"location_checks_p202701"}, nil
+		},
+		dropFn: func(ctx context.Context, before time.Time) ([]string, error) {
+			dropCalls++
+			dropBefore = before
+			return []string{"location_checks_p202605"}, nil
+		},
+	}
+
+	// Без срока хранения секции только создаются
+	created, dropped, err := svc.NewLocationCheckRetention(repo, 0, 3, time.Hour).RunOnce(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected retention error: %v", err)
+	}
+	if len(created) != 1 || dropped != nil || dropCalls != 0 {
+		t.Fatalf("expected partitions to be kept forever, got %v / %v", created, dropped)
+	}
+	if months := ensureTo.Sub(ensureFrom); months < 89*24*time.Hour || months > 93*24*time.Hour {
+		t.Fatalf("expected partitions three months ahead, got %s - %s", ensureFrom, ensureTo)
+	}
+
+	_, dropped, err = svc.NewLocationCheckRetention(repo, 90*24*time.Hour, 3, time.Hour).RunOnce(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected retention error: %v", err)
+	}
+	if len(dropped) != 1 {
+		t.Fatalf("expected expired partition to be dropped, got %v", dropped)
+	}
+	expected := time.Now().UTC().Add(-90 * 24 * time.Hour)
+	if dropBefore.Sub(expected) > time.Second || expected.Sub(dropBefore) > time.Second {
+		t.Fatalf("unexpected retention cutoff %s, expected about %s", dropBefore, expected)
+	}
+}
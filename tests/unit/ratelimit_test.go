@@ -0,0 +1,95 @@
+package unit
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/ruslanuskembaev/geo-alerts-system/internal/handler"
+	svc "github.com/ruslanuskembaev/geo-alerts-system/internal/service"
+)
+
+func TestRateLimitService_ReturnsRetryAfter(t *testing.T) {
+	limiter := &fakeRateLimiter{
+		allowFn: func(ctx context.Context, key string, limit int, window time.Duration) (bool, time.Duration, error) {
+			if limit != 2 || window != time.Minute {
+				t.Fatalf("unexpected limit %d / window %s", limit, window)
+			}
+			return false, 30 * time.Second, nil
+		},
+	}
+	limits := svc.NewRateLimitService(limiter, 2, 5, time.Minute)
+
+	allowed, retryAfter := limits.AllowUser(context.Background(), "user-1")
+	if allowed {
+		t.Fatalf("expected user to be rate limited")
+	}
+	if retryAfter != 30*time.Second {
+		t.Fatalf("unexpected retry after: %s", retryAfter)
+	}
+	if len(limiter.keys) != 1 || limiter.keys[0] != "user:default:user-1" {
+		t.Fatalf("expected per-user key, got %v", limiter.keys)
+	}
+}
+
+func TestRateLimitService_DisabledAndFailOpen(t *testing.T) {
+	limiter := &fakeRateLimiter{
+		allowFn: func(ctx context.Context, key string, limit int, window time.Duration) (bool, time.Duration, error) {
+			return false, 0, errors.New("redis down")
+		},
+	}
+	limits := svc.NewRateLimitService(limiter, 0, 5, time.Minute)
+
+	if allowed, _ := limits.AllowUser(context.Background(), "user-1"); !allowed {
+		t.Fatalf("expected zero limit to disable per-user limiting")
+	}
+	if len(limiter.keys) != 0 {
+		t.Fatalf("expected limiter not called when disabled")
+	}
+	if allowed, _ := limits.AllowIP(context.Background(), "10.0.0.1"); !allowed {
+		t.Fatalf("expected limiter errors to fail open")
+	}
+}
+
+func TestIPRateLimitMiddleware_IgnoresForwardedForFromUntrustedClients(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	limiter := &fakeRateLimiter{}
+	limits := svc.NewRateLimitService(limiter, 0, 5, time.Minute)
+
+	newRouter := func(trustedProxies []string) *gin.Engine {
+		r := gin.New()
+		if err := r.SetTrustedProxies(trustedProxies); err != nil {
+			t.Fatalf("set trusted proxies: %v", err)
+		}
+		r.POST("/check", handler.IPRateLimitMiddleware(limits), func(c *gin.Context) {
+			c.Status(http.StatusOK)
+		})
+		return r
+	}
+	send := func(r *gin.Engine, remoteAddr, forwardedFor string) {
+		req := httptest.NewRequest(http.MethodPost, "/check", nil)
+		req.RemoteAddr = remoteAddr
+		req.Header.Set("X-Forwarded-For", forwardedFor)
+		r.ServeHTTP(httptest.NewRecorder(), req)
+	}
+
+	// Подмена X-Forwarded-For не даёт клиенту новую корзину
+	direct := newRouter(nil)
+	send(direct, "203.0.113.5:1234", "198.51.100.1")
+	send(direct, "203.0.113.5:1234", "198.51.100.2")
+	if len(limiter.keys) != 2 || limiter.keys[0] != "ip:203.0.113.5" || limiter.keys[1] != "ip:203.0.113.5" {
+		t.Fatalf("expected limit keyed by remote address, got %v", limiter.keys)
+	}
+
+	// За доверенным прокси клиентом считается адрес из X-Forwarded-For
+	limiter.keys = nil
+	send(newRouter([]string{"10.0.0.0/8"}), "10.0.0.7:1234", "198.51.100.1")
+	if len(limiter.keys) != 1 || limiter.keys[0] != "ip:198.51.100.1" {
+		t.Fatalf("expected forwarded client address behind trusted proxy, got %v", limiter.keys)
+	}
+}
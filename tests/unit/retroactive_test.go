@@ -0,0 +1,215 @@
+package unit
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/ruslanuskembaev/geo-alerts-system/internal/domain"
+	"github.com/ruslanuskembaev/geo-alerts-system/internal/repository"
+	svc "github.com/ruslanuskembaev/geo-alerts-system/internal/service"
+)
+
+func retroactiveIncident(id string) *domain.Incident {
+	return &domain.Incident{
+		ID:           id,
+		TenantID:     "city-a",
+		Title:        "Gas leak",
+		Severity:     domain.SeverityHigh,
+		Latitude:     55.75,
+		Longitude:    37.61,
+		RadiusMeters: 500,
+		IsActive:     true,
+	}
+}
+
+func TestIncidentService_CreateRequestsRetroactiveAlerts(t *testing.T) {
+	repo := &fakeIncidentRepo{
+		createFn: func(ctx context.Context, req domain.CreateIncidentRequest) (*domain.Incident, error) {
+			return retroactiveIncident("incident-1"), nil
+		},
+	}
+	checkRepo := &fakeCheckRepo{
+		latestInZoneFn: func(ctx context.Context, incident domain.Incident, since time.Time) ([]domain.LocationCheck, error) {
+			t.Fatalf("lookup must run in the background alerter, not in the request")
+			return nil, nil
+		},
+	}
+	retro := &fakeRetroactiveRepo{}
+	service := svc.NewIncidentService(repo, &fakeIncidentCache{}, checkRepo).
+		WithRetroactiveAlerts(retro, 15*time.Minute, domain.LocationPrivacy{})
+
+	ctx := domain.WithTenant(context.Background(), "city-a")
+	if _, err := service.Create(ctx, domain.CreateIncidentRequest{NotifyRecentUsers: true}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(retro.requests) != 1 {
+		t.Fatalf("expected one queued request, got %+v", retro.requests)
+	}
+	req := retro.requests[0]
+	if req.TenantID != "city-a" || req.IncidentID != "incident-1" {
+		t.Fatalf("unexpected request: %+v", req)
+	}
+	if since := time.Since(req.Since); since < 15*time.Minute || since > 16*time.Minute {
+		t.Fatalf("expected 15 minute lookback, got %s", since)
+	}
+}
+
+func TestIncidentService_RetroactiveAlertsOnlyWhenRequested(t *testing.T) {
+	repo := &fakeIncidentRepo{
+		createFn: func(ctx context.Context, req domain.CreateIncidentRequest) (*domain.Incident, error) {
+			return retroactiveIncident("incident-1"), nil
+		},
+		updateFn: func(ctx context.Context, id string, req domain.UpdateIncidentRequest, expectedVersion *int) (*domain.Incident, error) {
+			return retroactiveIncident(id), nil
+		},
+	}
+	retro := &fakeRetroactiveRepo{}
+	service := svc.NewIncidentService(repo, &fakeIncidentCache{}, &fakeCheckRepo{}).
+		WithRetroactiveAlerts(retro, 15*time.Minute, domain.LocationPrivacy{})
+	ctx := context.Background()
+
+	title := "Renamed"
+	radius := 800
+	if _, err := service.Create(ctx, domain.CreateIncidentRequest{}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := service.Update(ctx, "incident-1", domain.UpdateIncidentRequest{Title: &title, NotifyRecentUsers: true}, nil); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(retro.requests) != 0 {
+		t.Fatalf("expected no request without flag or geometry change, got %+v", retro.requests)
+	}
+
+	if _, err := service.Update(ctx, "incident-1", domain.UpdateIncidentRequest{RadiusMeters: &radius, NotifyRecentUsers: true}, nil); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(retro.requests) != 1 {
+		t.Fatalf("expected request after radius change, got %+v", retro.requests)
+	}
+}
+
+func TestIncidentService_RetroactiveAlertsDisabledByPrivacy(t *testing.T) {
+	tests := map[string]domain.LocationPrivacy{
+		"pseudonymised":  {Keys: []domain.UserIDKey{{ID: "k1", Secret: "secret"}}},
+		"no coordinates": {Coordinates: domain.CoordinatesNone},
+		"danger only":    {DangerOnly: true},
+	}
+	for name, privacy := range tests {
+		t.Run(name, func(t *testing.T) {
+			repo := &fakeIncidentRepo{
+				createFn: func(ctx context.Context, req domain.CreateIncidentRequest) (*domain.Incident, error) {
+					return retroactiveIncident("incident-1"), nil
+				},
+			}
+			retro := &fakeRetroactiveRepo{}
+			service := svc.NewIncidentService(repo, &fakeIncidentCache{}, &fakeCheckRepo{}).
+				WithRetroactiveAlerts(retro, 15*time.Minute, privacy)
+
+			if _, err := service.Create(context.Background(), domain.CreateIncidentRequest{NotifyRecentUsers: true}); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if len(retro.requests) != 0 || service.RetroactiveLookback() != 0 {
+				t.Fatalf("expected retroactive alerts disabled, got %+v", retro.requests)
+			}
+		})
+	}
+}
+
+func TestIncidentService_RetroactiveRequestFailureDoesNotFailCreate(t *testing.T) {
+	repo := &fakeIncidentRepo{
+		createFn: func(ctx context.Context, req domain.CreateIncidentRequest) (*domain.Incident, error) {
+			return retroactiveIncident("incident-1"), nil
+		},
+	}
+	retro := &fakeRetroactiveRepo{requestFn: func(context.Context, string, time.Time) error {
+		return errors.New("db down")
+	}}
+	service := svc.NewIncidentService(repo, &fakeIncidentCache{}, &fakeCheckRepo{}).
+		WithRetroactiveAlerts(retro, 15*time.Minute, domain.LocationPrivacy{})
+
+	incident, err := service.Create(context.Background(), domain.CreateIncidentRequest{NotifyRecentUsers: true})
+	if err != nil || incident == nil {
+		t.Fatalf("expected incident created despite request failure, got %v", err)
+	}
+}
+
+func TestRetroactiveAlerter_WritesAlertsInOneBatch(t *testing.T) {
+	incidentRepo := &fakeIncidentRepo{
+		getByIDFn: func(ctx context.Context, id string) (*domain.Incident, error) {
+			if tenant := domain.TenantFromContext(ctx); tenant != "city-a" {
+				t.Fatalf("expected request tenant in context, got %q", tenant)
+			}
+			return retroactiveIncident(id), nil
+		},
+	}
+	since := time.Now().UTC().Add(-15 * time.Minute)
+	checkedAt := time.Now().UTC().Add(-5 * time.Minute)
+	var gotSince time.Time
+	checkRepo := &fakeCheckRepo{
+		latestInZoneFn: func(ctx context.Context, incident domain.Incident, from time.Time) ([]domain.LocationCheck, error) {
+			gotSince = from
+			return []domain.LocationCheck{
+				{ID: "check-1", TenantID: "city-a", UserID: "user-1", Latitude: 55.751, Longitude: 37.61, CheckedAt: checkedAt},
+				{ID: "check-2", TenantID: "city-a", UserID: "user-2", Latitude: 55.75, Longitude: 37.61, CheckedAt: checkedAt},
+			}, nil
+		},
+	}
+	retro := &fakeRetroactiveRepo{requests: []domain.RetroactiveAlertRequest{
+		{ID: 1, TenantID: "city-a", IncidentID: "incident-1", Since: since},
+	}}
+	alerter := svc.NewRetroactiveAlerter(retro, incidentRepo, checkRepo, 15*time.Minute, time.Second)
+
+	alerted, err := alerter.RunOnce(context.Background())
+	if err != nil || alerted != 2 {
+		t.Fatalf("expected 2 alerts, got %d (%v)", alerted, err)
+	}
+	if !gotSince.Equal(since) {
+		t.Fatalf("expected lookup from request time window, got %s", gotSince)
+	}
+	if retro.batches != 1 || len(retro.outbox) != 2 || len(retro.completed) != 1 {
+		t.Fatalf("expected both alerts written with the request, got %d writes / %+v", retro.batches, retro.outbox)
+	}
+	job := retro.outbox[0]
+	if job.TenantID != "city-a" || !job.Payload.Retroactive || !job.Payload.IsInDangerZone ||
+		job.Payload.UserID != "user-1" || job.Payload.CheckID != "check-1" || !job.Payload.CheckedAt.Equal(checkedAt) {
+		t.Fatalf("unexpected job: %+v", job)
+	}
+	if len(job.Payload.Incidents) != 1 || job.Payload.Incidents[0].ID != "incident-1" ||
+		job.Payload.Incidents[0].DistanceMeters < 100 || job.Payload.Incidents[0].DistanceMeters > 120 {
+		t.Fatalf("unexpected incidents: %+v", job.Payload.Incidents)
+	}
+}
+
+func TestRetroactiveAlerter_SkipsGoneIncidents(t *testing.T) {
+	incidentRepo := &fakeIncidentRepo{
+		getByIDFn: func(ctx context.Context, id string) (*domain.Incident, error) {
+			if id == "deleted" {
+				return nil, repository.ErrNotFound
+			}
+			incident := retroactiveIncident(id)
+			incident.IsActive = false
+			return incident, nil
+		},
+	}
+	checkRepo := &fakeCheckRepo{
+		latestInZoneFn: func(ctx context.Context, incident domain.Incident, since time.Time) ([]domain.LocationCheck, error) {
+			t.Fatalf("lookup must not run for deleted or inactive incidents")
+			return nil, nil
+		},
+	}
+	retro := &fakeRetroactiveRepo{requests: []domain.RetroactiveAlertRequest{
+		{ID: 1, TenantID: "city-a", IncidentID: "deleted"},
+		{ID: 2, TenantID: "city-a", IncidentID: "inactive"},
+	}}
+	alerter := svc.NewRetroactiveAlerter(retro, incidentRepo, checkRepo, 15*time.Minute, time.Second)
+
+	if alerted, err := alerter.RunOnce(context.Background()); err != nil || alerted != 0 {
+		t.Fatalf("expected no alerts, got %d (%v)", alerted, err)
+	}
+	if len(retro.completed) != 2 || len(retro.outbox) != 0 {
+		t.Fatalf("expected both requests completed without alerts, got %+v / %+v", retro.completed, retro.outbox)
+	}
+}
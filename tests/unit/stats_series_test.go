@@ -0,0 +1,114 @@
+package unit
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/ruslanuskembaev/geo-alerts-system/internal/domain"
+	"github.com/ruslanuskembaev/geo-alerts-system/internal/handler"
+	svc "github.com/ruslanuskembaev/geo-alerts-system/internal/service"
+)
+
+const seriesIncidentID = "6f1c2f8e-3d4b-4a5c-9e7f-0a1b2c3d4e5f"
+
+func newStatsSeriesRouter(checkRepo *fakeCheckRepo) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	service := svc.NewIncidentService(&fakeIncidentRepo{}, &fakeIncidentCache{}, checkRepo)
+	h := handler.NewIncidentHandler(service, time.Hour)
+
+	r := gin.New()
+	r.GET("/incidents/stats/timeseries", h.StatsSeries)
+	return r
+}
+
+func TestIncidentHandler_StatsSeriesFillsEmptyBuckets(t *testing.T) {
+	var got domain.StatsSeriesQuery
+	checkRepo := &fakeCheckRepo{
+		statsSeriesFn: func(ctx context.Context, query domain.StatsSeriesQuery) ([]domain.IncidentStatsSeries, error) {
+			got = query
+			return []domain.IncidentStatsSeries{{
+				IncidentID: seriesIncidentID,
+				Title:      "Flood",
+				Points: []domain.StatsPoint{
+					{BucketStart: time.Date(2026, 10, 19, 10, 0, 0, 0, time.UTC), Checks: 5, Users: 3, NewUsers: 3},
+					{BucketStart: time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC), Checks: 2, Users: 2, NewUsers: 1},
+				},
+			}}, nil
+		},
+	}
+
+	rec := getFeed(newStatsSeriesRouter(checkRepo),
+		"/incidents/stats/timeseries?from=2026-10-19T09:30:00Z&to=2026-10-19T13:00:00Z&bucket=hour&incident_id="+seriesIncidentID, nil)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if !got.From.Equal(time.Date(2026, 10, 19, 9, 0, 0, 0, time.UTC)) || got.Bucket != domain.StatsBucketHour {
+		t.Fatalf("expected from aligned to bucket start, got %+v", got)
+	}
+	if len(got.IncidentIDs) != 1 || got.IncidentIDs[0] != seriesIncidentID {
+		t.Fatalf("unexpected incident filter: %v", got.IncidentIDs)
+	}
+
+	var result domain.StatsSeries
+	if err := json.Unmarshal(rec.Body.Bytes(), &result); err != nil {
+		t.Fatalf("invalid response: %v", err)
+	}
+	points := result.Series[0].Points
+	if len(points) != 4 {
+		t.Fatalf("expected 4 hourly buckets 09..12, got %+v", points)
+	}
+	expected := []int64{0, 5, 0, 2}
+	for i, point := range points {
+		if point.Checks != expected[i] || !point.BucketStart.Equal(got.From.Add(time.Duration(i)*time.Hour)) {
+			t.Fatalf("unexpected point %d: %+v", i, point)
+		}
+	}
+	if points[3].NewUsers != 1 || points[1].Users != 3 {
+		t.Fatalf("expected users and new users preserved, got %+v", points)
+	}
+}
+
+func TestIncidentHandler_StatsSeriesValidation(t *testing.T) {
+	router := newStatsSeriesRouter(&fakeCheckRepo{})
+	for _, query := range []string{
+		"bucket=week",
+		"from=2026-10-19T10:00:00Z&to=2026-10-19T09:00:00Z",
+		"from=2026-01-01T00:00:00Z&to=2026-10-19T00:00:00Z&bucket=minute",
+		"incident_id=not-a-uuid",
+		"to=tomorrow",
+	} {
+		if rec := getFeed(router, "/incidents/stats/timeseries?"+query, nil); rec.Code != http.StatusBadRequest {
+			t.Fatalf("%s: expected 400, got %d", query, rec.Code)
+		}
+	}
+
+	if rec := getFeed(router, "/incidents/stats/timeseries?from=2026-01-01T00:00:00Z&to=2026-10-19T00:00:00Z&bucket=day", nil); rec.Code != http.StatusOK {
+		t.Fatalf("expected daily buckets over months to be accepted, got %d", rec.Code)
+	}
+}
+
+func TestStatsRollupPruner_RunOnce_KeepsOpenBuckets(t *testing.T) {
+	var cutoff time.Time
+	checkRepo := &fakeCheckRepo{
+		pruneFn: func(ctx context.Context, before time.Time) (int, error) {
+			cutoff = before
+			return 7, nil
+		},
+	}
+
+	pruned, err := svc.NewStatsRollupPruner(checkRepo, time.Hour).RunOnce(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected prune error: %v", err)
+	}
+	if pruned != 7 {
+		t.Fatalf("expected 7 pruned marks, got %d", pruned)
+	}
+	if age := time.Since(cutoff); age < 24*time.Hour {
+		t.Fatalf("expected cutoff to keep at least the open day bucket, got %s", age)
+	}
+}
@@ -0,0 +1,308 @@
+package unit
+
+import (
+	"context"
+	"encoding/binary"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/ruslanuskembaev/geo-alerts-system/internal/domain"
+	"github.com/ruslanuskembaev/geo-alerts-system/internal/handler"
+	svc "github.com/ruslanuskembaev/geo-alerts-system/internal/service"
+)
+
+// mvtFeature разобранный объект слоя: атрибуты и геометрия в абсолютных координатах тайла
+type mvtFeature struct {
+	geomType uint64
+	attrs    map[string]interface{}
+	points   [][2]int
+	closed   bool
+}
+
+type mvtDecodedLayer struct {
+	name     string
+	version  uint64
+	extent   uint64
+	features []mvtFeature
+}
+
+// pbFields разбирает protobuf-сообщение из varint и length-delimited полей
+func pbFields(t *testing.T, data []byte, fn func(field int, varint uint64, bytes []byte)) {
+	t.Helper()
+	for len(data) > 0 {
+		tag, n := binary.Uvarint(data)
+		if n <= 0 {
+			t.Fatalf("invalid tag")
+		}
+		data = data[n:]
+		switch tag & 0x7 {
+		case 0:
+			v, n := binary.Uvarint(data)
+			if n <= 0 {
+				t.Fatalf("invalid varint")
+			}
+			data = data[n:]
+			fn(int(tag>>3), v, nil)
+		case 2:
+			size, n := binary.Uvarint(data)
+			if n <= 0 || int(size) > len(data)-n {
+				t.Fatalf("invalid length")
+			}
+			fn(int(tag>>3), 0, data[n:n+int(size)])
+			data = data[n+int(size):]
+		default:
+			t.Fatalf("unexpected wire type %d", tag&0x7)
+		}
+	}
+}
+
+func pbPacked(t *testing.T, data []byte) []uint64 {
+	t.Helper()
+	var values []uint64
+	for len(data) > 0 {
+		v, n := binary.Uvarint(data)
+		if n <= 0 {
+			t.Fatalf("invalid packed varint")
+		}
+		values = append(values, v)
+		data = data[n:]
+	}
+	return values
+}
+
+func decodeMVT(t *testing.T, tile []byte) []mvtDecodedLayer {
+	t.Helper()
+	var layers []mvtDecodedLayer
+	pbFields(t, tile, func(field int, _ uint64, raw []byte) {
+		if field != 3 {
+			t.Fatalf("unexpected tile field %d", field)
+		}
+		layer := mvtDecodedLayer{}
+		var keys []string
+		var values []interface{}
+		var rawFeatures [][]byte
+		pbFields(t, raw, func(field int, v uint64, b []byte) {
+			switch field {
+			case 15:
+				layer.version = v
+			case 1:
+				layer.name = string(b)
+			case 2:
+				rawFeatures = append(rawFeatures, b)
+			case 3:
+				keys = append(keys, string(b))
+			case 4:
+				pbFields(t, b, func(field int, v uint64, b []byte) {
+					switch field {
+					case 1:
+						values = append(values, string(b))
+					case 5:
+						values = append(values, v)
+					default:
+						t.Fatalf("unexpected value field %d", field)
+					}
+				})
+			case 5:
+				layer.extent = v
+			}
+		})
+		for _, raw := range rawFeatures {
+			feature := mvtFeature{attrs: map[string]interface{}{}}
+			var tags, geometry []uint64
+			pbFields(t, raw, func(field int, v uint64, b []byte) {
+				switch field {
+				case 2:
+					tags = pbPacked(t, b)
+				case 3:
+					feature.geomType = v
+				case 4:
+					geometry = pbPacked(t, b)
+				}
+			})
+			for i := 0; i+1 < len(tags); i += 2 {
+				feature.attrs[keys[tags[i]]] = values[tags[i+1]]
+			}
+			feature.points, feature.closed = decodeMVTGeometry(t, geometry)
+			layer.features = append(layer.features, feature)
+		}
+		layers = append(layers, layer)
+	})
+	return layers
+}
+
+func decodeMVTGeometry(t *testing.T, geometry []uint64) ([][2]int, bool) {
+	t.Helper()
+	unzigzag := func(v uint64) int { return int(int32(v>>1) ^ -int32(v&1)) }
+	var points [][2]int
+	var x, y int
+	closed := false
+	for i := 0; i < len(geometry); {
+		id, count := geometry[i]&0x7, int(geometry[i]>>3)
+		i++
+		if id == 7 {
+			closed = true
+			continue
+		}
+		for j := 0; j < count; j++ {
+			x += unzigzag(geometry[i])
+			y += unzigzag(geometry[i+1])
+			i += 2
+			points = append(points, [2]int{x, y})
+		}
+	}
+	return points, closed
+}
+
+// moscowTile тайл z10, содержащий центр Москвы (55.75, 37.61)
+var moscowTile = domain.TileCoord{Z: 10, X: 618, Y: 320}
+
+func tileIncidents() []*domain.Incident {
+	expired := time.Now().Add(-time.Minute)
+	return []*domain.Incident{
+		{ID: "storm", Title: "Storm", Severity: domain.SeverityHigh, Latitude: 55.75, Longitude: 37.61, RadiusMeters: 2000, IsActive: true},
+		{ID: "expired", Title: "Expired", Severity: domain.SeverityLow, Latitude: 55.75, Longitude: 37.61, RadiusMeters: 500, IsActive: true, ExpiresAt: &expired},
+		{ID: "piter", Title: "Flood", Severity: domain.SeverityMedium, Latitude: 59.93, Longitude: 30.33, RadiusMeters: 3000, IsActive: true},
+	}
+}
+
+func newTileService(incidents []*domain.Incident) (*svc.TileService, *fakeIncidentRepo, *fakeTileCache) {
+	repo := &fakeIncidentRepo{
+		listActiveFn: func(ctx context.Context) ([]*domain.Incident, error) {
+			return incidents, nil
+		},
+	}
+	tiles := &fakeTileCache{}
+	return svc.NewTileService(repo, &fakeIncidentCache{}, tiles), repo, tiles
+}
+
+func TestTileService_RendersZonesAsClockwisePolygons(t *testing.T) {
+	service, _, _ := newTileService(tileIncidents())
+	tile, err := service.IncidentTile(context.Background(), moscowTile)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	layers := decodeMVT(t, tile)
+	if len(layers) != 1 || layers[0].name != "incidents" || layers[0].version != 2 || layers[0].extent != 4096 {
+		t.Fatalf("unexpected layers: %+v", layers)
+	}
+	features := layers[0].features
+	if len(features) != 1 {
+		t.Fatalf("expected only the active zone inside the tile, got %d features", len(features))
+	}
+
+	feature := features[0]
+	if feature.geomType != 3 || !feature.closed || len(feature.points) < 32 {
+		t.Fatalf("expected closed polygon, got type %d with %d points", feature.geomType, len(feature.points))
+	}
+	if feature.attrs["id"] != "storm" || feature.attrs["title"] != "Storm" || feature.attrs["severity"] != "high" || feature.attrs["radius_meters"] != uint64(2000) {
+		t.Fatalf("unexpected attributes: %v", feature.attrs)
+	}
+
+	area := 0
+	for i := range feature.points {
+		j := (i + 1) % len(feature.points)
+		area += feature.points[i][0]*feature.points[j][1] - feature.points[j][0]*feature.points[i][1]
+	}
+	if area <= 0 {
+		t.Fatalf("expected clockwise exterior ring in tile coordinates, got area %d", area)
+	}
+	for _, p := range feature.points {
+		if p[0] < -64 || p[0] > 4096+64 || p[1] < -64 || p[1] > 4096+64 {
+			t.Fatalf("point %v outside of buffered tile", p)
+		}
+	}
+}
+
+func TestTileService_SmallZoneBecomesPointAtLowZoom(t *testing.T) {
+	service, _, _ := newTileService(tileIncidents())
+	tile, err := service.IncidentTile(context.Background(), domain.TileCoord{Z: 0, X: 0, Y: 0})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	layers := decodeMVT(t, tile)
+	if len(layers) != 1 || len(layers[0].features) != 2 {
+		t.Fatalf("expected both active zones on world tile, got %+v", layers)
+	}
+	for _, feature := range layers[0].features {
+		if feature.geomType != 1 || len(feature.points) != 1 {
+			t.Fatalf("expected point for sub-pixel zone, got %+v", feature)
+		}
+	}
+}
+
+func TestTileService_EmptyTileAndInvalidCoordinates(t *testing.T) {
+	service, _, _ := newTileService(tileIncidents())
+	tile, err := service.IncidentTile(context.Background(), domain.TileCoord{Z: 10, X: 0, Y: 0})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(tile) != 0 {
+		t.Fatalf("expected empty tile, got %d bytes", len(tile))
+	}
+
+	if _, err := service.IncidentTile(context.Background(), domain.TileCoord{Z: 2, X: 4, Y: 0}); err != svc.ErrInvalidTile {
+		t.Fatalf("expected ErrInvalidTile, got %v", err)
+	}
+}
+
+func TestTileService_CachesPerGeneration(t *testing.T) {
+	service, repo, tiles := newTileService(tileIncidents())
+	ctx := context.Background()
+
+	first, err := service.IncidentTile(ctx, moscowTile)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	second, err := service.IncidentTile(ctx, moscowTile)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if string(first) != string(second) || repo.listActiveCalls != 1 || tiles.setCalls != 1 {
+		t.Fatalf("expected cached tile on second request, got %d loads / %d sets", repo.listActiveCalls, tiles.setCalls)
+	}
+
+	// Изменение инцидентов увеличивает поколение — тайл отрисовывается заново
+	tiles.generation++
+	if _, err := service.IncidentTile(ctx, moscowTile); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if repo.listActiveCalls != 2 || tiles.setCalls != 2 {
+		t.Fatalf("expected re-render after invalidation, got %d loads / %d sets", repo.listActiveCalls, tiles.setCalls)
+	}
+}
+
+func TestTileHandler_PathValidationAndETag(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	service, _, _ := newTileService(tileIncidents())
+	r := gin.New()
+	r.GET("/tiles/:z/:x/:y", handler.NewTileHandler(service).Incidents)
+
+	for _, path := range []string{"/tiles/10/618/320.png", "/tiles/10/618/abc.mvt", "/tiles/23/0/0.mvt", "/tiles/1/2/0.mvt"} {
+		if rec := getFeed(r, path, nil); rec.Code != http.StatusBadRequest {
+			t.Fatalf("%s: expected 400, got %d", path, rec.Code)
+		}
+	}
+
+	rec := getFeed(r, "/tiles/10/618/320.mvt", nil)
+	if rec.Code != http.StatusOK || rec.Header().Get("Content-Type") != "application/vnd.mapbox-vector-tile" || rec.Body.Len() == 0 {
+		t.Fatalf("unexpected tile response: %d %v", rec.Code, rec.Header())
+	}
+	etag := rec.Header().Get("ETag")
+	if etag == "" {
+		t.Fatalf("expected ETag")
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/tiles/10/618/320.mvt", nil)
+	req.Header.Set("If-None-Match", etag)
+	notModified := httptest.NewRecorder()
+	r.ServeHTTP(notModified, req)
+	if notModified.Code != http.StatusNotModified || notModified.Body.Len() != 0 {
+		t.Fatalf("expected 304 for matching ETag, got %d", notModified.Code)
+	}
+}
@@ -0,0 +1,68 @@
+package unit
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	svc "github.com/ruslanuskembaev/geo-alerts-system/internal/service"
+)
+
+func TestTokenService_IssueAndVerify(t *testing.T) {
+	tokens := svc.NewTokenService("secret", time.Minute)
+
+	issued, err := tokens.Issue(context.Background(), "user-1")
+	if err != nil {
+		t.Fatalf("unexpected issue error: %v", err)
+	}
+	if issued.ExpiresAt.Before(time.Now()) {
+		t.Fatalf("expected expiry in the future")
+	}
+
+	claims, err := tokens.Verify(issued.Token)
+	if err != nil {
+		t.Fatalf("unexpected verify error: %v", err)
+	}
+	if claims.UserID != "user-1" {
+		t.Fatalf("expected token bound to user-1, got %s", claims.UserID)
+	}
+}
+
+func TestTokenService_RejectsTamperedAndExpired(t *testing.T) {
+	tokens := svc.NewTokenService("secret", time.Minute)
+	issued, err := tokens.Issue(context.Background(), "user-1")
+	if err != nil {
+		t.Fatalf("unexpected issue error: %v", err)
+	}
+
+	payload, signature, _ := strings.Cut(issued.Token, ".")
+	forged, err := svc.NewTokenService("other-secret", time.Minute).Issue(context.Background(), "user-2")
+	if err != nil {
+		t.Fatalf("unexpected issue error: %v", err)
+	}
+	forgedPayload, _, _ := strings.Cut(forged.Token, ".")
+
+	if _, err := tokens.Verify(forgedPayload + "." + signature); !errors.Is(err, svc.ErrInvalidToken) {
+		t.Fatalf("expected invalid token for swapped payload, got %v", err)
+	}
+	if _, err := tokens.Verify(payload); !errors.Is(err, svc.ErrInvalidToken) {
+		t.Fatalf("expected invalid token without signature, got %v", err)
+	}
+
+	expired, err := svc.NewTokenService("secret", -time.Minute).Issue(context.Background(), "user-1")
+	if err != nil {
+		t.Fatalf("unexpected issue error: %v", err)
+	}
+	if _, err := tokens.Verify(expired.Token); !errors.Is(err, svc.ErrTokenExpired) {
+		t.Fatalf("expected expired token error, got %v", err)
+	}
+}
+
+func TestTokenService_DisabledWithoutSecret(t *testing.T) {
+	tokens := svc.NewTokenService("", time.Minute)
+	if _, err := tokens.Issue(context.Background(), "user-1"); !errors.Is(err, svc.ErrTokensDisabled) {
+		t.Fatalf("expected tokens disabled error, got %v", err)
+	}
+}
@@ -0,0 +1,237 @@
+package unit
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ruslanuskembaev/geo-alerts-system/internal/domain"
+	svc "github.com/ruslanuskembaev/geo-alerts-system/internal/service"
+)
+
+var testAuditKey = domain.UserIDKey{ID: "audit", Secret: "audit-secret"}
+
+func userDataService(repo *fakeUserDataRepo, queue *fakeQueue, limiter *fakeRateLimiter) *svc.UserDataService {
+	privacy := domain.LocationPrivacy{Keys: []domain.UserIDKey{{ID: "k1", Secret: "secret"}}}
+	rateLimit := svc.NewRateLimitService(limiter, 10, 10, time.Minute)
+	return svc.NewUserDataService(repo, queue, rateLimit, privacy, testAuditKey)
+}
+
+func queuedJob(tenantID, userID string) domain.WebhookJob {
+	return domain.WebhookJob{TenantID: tenantID, Payload: domain.WebhookPayload{CheckID: "check-" + userID, UserID: userID}}
+}
+
+func TestUserDataService_ExportLooksUpPseudonyms(t *testing.T) {
+	repo := &fakeUserDataRepo{
+		checks:     []domain.UserCheck{{ID: "check-1"}},
+		deliveries: []domain.WebhookDelivery{{CheckID: "check-1", Status: domain.WebhookDelivered}},
+		outbox:     []domain.WebhookJob{queuedJob("city-a", "user-1")},
+	}
+	retry := queuedJob("city-a", "user-1")
+	retry.Attempt = 1
+	queue := &fakeQueue{
+		enqueued: []domain.WebhookJob{
+			queuedJob("city-a", "user-1"),
+			queuedJob("city-a", "user-2"),
+			queuedJob("city-b", "user-1"),
+		},
+		retried: []domain.WebhookJob{retry},
+	}
+	service := userDataService(repo, queue, &fakeRateLimiter{})
+
+	export, err := service.Export(domain.WithTenant(context.Background(), "city-a"), "user-1")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	pseudonym := domain.PseudonymizeUserID(domain.UserIDKey{ID: "k1", Secret: "secret"}, "city-a", "user-1")
+	if len(repo.exportIDs) != 2 || repo.exportIDs[0] != "user-1" || repo.exportIDs[1] != pseudonym {
+		t.Fatalf("expected raw id and pseudonym, got %v", repo.exportIDs)
+	}
+	if export.TenantID != "city-a" || len(export.Checks) != 1 || len(export.WebhookDeliveries) != 1 {
+		t.Fatalf("unexpected export: %+v", export)
+	}
+	// Outbox, очередь и отложенный повтор — всё, что удаляет Erase
+	if len(export.QueuedWebhooks) != 3 || export.QueuedWebhooks[2].Attempt != 1 {
+		t.Fatalf("expected outbox, queued and retry jobs of the tenant's user, got %+v", export.QueuedWebhooks)
+	}
+	for _, job := range export.QueuedWebhooks {
+		if job.TenantID != "city-a" || job.Payload.UserID != "user-1" {
+			t.Fatalf("expected only tenant's jobs of the user, got %+v", export.QueuedWebhooks)
+		}
+	}
+}
+
+func TestUserDataService_EraseClearsRedisBeforePostgres(t *testing.T) {
+	queue := &fakeQueue{enqueued: []domain.WebhookJob{
+		queuedJob("city-a", "user-1"),
+		queuedJob("city-a", "user-2"),
+	}}
+	limiter := &fakeRateLimiter{}
+	repo := &fakeUserDataRepo{checks: []domain.UserCheck{{ID: "check-1"}, {ID: "check-2"}}}
+	repo.eraseFn = func(ctx context.Context, userIDs []string, erasure *domain.UserErasure) error {
+		if erasure.QueuedWebhooks != 1 || erasure.RateLimitKeys != 1 {
+			t.Fatalf("expected redis state cleared before audit record, got %+v", erasure)
+		}
+		erasure.Checks = 2
+		return nil
+	}
+	service := userDataService(repo, queue, limiter)
+
+	erasure, err := service.Erase(domain.WithTenant(context.Background(), "city-a"), "user-1")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if erasure.UserID != "user-1" || erasure.Checks != 2 || len(repo.erased) != 2 {
+		t.Fatalf("unexpected erasure: %+v (ids %v)", erasure, repo.erased)
+	}
+	if len(queue.enqueued) != 1 || queue.enqueued[0].Payload.UserID != "user-2" {
+		t.Fatalf("expected other users' jobs kept, got %+v", queue.enqueued)
+	}
+	if len(limiter.reset) != 1 || limiter.reset[0] != "user:city-a:user-1" {
+		t.Fatalf("expected user rate limit reset, got %v", limiter.reset)
+	}
+}
+
+func TestUserDataService_EraseSweepsJobsRelayedDuringErase(t *testing.T) {
+	queue := &fakeQueue{}
+	repo := &fakeUserDataRepo{}
+	repo.eraseFn = func(ctx context.Context, userIDs []string, erasure *domain.UserErasure) error {
+		// Ретранслятор outbox успел передать задачу, пока удаление ждало блокировки
+		queue.enqueued = append(queue.enqueued, queuedJob("city-a", "user-1"))
+		return nil
+	}
+	service := userDataService(repo, queue, &fakeRateLimiter{})
+
+	erasure, err := service.Erase(domain.WithTenant(context.Background(), "city-a"), "user-1")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(queue.enqueued) != 0 || erasure.QueuedWebhooks != 1 {
+		t.Fatalf("expected relayed job removed and counted, got %+v / %+v", queue.enqueued, erasure)
+	}
+}
+
+func TestUserDataService_EraseFailure(t *testing.T) {
+	repo := &fakeUserDataRepo{eraseFn: func(context.Context, []string, *domain.UserErasure) error {
+		return errors.New("db down")
+	}}
+	service := userDataService(repo, &fakeQueue{}, &fakeRateLimiter{})
+
+	if _, err := service.Erase(context.Background(), "user-1"); err == nil {
+		t.Fatalf("expected error")
+	}
+}
+
+func TestWebhookWorker_RecordsDeliveries(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	jobs := []domain.WebhookJob{
+		{TenantID: "city-a", Payload: domain.WebhookPayload{CheckID: "check-1"}},
+	}
+	queue := &fakeQueue{}
+	queue.dequeueFn = func(context.Context, time.Duration) (*domain.WebhookJob, bool, error) {
+		if len(jobs) == 0 {
+			cancel()
+			return nil, false, nil
+		}
+		job := jobs[0]
+		jobs = jobs[1:]
+		return &job, true, nil
+	}
+
+	// Недоступный URL: попытка завершается ошибкой и ставится на повтор
+	sender := svc.NewWebhookSender("http://127.0.0.1:1", nil, time.Second)
+	deliveries := &fakeDeliveryRepo{}
+	worker := svc.NewWebhookWorker(queue, sender, 3, time.Hour).WithDeliveryLog(deliveries)
+	worker.Start(ctx)
+
+	if len(deliveries.recorded) != 1 {
+		t.Fatalf("expected one recorded attempt, got %+v", deliveries.recorded)
+	}
+	got := deliveries.recorded[0]
+	if got.CheckID != "check-1" || got.TenantID != "city-a" || got.Attempt != 1 ||
+		got.Status != domain.WebhookRetrying || got.Error == "" {
+		t.Fatalf("unexpected delivery record: %+v", got)
+	}
+}
+
+func TestWebhookWorker_RetryIsErasable(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	jobs := []domain.WebhookJob{queuedJob("city-a", "user-1")}
+	queue := &fakeQueue{}
+	queue.dequeueFn = func(context.Context, time.Duration) (*domain.WebhookJob, bool, error) {
+		if len(jobs) == 0 {
+			cancel()
+			return nil, false, nil
+		}
+		job := jobs[0]
+		jobs = jobs[1:]
+		return &job, true, nil
+	}
+
+	sender := svc.NewWebhookSender("http://127.0.0.1:1", nil, time.Second)
+	started := time.Now()
+	svc.NewWebhookWorker(queue, sender, 3, time.Hour).Start(ctx)
+
+	// Повтор отложен в очереди, а не в памяти воркера
+	if len(queue.retried) != 1 || queue.retried[0].Attempt != 1 || len(queue.enqueued) != 0 {
+		t.Fatalf("expected one delayed retry, got retried %+v / enqueued %+v", queue.retried, queue.enqueued)
+	}
+	if delay := queue.retryAt[0].Sub(started); delay < time.Hour || delay > time.Hour+time.Minute {
+		t.Fatalf("expected retry in about an hour, got %s", delay)
+	}
+
+	erasure, err := userDataService(&fakeUserDataRepo{}, queue, &fakeRateLimiter{}).
+		Erase(domain.WithTenant(context.Background(), "city-a"), "user-1")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(queue.retried) != 0 || erasure.QueuedWebhooks != 1 {
+		t.Fatalf("expected delayed retry erased, got %+v / %+v", queue.retried, erasure)
+	}
+}
+
+func TestUserDataService_EraseAuditsHashedUserID(t *testing.T) {
+	var audited []string
+	repo := &fakeUserDataRepo{eraseFn: func(ctx context.Context, userIDs []string, erasure *domain.UserErasure) error {
+		audited = append(audited, erasure.UserIDHash)
+		return nil
+	}}
+	ctx := domain.WithTenant(context.Background(), "city-a")
+
+	// Реплики с одним ключом дают один хеш: запись пользователя находится по нему
+	for i := 0; i < 2; i++ {
+		erasure, err := userDataService(repo, &fakeQueue{}, &fakeRateLimiter{}).Erase(ctx, "user-1")
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if erasure.UserID != "user-1" {
+			t.Fatalf("expected raw id in the response, got %+v", erasure)
+		}
+	}
+	want := domain.PseudonymizeUserID(testAuditKey, "city-a", "user-1")
+	if len(audited) != 2 || audited[0] != want || audited[1] != want {
+		t.Fatalf("expected hash under audit key %q, got %v", want, audited)
+	}
+	if strings.Contains(want, "user-1") {
+		t.Fatalf("expected keyed hash without raw id, got %s", want)
+	}
+}
+
+func TestUserIDKey_ValidateRequiresIDAndSecret(t *testing.T) {
+	for _, key := range []domain.UserIDKey{{}, {ID: "audit"}, {Secret: "secret"}} {
+		if err := key.Validate(); err == nil {
+			t.Fatalf("expected %+v rejected", key)
+		}
+	}
+	if err := testAuditKey.Validate(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
@@ -0,0 +1,123 @@
+package unit
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/ruslanuskembaev/geo-alerts-system/internal/domain"
+	"github.com/ruslanuskembaev/geo-alerts-system/internal/handler"
+	"github.com/ruslanuskembaev/geo-alerts-system/internal/repository"
+	svc "github.com/ruslanuskembaev/geo-alerts-system/internal/service"
+)
+
+func newHistoryRouter(incidentRepo *fakeIncidentRepo, checkRepo *fakeCheckRepo) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	privacy := domain.LocationPrivacy{Keys: []domain.UserIDKey{{ID: "k1", Secret: "secret"}}}
+	h := handler.NewLocationHistoryHandler(svc.NewLocationHistoryService(incidentRepo, checkRepo, privacy))
+
+	r := gin.New()
+	r.GET("/users/:user_id/checks", h.UserChecks)
+	r.GET("/incidents/:id/users", h.IncidentUsers)
+	return r
+}
+
+func TestLocationHistoryHandler_UserChecks(t *testing.T) {
+	var gotIDs []string
+	var gotFilter domain.UserChecksFilter
+	var gotPage domain.PageRequest
+	checkRepo := &fakeCheckRepo{
+		listByUserFn: func(ctx context.Context, userIDs []string, filter domain.UserChecksFilter, page domain.PageRequest) ([]domain.UserCheck, domain.PageInfo, error) {
+			gotIDs, gotFilter, gotPage = userIDs, filter, page
+			return []domain.UserCheck{{ID: "check-1", IsInDangerZone: true}}, domain.PageInfo{NextCursor: "next"}, nil
+		},
+	}
+	r := newHistoryRouter(&fakeIncidentRepo{}, checkRepo)
+
+	rec := getFeed(r, "/users/user-1/checks?from=2026-10-01T00:00:00Z&to=2026-10-02T00:00:00Z&danger_only=true&page_size=5", nil)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	pseudonym := domain.PseudonymizeUserID(domain.UserIDKey{ID: "k1", Secret: "secret"}, domain.DefaultTenantID, "user-1")
+	if len(gotIDs) != 2 || gotIDs[0] != "user-1" || gotIDs[1] != pseudonym {
+		t.Fatalf("expected raw id and pseudonym, got %v", gotIDs)
+	}
+	if !gotFilter.DangerOnly || gotFilter.From == nil || gotFilter.To == nil || gotPage.Limit != 5 {
+		t.Fatalf("unexpected filter %+v / page %+v", gotFilter, gotPage)
+	}
+
+	var body struct {
+		Checks     []domain.UserCheck `json:"checks"`
+		NextCursor string             `json:"next_cursor"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+		t.Fatalf("decode failed: %v", err)
+	}
+	if len(body.Checks) != 1 || body.NextCursor != "next" {
+		t.Fatalf("unexpected body: %s", rec.Body.String())
+	}
+}
+
+func TestLocationHistoryHandler_UserChecksInvalidFilter(t *testing.T) {
+	r := newHistoryRouter(&fakeIncidentRepo{}, &fakeCheckRepo{})
+
+	for _, path := range []string{
+		"/users/user-1/checks?from=yesterday",
+		"/users/user-1/checks?from=2026-10-02T00:00:00Z&to=2026-10-01T00:00:00Z",
+		"/users/user-1/checks?danger_only=maybe",
+	} {
+		if rec := getFeed(r, path, nil); rec.Code != http.StatusBadRequest {
+			t.Fatalf("%s: expected 400, got %d", path, rec.Code)
+		}
+	}
+}
+
+func TestLocationHistoryHandler_IncidentUsers(t *testing.T) {
+	var gotQuery domain.IncidentUsersQuery
+	incidentRepo := &fakeIncidentRepo{
+		getByIDFn: func(ctx context.Context, id string) (*domain.Incident, error) {
+			if id != "incident-1" {
+				return nil, repository.ErrNotFound
+			}
+			return &domain.Incident{ID: id}, nil
+		},
+	}
+	checkRepo := &fakeCheckRepo{
+		incidentUsersFn: func(ctx context.Context, incidentID string, query domain.IncidentUsersQuery, page domain.PageRequest) ([]domain.IncidentUser, domain.PageInfo, error) {
+			gotQuery = query
+			return []domain.IncidentUser{{UserID: "user-1", Checks: 3}}, domain.PageInfo{}, nil
+		},
+	}
+	r := newHistoryRouter(incidentRepo, checkRepo)
+
+	rec := getFeed(r, "/incidents/incident-1/users?until=2026-10-19T12:00:00Z", nil)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	until := time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC)
+	if !gotQuery.Until.Equal(until) || !gotQuery.Since.Equal(until.Add(-domain.DefaultIncidentUsersWindow)) {
+		t.Fatalf("expected default 24h window, got %+v", gotQuery)
+	}
+
+	var body struct {
+		Users []domain.IncidentUser `json:"users"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+		t.Fatalf("decode failed: %v", err)
+	}
+	if len(body.Users) != 1 || body.Users[0].Checks != 3 {
+		t.Fatalf("unexpected body: %s", rec.Body.String())
+	}
+
+	if rec := getFeed(r, "/incidents/missing/users", nil); rec.Code != http.StatusNotFound {
+		t.Fatalf("expected 404, got %d", rec.Code)
+	}
+	if rec := getFeed(r, "/incidents/incident-1/users?since=2026-01-01T00:00:00Z&until=2026-10-01T00:00:00Z", nil); rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for too long interval, got %d", rec.Code)
+	}
+}
@@ -49,7 +49,7 @@ func TestWebhookSender_Send_OK(t *testing.T) {
 	}))
 	defer server.Close()
 
-	sender := svc.NewWebhookSender(server.URL, 2*time.Second)
+	sender := svc.NewWebhookSender(server.URL, nil, 2*time.Second)
 	if err := sender.Send(context.Background(), expected); err != nil {
 		t.Fatalf("unexpected send error: %v", err)
 	}
@@ -61,8 +61,35 @@ func TestWebhookSender_Send_ErrorStatus(t *testing.T) {
 	}))
 	defer server.Close()
 
-	sender := svc.NewWebhookSender(server.URL, 2*time.Second)
+	sender := svc.NewWebhookSender(server.URL, nil, 2*time.Second)
 	if err := sender.Send(context.Background(), domain.WebhookPayload{}); err == nil {
 		t.Fatalf("expected error on non-2xx response")
 	}
 }
+
+func TestWebhookSender_Send_TenantURL(t *testing.T) {
+	defaultHits, tenantHits := 0, 0
+	defaultServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		defaultHits++
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer defaultServer.Close()
+	tenantServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		tenantHits++
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer tenantServer.Close()
+
+	sender := svc.NewWebhookSender(defaultServer.URL, map[string]string{"city-a": tenantServer.URL}, 2*time.Second)
+
+	if err := sender.Send(domain.WithTenant(context.Background(), "city-a"), domain.WebhookPayload{}); err != nil {
+		t.Fatalf("unexpected send error: %v", err)
+	}
+	if err := sender.Send(domain.WithTenant(context.Background(), "city-b"), domain.WebhookPayload{}); err != nil {
+		t.Fatalf("unexpected send error: %v", err)
+	}
+
+	if tenantHits != 1 || defaultHits != 1 {
+		t.Fatalf("expected one delivery per URL, got tenant=%d default=%d", tenantHits, defaultHits)
+	}
+}